@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/cache"
 	"geocoding-api/internal/config"
 	"geocoding-api/internal/handler"
 	"geocoding-api/internal/repository"
 	"geocoding-api/internal/service"
+	"geocoding-api/internal/stats"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	files "github.com/swaggo/files"
@@ -32,29 +41,227 @@ import (
 // @host localhost:8080
 // @BasePath /
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description "Bearer " followed by the configured ADMIN_TOKEN
+
 func main() {
-	config, err := config.LoadConfig(filepath.Join(".", "configs"))
+	// Log through the global logger when a context carries no request-scoped one
+	zerolog.DefaultContextLogger = &log.Logger
+
+	cfg, err := config.LoadConfig(filepath.Join(".", "configs"))
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot load config")
 	}
 
-	// Database connection
-	conn, err := pgxpool.New(context.Background(), config.DBSource)
+	// Database connection; the DSN's password is redacted wherever it could be logged
+	log.Info().Str("db", config.RedactDSN(cfg.DBSource)).Msg("connecting to database")
+	conn, err := pgxpool.New(context.Background(), cfg.DBSource)
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot connect to db")
+		log.Fatal().Err(config.RedactError(err, cfg.DBSource)).Msg("cannot connect to db")
 	}
 	defer conn.Close()
 
+	// The pool connects lazily, so wait here for a database that is still starting up
+	// instead of failing on the first query
+	err = repository.WaitForDatabase(context.Background(), conn, repository.ConnectRetry{Timeout: cfg.DBConnectTimeout},
+		func(attempt int, wait time.Duration, err error) {
+			log.Warn().Err(config.RedactError(err, cfg.DBSource)).Int("attempt", attempt).Dur("retry_in", wait).Msg("database not reachable, retrying")
+		})
+	if err != nil {
+		log.Fatal().Err(config.RedactError(err, cfg.DBSource)).Dur("timeout", cfg.DBConnectTimeout).Msg("cannot connect to db")
+	}
+	log.Info().Msg("connected to database")
+
+	// Make sure full-text search won't fail on a missing text search configuration
+	textSearchConfig, fellBack, err := repository.ResolveTextSearchConfig(context.Background(), conn, cfg.TextSearchConfig, cfg.TextSearchFallback)
+	switch {
+	case errors.Is(err, repository.ErrTextSearchConfigMissing):
+		log.Fatal().Err(config.RedactError(err, cfg.DBSource)).Msg("cannot use text search configuration")
+	case err != nil:
+		textSearchConfig = cfg.TextSearchConfig
+		log.Error().Err(config.RedactError(err, cfg.DBSource)).Str("text_search_config", textSearchConfig).Msg("cannot verify text search configuration, using it as configured")
+	case fellBack:
+		log.Warn().Str("configured", cfg.TextSearchConfig).Str("using", textSearchConfig).Msg("text search configuration is not installed, falling back")
+	}
+
+	var retryConfigs []string
+	for _, name := range cfg.TextSearchRetryConfigs {
+		resolved, _, err := repository.ResolveTextSearchConfig(context.Background(), conn, name, false)
+		switch {
+		case errors.Is(err, repository.ErrTextSearchConfigMissing):
+			log.Warn().Str("text_search_config", name).Msg("retry text search configuration is not installed, skipping it")
+			continue
+		case err != nil:
+			log.Fatal().Err(config.RedactError(err, cfg.DBSource)).Str("text_search_config", name).Msg("cannot use retry text search configuration")
+		}
+		retryConfigs = append(retryConfigs, resolved)
+	}
+
+	dbBreaker := breaker.New(breaker.Config{
+		FailureThreshold: cfg.DBBreakerFailures,
+		Cooldown:         cfg.DBBreakerCooldown,
+		OnStateChange: func(from, to breaker.State) {
+			event := log.Info()
+			if to == breaker.Open {
+				event = log.Warn()
+			}
+			event.Str("from", from.String()).Str("to", to.String()).Msg("database circuit breaker state changed")
+		},
+	})
+
 	// Initialize layers
-	repo := repository.NewRepository(conn)
+	repo := repository.NewRepository(conn, repository.Config{
+		TextSearchConfig:       textSearchConfig,
+		TextSearchRetryConfigs: retryConfigs,
+		TextSearchMinResults:   cfg.GeocodeStrategyMinResults,
+		SlowQueryThreshold:     cfg.SlowQueryThreshold,
+		CaptureQueryPlans:      cfg.DebugQueryPlans,
+		MaxRadiusMeters:        cfg.MaxSpatialRadiusMeters,
+		StatementTimeout:       cfg.StatementTimeout,
+		Breaker:                dbBreaker,
+	})
+
+	strategies, err := service.NewSearchStrategies(cfg.GeocodeStrategies, repo)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid GEOCODE_STRATEGIES")
+	}
+
+	counters := stats.New()
+	counters.SetBreaker(dbBreaker)
+	geoCodeCacheConfig := service.GeoCodeConfig{
+		CacheTTL:                cfg.GeocodeCacheTTL,
+		CacheSize:               cfg.GeocodeCacheSize,
+		Stats:                   counters,
+		Strategies:              strategies,
+		MinStrategyResults:      cfg.GeocodeStrategyMinResults,
+		DisambiguationThreshold: cfg.DisambiguationThreshold,
+		SnapshotTTL:             cfg.PaginationSnapshotTTL,
+		SnapshotSize:            cfg.PaginationSnapshotSize,
+		DensityRadius:           cfg.DensityRadiusMeters,
+		MaxDensityResults:       cfg.DensityMaxResults,
+	}
+	if cfg.RedisURL != "" && cfg.GeocodeCacheTTL > 0 {
+		redisCache, err := cache.NewRedisCache(cfg.RedisURL, cache.RedisConfig{
+			TTL:     cfg.GeocodeCacheTTL,
+			Timeout: cfg.RedisTimeout,
+		})
+		if err != nil {
+			log.Fatal().Err(config.RedactError(err, cfg.RedisURL)).Msg("cannot configure redis cache")
+		}
+		defer redisCache.Close()
+		geoCodeCacheConfig.Cache = redisCache
+	}
+	geoCodeService := service.NewGeoCodeService(repo, geoCodeCacheConfig)
+	reverseGeocodeService := service.NewReverseGeoCodeService(repo, service.SpatialConfig{
+		MaxRadiusMeters:        cfg.MaxSpatialRadiusMeters,
+		AddressRanges:          cfg.AddressRanges,
+		ColocationMeters:       cfg.ColocationMeters,
+		MunicipalityBoundaries: cfg.MunicipalityBoundaries,
+		TieMeters:              cfg.ReverseTieMeters,
+	})
+	areaService := service.NewAreaService(repo, service.SpatialConfig{MaxRadiusMeters: cfg.MaxSpatialRadiusMeters})
+	locationService := service.NewLocationService(repo)
+	healthService := service.NewHealthService(repo)
+	freshnessService := service.NewFreshnessService(repo)
+	distanceService := service.NewDistanceService(repo)
+	autocompleteService := service.NewAutocompleteService(repo)
+	exportService := service.NewExportService(repo)
+	roundTripService := service.NewRoundTripService(geoCodeService, reverseGeocodeService)
+	batchService := service.NewBatchService(repo, geoCodeService, service.BatchConfig{
+		PollInterval: cfg.BatchPollInterval,
+		StaleAfter:   cfg.BatchStaleAfter,
+	})
 
-	geoCodeService := service.NewGeoCodeService(repo)
-	reverseGeocodeService := service.NewReverseGeoCodeService(repo)
+	// Surface a missing PostGIS up front instead of as errors on every spatial query;
+	// /readyz keeps reporting it until the extension is installed
+	postgisVersion, err := healthService.CheckPostGIS(context.Background())
+	if err != nil {
+		log.Error().Err(config.RedactError(err, cfg.DBSource)).Msg("database is missing a usable PostGIS extension, /readyz will fail")
+	} else {
+		log.Info().Str("postgis_version", postgisVersion).Msg("PostGIS extension detected")
+	}
 
-	geoCodeHandler := handler.NewGeoCodeHandler(geoCodeService)
+	// Best effort: a failed warmup only means the first spatial queries are slower
+	if cfg.SpatialWarmup {
+		warmupCtx, cancel := context.Background(), context.CancelFunc(func() {})
+		if cfg.SpatialWarmupTimeout > 0 {
+			warmupCtx, cancel = context.WithTimeout(warmupCtx, cfg.SpatialWarmupTimeout)
+		}
+		start := time.Now()
+		count, err := healthService.WarmSpatialIndex(warmupCtx, cfg.SpatialWarmupLat, cfg.SpatialWarmupLon)
+		cancel()
+		if err != nil {
+			log.Warn().Err(config.RedactError(err, cfg.DBSource)).Dur("duration", time.Since(start)).Msg("spatial index warmup failed")
+		} else {
+			log.Info().Int("locations", count).Dur("duration", time.Since(start)).Msg("spatial index warmed up")
+		}
+	}
+
+	// Batch jobs are persisted, so any instance's workers can run jobs submitted to another
+	for i := 0; i < cfg.BatchWorkers; i++ {
+		go batchService.RunWorker(context.Background())
+	}
+
+	// Compile the blocklist once; an invalid pattern is a configuration error
+	blockedQueries, err := cfg.BlockedQueries()
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot compile blocked query patterns")
+	}
+	blocklist := handler.NewQueryBlocklist(blockedQueries)
+
+	apiKeys, err := cfg.APIKeyQuotas()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid API_KEYS")
+	}
+	// Open deployments list no keys and skip the key check and quotas entirely
+	var apiMiddleware []gin.HandlerFunc
+	if len(apiKeys) > 0 {
+		apiMiddleware = append(apiMiddleware, handler.APIKeyQuota(service.NewQuotaService(repo, apiKeys)))
+		log.Info().Int("api_keys", len(apiKeys)).Msg("requiring API keys")
+	}
+	reloadOnSIGHUP(blocklist)
+
+	formatMaxLimits, err := cfg.GeocodeFormatMaxLimits()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid GEOCODE_FORMAT_LIMITS")
+	}
+
+	geoCodeConfig := handler.GeoCodeConfig{
+		MinQueryLength:     cfg.MinQueryLength,
+		MaxQueryTerms:      cfg.MaxQueryTerms,
+		BlockedQueries:     blocklist,
+		NormalizeAddresses: cfg.AddressNormalization,
+		StripBuildingNames: cfg.StripBuildingNames,
+		RejectControlChars: cfg.RejectControlChars,
+		QueryPlans:         cfg.DebugQueryPlans,
+		FormatMaxLimits:    formatMaxLimits,
+	}
+	geoCodeHandler := handler.NewGeoCodeHandler(geoCodeService, geoCodeConfig)
+	batchHandler := handler.NewBatchHandler(batchService, geoCodeConfig)
 	reverseGeocodeHandler := handler.NewReverseGeocodeHandler(reverseGeocodeService)
+	locationHandler := handler.NewLocationHandler(locationService)
+	areaHandler := handler.NewAreaHandler(areaService, handler.AreaConfig{
+		NearbyDefaultLimit: cfg.NearbyDefaultLimit,
+		NearbyMaxLimit:     cfg.NearbyMaxLimit,
+		WithinDefaultLimit: cfg.WithinDefaultLimit,
+		WithinMaxLimit:     cfg.WithinMaxLimit,
+	})
+	healthHandler := handler.NewHealthHandler(healthService)
+	freshnessHandler := handler.NewFreshnessHandler(freshnessService)
+	distanceHandler := handler.NewDistanceHandler(distanceService)
+	autocompleteHandler := handler.NewAutocompleteHandler(autocompleteService)
+	roundTripHandler := handler.NewRoundTripHandler(roundTripService, geoCodeConfig)
+	statsHandler := handler.NewStatsHandler(counters)
+	exportHandler := handler.NewExportHandler(exportService, handler.ExportConfig{FlushEvery: cfg.ExportFlushEvery})
 
 	r := gin.Default()
+	r.Use(handler.RequestID())
+	r.Use(handler.Stats(counters))
+	r.Use(handler.SecurityHeaders(cfg.FrameOptions))
+	r.Use(handler.Language(cfg.DefaultLanguage))
+	r.NoRoute(handler.NotFound)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -62,11 +269,60 @@ func main() {
 		})
 	})
 
-	r.GET("/geocode", geoCodeHandler.GeoCode)
-	r.GET("/reverse-geocode", reverseGeocodeHandler.ReverseGeocode)
+	r.GET("/readyz", healthHandler.Readyz)
+
+	timeouts := cfg.Timeouts()
+	api := r.Group("/", apiMiddleware...)
+	api.GET("/geocode", handler.Timeout(timeouts.Geocode), geoCodeHandler.GeoCode)
+	api.GET("/reverse-geocode", handler.Timeout(timeouts.ReverseGeocode), reverseGeocodeHandler.ReverseGeocode)
+	api.GET("/autocomplete", handler.Timeout(cfg.QueryTimeout), autocompleteHandler.Autocomplete)
+	api.GET("/locations", handler.Timeout(timeouts.Locations), locationHandler.GetLocations)
+	api.GET("/locations/in", handler.Timeout(timeouts.Locations), locationHandler.GetLocationsInArea)
+	api.GET("/nearby", handler.Timeout(timeouts.ReverseGeocode), areaHandler.Nearby)
+	api.GET("/within", handler.Timeout(timeouts.Locations), areaHandler.Within)
+	api.GET("/data/freshness", handler.Timeout(cfg.QueryTimeout), freshnessHandler.Freshness)
+	api.POST("/distance-matrix", handler.Timeout(timeouts.DistanceMatrix), distanceHandler.DistanceMatrix)
+	api.POST("/geocode/batch", handler.Timeout(cfg.QueryTimeout), batchHandler.Batch)
+	api.GET("/jobs/:id", handler.Timeout(cfg.QueryTimeout), batchHandler.BatchJob)
+	api.GET("/jobs/:id/results", handler.Timeout(cfg.QueryTimeout), batchHandler.BatchJobResults)
+
+	// Admin diagnostics, only served with ADMIN_TOKEN set
+	if cfg.AdminToken != "" {
+		admin := r.Group("/validate", handler.AdminOnly(cfg.AdminToken))
+		admin.GET("/roundtrip", handler.Timeout(cfg.QueryTimeout), roundTripHandler.RoundTrip)
+
+		adminStats := r.Group("/admin/stats", handler.AdminOnly(cfg.AdminToken))
+		adminStats.GET("/runtime", statsHandler.Runtime)
+
+		// No timeout: the export runs as long as the client keeps reading
+		r.GET("/admin/export", handler.AdminOnly(cfg.AdminToken), exportHandler.Export)
+	}
 
 	// Swagger UI route
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(files.Handler))
 
-	r.Run(config.ServerAddress)
+	r.Run(cfg.ServerAddress)
+}
+
+// reloadOnSIGHUP re-reads the configuration whenever the process receives SIGHUP and swaps in
+// the recompiled blocked query patterns. An invalid pattern keeps the previous list in place.
+func reloadOnSIGHUP(blocklist *handler.QueryBlocklist) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			cfg, err := config.LoadConfig(filepath.Join(".", "configs"))
+			if err != nil {
+				log.Error().Err(err).Msg("cannot reload config, keeping the current one")
+				continue
+			}
+			patterns, err := cfg.BlockedQueries()
+			if err != nil {
+				log.Error().Err(err).Msg("cannot compile blocked query patterns, keeping the current ones")
+				continue
+			}
+			blocklist.Store(patterns)
+			log.Info().Int("blocked_query_patterns", len(patterns)).Msg("reloaded blocked query patterns")
+		}
+	}()
 }
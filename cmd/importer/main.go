@@ -1,18 +1,78 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"geocoding-api/internal/config"
+	"geocoding-api/internal/normalize"
+	"geocoding-api/internal/repository"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// utf8BOM is the byte order mark some spreadsheet exports prepend to UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// tableNamePattern restricts target tables to plain lowercase identifiers.
+var tableNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Bounding box of Japan including its outlying islands, used to sanity check coordinates.
+const (
+	japanMinLat = 20.0  // Okinotorishima
+	japanMaxLat = 46.0  // northern Hokkaido
+	japanMinLon = 122.0 // Yonaguni
+	japanMaxLon = 154.0 // Minamitorishima
+)
+
+// prefectureCodes maps each prefecture to its JIS X 0401 code, which names its partition of a
+// --partition-by-prefecture table.
+var prefectureCodes = map[string]string{
+	"北海道": "01", "青森県": "02", "岩手県": "03", "宮城県": "04", "秋田県": "05", "山形県": "06", "福島県": "07",
+	"茨城県": "08", "栃木県": "09", "群馬県": "10", "埼玉県": "11", "千葉県": "12", "東京都": "13", "神奈川県": "14",
+	"新潟県": "15", "富山県": "16", "石川県": "17", "福井県": "18", "山梨県": "19", "長野県": "20", "岐阜県": "21",
+	"静岡県": "22", "愛知県": "23", "三重県": "24", "滋賀県": "25", "京都府": "26", "大阪府": "27", "兵庫県": "28",
+	"奈良県": "29", "和歌山県": "30", "鳥取県": "31", "島根県": "32", "岡山県": "33", "広島県": "34", "山口県": "35",
+	"徳島県": "36", "香川県": "37", "愛媛県": "38", "高知県": "39", "福岡県": "40", "佐賀県": "41", "長崎県": "42",
+	"熊本県": "43", "大分県": "44", "宮崎県": "45", "鹿児島県": "46", "沖縄県": "47",
+}
+
+// importedFile is a file loaded during a --swap import, recorded as processed once the swap commits.
+type importedFile struct {
+	path        string
+	recordCount int
+}
+
+// rowError is an invalid row skipped during parsing, written to the --error-file.
+type rowError struct {
+	file   string
+	line   int
+	record []string
+	reason string
+}
+
+// errorFileColumns heads the --error-file CSV: the import columns first, so corrected rows can be
+// imported again as they are, then where each row came from and why it was skipped.
+var errorFileColumns = []string{
+	"都道府県名", "市区町村名", "大字_丁目名", "小字_通称名", "街区符号_地番", "座標系番号", "Ｘ座標", "Ｙ座標", "住居表示フラグ", "緯度", "経度",
+	"source_file", "line", "reason",
+}
+
 type LocationRecord struct {
 	Prefecture   string
 	Municipality string
@@ -21,13 +81,114 @@ type LocationRecord struct {
 	BlockLot     string
 	Lat          float64
 	Lon          float64
+	// ExternalID is the dataset's own ID for the address, empty when it has none
+	ExternalID string
+	// Importance ranks the address for /geocode?order_by=importance, e.g. its area's
+	// population; it is NULL when the file has no importance column or the field is empty
+	Importance sql.NullFloat64
+	// Altitude is the address's elevation in meters, stored as the Z of its point (see geom);
+	// it is only set when importing with --altitude-column, which requires it on every row
+	Altitude sql.NullFloat64
+	// NormalizedAddress is the full address's normalize.SearchKey, set by --normalized-key;
+	// it is NULL when empty
+	NormalizedAddress string
+	// SearchText is the text full_address_tsvector is generated from in a table created with
+	// --app-search-text (see searchText); it is NULL when empty
+	SearchText string
+	// PrefectureKana, MunicipalityKana, Address1Kana and Address2Kana are the kana readings of
+	// the address parts from the file's kana columns (see kanaColumns); each is NULL when empty
+	PrefectureKana   string
+	MunicipalityKana string
+	Address1Kana     string
+	Address2Kana     string
+	// KanaKey is the readings' normalize.Kana key, matched by the "kana" search strategy; it is
+	// NULL when the record has no readings
+	KanaKey string
+}
+
+// importance returns the record's importance as a COPY value, nil for NULL
+func (r LocationRecord) importance() any {
+	if !r.Importance.Valid {
+		return nil
+	}
+	return r.Importance.Float64
 }
 
+// geom returns the record's point as a COPY value in PostGIS's EWKT format (lon lat), with
+// its altitude as the Z coordinate when it has one
+func (r LocationRecord) geom() string {
+	if r.Altitude.Valid {
+		return fmt.Sprintf("SRID=4326;POINT Z(%f %f %f)", r.Lon, r.Lat, r.Altitude.Float64)
+	}
+	return fmt.Sprintf("SRID=4326;POINT(%f %f)", r.Lon, r.Lat)
+}
+
+// normalizedAddress returns the record's search key as a COPY value, nil for NULL
+func (r LocationRecord) normalizedAddress() any {
+	if r.NormalizedAddress == "" {
+		return nil
+	}
+	return r.NormalizedAddress
+}
+
+// searchText returns the record's search text as a COPY value, nil for NULL
+func (r LocationRecord) searchText() any {
+	if r.SearchText == "" {
+		return nil
+	}
+	return r.SearchText
+}
+
+// kana returns the record's readings and their key as COPY values, in the order of kanaCopyColumns,
+// nil for NULL
+func (r LocationRecord) kana() []any {
+	values := []any{r.PrefectureKana, r.MunicipalityKana, r.Address1Kana, r.Address2Kana, r.KanaKey}
+	for i, v := range values {
+		if v == "" {
+			values[i] = nil
+		}
+	}
+	return values
+}
+
+// kanaCopyColumns are the columns LocationRecord.kana is copied into
+var kanaCopyColumns = []string{"prefecture_kana", "municipality_kana", "address_1_kana", "address_2_kana", "kana_key"}
+
 func main() {
-	file := flag.String("file", "", "Path to the CSV file to import")
-	directory := flag.String("directory", "", "Path to the directory containing CSV files to import")
+	file := flag.String("file", "", "Path to the CSV or TSV file to import")
+	directory := flag.String("directory", "", "Path to the directory containing CSV/TSV files to import")
+	table := flag.String("table", "locations", "Target table to load into; must be \"locations\" or listed in IMPORT_TABLES")
+	deferIndexes := flag.Bool("defer-indexes", false, "Drop the target table's indexes before loading and rebuild them afterwards (only allowed on an empty table)")
+	swap := flag.Bool("swap", false, "Load into a staging table and atomically swap it in for the target table (full reload; readers never see a partial table)")
+	fixSwapped := flag.Bool("fix-swapped-coords", false, "Swap lat/lon back for rows outside Japan whose swapped coordinates fall inside it (otherwise they are only reported)")
+	commitEvery := flag.Int("commit-every", 0, "Commit after every N records instead of loading each file in one transaction; a failed file keeps its committed batches and resumes from them on rerun (0 disables)")
+	checkRows := flag.Bool("check-rows", false, "After a directory import, also count the rows stored for each imported file (one scan of the target table) in the integrity check")
+	verify := flag.Bool("verify", true, "Check the target table's row count and a sample geometry after the import (default on; once at the end in directory mode, since the COUNT(*) scans the whole table)")
+	noVerify := flag.Bool("no-verify", false, "Skip the post-import verification; same as --verify=false")
+	analyze := flag.Bool("analyze", true, "Run ANALYZE on the target table after the import so the planner's statistics reflect the new rows (default on; --swap already analyzes the staging table)")
+	noAnalyze := flag.Bool("no-analyze", false, "Skip the post-import ANALYZE; same as --analyze=false")
+	vacuum := flag.Bool("vacuum", false, "Run VACUUM ANALYZE instead of ANALYZE after the import, also reclaiming space left by earlier loads")
+	errorFile := flag.String("error-file", "", "Skip invalid rows instead of failing their file, writing them with their source file, line and reason to this CSV for correction and re-import")
+	externalIDColumn := flag.String("external-id-column", "", "Column holding each address's stable ID in the source dataset, by header name or 1-based position; rows are upserted on it so re-imports update them in place (files without the column are imported without IDs)")
+	importanceColumn := flag.String("importance-column", "", "Column holding each address's importance (e.g. the population of its area), by header name or 1-based position; /geocode?order_by=importance ranks by it, putting addresses without one last")
+	altitudeColumn := flag.String("altitude-column", "", "Column holding each address's elevation in meters, by header name or 1-based position; it is stored as the Z of each point and returned as altitude. A new table then stores POINTZ points, so every row needs a value; an existing 2D table can't take them, and an existing 3D table requires the flag")
+	repairUTF8 := flag.Bool("repair-utf8", false, "Remove invalid UTF-8 byte sequences from fields instead of rejecting their rows (a rejected row fails its file unless --error-file is set)")
+	expectCount := flag.Int("expect-count", -1, "Fail unless exactly N valid records are parsed, catching truncated files; checked before loading with --file, and across all files parsed in this run (before any --swap) with --directory (negative disables)")
+	partitionByPrefecture := flag.Bool("partition-by-prefecture", false, "Create the target table partitioned by prefecture, adding each prefecture's partition as it first appears (new tables only; cannot be combined with --swap)")
+	outOfBounds := flag.String("out-of-bounds", outOfBoundsKeep, "What to do with records whose coordinates fall outside Japan (and aren't suspected lat/lon swaps): keep imports them, skip drops them, error fails their file; each file's report shows sample coordinates")
+	coordPrecision := flag.Int("coord-precision", -1, "Round lat/lon to N decimals and drop rows that become identical (6 ≈ 0.1m, 5 ≈ 1m, 4 ≈ 11m); negative disables")
+	appSearchText := flag.Bool("app-search-text", false, "Create the target table with full_address_tsvector generated from search_text, which the importer fills with address text normalized in Go (NFKC, numerals, building names stripped) instead of the raw address columns; a table created this way keeps it on later runs (new tables only)")
+	normalizedKey := flag.Bool("normalized-key", false, "Store each address's normalized search key in normalized_address for the \"normalized\" search strategy; the key depends on the normalize package's rules, so rerun the import (or recompute the column) after they change")
 	flag.Parse()
 
+	if *noVerify {
+		*verify = false
+	}
+
+	if *noAnalyze {
+		*analyze = false
+	}
+
 	if *file == "" && *directory == "" {
 		fmt.Println("Error: either --file or --directory flag is required")
 		os.Exit(1)
@@ -38,6 +199,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *vacuum && !*analyze {
+		fmt.Println("Error: --vacuum cannot be combined with --no-analyze")
+		os.Exit(1)
+	}
+
+	if *swap && *deferIndexes {
+		fmt.Println("Error: --defer-indexes cannot be combined with --swap, which already builds indexes after the load")
+		os.Exit(1)
+	}
+
+	switch *outOfBounds {
+	case outOfBoundsKeep, outOfBoundsSkip, outOfBoundsError:
+	default:
+		fmt.Printf("Error: --out-of-bounds must be %s, %s or %s\n", outOfBoundsKeep, outOfBoundsSkip, outOfBoundsError)
+		os.Exit(1)
+	}
+
+	if *commitEvery < 0 {
+		fmt.Println("Error: --commit-every must not be negative")
+		os.Exit(1)
+	}
+
+	if *partitionByPrefecture && *swap {
+		fmt.Println("Error: --partition-by-prefecture cannot be combined with --swap, whose staging table is not partitioned")
+		os.Exit(1)
+	}
+
+	if *commitEvery > 0 && *swap {
+		fmt.Println("Error: --commit-every cannot be combined with --swap, whose staging table is rebuilt on every run")
+		os.Exit(1)
+	}
+
 	// Load config
 	cfg, err := config.LoadConfig(filepath.Join(".", "configs"))
 	if err != nil {
@@ -48,49 +241,198 @@ func main() {
 	// Connect to DB
 	conn, err := pgx.Connect(context.Background(), cfg.DBSource)
 	if err != nil {
-		fmt.Printf("Error connecting to database: %v\n", err)
+		fmt.Printf("Error connecting to database %s: %v\n", config.RedactDSN(cfg.DBSource), config.RedactError(err, cfg.DBSource))
 		os.Exit(1)
 	}
 	defer conn.Close(context.Background())
 
-	// Ensure tables exist
-	err = createTablesIfNotExists(conn)
+	err = validateTable(*table, append([]string{"locations"}, cfg.ImportTables...))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Use the same text search configuration as the API so queries match the stored vectors
+	textSearchConfig, fellBack, err := repository.ResolveTextSearchConfig(context.Background(), conn, cfg.TextSearchConfig, cfg.TextSearchFallback)
+	if err != nil {
+		fmt.Printf("Error resolving text search configuration: %v\n", config.RedactError(err, cfg.DBSource))
+		os.Exit(1)
+	}
+	if fellBack {
+		fmt.Printf("Warning: text search configuration %q is not installed, falling back to %q\n", cfg.TextSearchConfig, textSearchConfig)
+	}
+
+	// Ensure tables exist. ADDRESS_NORMALIZATION implies --app-search-text: the normalized address
+	// numbers go into the search text only, since the address columns keep the dataset's text,
+	// which responses return
+	err = createTablesIfNotExists(conn, *table, textSearchConfig, *partitionByPrefecture, *appSearchText || cfg.AddressNormalization, *altitudeColumn != "")
+	if err != nil {
+		if cfg.AddressNormalization && !*appSearchText {
+			fmt.Printf("Error creating tables: %v (ADDRESS_NORMALIZATION requires search text)\n", err)
+		} else {
+			fmt.Printf("Error creating tables: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	// Like partitioning, a table created with --app-search-text keeps it whether or not the flag
+	// is repeated: its search vectors stay empty unless every row gets its search text
+	searchTextTable, err := usesSearchText(conn, *table)
+	if err != nil {
+		fmt.Printf("Error checking %s table: %v\n", *table, err)
+		os.Exit(1)
+	}
+
+	// A POINTZ table rejects 2D points, so its rows can't be imported without their altitude
+	altitudeTable, err := usesAltitude(conn, *table)
 	if err != nil {
-		fmt.Printf("Error creating tables: %v\n", err)
+		fmt.Printf("Error checking %s table: %v\n", *table, err)
+		os.Exit(1)
+	}
+	if altitudeTable && *altitudeColumn == "" {
+		fmt.Printf("Error: %s stores 3D points, set --altitude-column\n", *table)
+		os.Exit(1)
+	}
+
+	// A table partitioned by an earlier run stays partitioned whether or not the flag is repeated
+	_, partitioned, err := tableKind(conn, *table)
+	if err != nil {
+		fmt.Printf("Error checking %s table: %v\n", *table, err)
+		os.Exit(1)
+	}
+	if partitioned && *swap {
+		fmt.Printf("Error: --swap is not supported for the partitioned %s table\n", *table)
 		os.Exit(1)
 	}
 
+	if *deferIndexes {
+		// The API relies on these indexes, so only drop them when nothing is being served yet
+		empty, err := isTableEmpty(conn, *table)
+		if err != nil {
+			fmt.Printf("Error checking %s table: %v\n", *table, err)
+			os.Exit(1)
+		}
+		if !empty {
+			fmt.Printf("Error: --defer-indexes is only allowed when the %s table is empty\n", *table)
+			os.Exit(1)
+		}
+
+		err = dropLocationIndexes(conn, *table)
+		if err != nil {
+			fmt.Printf("Error dropping indexes: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Dropped %s indexes, they will be rebuilt after the load\n", *table)
+	}
+
+	var rowErrors *csv.Writer
+	if *errorFile != "" {
+		f, err := os.Create(*errorFile)
+		if err != nil {
+			fmt.Printf("Error creating error file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		rowErrors = csv.NewWriter(f)
+		defer rowErrors.Flush()
+		if err := rowErrors.Write(errorFileColumns); err != nil {
+			fmt.Printf("Error writing error file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	targetTable := *table
+	if *swap {
+		err = createStagingTable(conn, *table)
+		if err != nil {
+			fmt.Printf("Error creating staging table: %v\n", err)
+			os.Exit(1)
+		}
+		targetTable = stagingTableFor(*table)
+		fmt.Printf("Loading into staging table %s\n", targetTable)
+	}
+
 	var totalRecords int
+	var parsedRecords int
 	var processedFiles int
 	var failedFiles int
+	var outOfBoundsRecords int
+	var importedFiles []importedFile
 
 	if *file != "" {
 		// Single file import (backward compatibility)
 		fmt.Printf("Starting import from file: %s\n", *file)
 
-		records, err := parseCSV(*file)
+		records, invalid, err := parseCSV(*file, *externalIDColumn, *importanceColumn, *altitudeColumn, *repairUTF8, rowErrors != nil)
 		if err != nil {
 			fmt.Printf("Error parsing CSV: %v\n", err)
 			os.Exit(1)
 		}
 
 		fmt.Printf("Parsed %d records\n", len(records))
+		if err := reportRowErrors(rowErrors, invalid, *errorFile); err != nil {
+			fmt.Printf("Error writing error file: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := checkExpectedCount(*expectCount, len(records)); err != nil {
+			fmt.Printf("Error: %v in %s, nothing was imported\n", err, *file)
+			os.Exit(1)
+		}
+
+		records, outOfBoundsRecords, err = reportCoordinateCheck(records, *fixSwapped, *outOfBounds, *file)
+		if err != nil {
+			fmt.Printf("Error: %v in %s, nothing was imported\n", err, *file)
+			os.Exit(1)
+		}
+
+		if *normalizedKey {
+			setSearchKeys(records)
+		}
+
+		if searchTextTable {
+			setSearchTexts(records)
+		}
+
+		if *coordPrecision >= 0 {
+			var dropped int
+			records, dropped = quantizeRecords(records, *coordPrecision)
+			fmt.Printf("Rounded coordinates to %d decimals, dropped %d duplicate records\n", *coordPrecision, dropped)
+		}
+
+		var duplicateIDs int
+		records, duplicateIDs = dedupeExternalIDs(records)
+		if duplicateIDs > 0 {
+			fmt.Printf("Dropped %d records whose external ID appears again later in the file\n", duplicateIDs)
+		}
 
 		// Insert records
-		err = insertRecords(conn, records)
+		err = loadRecords(conn, targetTable, *file, records, *commitEvery, partitioned)
 		if err != nil {
 			fmt.Printf("Error inserting records: %v\n", err)
 			os.Exit(1)
 		}
 
+		if *swap {
+			err = swapStagingTable(conn, *table, []importedFile{{path: *file, recordCount: len(records)}})
+			if err != nil {
+				fmt.Printf("Error swapping staging table: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Swapped staging table into %s\n", *table)
+		}
+
 		// Verify data
-		err = verifyImport(conn, len(records))
-		if err != nil {
-			fmt.Printf("Error verifying import: %v\n", err)
-			os.Exit(1)
+		if *verify {
+			err = verifyImport(conn, *table, len(records))
+			if err != nil {
+				fmt.Printf("Error verifying import: %v\n", err)
+				os.Exit(1)
+			}
 		}
 
 		fmt.Printf("Successfully imported %d records\n", len(records))
+		reportOutOfBounds(outOfBoundsRecords, *outOfBounds)
 	} else {
 		// Directory import
 		fmt.Printf("Starting import from directory: %s\n", *directory)
@@ -106,41 +448,80 @@ func main() {
 		for _, filePath := range files {
 			fmt.Printf("Processing file: %s\n", filePath)
 
-			// Check if file has been processed
-			processed, err := isFileProcessed(conn, filePath)
+			// Check if file has been processed; a swap import is a full reload so loads everything
+			if !*swap {
+				processed, err := isFileProcessed(conn, filePath)
+				if err != nil {
+					fmt.Printf("Error checking if file processed: %v\n", err)
+					failedFiles++
+					continue
+				}
+
+				if processed {
+					fmt.Printf("Skipping already processed file: %s\n", filePath)
+					continue
+				}
+			}
+
+			records, invalid, err := parseCSV(filePath, *externalIDColumn, *importanceColumn, *altitudeColumn, *repairUTF8, rowErrors != nil)
 			if err != nil {
-				fmt.Printf("Error checking if file processed: %v\n", err)
+				fmt.Printf("Error parsing CSV %s: %v\n", filePath, err)
 				failedFiles++
 				continue
 			}
 
-			if processed {
-				fmt.Printf("Skipping already processed file: %s\n", filePath)
-				continue
+			fmt.Printf("Parsed %d records from %s\n", len(records), filePath)
+			parsedRecords += len(records)
+			if err := reportRowErrors(rowErrors, invalid, *errorFile); err != nil {
+				fmt.Printf("Error writing error file: %v\n", err)
+				os.Exit(1)
 			}
 
-			records, err := parseCSV(filePath)
+			var fileOutOfBounds int
+			records, fileOutOfBounds, err = reportCoordinateCheck(records, *fixSwapped, *outOfBounds, filePath)
+			outOfBoundsRecords += fileOutOfBounds
 			if err != nil {
-				fmt.Printf("Error parsing CSV %s: %v\n", filePath, err)
+				fmt.Printf("Error: %v in %s\n", err, filePath)
 				failedFiles++
 				continue
 			}
 
-			fmt.Printf("Parsed %d records from %s\n", len(records), filePath)
+			if *normalizedKey {
+				setSearchKeys(records)
+			}
+
+			if searchTextTable {
+				setSearchTexts(records)
+			}
+
+			if *coordPrecision >= 0 {
+				var dropped int
+				records, dropped = quantizeRecords(records, *coordPrecision)
+				fmt.Printf("Rounded coordinates to %d decimals, dropped %d duplicate records from %s\n", *coordPrecision, dropped, filePath)
+			}
+
+			var duplicateIDs int
+			records, duplicateIDs = dedupeExternalIDs(records)
+			if duplicateIDs > 0 {
+				fmt.Printf("Dropped %d records from %s whose external ID appears again later in the file\n", duplicateIDs, filePath)
+			}
 
 			// Insert records
-			err = insertRecords(conn, records)
+			err = loadRecords(conn, targetTable, filePath, records, *commitEvery, partitioned)
 			if err != nil {
 				fmt.Printf("Error inserting records from %s: %v\n", filePath, err)
 				failedFiles++
 				continue
 			}
 
-			// Mark file as processed
-			err = markFileProcessed(conn, filePath, len(records))
-			if err != nil {
-				fmt.Printf("Error marking file as processed: %v\n", err)
-				// Don't increment failedFiles here as the data was inserted successfully
+			// Mark file as processed; swapped files are recorded when the swap commits
+			importedFiles = append(importedFiles, importedFile{path: filePath, recordCount: len(records)})
+			if !*swap {
+				err = markFileProcessed(conn, filePath, len(records))
+				if err != nil {
+					fmt.Printf("Error marking file as processed: %v\n", err)
+					// Don't increment failedFiles here as the data was inserted successfully
+				}
 			}
 
 			totalRecords += len(records)
@@ -148,132 +529,1157 @@ func main() {
 			fmt.Printf("Successfully processed %s (%d records)\n", filePath, len(records))
 		}
 
+		// Checked before the swap, so a short import never replaces the live table
+		if err := checkExpectedCount(*expectCount, parsedRecords); err != nil {
+			fmt.Printf("Error: %v across the files parsed in this run\n", err)
+			os.Exit(1)
+		}
+
+		// A file that failed is missing from the staging table, which must not replace the live one
+		if *swap && failedFiles > 0 {
+			fmt.Printf("Error: %d files failed, leaving %s unchanged\n", failedFiles, *table)
+			os.Exit(1)
+		}
+
+		if *swap {
+			err = swapStagingTable(conn, *table, importedFiles)
+			if err != nil {
+				fmt.Printf("Error swapping staging table: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Swapped staging table into %s\n", *table)
+		}
+
 		fmt.Printf("Directory import completed: %d files processed, %d files failed, %d total records imported\n", processedFiles, failedFiles, totalRecords)
+		reportOutOfBounds(outOfBoundsRecords, *outOfBounds)
+
+		if *verify && processedFiles > 0 {
+			err = verifyImport(conn, *table, totalRecords)
+			if err != nil {
+				fmt.Printf("Error verifying import: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if len(importedFiles) > 0 {
+			discrepancies, err := checkIntegrity(conn, *table, importedFiles, *checkRows)
+			if err != nil {
+				fmt.Printf("Error checking import integrity: %v\n", err)
+				os.Exit(1)
+			}
+			if discrepancies > 0 {
+				fmt.Printf("Error: integrity check found discrepancies in %d of %d files\n", discrepancies, len(importedFiles))
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Integrity check passed for %d files\n", len(importedFiles))
+		}
+	}
+
+	if *deferIndexes {
+		fmt.Printf("Building %s indexes...\n", *table)
+		start := time.Now()
+		err = createLocationIndexes(conn, *table, partitioned)
+		if err != nil {
+			fmt.Printf("Error creating indexes: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Built %s indexes in %s\n", *table, time.Since(start).Round(time.Millisecond))
+	}
+
+	// A swap already analyzed the staging table before renaming it; anything else leaves
+	// stale statistics until autovacuum catches up, so refresh them for the first queries
+	if *analyze && (!*swap || *vacuum) {
+		fmt.Printf("Analyzing %s...\n", *table)
+		start := time.Now()
+		err = analyzeTable(conn, *table, *vacuum)
+		if err != nil {
+			fmt.Printf("Error analyzing %s: %v\n", *table, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Analyzed %s in %s\n", *table, time.Since(start).Round(time.Millisecond))
 	}
 }
 
-func parseCSV(filePath string) ([]LocationRecord, error) {
+// parseCSV reads the records of a CSV or TSV file, taking each record's external ID, importance
+// and altitude from externalIDColumn, importanceColumn and altitudeColumn when set (see
+// columnIndex), and its kana readings from the file's kana columns when it has them (see
+// kanaColumns). An invalid row fails the whole file unless skipInvalid is set, in which case it
+// is returned as a rowError and parsing continues. A row with invalid UTF-8 is invalid too,
+// unless repairUTF8 is set to remove the offending bytes, and so is one whose importance or
+// altitude isn't a number. With altitudeColumn every row needs an altitude, since the points
+// are stored as POINTZ, and a file without the column is an error.
+func parseCSV(filePath, externalIDColumn, importanceColumn, altitudeColumn string, repairUTF8, skipInvalid bool) ([]LocationRecord, []rowError, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	// Strip a leading UTF-8 BOM so it doesn't end up in the first column.
+	// CRLF line endings are already handled by encoding/csv.
+	buffered := bufio.NewReader(file)
+	if prefix, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(buffered)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	if strings.EqualFold(filepath.Ext(filePath), ".tsv") {
+		reader.Comma = '\t'
+	}
 
-	// Skip header
-	_, err = reader.Read()
+	// Files normally start with a header row, but some are exported without one. Treat the
+	// first row as data when its coordinate columns parse as numbers, so headerless files
+	// don't silently lose their first record.
+	first, err := reader.Read()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read header: %w", err)
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	idIndex := -1
+	if externalIDColumn != "" {
+		idIndex = columnIndex(first, externalIDColumn)
+		if idIndex < 0 {
+			fmt.Printf("Warning: %s has no %q column, importing it without external IDs\n", filePath, externalIDColumn)
+		}
+	}
+	importanceIndex := -1
+	if importanceColumn != "" {
+		importanceIndex = columnIndex(first, importanceColumn)
+		if importanceIndex < 0 {
+			fmt.Printf("Warning: %s has no %q column, importing it without importance\n", filePath, importanceColumn)
+		}
+	}
+	altitudeIndex := -1
+	if altitudeColumn != "" {
+		altitudeIndex = columnIndex(first, altitudeColumn)
+		if altitudeIndex < 0 {
+			return nil, nil, fmt.Errorf("no %q column, which the table's 3D points need", altitudeColumn)
+		}
+	}
+
+	kanaIndexes := kanaColumns(first)
+
+	parse := func(record []string) (LocationRecord, bool, error) {
+		location, fixed, err := parseRow(record, repairUTF8)
+		if err != nil {
+			return location, fixed, err
+		}
+		location.ExternalID = columnValue(record, idIndex)
+		location.PrefectureKana = columnValue(record, kanaIndexes[0])
+		location.MunicipalityKana = columnValue(record, kanaIndexes[1])
+		location.Address1Kana = columnValue(record, kanaIndexes[2])
+		location.Address2Kana = columnValue(record, kanaIndexes[3])
+		location.KanaKey = normalize.Kana(location.PrefectureKana + location.MunicipalityKana + location.Address1Kana + location.Address2Kana)
+		location.Importance, err = parseNullFloat("importance", columnValue(record, importanceIndex))
+		if err != nil {
+			return location, fixed, err
+		}
+		location.Altitude, err = parseNullFloat("altitude", columnValue(record, altitudeIndex))
+		if err == nil && altitudeIndex >= 0 && !location.Altitude.Valid {
+			err = errors.New("missing altitude")
+		}
+		return location, fixed, err
 	}
 
 	var records []LocationRecord
+	var invalid []rowError
+	var repaired int
+	if isDataRow(first) {
+		location, fixed, err := parse(first)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fixed {
+			repaired++
+		}
+		records = append(records, location)
+	}
+
 	for {
 		record, err := reader.Read()
 		if err != nil {
 			if err.Error() == "EOF" {
 				break
 			}
-			return nil, fmt.Errorf("failed to read record: %w", err)
-		}
-
-		if len(record) < 11 {
-			return nil, fmt.Errorf("invalid record length: %d, expected at least 11 columns", len(record))
-		}
-
-		lat, err := strconv.ParseFloat(record[9], 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid latitude: %s", record[9])
+			return nil, nil, fmt.Errorf("failed to read record: %w", err)
 		}
 
-		lon, err := strconv.ParseFloat(record[10], 64)
+		location, fixed, err := parse(record)
 		if err != nil {
-			return nil, fmt.Errorf("invalid longitude: %s", record[10])
+			line, _ := reader.FieldPos(0)
+			if !skipInvalid {
+				return nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			invalid = append(invalid, rowError{file: filePath, line: line, record: record, reason: err.Error()})
+			continue
 		}
-
-		location := LocationRecord{
-			Prefecture:   record[0],
-			Municipality: record[1],
-			Address1:     record[2],
-			Address2:     record[3],
-			BlockLot:     record[4],
-			Lat:          lat,
-			Lon:          lon,
+		if fixed {
+			repaired++
 		}
 
 		records = append(records, location)
 	}
 
-	return records, nil
+	if repaired > 0 {
+		fmt.Printf("Warning: removed invalid UTF-8 bytes from %d rows in %s\n", repaired, filePath)
+	}
+	return records, invalid, nil
 }
 
-func createTablesIfNotExists(conn *pgx.Conn) error {
-	// Create locations table
-	locationsQuery := `
-	CREATE TABLE IF NOT EXISTS locations (
-		id BIGSERIAL PRIMARY KEY,
-		prefecture VARCHAR(255),
-		municipality VARCHAR(255),
-		address_1 VARCHAR(255),
-		address_2 VARCHAR(255),
-		block_lot VARCHAR(255),
-		full_address_tsvector TSVECTOR GENERATED ALWAYS AS (
-			to_tsvector('japanese', prefecture || ' ' || municipality || ' ' || address_1 || ' ' || address_2)
-		) STORED,
-		geom GEOGRAPHY(POINT, 4326)
-	);
-	CREATE INDEX IF NOT EXISTS locations_geom_idx ON locations USING GIST (geom);
-	CREATE INDEX IF NOT EXISTS locations_full_address_tsvector_idx ON locations USING GIN (full_address_tsvector);
-	`
-	_, err := conn.Exec(context.Background(), locationsQuery)
-	if err != nil {
-		return err
+// parseRow checks a row's encoding before parsing it, reporting whether invalid UTF-8 had to
+// be removed from it. Without repair, such bytes would reach the database as U+FFFD or fail
+// the COPY, so the row is rejected instead.
+func parseRow(record []string, repair bool) (LocationRecord, bool, error) {
+	var repaired bool
+	for i, field := range record {
+		if utf8.ValidString(field) {
+			continue
+		}
+		if !repair {
+			return LocationRecord{}, false, fmt.Errorf("column %d is not valid UTF-8", i+1)
+		}
+		record[i] = strings.ToValidUTF8(field, "")
+		repaired = true
 	}
 
-	// Create processed_files table
-	processedFilesQuery := `
-	CREATE TABLE IF NOT EXISTS processed_files (
-		id BIGSERIAL PRIMARY KEY,
-		file_path TEXT UNIQUE NOT NULL,
-		processed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-		record_count INTEGER NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS processed_files_path_idx ON processed_files (file_path);
-	`
-	_, err = conn.Exec(context.Background(), processedFilesQuery)
-	return err
+	location, err := parseRecord(record)
+	return location, repaired, err
 }
 
-func insertRecords(conn *pgx.Conn, records []LocationRecord) error {
-	// Use CopyFrom for bulk insert
-	_, err := conn.CopyFrom(
-		context.Background(),
-		pgx.Identifier{"locations"},
-		[]string{"prefecture", "municipality", "address_1", "address_2", "block_lot", "geom"},
-		pgx.CopyFromSlice(len(records), func(i int) ([]interface{}, error) {
-			r := records[i]
-			geom := fmt.Sprintf("SRID=4326;POINT(%f %f)", r.Lon, r.Lat) // PostGIS format: lon lat
-			return []interface{}{r.Prefecture, r.Municipality, r.Address1, r.Address2, r.BlockLot, geom}, nil
-		}),
-	)
-	return err
+// checkExpectedCount compares the number of valid records parsed with the --expect-count;
+// a negative expected count disables the check
+func checkExpectedCount(expected, parsed int) error {
+	if expected < 0 || parsed == expected {
+		return nil
+	}
+	return fmt.Errorf("expected %d records but parsed %d (%+d)", expected, parsed, parsed-expected)
 }
 
-func verifyImport(conn *pgx.Conn, expectedCount int) error {
-	var count int
-	err := conn.QueryRow(context.Background(), "SELECT COUNT(*) FROM locations").Scan(&count)
-	if err != nil {
-		return fmt.Errorf("failed to count records: %w", err)
+// reportRowErrors writes the rows skipped from one file to the error file and reports how many there were
+func reportRowErrors(w *csv.Writer, invalid []rowError, errorFile string) error {
+	if len(invalid) == 0 {
+		return nil
+	}
+	for _, e := range invalid {
+		if err := w.Write(errorFileRow(e)); err != nil {
+			return err
+		}
 	}
+	w.Flush()
+	fmt.Printf("Warning: skipped %d invalid rows in %s, written to %s\n", len(invalid), invalid[0].file, errorFile)
+	return w.Error()
+}
 
-	if count < expectedCount {
-		return fmt.Errorf("record count mismatch: expected at least %d, got %d", expectedCount, count)
+// errorFileRow lays out a skipped row under errorFileColumns, padding short rows and trimming
+// extra columns so the source_file, line and reason columns stay aligned
+func errorFileRow(e rowError) []string {
+	importColumns := len(errorFileColumns) - 3
+	row := make([]string, importColumns, len(errorFileColumns))
+	copy(row, e.record)
+	return append(row, e.file, strconv.Itoa(e.line), e.reason)
+}
+
+// isDataRow reports whether a row's latitude and longitude columns hold numbers, which a
+// header row's column names never do
+func isDataRow(record []string) bool {
+	if len(record) < 11 {
+		return false
 	}
+	_, latErr := strconv.ParseFloat(record[9], 64)
+	_, lonErr := strconv.ParseFloat(record[10], 64)
+	return latErr == nil && lonErr == nil
+}
 
-	// Check a sample geom
-	var geom string
-	err = conn.QueryRow(context.Background(), "SELECT ST_AsText(geom) FROM locations LIMIT 1").Scan(&geom)
+// columnIndex returns the position of an optional column, such as the external ID, given the
+// file's first row. column is a header name, or a 1-based column number for files without a
+// header; -1 means the file has no such column.
+func columnIndex(first []string, column string) int {
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return -1
+		}
+		return n - 1
+	}
+	if isDataRow(first) {
+		return -1
+	}
+	for i, name := range first {
+		if strings.TrimSpace(name) == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// kanaColumns returns the positions of the kana readings of the prefecture, municipality,
+// address_1 and address_2 columns given the file's first row: the columns named like theirs
+// plus "_カナ", e.g. 市区町村名_カナ. Files without a header, or without such a column, have
+// -1 for it.
+func kanaColumns(first []string) [4]int {
+	var indexes [4]int
+	for i := range indexes {
+		indexes[i] = columnIndex(first, errorFileColumns[i]+"_カナ")
+	}
+	return indexes
+}
+
+// columnValue returns the trimmed value of an optional column, or "" when there is none
+func columnValue(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// parseNullFloat parses the numeric field of an optional column such as importance; an empty
+// one is NULL
+func parseNullFloat(column, field string) (sql.NullFloat64, error) {
+	if field == "" {
+		return sql.NullFloat64{}, nil
+	}
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return sql.NullFloat64{}, fmt.Errorf("invalid %s: %s", column, field)
+	}
+	return sql.NullFloat64{Float64: v, Valid: true}, nil
+}
+
+// dedupeExternalIDs keeps only the last record of each external ID, since a single upsert
+// can't update the same row twice. Records without an external ID are all kept.
+func dedupeExternalIDs(records []LocationRecord) ([]LocationRecord, int) {
+	last := make(map[string]int)
+	for i, r := range records {
+		if r.ExternalID != "" {
+			last[r.ExternalID] = i
+		}
+	}
+
+	kept := records[:0]
+	for i, r := range records {
+		if r.ExternalID == "" || last[r.ExternalID] == i {
+			kept = append(kept, r)
+		}
+	}
+	return kept, len(records) - len(kept)
+}
+
+func parseRecord(record []string) (LocationRecord, error) {
+	if len(record) < 11 {
+		return LocationRecord{}, fmt.Errorf("invalid record length: %d, expected at least 11 columns", len(record))
+	}
+
+	lat, err := strconv.ParseFloat(record[9], 64)
+	if err != nil {
+		return LocationRecord{}, fmt.Errorf("invalid latitude: %s", record[9])
+	}
+
+	lon, err := strconv.ParseFloat(record[10], 64)
+	if err != nil {
+		return LocationRecord{}, fmt.Errorf("invalid longitude: %s", record[10])
+	}
+
+	return LocationRecord{
+		Prefecture:   record[0],
+		Municipality: record[1],
+		Address1:     record[2],
+		Address2:     record[3],
+		BlockLot:     record[4],
+		Lat:          lat,
+		Lon:          lon,
+	}, nil
+}
+
+// Policies for records whose coordinates fall outside Japan without being suspected swaps,
+// see --out-of-bounds. Points near borders and remote islands can be valid, so they are a
+// category of their own rather than invalid rows.
+const (
+	outOfBoundsKeep  = "keep"
+	outOfBoundsSkip  = "skip"
+	outOfBoundsError = "error"
+)
+
+// maxOutOfBoundsSamples is the number of out-of-Japan coordinates shown in a file's report
+const maxOutOfBoundsSamples = 5
+
+func inJapan(lat, lon float64) bool {
+	return lat >= japanMinLat && lat <= japanMaxLat && lon >= japanMinLon && lon <= japanMaxLon
+}
+
+// checkCoordinates finds the records that fall outside Japan. Those whose swapped coordinates
+// land inside Japan are counted as suspected swaps and, when fix is set, corrected in place;
+// the indexes of the others are returned as out of bounds.
+func checkCoordinates(records []LocationRecord, fix bool) (swapped int, outOfBounds []int) {
+	for i := range records {
+		r := &records[i]
+		if inJapan(r.Lat, r.Lon) {
+			continue
+		}
+		if inJapan(r.Lon, r.Lat) {
+			swapped++
+			if fix {
+				r.Lat, r.Lon = r.Lon, r.Lat
+			}
+			continue
+		}
+		outOfBounds = append(outOfBounds, i)
+	}
+	return swapped, outOfBounds
+}
+
+// reportCoordinateCheck checks the coordinates of a file's records, reporting suspected swaps
+// and the records outside Japan with a few sample coordinates, and applies the out-of-bounds
+// policy to the latter: they are kept, skipped, or fail the file. It returns the records to
+// import and the number found out of bounds.
+func reportCoordinateCheck(records []LocationRecord, fix bool, policy, filePath string) ([]LocationRecord, int, error) {
+	swapped, outOfBounds := checkCoordinates(records, fix)
+	if swapped > 0 {
+		action := "left as is, use --fix-swapped-coords to correct"
+		if fix {
+			action = "corrected"
+		}
+		fmt.Printf("Warning: %d records in %s have suspected swapped lat/lon (%s)\n", swapped, filePath, action)
+	}
+	if len(outOfBounds) == 0 {
+		return records, 0, nil
+	}
+
+	samples := make([]string, 0, maxOutOfBoundsSamples)
+	for _, i := range outOfBounds[:min(len(outOfBounds), maxOutOfBoundsSamples)] {
+		samples = append(samples, fmt.Sprintf("(%g, %g)", records[i].Lat, records[i].Lon))
+	}
+	sample := strings.Join(samples, ", ")
+	if len(outOfBounds) > maxOutOfBoundsSamples {
+		sample += ", ..."
+	}
+
+	switch policy {
+	case outOfBoundsError:
+		return nil, len(outOfBounds), fmt.Errorf("%d records have coordinates outside Japan: %s", len(outOfBounds), sample)
+	case outOfBoundsSkip:
+		fmt.Printf("Warning: skipping %d records in %s with coordinates outside Japan: %s\n", len(outOfBounds), filePath, sample)
+		return removeRecords(records, outOfBounds), len(outOfBounds), nil
+	default:
+		fmt.Printf("Warning: %d records in %s have coordinates outside Japan (kept, use --out-of-bounds=skip to drop them): %s\n", len(outOfBounds), filePath, sample)
+		return records, len(outOfBounds), nil
+	}
+}
+
+// reportOutOfBounds prints the import's out-of-Japan bucket in the summary, if it has any records
+func reportOutOfBounds(count int, policy string) {
+	if count == 0 {
+		return
+	}
+	action := map[string]string{outOfBoundsKeep: "kept", outOfBoundsSkip: "skipped", outOfBoundsError: "failed their files"}[policy]
+	fmt.Printf("Out-of-Japan coordinates: %d records (%s)\n", count, action)
+}
+
+// removeRecords drops the records at the given ascending indexes in place
+func removeRecords(records []LocationRecord, indexes []int) []LocationRecord {
+	kept := records[:0]
+	next := 0
+	for i, r := range records {
+		if next < len(indexes) && indexes[next] == i {
+			next++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// quantizeRecords rounds every coordinate to the given number of decimals and
+// removes records that become identical, returning the kept records and the
+// number dropped. Each decimal place is roughly a factor of ten in accuracy:
+// 6 decimals is about 0.1m at Japanese latitudes, which absorbs jitter without
+// merging distinct addresses; fewer decimals dedupe more aggressively but can
+// collapse neighbouring lots into one point.
+func quantizeRecords(records []LocationRecord, precision int) ([]LocationRecord, int) {
+	scale := math.Pow(10, float64(precision))
+	seen := make(map[LocationRecord]struct{}, len(records))
+	kept := records[:0]
+	for _, r := range records {
+		r.Lat = math.Round(r.Lat*scale) / scale
+		r.Lon = math.Round(r.Lon*scale) / scale
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		kept = append(kept, r)
+	}
+	return kept, len(records) - len(kept)
+}
+
+// setSearchKeys sets each record's NormalizedAddress to the search key of its full address
+func setSearchKeys(records []LocationRecord) {
+	for i, r := range records {
+		records[i].NormalizedAddress = normalize.SearchKey(joinAddress(r.Prefecture, r.Municipality, r.Address1, r.Address2, r.BlockLot))
+	}
+}
+
+// setSearchTexts sets each record's SearchText, see searchText
+func setSearchTexts(records []LocationRecord) {
+	for i, r := range records {
+		records[i].SearchText = searchText(r)
+	}
+}
+
+// searchText returns the search text of a record: its prefecture, municipality and street-level
+// address, each rewritten by normalize.SearchText, joined by tabs. normalize.SearchText leaves no
+// tabs inside the parts, so the generated full_address_tsvector can split them back apart with
+// split_part and weight them like the address columns (see locationsTableDDL).
+func searchText(r LocationRecord) string {
+	address := normalize.SearchText(joinAddress(r.Address1, r.Address2))
+	return normalize.SearchText(r.Prefecture) + "\t" + normalize.SearchText(r.Municipality) + "\t" + address
+}
+
+// joinAddress concatenates address fields, putting a hyphen between two fields where one ends
+// and the next starts with a digit, so address_2 "9" and block_lot "1" become "9-1", not "91"
+func joinAddress(fields ...string) string {
+	var b strings.Builder
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		last, _ := utf8.DecodeLastRuneInString(b.String())
+		first, _ := utf8.DecodeRuneInString(f)
+		if unicode.IsDigit(last) && unicode.IsDigit(first) {
+			b.WriteByte('-')
+		}
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+// createTablesIfNotExists creates the schema. table must have passed validateTable and
+// textSearchConfig must come from repository.ResolveTextSearchConfig, which both restrict
+// the names to plain identifiers that are safe to embed in DDL. With partitionByPrefecture a
+// new table is created partitioned by prefecture (see locationsTableDDL); an existing table
+// keeps its layout, and an unpartitioned one is an error since Postgres can't convert it.
+// appSearchText likewise only applies to a new table, whose search vector is then generated
+// from search_text, and so does altitude, whose geom then stores 3D points.
+func createTablesIfNotExists(conn *pgx.Conn, table string, textSearchConfig string, partitionByPrefecture, appSearchText, altitude bool) error {
+	exists, partitioned, err := tableKind(conn, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		partitioned = partitionByPrefecture
+	} else if partitionByPrefecture && !partitioned {
+		return fmt.Errorf("%s already exists and is not partitioned; partitioning only applies to new tables", table)
+	}
+	if exists && appSearchText {
+		uses, err := usesSearchText(conn, table)
+		if err != nil {
+			return err
+		}
+		if !uses {
+			return fmt.Errorf("%s already exists with its search vector generated from the address columns; --app-search-text only applies to new tables", table)
+		}
+	}
+	if exists && altitude {
+		uses, err := usesAltitude(conn, table)
+		if err != nil {
+			return err
+		}
+		if !uses {
+			return fmt.Errorf("%s already exists with 2D points; --altitude-column only applies to new tables", table)
+		}
+	}
+
+	// The trigram indexes of createLocationIndexes need pg_trgm
+	_, err = conn.Exec(context.Background(), "CREATE EXTENSION IF NOT EXISTS pg_trgm")
+	if err != nil {
+		return err
+	}
+
+	// Create locations table
+	_, err = conn.Exec(context.Background(), locationsTableDDL(table, textSearchConfig, partitioned, appSearchText, altitude))
+	if err != nil {
+		return err
+	}
+
+	err = createLocationIndexes(conn, table, partitioned)
+	if err != nil {
+		return err
+	}
+
+	if partitioned {
+		// Rows of unrecognized prefectures land here instead of failing the load
+		_, err = conn.Exec(context.Background(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %[1]s_default PARTITION OF %[1]s DEFAULT", table))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create processed_files table
+	processedFilesQuery := `
+	CREATE TABLE IF NOT EXISTS processed_files (
+		id BIGSERIAL PRIMARY KEY,
+		file_path TEXT UNIQUE NOT NULL,
+		processed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		record_count INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS processed_files_path_idx ON processed_files (file_path);
+
+	CREATE TABLE IF NOT EXISTS import_progress (
+		file_path TEXT NOT NULL,
+		table_name TEXT NOT NULL,
+		records_committed INTEGER NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		PRIMARY KEY (file_path, table_name)
+	);
+	`
+	_, err = conn.Exec(context.Background(), processedFilesQuery)
+	return err
+}
+
+// locationsTableDDL returns the statements creating the locations table, or upgrading one
+// created by an older importer.
+//
+// A partitioned table is split by LIST (prefecture) into one partition per prefecture, named
+// after its JIS code (locations_p13 for 東京都) and created as the prefecture first appears
+// (see createPartitions), plus a default partition for anything else. Queries filtering on a
+// prefecture then only scan its partition, and a prefecture can be reloaded, vacuumed or
+// reindexed on its own. Postgres requires unique constraints on a partitioned table to include
+// the partition key, so the primary key is (id, prefecture) and external IDs are only unique
+// within a prefecture: a re-imported address whose prefecture changed is added as a new row
+// instead of updating the old one.
+//
+// full_address_tsvector is generated from the address columns, leaving normalization to the text
+// search configuration. With appSearchText it is generated from search_text instead, which the
+// importer fills with text it normalized itself (see searchText), so matching doesn't depend on
+// how each environment's configuration handles numerals, width or building names. Either way
+// municipality matches weigh most (A), then prefecture (B), then the street-level address (C).
+//
+// geom is a GEOGRAPHY(POINT) by default. With altitude it is a GEOGRAPHY(POINTZ) holding each
+// address's elevation as its Z coordinate, which the API reads back with ST_Z; a POINTZ column
+// rejects 2D points, so every row of such a table needs an altitude.
+func locationsTableDDL(table, textSearchConfig string, partitioned, appSearchText, altitude bool) string {
+	id, primaryKey, partitionBy := "id BIGSERIAL PRIMARY KEY,", "", ""
+	if partitioned {
+		id, primaryKey, partitionBy = "id BIGSERIAL,", ",\n\t\tPRIMARY KEY (id, prefecture)", " PARTITION BY LIST (prefecture)"
+	}
+	point := "POINT"
+	if altitude {
+		point = "POINTZ"
+	}
+	vector := fmt.Sprintf(`setweight(to_tsvector('%[1]s', coalesce(municipality, '')), 'A') ||
+			setweight(to_tsvector('%[1]s', coalesce(prefecture, '')), 'B') ||
+			setweight(to_tsvector('%[1]s', coalesce(address_1, '') || ' ' || coalesce(address_2, '')), 'C')`, textSearchConfig)
+	if appSearchText {
+		vector = fmt.Sprintf(`setweight(to_tsvector('%[1]s', split_part(coalesce(search_text, ''), E'\t', 2)), 'A') ||
+			setweight(to_tsvector('%[1]s', split_part(coalesce(search_text, ''), E'\t', 1)), 'B') ||
+			setweight(to_tsvector('%[1]s', split_part(coalesce(search_text, ''), E'\t', 3)), 'C')`, textSearchConfig)
+	}
+
+	return fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		%[3]s
+		prefecture VARCHAR(255),
+		municipality VARCHAR(255),
+		address_1 VARCHAR(255),
+		address_2 VARCHAR(255),
+		block_lot VARCHAR(255),
+		external_id TEXT,
+		source_file TEXT,
+		search_text TEXT,
+		full_address_tsvector TSVECTOR GENERATED ALWAYS AS (
+			%[2]s
+		) STORED,
+		importance REAL,
+		normalized_address TEXT,
+		prefecture_kana TEXT,
+		municipality_kana TEXT,
+		address_1_kana TEXT,
+		address_2_kana TEXT,
+		kana_key TEXT,
+		geom GEOGRAPHY(%[6]s, 4326)%[4]s
+	)%[5]s;
+	-- Tables created before rows were tagged with their source file
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS source_file TEXT;
+	-- Tables created before external IDs were imported
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS external_id TEXT;
+	-- Tables created before addresses were ranked by importance
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS importance REAL;
+	-- Tables created before normalized search keys were imported
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS normalized_address TEXT;
+	-- Tables created before the importer could compute the search text
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS search_text TEXT;
+	-- Tables created before kana readings were imported
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS prefecture_kana TEXT;
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS municipality_kana TEXT;
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS address_1_kana TEXT;
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS address_2_kana TEXT;
+	ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS kana_key TEXT;
+	`, table, vector, id, primaryKey, partitionBy, point)
+}
+
+// validateTable checks table against the allowlist, since table names can't be bound as
+// query parameters and are embedded directly in the importer's SQL.
+func validateTable(table string, allowed []string) error {
+	if !tableNamePattern.MatchString(table) {
+		return fmt.Errorf("invalid table name %q", table)
+	}
+	for _, a := range allowed {
+		if a == table {
+			return nil
+		}
+	}
+	return fmt.Errorf("table %q is not in the allowlist %v (configure IMPORT_TABLES)", table, allowed)
+}
+
+func stagingTableFor(table string) string {
+	return table + "_staging"
+}
+
+// createLocationIndexes creates table's indexes. On a partitioned table they are defined on
+// the parent, which builds them on every partition and on each partition created later.
+func createLocationIndexes(conn *pgx.Conn, table string, partitioned bool) error {
+	indexesQuery := fmt.Sprintf(`
+	CREATE INDEX IF NOT EXISTS %[1]s_geom_idx ON %[1]s USING GIST (geom);
+	CREATE INDEX IF NOT EXISTS %[1]s_full_address_tsvector_idx ON %[1]s USING GIN (full_address_tsvector);
+	CREATE INDEX IF NOT EXISTS %[1]s_area_idx ON %[1]s (prefecture, municipality);
+	CREATE INDEX IF NOT EXISTS %[1]s_normalized_address_idx ON %[1]s (normalized_address);
+	CREATE INDEX IF NOT EXISTS %[1]s_kana_key_idx ON %[1]s USING GIN (kana_key gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS %[1]s_full_address_trgm_idx ON %[1]s USING GIN (%[3]s gin_trgm_ops);
+	CREATE UNIQUE INDEX IF NOT EXISTS %[1]s_external_id_idx ON %[1]s (%[2]s);
+	`, table, externalIDKey(partitioned), repository.FullAddress)
+	for _, idx := range suggestIndexes {
+		indexesQuery += fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_%[2]s ON %[1]s (%[3]s text_pattern_ops);\n", table, idx.name, idx.expr)
+	}
+	_, err := conn.Exec(context.Background(), indexesQuery)
+	return err
+}
+
+// suggestIndexes are the name suffixes and expressions of the text_pattern_ops indexes serving the
+// autocomplete prefix matches
+var suggestIndexes = []struct{ name, expr string }{
+	{"suggest_full_town_idx", repository.SuggestFullTown},
+	{"suggest_municipality_town_idx", repository.SuggestMunicipalityTown},
+	{"suggest_town_idx", repository.SuggestTown},
+	{"suggest_full_municipality_idx", repository.SuggestFullMunicipality},
+	{"suggest_municipality_idx", repository.SuggestMunicipality},
+}
+
+// externalIDKey returns the columns of the unique external ID index, which upserts name as
+// their conflict target; a partitioned table's has to include the partition key
+func externalIDKey(partitioned bool) string {
+	if partitioned {
+		return "external_id, prefecture"
+	}
+	return "external_id"
+}
+
+// usesSearchText reports whether table's full_address_tsvector is generated from search_text,
+// i.e. whether it was created with --app-search-text
+func usesSearchText(conn *pgx.Conn, table string) (bool, error) {
+	var uses bool
+	err := conn.QueryRow(context.Background(), `
+	SELECT coalesce(bool_or(pg_get_expr(d.adbin, d.adrelid) LIKE '%search_text%'), false)
+	FROM pg_attribute a
+	JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE a.attrelid = to_regclass($1) AND a.attname = 'full_address_tsvector'
+	`, table).Scan(&uses)
+	return uses, err
+}
+
+// usesAltitude reports whether table's geom stores POINTZ points (see locationsTableDDL)
+func usesAltitude(conn *pgx.Conn, table string) (bool, error) {
+	var uses bool
+	err := conn.QueryRow(context.Background(), `
+	SELECT coalesce(bool_or(postgis_typmod_dims(a.atttypmod) = 3), false)
+	FROM pg_attribute a
+	WHERE a.attrelid = to_regclass($1) AND a.attname = 'geom'
+	`, table).Scan(&uses)
+	return uses, err
+}
+
+// tableKind reports whether table exists and whether it is partitioned
+func tableKind(conn *pgx.Conn, table string) (exists bool, partitioned bool, err error) {
+	err = conn.QueryRow(context.Background(), "SELECT relkind = 'p' FROM pg_class WHERE oid = to_regclass($1)", table).Scan(&partitioned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, false, nil
+	}
+	return err == nil, partitioned, err
+}
+
+// prefecturePartitions returns the partitions of table that records need, keyed by partition
+// name, with the prefecture each one holds. Records of unrecognized prefectures need none,
+// since they go to the default partition.
+func prefecturePartitions(table string, records []LocationRecord) map[string]string {
+	partitions := make(map[string]string)
+	for _, r := range records {
+		if code, ok := prefectureCodes[r.Prefecture]; ok {
+			partitions[table+"_p"+code] = r.Prefecture
+		}
+	}
+	return partitions
+}
+
+// createPartitions adds the partitions records need that table doesn't have yet. A partition
+// must exist before its rows are loaded, or they would land in the default partition, which
+// then blocks creating it.
+func createPartitions(conn *pgx.Conn, table string, records []LocationRecord) error {
+	for name, prefecture := range prefecturePartitions(table, records) {
+		exists, _, err := tableKind(conn, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		// The prefecture comes from prefectureCodes, so it is safe to embed as a literal
+		_, err = conn.Exec(context.Background(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES IN ('%s')", name, table, prefecture))
+		if err != nil {
+			return fmt.Errorf("failed to create partition %s for %s: %w", name, prefecture, err)
+		}
+		fmt.Printf("Created partition %s for %s\n", name, prefecture)
+	}
+	return nil
+}
+
+// dropLocationIndexes drops the indexes that only speed up queries. The external ID index is
+// kept, since upserts during the load resolve conflicts through it.
+func dropLocationIndexes(conn *pgx.Conn, table string) error {
+	sql := fmt.Sprintf(`
+	DROP INDEX IF EXISTS %[1]s_geom_idx;
+	DROP INDEX IF EXISTS %[1]s_full_address_tsvector_idx;
+	DROP INDEX IF EXISTS %[1]s_area_idx;
+	DROP INDEX IF EXISTS %[1]s_normalized_address_idx;
+	DROP INDEX IF EXISTS %[1]s_kana_key_idx;
+	DROP INDEX IF EXISTS %[1]s_full_address_trgm_idx;
+	`, table)
+	for _, idx := range suggestIndexes {
+		sql += fmt.Sprintf("DROP INDEX IF EXISTS %s_%s;\n", table, idx.name)
+	}
+	_, err := conn.Exec(context.Background(), sql)
+	return err
+}
+
+func isTableEmpty(conn *pgx.Conn, table string) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(context.Background(), fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s)", table)).Scan(&exists)
+	return !exists, err
+}
+
+// analyzeTable refreshes the planner statistics for table, first vacuuming it when vacuum is set.
+// VACUUM can't run inside a transaction, so this must be called outside one.
+func analyzeTable(conn *pgx.Conn, table string, vacuum bool) error {
+	sql := "ANALYZE " + table
+	if vacuum {
+		sql = "VACUUM ANALYZE " + table
+	}
+	_, err := conn.Exec(context.Background(), sql)
+	return err
+}
+
+// createStagingTable (re)creates an empty staging copy of table with only the external ID
+// index that upserts need; the other indexes are built by swapStagingTable after the load.
+func createStagingTable(conn *pgx.Conn, table string) error {
+	_, err := conn.Exec(context.Background(), fmt.Sprintf(`
+	DROP TABLE IF EXISTS %[2]s;
+	CREATE TABLE %[2]s (LIKE %[1]s INCLUDING DEFAULTS INCLUDING GENERATED);
+	CREATE UNIQUE INDEX %[2]s_external_id_idx ON %[2]s (external_id);
+	`, table, stagingTableFor(table)))
+	return err
+}
+
+// swapStagingTable adds the primary key and indexes to the staging table and then, in a single
+// transaction, replaces table with it and resets processed_files to the given files. Readers
+// block only for the duration of the renames and never observe a partially loaded table.
+func swapStagingTable(conn *pgx.Conn, table string, files []importedFile) error {
+	ctx := context.Background()
+	staging := stagingTableFor(table)
+
+	// LIKE copies no constraints, so the primary key is added here, after the load like the indexes
+	indexes := fmt.Sprintf(`
+	ALTER TABLE %[1]s ADD CONSTRAINT %[1]s_pkey PRIMARY KEY (id);
+	CREATE INDEX %[1]s_geom_idx ON %[1]s USING GIST (geom);
+	CREATE INDEX %[1]s_full_address_tsvector_idx ON %[1]s USING GIN (full_address_tsvector);
+	CREATE INDEX %[1]s_area_idx ON %[1]s (prefecture, municipality);
+	CREATE INDEX %[1]s_normalized_address_idx ON %[1]s (normalized_address);
+	CREATE INDEX %[1]s_kana_key_idx ON %[1]s USING GIN (kana_key gin_trgm_ops);
+	CREATE INDEX %[1]s_full_address_trgm_idx ON %[1]s USING GIN (%[2]s gin_trgm_ops);
+	`, staging, repository.FullAddress)
+	for _, idx := range suggestIndexes {
+		indexes += fmt.Sprintf("CREATE INDEX %[1]s_%[2]s ON %[1]s (%[3]s text_pattern_ops);\n", staging, idx.name, idx.expr)
+	}
+	_, err := conn.Exec(ctx, indexes+"ANALYZE "+staging+";")
+	if err != nil {
+		return fmt.Errorf("failed to index staging table: %w", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin swap transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The id sequence belongs to the old table and would be dropped with it
+	swap := fmt.Sprintf(`
+	ALTER SEQUENCE %[1]s_id_seq OWNED BY %[2]s.id;
+	DROP TABLE %[1]s;
+	ALTER TABLE %[2]s RENAME TO %[1]s;
+	ALTER TABLE %[1]s RENAME CONSTRAINT %[2]s_pkey TO %[1]s_pkey;
+	ALTER INDEX %[2]s_geom_idx RENAME TO %[1]s_geom_idx;
+	ALTER INDEX %[2]s_full_address_tsvector_idx RENAME TO %[1]s_full_address_tsvector_idx;
+	ALTER INDEX %[2]s_area_idx RENAME TO %[1]s_area_idx;
+	ALTER INDEX %[2]s_normalized_address_idx RENAME TO %[1]s_normalized_address_idx;
+	ALTER INDEX %[2]s_kana_key_idx RENAME TO %[1]s_kana_key_idx;
+	ALTER INDEX %[2]s_full_address_trgm_idx RENAME TO %[1]s_full_address_trgm_idx;
+	ALTER INDEX %[2]s_external_id_idx RENAME TO %[1]s_external_id_idx;
+	`, table, staging)
+	for _, idx := range suggestIndexes {
+		swap += fmt.Sprintf("ALTER INDEX %[2]s_%[3]s RENAME TO %[1]s_%[3]s;\n", table, staging, idx.name)
+	}
+	_, err = tx.Exec(ctx, swap+"DELETE FROM processed_files;")
+	if err != nil {
+		return fmt.Errorf("failed to swap tables: %w", err)
+	}
+
+	for _, f := range files {
+		_, err = tx.Exec(ctx, "INSERT INTO processed_files (file_path, record_count) VALUES ($1, $2)", f.path, f.recordCount)
+		if err != nil {
+			return fmt.Errorf("failed to mark %s as processed: %w", f.path, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// copier is satisfied by both *pgx.Conn and pgx.Tx.
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// loadRecords inserts the records of one file in a single transaction unless commitEvery is set,
+// so a copy that fails part way leaves none of the file behind. A partitioned table first gets
+// the partitions the records need.
+func loadRecords(conn *pgx.Conn, table, filePath string, records []LocationRecord, commitEvery int, partitioned bool) error {
+	if partitioned {
+		if err := createPartitions(conn, table, records); err != nil {
+			return err
+		}
+	}
+
+	if commitEvery > 0 {
+		return insertRecordsInBatches(conn, table, filePath, records, commitEvery, partitioned)
+	}
+
+	var copied int64
+	err := pgx.BeginFunc(context.Background(), conn, func(tx pgx.Tx) error {
+		var err error
+		copied, err = insertRecords(tx, table, filePath, records, partitioned)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Rolled back %d of %d records copied from %s before the failure\n", copied, len(records), filePath)
+		return err
+	}
+	return nil
+}
+
+// insertRecordsInBatches commits records commitEvery rows at a time, storing the number of
+// committed rows in import_progress with each batch so a rerun after a crash resumes after
+// the last commit. This trades the all-or-nothing load of a single CopyFrom for shorter
+// transactions and steadier WAL: a failure part way through leaves the earlier batches
+// visible to readers until the file is rerun. Resuming relies on the file being unchanged
+// between runs, since progress is tracked by record offset.
+func insertRecordsInBatches(conn *pgx.Conn, table, filePath string, records []LocationRecord, commitEvery int, partitioned bool) error {
+	ctx := context.Background()
+
+	start, err := committedRecords(conn, table, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read import progress: %w", err)
+	}
+	if start > len(records) {
+		return fmt.Errorf("import progress for %s is at record %d but the file has only %d records", filePath, start, len(records))
+	}
+	if start > 0 {
+		fmt.Printf("Resuming %s after %d committed records\n", filePath, start)
+	}
+
+	for start < len(records) {
+		end := min(start+commitEvery, len(records))
+
+		var copied int64
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			var err error
+			copied, err = insertRecords(tx, table, filePath, records[start:end], partitioned)
+			if err != nil {
+				return err
+			}
+
+			// The final batch clears the progress so a later import of the same path starts over
+			if end == len(records) {
+				_, err = tx.Exec(ctx, "DELETE FROM import_progress WHERE file_path = $1 AND table_name = $2", filePath, table)
+				return err
+			}
+
+			_, err = tx.Exec(ctx, `
+			INSERT INTO import_progress (file_path, table_name, records_committed) VALUES ($1, $2, $3)
+			ON CONFLICT (file_path, table_name) DO UPDATE SET records_committed = EXCLUDED.records_committed, updated_at = NOW()`,
+				filePath, table, end)
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Rolled back %d of %d records copied from %s in the failed batch\n", copied, end-start, filePath)
+			return fmt.Errorf("failed to commit records %d-%d: %w", start+1, end, err)
+		}
+
+		fmt.Printf("Committed %d/%d records from %s\n", end, len(records), filePath)
+		start = end
+	}
+
+	return nil
+}
+
+func committedRecords(conn *pgx.Conn, table, filePath string) (int, error) {
+	var committed int
+	err := conn.QueryRow(context.Background(),
+		"SELECT COALESCE(MAX(records_committed), 0) FROM import_progress WHERE file_path = $1 AND table_name = $2",
+		filePath, table).Scan(&committed)
+	return committed, err
+}
+
+// insertRecords copies records into table, tagging each row with the file it came from, and
+// returns the number of rows copied. On failure the count is what the copy reached before it
+// stopped, which the caller reports since its transaction discards those rows.
+// Records with external IDs are upserted instead, see upsertRecords.
+func insertRecords(db copier, table, sourceFile string, records []LocationRecord, partitioned bool) (int64, error) {
+	if hasExternalIDs(records) {
+		return upsertRecords(db, table, sourceFile, records, partitioned)
+	}
+
+	// Use CopyFrom for bulk insert
+	return db.CopyFrom(
+		context.Background(),
+		pgx.Identifier{table},
+		append([]string{"prefecture", "municipality", "address_1", "address_2", "block_lot", "source_file", "importance", "normalized_address", "search_text", "geom"}, kanaCopyColumns...),
+		pgx.CopyFromSlice(len(records), func(i int) ([]interface{}, error) {
+			r := records[i]
+			return append([]interface{}{r.Prefecture, r.Municipality, r.Address1, r.Address2, r.BlockLot, sourceFile, r.importance(), r.normalizedAddress(), r.searchText(), r.geom()}, r.kana()...), nil
+		}),
+	)
+}
+
+// hasExternalIDs reports whether any of the records carries an external ID
+func hasExternalIDs(records []LocationRecord) bool {
+	for _, r := range records {
+		if r.ExternalID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertRecords copies records into a temporary table and merges them into table, so a record
+// whose external ID is already stored updates that row instead of adding a duplicate. Records
+// without an external ID are inserted as new rows. The records must not repeat an external ID
+// (see dedupeExternalIDs). On a partitioned table an ID only matches rows of the same prefecture.
+// The count returned is the rows copied into the temporary table, all of which are merged once
+// the copy succeeds.
+func upsertRecords(db copier, table, sourceFile string, records []LocationRecord, partitioned bool) (int64, error) {
+	ctx := context.Background()
+
+	_, err := db.Exec(ctx, `
+	CREATE TEMP TABLE IF NOT EXISTS import_upsert (
+		external_id TEXT,
+		prefecture VARCHAR(255),
+		municipality VARCHAR(255),
+		address_1 VARCHAR(255),
+		address_2 VARCHAR(255),
+		block_lot VARCHAR(255),
+		source_file TEXT,
+		importance REAL,
+		normalized_address TEXT,
+		search_text TEXT,
+		-- Untyped so it takes the target table's 2D or POINTZ points alike
+		geom GEOGRAPHY,
+		prefecture_kana TEXT,
+		municipality_kana TEXT,
+		address_1_kana TEXT,
+		address_2_kana TEXT,
+		kana_key TEXT
+	);
+	-- Temp tables created before importance, search keys/text and kana were imported, earlier on this connection
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS importance REAL;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS normalized_address TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS search_text TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS prefecture_kana TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS municipality_kana TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS address_1_kana TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS address_2_kana TEXT;
+	ALTER TABLE import_upsert ADD COLUMN IF NOT EXISTS kana_key TEXT;
+	TRUNCATE import_upsert;
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create upsert table: %w", err)
+	}
+
+	copied, err := db.CopyFrom(
+		ctx,
+		pgx.Identifier{"import_upsert"},
+		append([]string{"external_id", "prefecture", "municipality", "address_1", "address_2", "block_lot", "source_file", "importance", "normalized_address", "search_text", "geom"}, kanaCopyColumns...),
+		pgx.CopyFromSlice(len(records), func(i int) ([]interface{}, error) {
+			r := records[i]
+			var externalID any
+			if r.ExternalID != "" {
+				externalID = r.ExternalID
+			}
+			return append([]interface{}{externalID, r.Prefecture, r.Municipality, r.Address1, r.Address2, r.BlockLot, sourceFile, r.importance(), r.normalizedAddress(), r.searchText(), r.geom()}, r.kana()...), nil
+		}),
+	)
+	if err != nil {
+		return copied, fmt.Errorf("failed to copy records: %w", err)
+	}
+
+	_, err = db.Exec(ctx, fmt.Sprintf(`
+	INSERT INTO %s (external_id, prefecture, municipality, address_1, address_2, block_lot, source_file, importance, normalized_address, search_text, geom,
+		prefecture_kana, municipality_kana, address_1_kana, address_2_kana, kana_key)
+	SELECT external_id, prefecture, municipality, address_1, address_2, block_lot, source_file, importance, normalized_address, search_text, geom,
+		prefecture_kana, municipality_kana, address_1_kana, address_2_kana, kana_key
+	FROM import_upsert
+	ON CONFLICT (%s) DO UPDATE SET
+		prefecture = EXCLUDED.prefecture,
+		municipality = EXCLUDED.municipality,
+		address_1 = EXCLUDED.address_1,
+		address_2 = EXCLUDED.address_2,
+		block_lot = EXCLUDED.block_lot,
+		source_file = EXCLUDED.source_file,
+		importance = EXCLUDED.importance,
+		normalized_address = EXCLUDED.normalized_address,
+		search_text = EXCLUDED.search_text,
+		geom = EXCLUDED.geom,
+		prefecture_kana = EXCLUDED.prefecture_kana,
+		municipality_kana = EXCLUDED.municipality_kana,
+		address_1_kana = EXCLUDED.address_1_kana,
+		address_2_kana = EXCLUDED.address_2_kana,
+		kana_key = EXCLUDED.kana_key
+	`, table, externalIDKey(partitioned)))
+	if err != nil {
+		return copied, fmt.Errorf("failed to upsert records: %w", err)
+	}
+	return copied, nil
+}
+
+func verifyImport(conn *pgx.Conn, table string, expectedCount int) error {
+	var count int
+	err := conn.QueryRow(context.Background(), fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count records: %w", err)
+	}
+
+	if count < expectedCount {
+		return fmt.Errorf("record count mismatch: expected at least %d, got %d", expectedCount, count)
+	}
+
+	// Check a sample geom
+	var geom string
+	err = conn.QueryRow(context.Background(), fmt.Sprintf("SELECT ST_AsText(geom) FROM %s LIMIT 1", table)).Scan(&geom)
 	if err != nil {
 		return fmt.Errorf("failed to check geom: %w", err)
 	}
@@ -283,13 +1689,84 @@ func verifyImport(conn *pgx.Conn, expectedCount int) error {
 	return nil
 }
 
+// checkIntegrity compares, for each file imported in this run, the number of parsed records with
+// the count stored in processed_files and, when countRows is set, with the rows in table tagged
+// with that file. It prints every discrepancy and returns how many files had one. Counting rows
+// scans table once, so it is opt-in for large tables.
+func checkIntegrity(conn *pgx.Conn, table string, files []importedFile, countRows bool) (int, error) {
+	ctx := context.Background()
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+
+	recorded, err := countsByFile(ctx, conn, "SELECT file_path, record_count FROM processed_files WHERE file_path = ANY($1)", paths)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read processed_files: %w", err)
+	}
+
+	var stored map[string]int
+	if countRows {
+		stored, err = countsByFile(ctx, conn, fmt.Sprintf("SELECT source_file, COUNT(*) FROM %s WHERE source_file = ANY($1) GROUP BY source_file", table), paths)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count rows per file: %w", err)
+		}
+	}
+
+	discrepancies := 0
+	for _, f := range files {
+		recordedCount, ok := recorded[f.path]
+		mismatch := !ok || recordedCount != f.recordCount
+		if countRows && stored[f.path] != f.recordCount {
+			mismatch = true
+		}
+		if !mismatch {
+			continue
+		}
+
+		discrepancies++
+		msg := fmt.Sprintf("✗ %s: parsed %d records", f.path, f.recordCount)
+		if ok {
+			msg += fmt.Sprintf(", processed_files has %d", recordedCount)
+		} else {
+			msg += ", missing from processed_files"
+		}
+		if countRows {
+			msg += fmt.Sprintf(", %d rows in %s", stored[f.path], table)
+		}
+		fmt.Println(msg)
+	}
+
+	return discrepancies, nil
+}
+
+func countsByFile(ctx context.Context, conn *pgx.Conn, sql string, paths []string) (map[string]int, error) {
+	rows, err := conn.Query(ctx, sql, paths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var path string
+		var count int
+		if err := rows.Scan(&path, &count); err != nil {
+			return nil, err
+		}
+		counts[path] = count
+	}
+	return counts, rows.Err()
+}
+
 func findCSVFiles(directory string) ([]string, error) {
 	var files []string
 	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".csv" {
+		ext := strings.ToLower(filepath.Ext(path))
+		if !info.IsDir() && (ext == ".csv" || ext == ".tsv") {
 			files = append(files, path)
 		}
 		return nil
@@ -0,0 +1,396 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"geocoding-api/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func setupTestDatabase(t *testing.T) string {
+	ctx := context.Background()
+
+	// Start PostgreSQL container with PostGIS
+	req := testcontainers.ContainerRequest{
+		Image:        "postgis/postgis:16-3.4",
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_DB":       "testdb",
+			"POSTGRES_USER":     "testuser",
+			"POSTGRES_PASSWORD": "testpass",
+		},
+		WaitingFor: wait.ForLog("database system is ready to accept connections"),
+	}
+
+	postgresC, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		postgresC.Terminate(ctx)
+	})
+
+	host, err := postgresC.Host(ctx)
+	require.NoError(t, err)
+
+	port, err := postgresC.MappedPort(ctx, "5432")
+	require.NoError(t, err)
+
+	return "postgres://testuser:testpass@" + host + ":" + port.Port() + "/testdb?sslmode=disable"
+}
+
+func TestSwapStagingTable_ConcurrentReads(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, false))
+
+	initial := make([]LocationRecord, 100)
+	for i := range initial {
+		initial[i] = LocationRecord{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Lat: 35.681236, Lon: 139.767125}
+	}
+	_, err = insertRecords(conn, "locations", "initial.csv", initial, false)
+	require.NoError(t, err)
+
+	reloaded := make([]LocationRecord, 5000)
+	for i := range reloaded {
+		reloaded[i] = LocationRecord{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", Lat: 35.675, Lon: 139.732}
+	}
+
+	pool, err := pgxpool.New(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	// Readers must only ever see the complete old table or the complete new one
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := map[int]bool{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var count int
+				if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM locations").Scan(&count); err != nil {
+					continue
+				}
+				mu.Lock()
+				seen[count] = true
+				mu.Unlock()
+			}
+		}()
+	}
+
+	require.NoError(t, createStagingTable(conn, "locations"))
+	_, err = insertRecords(conn, stagingTableFor("locations"), "reloaded.csv", reloaded, false)
+	require.NoError(t, err)
+	require.NoError(t, swapStagingTable(conn, "locations", []importedFile{{path: "reloaded.csv", recordCount: len(reloaded)}}))
+
+	close(done)
+	wg.Wait()
+
+	for count := range seen {
+		assert.Contains(t, []int{len(initial), len(reloaded)}, count)
+	}
+
+	var count int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM locations").Scan(&count))
+	assert.Equal(t, len(reloaded), count)
+
+	processed, err := isFileProcessed(conn, "reloaded.csv")
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	discrepancies, err := checkIntegrity(conn, "locations", []importedFile{{path: "reloaded.csv", recordCount: len(reloaded)}}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 0, discrepancies)
+
+	discrepancies, err = checkIntegrity(conn, "locations", []importedFile{{path: "reloaded.csv", recordCount: len(reloaded) + 1}}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, discrepancies)
+
+	// A second swap must still work with the renamed sequence, primary key and indexes
+	require.NoError(t, createStagingTable(conn, "locations"))
+	_, err = insertRecords(conn, stagingTableFor("locations"), "initial.csv", initial, false)
+	require.NoError(t, err)
+	require.NoError(t, swapStagingTable(conn, "locations", nil))
+	_, err = insertRecords(conn, "locations", "initial.csv", initial[:1], false)
+	require.NoError(t, err)
+
+	var primaryKey string
+	require.NoError(t, conn.QueryRow(ctx, `
+		SELECT i.indexrelid::regclass::text
+		FROM pg_index i
+		WHERE i.indrelid = 'locations'::regclass AND i.indisprimary
+	`).Scan(&primaryKey))
+	assert.Equal(t, "locations_pkey", primaryKey)
+
+	var suggestIndex *string
+	require.NoError(t, conn.QueryRow(ctx, "SELECT to_regclass('locations_suggest_full_town_idx')::text").Scan(&suggestIndex))
+	assert.NotNil(t, suggestIndex)
+}
+
+func TestAnalyzeTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, false))
+
+	records := make([]LocationRecord, 500)
+	for i := range records {
+		records[i] = LocationRecord{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Lat: 35.681236, Lon: 139.767125}
+	}
+	_, err = insertRecords(conn, "locations", "records.csv", records, false)
+	require.NoError(t, err)
+
+	for _, vacuum := range []bool{false, true} {
+		require.NoError(t, analyzeTable(conn, "locations", vacuum))
+
+		// ANALYZE records the row estimate the planner uses in pg_class
+		var estimate float64
+		require.NoError(t, conn.QueryRow(ctx, "SELECT reltuples FROM pg_class WHERE relname = 'locations'").Scan(&estimate))
+		assert.Equal(t, float64(len(records)), estimate)
+	}
+}
+
+func TestInsertRecords_UpsertsExternalIDs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, false))
+
+	copied, err := insertRecords(conn, "locations", "v1.csv", []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", BlockLot: "1", Lat: 35.681236, Lon: 139.767125, ExternalID: "13101-000001"},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+	}, false)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, copied)
+
+	// Re-importing the dataset updates the row with the same external ID in place
+	_, err = insertRecords(conn, "locations", "v2.csv", []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", BlockLot: "1-2", Lat: 35.6813, Lon: 139.7672, ExternalID: "13101-000001"},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+	}, false)
+	require.NoError(t, err)
+
+	var total, withID int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*), COUNT(external_id) FROM locations").Scan(&total, &withID))
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 1, withID)
+
+	var blockLot, sourceFile string
+	require.NoError(t, conn.QueryRow(ctx, "SELECT block_lot, source_file FROM locations WHERE external_id = '13101-000001'").Scan(&blockLot, &sourceFile))
+	assert.Equal(t, "1-2", blockLot)
+	assert.Equal(t, "v2.csv", sourceFile)
+}
+
+func TestCreateTablesIfNotExists_PartitionByPrefecture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, true, false, false))
+
+	// An existing unpartitioned table can't be converted
+	_, err = conn.Exec(ctx, "CREATE TABLE plain_locations (LIKE locations)")
+	require.NoError(t, err)
+	assert.Error(t, createTablesIfNotExists(conn, "plain_locations", repository.FallbackTextSearchConfig, true, false, false))
+
+	require.NoError(t, loadRecords(conn, "locations", "v1.csv", []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", BlockLot: "1", Lat: 35.681236, Lon: 139.767125, ExternalID: "13101-000001"},
+		{Prefecture: "大阪府", Municipality: "大阪市北区", Address1: "梅田", BlockLot: "3", Lat: 34.7025, Lon: 135.4959},
+		{Prefecture: "不明", Municipality: "どこか", Address1: "どこか", Lat: 35.0, Lon: 135.0},
+	}, 0, true))
+
+	// Rows with the same external ID in the same prefecture are updated in place
+	require.NoError(t, loadRecords(conn, "locations", "v2.csv", []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", BlockLot: "1-2", Lat: 35.6813, Lon: 139.7672, ExternalID: "13101-000001"},
+	}, 0, true))
+
+	counts := map[string]int{}
+	for _, partition := range []string{"locations", "locations_p13", "locations_p27", "locations_default"} {
+		var count int
+		require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+partition).Scan(&count))
+		counts[partition] = count
+	}
+	assert.Equal(t, map[string]int{"locations": 3, "locations_p13": 1, "locations_p27": 1, "locations_default": 1}, counts)
+
+	var blockLot string
+	require.NoError(t, conn.QueryRow(ctx, "SELECT block_lot FROM locations WHERE external_id = '13101-000001'").Scan(&blockLot))
+	assert.Equal(t, "1-2", blockLot)
+
+	// Partitions created on demand inherit the parent's indexes
+	var indexes int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_indexes WHERE tablename = 'locations_p27'").Scan(&indexes))
+	assert.Equal(t, 6, indexes)
+
+	// Without the flag the table is still recognized as partitioned
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, false))
+	_, partitioned, err := tableKind(conn, "locations")
+	require.NoError(t, err)
+	assert.True(t, partitioned)
+}
+
+func TestCreateTablesIfNotExists_AppSearchText(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, true, false))
+
+	uses, err := usesSearchText(conn, "locations")
+	require.NoError(t, err)
+	assert.True(t, uses)
+
+	records := []LocationRecord{{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", Address2: "９番", Lat: 35.681236, Lon: 139.767125}}
+	setSearchTexts(records)
+	_, err = insertRecords(conn, "locations", "v1.csv", records, false)
+	require.NoError(t, err)
+
+	// The vector holds the normalized text, weighted like the address columns would be
+	var municipality, prefecture, address string
+	require.NoError(t, conn.QueryRow(ctx, `
+		SELECT ts_filter(full_address_tsvector, '{a}')::text, ts_filter(full_address_tsvector, '{b}')::text, ts_filter(full_address_tsvector, '{c}')::text
+		FROM locations
+	`).Scan(&municipality, &prefecture, &address))
+	assert.Contains(t, municipality, "千代田区")
+	assert.Contains(t, prefecture, "東京都")
+	assert.Contains(t, address, "丸の内1")
+	assert.NotContains(t, address, "一丁目")
+
+	// Without the flag the table keeps generating its vector from search_text
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, false))
+	uses, err = usesSearchText(conn, "locations")
+	require.NoError(t, err)
+	assert.True(t, uses)
+
+	// A table generating its vector from the address columns can't be converted
+	require.NoError(t, createTablesIfNotExists(conn, "plain_locations", repository.FallbackTextSearchConfig, false, false, false))
+	assert.Error(t, createTablesIfNotExists(conn, "plain_locations", repository.FallbackTextSearchConfig, false, true, false))
+}
+
+func TestCreateTablesIfNotExists_Altitude(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	ctx := context.Background()
+	connString := setupTestDatabase(t)
+
+	conn, err := pgx.Connect(ctx, connString)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close(ctx)
+	})
+
+	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS postgis")
+	require.NoError(t, err)
+	require.NoError(t, createTablesIfNotExists(conn, "locations", repository.FallbackTextSearchConfig, false, false, true))
+
+	uses, err := usesAltitude(conn, "locations")
+	require.NoError(t, err)
+	assert.True(t, uses)
+
+	records := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Lat: 35.681236, Lon: 139.767125, Altitude: sql.NullFloat64{Float64: 3.5, Valid: true}, ExternalID: "13101-000001"},
+	}
+	_, err = insertRecords(conn, "locations", "v1.csv", records, false)
+	require.NoError(t, err)
+	// Upserted through the temp table too
+	records[0].Altitude.Float64 = 4.5
+	_, err = insertRecords(conn, "locations", "v2.csv", records, false)
+	require.NoError(t, err)
+
+	var altitude float64
+	require.NoError(t, conn.QueryRow(ctx, "SELECT ST_Z(geom::geometry) FROM locations").Scan(&altitude))
+	assert.InDelta(t, 4.5, altitude, 0.001)
+
+	// A POINTZ column rejects 2D points
+	_, err = insertRecords(conn, "locations", "v3.csv", []LocationRecord{{Prefecture: "東京都", Lat: 35.675, Lon: 139.732}}, false)
+	assert.Error(t, err)
+
+	// A 2D table can't be converted
+	require.NoError(t, createTablesIfNotExists(conn, "plain_locations", repository.FallbackTextSearchConfig, false, false, false))
+	uses, err = usesAltitude(conn, "plain_locations")
+	require.NoError(t, err)
+	assert.False(t, uses)
+	assert.Error(t, createTablesIfNotExists(conn, "plain_locations", repository.FallbackTextSearchConfig, false, false, true))
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []LocationRecord
+	}{
+		{
+			name: "csv with BOM and CRLF",
+			file: "bom_crlf.csv",
+			expected: []LocationRecord{
+				{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+				{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂一丁目", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+			},
+		},
+		{
+			name: "tsv with BOM and CRLF",
+			file: "bom_crlf.tsv",
+			expected: []LocationRecord{
+				{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+			},
+		},
+		{
+			name: "csv without header",
+			file: "no_header.csv",
+			expected: []LocationRecord{
+				{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+				{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂一丁目", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, invalid, err := parseCSV(filepath.Join("testdata", tt.file), "", "", "", false, false)
+			require.NoError(t, err)
+			assert.Empty(t, invalid)
+			assert.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestQuantizeRecords(t *testing.T) {
+	records := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.6812361, Lon: 139.7671249},
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.6812359, Lon: 139.7671251},
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "2", Lat: 35.6812361, Lon: 139.7671249},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂一丁目", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+	}
+
+	kept, dropped := quantizeRecords(records, 6)
+
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "2", Lat: 35.681236, Lon: 139.767125},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂一丁目", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+	}, kept)
+}
+
+func TestCheckCoordinates(t *testing.T) {
+	newRecords := func() []LocationRecord {
+		return []LocationRecord{
+			{Prefecture: "東京都", Lat: 35.681236, Lon: 139.767125},
+			{Prefecture: "東京都", Lat: 139.732, Lon: 35.675},
+			{Prefecture: "沖縄県", Lat: 24.4672, Lon: 122.9877},
+			{Prefecture: "東京都", Lat: 0, Lon: 0},
+		}
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		records := newRecords()
+		swapped, outOfBounds := checkCoordinates(records, false)
+
+		assert.Equal(t, 1, swapped)
+		assert.Equal(t, []int{3}, outOfBounds)
+		assert.Equal(t, newRecords(), records)
+	})
+
+	t.Run("fix swapped", func(t *testing.T) {
+		records := newRecords()
+		swapped, outOfBounds := checkCoordinates(records, true)
+
+		assert.Equal(t, 1, swapped)
+		assert.Equal(t, []int{3}, outOfBounds)
+		assert.Equal(t, 35.675, records[1].Lat)
+		assert.Equal(t, 139.732, records[1].Lon)
+		assert.Equal(t, 0.0, records[3].Lat)
+	})
+}
+
+func TestReportCoordinateCheck_OutOfBounds(t *testing.T) {
+	newRecords := func() []LocationRecord {
+		return []LocationRecord{
+			{BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+			{BlockLot: "2", Lat: 0, Lon: 0},
+			{BlockLot: "3", Lat: 21.3, Lon: -157.8},
+			{BlockLot: "4", Lat: 139.732, Lon: 35.675},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected []string
+		err      string
+	}{
+		{name: "keep", policy: outOfBoundsKeep, expected: []string{"1", "2", "3", "4"}},
+		{name: "skip", policy: outOfBoundsSkip, expected: []string{"1", "4"}},
+		{name: "error", policy: outOfBoundsError, err: "2 records have coordinates outside Japan: (0, 0), (21.3, -157.8)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, outOfBounds, err := reportCoordinateCheck(newRecords(), false, tt.policy, "test.csv")
+
+			// Suspected swaps are not out of bounds
+			assert.Equal(t, 2, outOfBounds)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			blockLots := make([]string, len(records))
+			for i, r := range records {
+				blockLots[i] = r.BlockLot
+			}
+			assert.Equal(t, tt.expected, blockLots)
+		})
+	}
+}
+
+func TestRemoveRecords(t *testing.T) {
+	records := []LocationRecord{{BlockLot: "1"}, {BlockLot: "2"}, {BlockLot: "3"}, {BlockLot: "4"}}
+
+	assert.Equal(t, []LocationRecord{{BlockLot: "2"}, {BlockLot: "3"}}, removeRecords(records, []int{0, 3}))
+}
+
+func TestCheckExpectedCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected int
+		parsed   int
+		err      string
+	}{
+		{name: "disabled", expected: -1, parsed: 5},
+		{name: "match", expected: 5, parsed: 5},
+		{name: "truncated", expected: 5, parsed: 3, err: "expected 5 records but parsed 3 (-2)"},
+		{name: "extra rows", expected: 5, parsed: 6, err: "expected 5 records but parsed 6 (+1)"},
+		{name: "empty file", expected: 0, parsed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExpectedCount(tt.expected, tt.parsed)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateTable(t *testing.T) {
+	allowed := []string{"locations", "locations_test"}
+
+	tests := []struct {
+		name    string
+		table   string
+		wantErr bool
+	}{
+		{name: "default table", table: "locations"},
+		{name: "configured table", table: "locations_test"},
+		{name: "not allowlisted", table: "users", wantErr: true},
+		{name: "injection attempt", table: "locations; DROP TABLE users", wantErr: true},
+		{name: "quoted identifier", table: `"Locations"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTable(tt.table, allowed)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetSearchKeys(t *testing.T) {
+	records := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", Address2: "9番", BlockLot: "1号"},
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", Address2: "9", BlockLot: "1"},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", Address2: "１－２", BlockLot: ""},
+	}
+
+	setSearchKeys(records)
+
+	assert.Equal(t, "東京都千代田区丸の内1-9-1", records[0].NormalizedAddress)
+	assert.Equal(t, "東京都千代田区丸の内1-9-1", records[1].NormalizedAddress)
+	assert.Equal(t, "東京都港区赤坂1-2", records[2].NormalizedAddress)
+	assert.Equal(t, "東京都港区赤坂1-2", records[2].normalizedAddress())
+	assert.Nil(t, LocationRecord{}.normalizedAddress())
+}
+
+func TestSetSearchTexts(t *testing.T) {
+	records := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", Address2: "９番", BlockLot: "1号"},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", Address2: "1-2 ○○ビル 3F"},
+	}
+
+	setSearchTexts(records)
+
+	assert.Equal(t, "東京都\t千代田区\t丸の内1-9", records[0].SearchText)
+	assert.Equal(t, "東京都\t港区\t赤坂1-2", records[1].SearchText)
+	assert.Nil(t, LocationRecord{}.searchText())
+}
+
+func TestParseCSV_InvalidRows(t *testing.T) {
+	path := filepath.Join("testdata", "invalid_rows.csv")
+
+	t.Run("fails the file by default", func(t *testing.T) {
+		_, _, err := parseCSV(path, "", "", "", false, false)
+		assert.EqualError(t, err, "line 3: invalid latitude: north")
+	})
+
+	t.Run("skips and reports invalid rows", func(t *testing.T) {
+		records, invalid, err := parseCSV(path, "", "", "", false, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []LocationRecord{
+			{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+			{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂一丁目", BlockLot: "2", Lat: 35.675, Lon: 139.732},
+		}, records)
+		assert.Equal(t, []rowError{
+			{file: path, line: 3, record: []string{"東京都", "千代田区", "丸の内一丁目", "", "2", "9", "-35.1", "-6.2", "0", "north", "139.767125"}, reason: "invalid latitude: north"},
+			{file: path, line: 4, record: []string{"東京都", "千代田区"}, reason: "invalid record length: 2, expected at least 11 columns"},
+		}, invalid)
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		require.NoError(t, reportRowErrors(w, invalid, "errors.csv"))
+
+		// The error file can be fed back to the importer once the rows are corrected
+		assert.Equal(t, "東京都,千代田区,丸の内一丁目,,2,9,-35.1,-6.2,0,north,139.767125,"+path+",3,invalid latitude: north\n"+
+			"東京都,千代田区,,,,,,,,,,"+path+",4,\"invalid record length: 2, expected at least 11 columns\"\n", buf.String())
+	})
+}
+
+func TestParseCSV_InvalidUTF8(t *testing.T) {
+	path := filepath.Join("testdata", "invalid_utf8.csv")
+
+	t.Run("fails the file by default", func(t *testing.T) {
+		_, _, err := parseCSV(path, "", "", "", false, false)
+		assert.EqualError(t, err, "line 3: column 3 is not valid UTF-8")
+	})
+
+	t.Run("skips and reports rows with invalid bytes", func(t *testing.T) {
+		records, invalid, err := parseCSV(path, "", "", "", false, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []LocationRecord{
+			{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+		}, records)
+		require.Len(t, invalid, 2)
+		assert.Equal(t, 3, invalid[0].line)
+		assert.Equal(t, "column 3 is not valid UTF-8", invalid[0].reason)
+		assert.Equal(t, 4, invalid[1].line)
+		assert.Equal(t, "column 3 is not valid UTF-8", invalid[1].reason)
+	})
+
+	t.Run("repairs rows with invalid bytes", func(t *testing.T) {
+		records, invalid, err := parseCSV(path, "", "", "", true, false)
+		require.NoError(t, err)
+
+		assert.Empty(t, invalid)
+		assert.Equal(t, []LocationRecord{
+			{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Lat: 35.681236, Lon: 139.767125},
+			{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内二丁目", BlockLot: "2", Lat: 35.68, Lon: 139.765},
+			{Prefecture: "東京都", Municipality: "港区", Address1: "赤", BlockLot: "3", Lat: 35.675, Lon: 139.732},
+		}, records)
+	})
+}
+
+func TestParseCSV_ExternalID(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		column   string
+		expected []string
+	}{
+		{name: "by header name", file: "external_id.csv", column: "住所ID", expected: []string{"13101-000001", ""}},
+		{name: "by position", file: "external_id.csv", column: "12", expected: []string{"13101-000001", ""}},
+		{name: "by position without header", file: "no_header.csv", column: "5", expected: []string{"1", "2"}},
+		{name: "missing column", file: "bom_crlf.csv", column: "住所ID", expected: []string{"", ""}},
+		{name: "not configured", file: "external_id.csv", column: "", expected: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, _, err := parseCSV(filepath.Join("testdata", tt.file), tt.column, "", "", false, false)
+			require.NoError(t, err)
+
+			ids := make([]string, len(records))
+			for i, r := range records {
+				ids[i] = r.ExternalID
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestParseCSV_Importance(t *testing.T) {
+	path := filepath.Join("testdata", "importance.csv")
+
+	_, _, err := parseCSV(path, "", "人口", "", false, false)
+	assert.ErrorContains(t, err, "line 4: invalid importance: many")
+
+	records, invalid, err := parseCSV(path, "", "人口", "", false, true)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, sql.NullFloat64{Float64: 67216, Valid: true}, records[0].Importance)
+	assert.Equal(t, sql.NullFloat64{}, records[1].Importance)
+	assert.Equal(t, 67216.0, records[0].importance())
+	assert.Nil(t, records[1].importance())
+	require.Len(t, invalid, 1)
+	assert.Equal(t, 4, invalid[0].line)
+
+	// Without the column configured the values are ignored, even invalid ones
+	records, _, err = parseCSV(path, "", "", "", false, false)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.False(t, records[0].Importance.Valid)
+}
+
+func TestParseCSV_Altitude(t *testing.T) {
+	path := filepath.Join("testdata", "altitude.csv")
+
+	// The points are stored as POINTZ, so every row needs an altitude
+	_, _, err := parseCSV(path, "", "", "標高", false, false)
+	assert.ErrorContains(t, err, "line 3: missing altitude")
+
+	records, invalid, err := parseCSV(path, "", "", "標高", false, true)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, sql.NullFloat64{Float64: 3.5, Valid: true}, records[0].Altitude)
+	assert.Equal(t, "SRID=4326;POINT Z(139.767125 35.681236 3.500000)", records[0].geom())
+	require.Len(t, invalid, 2)
+	assert.Equal(t, "missing altitude", invalid[0].reason)
+	assert.Equal(t, "invalid altitude: high", invalid[1].reason)
+
+	_, _, err = parseCSV(path, "", "", "altitude", false, true)
+	assert.ErrorContains(t, err, `no "altitude" column`)
+
+	// Without the column configured every point stays 2D
+	records, _, err = parseCSV(path, "", "", "", false, false)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.False(t, records[0].Altitude.Valid)
+	assert.Equal(t, "SRID=4326;POINT(139.767125 35.681236)", records[0].geom())
+}
+
+func TestParseCSV_Kana(t *testing.T) {
+	records, _, err := parseCSV(filepath.Join("testdata", "kana.csv"), "", "", "", false, false)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	// The readings are stored as given, the key is normalized for search
+	assert.Equal(t, "トウキョウト", records[0].PrefectureKana)
+	assert.Equal(t, "チヨダク", records[0].MunicipalityKana)
+	assert.Equal(t, "マルノウチ 1チョウメ", records[0].Address1Kana)
+	assert.Equal(t, "", records[0].Address2Kana)
+	assert.Equal(t, "トウキョウトチヨダクマルノウチ1チョウメ", records[0].KanaKey)
+	assert.Equal(t, "トウキョウトミナトク", records[1].KanaKey)
+	assert.Equal(t, []any{"とうきょうと", "ﾐﾅﾄｸ", nil, nil, "トウキョウトミナトク"}, records[1].kana())
+
+	// A row without readings stores NULLs
+	assert.Equal(t, []any{nil, nil, nil, nil, nil}, records[2].kana())
+
+	// Files without kana columns are imported without readings
+	records, _, err = parseCSV(filepath.Join("testdata", "altitude.csv"), "", "", "", false, false)
+	require.NoError(t, err)
+	assert.Empty(t, records[0].KanaKey)
+}
+
+func TestDedupeExternalIDs(t *testing.T) {
+	records := []LocationRecord{
+		{ExternalID: "a", BlockLot: "1"},
+		{BlockLot: "2"},
+		{ExternalID: "b", BlockLot: "3"},
+		{ExternalID: "a", BlockLot: "4"},
+		{BlockLot: "5"},
+	}
+
+	kept, dropped := dedupeExternalIDs(records)
+
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, []LocationRecord{
+		{BlockLot: "2"},
+		{ExternalID: "b", BlockLot: "3"},
+		{ExternalID: "a", BlockLot: "4"},
+		{BlockLot: "5"},
+	}, kept)
+}
+
+func TestPrefecturePartitions(t *testing.T) {
+	records := []LocationRecord{
+		{Prefecture: "東京都"},
+		{Prefecture: "北海道"},
+		{Prefecture: "東京都"},
+		{Prefecture: "沖縄県"},
+		{Prefecture: "不明"},
+		{Prefecture: ""},
+	}
+
+	partitions := prefecturePartitions("locations", records)
+
+	assert.Equal(t, map[string]string{
+		"locations_p13": "東京都",
+		"locations_p01": "北海道",
+		"locations_p47": "沖縄県",
+	}, partitions)
+	assert.Len(t, prefectureCodes, 47)
+}
+
+func TestLocationsTableDDL(t *testing.T) {
+	plain := locationsTableDDL("locations", "simple", false, false, false)
+	assert.Contains(t, plain, "id BIGSERIAL PRIMARY KEY,")
+	assert.NotContains(t, plain, "PARTITION BY")
+	assert.Contains(t, plain, "setweight(to_tsvector('simple', coalesce(municipality, '')), 'A')")
+	assert.NotContains(t, plain, "split_part")
+
+	appSearchText := locationsTableDDL("locations", "simple", false, true, false)
+	assert.Contains(t, appSearchText, `setweight(to_tsvector('simple', split_part(coalesce(search_text, ''), E'\t', 2)), 'A')`)
+	assert.NotContains(t, appSearchText, "coalesce(municipality, '')")
+
+	partitioned := locationsTableDDL("locations", "simple", true, false, false)
+	assert.Contains(t, partitioned, "PRIMARY KEY (id, prefecture)")
+	assert.Contains(t, partitioned, ") PARTITION BY LIST (prefecture);")
+	assert.NotContains(t, partitioned, "id BIGSERIAL PRIMARY KEY")
+
+	assert.Contains(t, plain, "geom GEOGRAPHY(POINT, 4326)")
+	altitude := locationsTableDDL("locations", "simple", false, false, true)
+	assert.Contains(t, altitude, "geom GEOGRAPHY(POINTZ, 4326)")
+}
+
+// fakeCopier copies rows until failAt, then fails the way a rejected row would
+type fakeCopier struct {
+	failAt int
+	execs  []string
+}
+
+func (c *fakeCopier) CopyFrom(_ context.Context, _ pgx.Identifier, _ []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var copied int64
+	for rowSrc.Next() {
+		if int(copied) == c.failAt {
+			return copied, errors.New("invalid input syntax for type geography")
+		}
+		if _, err := rowSrc.Values(); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+func (c *fakeCopier) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	return pgconn.NewCommandTag("INSERT 0 0"), nil
+}
+
+func TestInsertRecords_CopiedCount(t *testing.T) {
+	records := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Lat: 35.681236, Lon: 139.767125},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", Lat: 35.675, Lon: 139.732},
+		{Prefecture: "大阪府", Municipality: "大阪市北区", Address1: "梅田", Lat: 34.7025, Lon: 135.4959},
+	}
+	withIDs := []LocationRecord{
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Lat: 35.681236, Lon: 139.767125, ExternalID: "13101-000001"},
+		{Prefecture: "東京都", Municipality: "港区", Address1: "赤坂", Lat: 35.675, Lon: 139.732},
+	}
+
+	tests := []struct {
+		name      string
+		records   []LocationRecord
+		failAt    int
+		expected  int64
+		expectErr bool
+		execs     int
+	}{
+		{name: "all rows copied", records: records, failAt: -1, expected: 3},
+		{name: "partial copy reports rows before the failure", records: records, failAt: 2, expected: 2, expectErr: true},
+		{name: "upsert counts staged rows", records: withIDs, failAt: -1, expected: 2, execs: 2},
+		{name: "failed upsert copy skips the merge", records: withIDs, failAt: 1, expected: 1, expectErr: true, execs: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeCopier{failAt: tt.failAt}
+
+			copied, err := insertRecords(db, "locations", "records.csv", tt.records, false)
+
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, copied)
+			assert.Len(t, db.execs, tt.execs)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package breaker implements the circuit breaker the API wraps its database calls in, so an
+// unavailable or overloaded database fails requests right away instead of making each one wait
+// for its timeout.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned instead of running a call while the circuit is open
+var ErrOpen = errors.New("breaker: circuit open")
+
+// DefaultCooldown is the default Config.Cooldown
+const DefaultCooldown = 30 * time.Second
+
+// State is the state of a circuit
+type State int
+
+const (
+	// Closed lets every call through, counting consecutive failures
+	Closed State = iota
+	// Open rejects every call with ErrOpen until the cooldown has passed
+	Open
+	// HalfOpen lets a single probe call through, whose outcome closes or reopens the circuit
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Config holds the breaker settings
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before a probe is let through. Defaults to
+	// DefaultCooldown.
+	Cooldown time.Duration
+	// OnStateChange, when set, is called after every transition, e.g. to log it
+	OnStateChange func(from, to State)
+}
+
+// Breaker is a consecutive-failure circuit breaker. It is safe for concurrent use, and a nil
+// *Breaker lets every call through, so components can be built without one.
+type Breaker struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	opens    int64
+	rejected int64
+}
+
+// New creates a closed breaker; a FailureThreshold of 0 or less disables it, returning nil
+func New(cfg Config) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+	return &Breaker{config: cfg, now: time.Now}
+}
+
+// Allow reports whether a call may run, returning ErrOpen while the circuit is open or a probe
+// is in flight. Every allowed call must be followed by Done with its outcome.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	switch {
+	case b.state == HalfOpen, b.state == Open && b.now().Sub(b.openedAt) < b.config.Cooldown:
+		b.rejected++
+		b.mu.Unlock()
+		return ErrOpen
+	case b.state == Open:
+		// The cooldown has passed; this call is the probe
+		b.transition(HalfOpen)
+		return nil
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// Done records the outcome of a call Allow let through
+func (b *Breaker) Done(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	switch {
+	case !failed:
+		b.failures = 0
+		if b.state != Closed {
+			b.transition(Closed)
+			return
+		}
+	case b.state == HalfOpen:
+		b.openedAt = b.now()
+		b.transition(Open)
+		return
+	case b.state == Closed:
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.failures = 0
+			b.openedAt = b.now()
+			b.transition(Open)
+			return
+		}
+	}
+	b.mu.Unlock()
+}
+
+// State returns the circuit's current state; a nil breaker is always closed
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Counts returns the number of times the circuit opened and the calls it rejected since b was created
+func (b *Breaker) Counts() (opens, rejected int64) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opens, b.rejected
+}
+
+// transition moves to state and unlocks b, calling OnStateChange after the lock is released
+func (b *Breaker) transition(state State) {
+	from := b.state
+	b.state = state
+	if state == Open {
+		b.opens++
+	}
+	b.mu.Unlock()
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, state)
+	}
+}
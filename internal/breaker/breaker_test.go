@@ -0,0 +1,74 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+	b := New(Config{FailureThreshold: 3, Cooldown: time.Minute, OnStateChange: func(from, to State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	call := func(failed bool) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		b.Done(failed)
+		return nil
+	}
+
+	// A success resets the count, so only consecutive failures open the circuit
+	require.NoError(t, call(true))
+	require.NoError(t, call(true))
+	require.NoError(t, call(false))
+	require.NoError(t, call(true))
+	require.NoError(t, call(true))
+	assert.Equal(t, Closed, b.State())
+	require.NoError(t, call(true))
+	assert.Equal(t, Open, b.State())
+
+	// Open: calls fail fast until the cooldown has passed
+	assert.ErrorIs(t, call(false), ErrOpen)
+	now = now.Add(59 * time.Second)
+	assert.ErrorIs(t, call(false), ErrOpen)
+
+	// Half-open: one probe is let through, others are rejected while it runs
+	now = now.Add(time.Second)
+	require.NoError(t, b.Allow())
+	assert.Equal(t, HalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// A failed probe reopens the circuit for another cooldown
+	b.Done(true)
+	assert.Equal(t, Open, b.State())
+	now = now.Add(30 * time.Second)
+	assert.ErrorIs(t, call(false), ErrOpen)
+
+	// A successful probe closes it
+	now = now.Add(30 * time.Second)
+	require.NoError(t, call(false))
+	assert.Equal(t, Closed, b.State())
+
+	opens, rejected := b.Counts()
+	assert.Equal(t, int64(2), opens)
+	assert.Equal(t, int64(4), rejected)
+	assert.Equal(t, []string{"closed->open", "open->half_open", "half_open->open", "open->half_open", "half_open->closed"}, transitions)
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := New(Config{})
+	require.Nil(t, b)
+
+	for range 10 {
+		require.NoError(t, b.Allow())
+		b.Done(true)
+	}
+	assert.Equal(t, Closed, b.State())
+}
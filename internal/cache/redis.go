@@ -0,0 +1,317 @@
+// Package cache provides geocode result caches shared between API instances.
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/rs/zerolog/log"
+)
+
+// keyPrefix namespaces the cache's keys; bump its version when the stored format changes so
+// instances running different versions don't read each other's entries
+const keyPrefix = "geocode:v1:"
+
+// RedisConfig holds the Redis cache settings
+type RedisConfig struct {
+	// TTL is how long a result stays cached; it must be positive
+	TTL time.Duration
+	// Timeout bounds each Redis round trip, including connecting; a slower lookup is a miss.
+	// Defaults to 100ms.
+	Timeout time.Duration
+	// PoolSize is the number of idle connections kept open. Defaults to 10.
+	PoolSize int
+	// QueueSize is the number of writes that may wait for the background writer; further
+	// writes are dropped until it catches up. Defaults to 1000.
+	QueueSize int
+}
+
+// RedisCache is a geocode result cache stored in Redis, so every API instance shares it. Reads
+// are bounded by the configured timeout and any failure is reported as a miss; writes are
+// queued and sent by a background goroutine, so Redis latency never delays a response.
+type RedisCache struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      *tls.Config
+	config   RedisConfig
+
+	idle   chan *redisConn
+	writes chan redisWrite
+	done   chan struct{}
+}
+
+// redisWrite is a cache entry waiting for the background writer
+type redisWrite struct {
+	key   string
+	value []byte
+}
+
+// NewRedisCache creates a cache on the Redis server at rawURL, a redis:// or rediss:// (TLS)
+// URL with an optional username, password and database number, e.g.
+// redis://:secret@localhost:6379/0. Connections are opened on first use, so a Redis that is
+// down only costs cache misses. Close stops the background writer.
+func NewRedisCache(rawURL string, cfg RedisConfig) (*RedisCache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cache: invalid redis url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("cache: invalid redis url scheme %q, must be redis or rediss", u.Scheme)
+	}
+
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("cache: redis ttl must be positive, got %s", cfg.TTL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 100 * time.Millisecond
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+
+	c := &RedisCache{
+		addr:   u.Host,
+		config: cfg,
+		idle:   make(chan *redisConn, cfg.PoolSize),
+		writes: make(chan redisWrite, cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		c.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("cache: invalid redis database %q", db)
+		}
+	}
+	if u.Scheme == "rediss" {
+		c.tls = &tls.Config{ServerName: u.Hostname()}
+	}
+
+	go c.writeLoop()
+	return c, nil
+}
+
+// Get implements service.Cache.
+func (c *RedisCache) Get(ctx context.Context, key string) (*models.GeocodeResult, bool) {
+	value, err := c.do(ctx, "GET", redisKey(key))
+	if err != nil || value == nil {
+		return nil, false
+	}
+
+	var result models.GeocodeResult
+	if err := json.Unmarshal(value, &result); err != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// Set implements service.Cache. The result is serialized right away and written in the
+// background; when the write queue is full the result is not cached.
+func (c *RedisCache) Set(ctx context.Context, key string, result *models.GeocodeResult) {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	select {
+	case c.writes <- redisWrite{key: redisKey(key), value: value}:
+	default:
+	}
+}
+
+// Close stops the background writer, dropping queued writes, and closes idle connections
+func (c *RedisCache) Close() {
+	close(c.done)
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// writeLoop sends queued writes to Redis until Close
+func (c *RedisCache) writeLoop() {
+	ttl := strconv.FormatInt(c.config.TTL.Milliseconds(), 10)
+	for {
+		select {
+		case <-c.done:
+			return
+		case w := <-c.writes:
+			if _, err := c.do(context.Background(), "SET", w.key, string(w.value), "PX", ttl); err != nil {
+				log.Warn().Err(err).Msg("failed to write geocode result to redis")
+			}
+		}
+	}
+}
+
+// do runs one command on a pooled connection, returning the reply's value (nil for a nil
+// reply). A connection that fails is closed rather than returned to the pool.
+func (c *RedisCache) do(ctx context.Context, args ...string) ([]byte, error) {
+	deadline := time.Now().Add(c.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	conn, err := c.conn(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(deadline, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection, or dials a new one authenticated and switched to the
+// configured database
+func (c *RedisCache) conn(deadline time.Time) (*redisConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	var netConn net.Conn
+	var err error
+	if c.tls != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", c.addr, c.tls)
+	} else {
+		netConn, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cache: failed to connect to redis: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, r: bufio.NewReader(netConn)}
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := conn.do(deadline, args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("cache: failed to authenticate to redis: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.do(deadline, "SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("cache: failed to select redis database: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// redisKey hashes a SearchOptions.CacheKey, which can be long, into a fixed-size Redis key
+func redisKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return keyPrefix + hex.EncodeToString(sum[:])
+}
+
+// redisError is an error reply from Redis; the connection is still usable after one
+type redisError string
+
+func (e redisError) Error() string {
+	return "cache: redis: " + string(e)
+}
+
+// redisConn speaks the subset of the Redis protocol (RESP) the cache needs: commands as
+// arrays of bulk strings, and simple string, error, integer and bulk string replies
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(deadline time.Time, args ...string) ([]byte, error) {
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.Conn, b.String()); err != nil {
+		return nil, fmt.Errorf("cache: failed to send redis command: %w", err)
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("cache: invalid redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, value); err != nil {
+			return nil, fmt.Errorf("cache: failed to read redis reply: %w", err)
+		}
+		return value[:n], nil
+	default:
+		return nil, fmt.Errorf("cache: unexpected redis reply %q", line)
+	}
+}
+
+// readLine reads one CRLF-terminated reply line without its terminator
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("cache: failed to read redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("cache: empty redis reply")
+	}
+	return line, nil
+}
@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ service.Cache = (*RedisCache)(nil)
+
+// fakeRedis is an in-process Redis server supporting the commands RedisCache sends. It records
+// every command, and answers GET and SET from a map, ignoring expiry.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands [][]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{listener: listener, password: password, values: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url(path string) string {
+	return "redis://" + f.listener.Addr().String() + path
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			authenticated = args[len(args)-1] == f.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required\r\n"
+		case args[0] == "SELECT":
+			reply = "+OK\r\n"
+		case args[0] == "SET":
+			f.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case args[0] == "GET":
+			value, ok := f.values[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+		args[i] = string(value[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) recorded() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string{}, f.commands...)
+}
+
+func (f *fakeRedis) stored() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.values)
+}
+
+func TestRedisCache_SetAndGet(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	c, err := NewRedisCache(redis.url(""), RedisConfig{TTL: time.Minute})
+	require.NoError(t, err)
+	defer c.Close()
+
+	result := &models.GeocodeResult{
+		Results: []models.Location{
+			{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125},
+		},
+		NextCursor: "abc",
+	}
+
+	_, ok := c.Get(context.Background(), "key")
+	assert.False(t, ok)
+
+	c.Set(context.Background(), "key", result)
+	require.Eventually(t, func() bool { return redis.stored() == 1 }, time.Second, 5*time.Millisecond)
+
+	cached, ok := c.Get(context.Background(), "key")
+	require.True(t, ok)
+	assert.Equal(t, result, cached)
+
+	_, ok = c.Get(context.Background(), "other key")
+	assert.False(t, ok)
+
+	var set []string
+	for _, cmd := range redis.recorded() {
+		if cmd[0] == "SET" {
+			set = cmd
+		}
+	}
+	require.Len(t, set, 5)
+	assert.Equal(t, redisKey("key"), set[1])
+	assert.True(t, strings.HasPrefix(set[1], "geocode:v1:"))
+	assert.Equal(t, []string{"PX", "60000"}, set[3:])
+}
+
+func TestRedisCache_AuthAndDatabase(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		expected [][]string
+		hit      bool
+	}{
+		{
+			name:     "password and database",
+			user:     ":secret@",
+			path:     "/2",
+			expected: [][]string{{"AUTH", "secret"}, {"SELECT", "2"}},
+			hit:      true,
+		},
+		{
+			name:     "username",
+			user:     "geocoder:secret@",
+			expected: [][]string{{"AUTH", "geocoder", "secret"}},
+			hit:      true,
+		},
+		{
+			name:     "wrong password",
+			user:     ":guess@",
+			expected: [][]string{{"AUTH", "guess"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis.mu.Lock()
+			redis.commands = nil
+			redis.values = map[string]string{redisKey("key"): `{"results":[]}`}
+			redis.mu.Unlock()
+
+			c, err := NewRedisCache("redis://"+tt.user+redis.listener.Addr().String()+tt.path, RedisConfig{TTL: time.Minute})
+			require.NoError(t, err)
+			defer c.Close()
+
+			_, ok := c.Get(context.Background(), "key")
+
+			assert.Equal(t, tt.hit, ok)
+			assert.Equal(t, tt.expected, redis.recorded()[:len(tt.expected)])
+		})
+	}
+}
+
+func TestRedisCache_Unavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c, err := NewRedisCache("redis://"+addr, RedisConfig{TTL: time.Minute, QueueSize: 1})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// A down Redis is a miss, and writes neither block nor fail the caller
+	_, ok := c.Get(context.Background(), "key")
+	assert.False(t, ok)
+	for i := 0; i < 10; i++ {
+		c.Set(context.Background(), "key", &models.GeocodeResult{})
+	}
+}
+
+func TestNewRedisCache_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		ttl  time.Duration
+	}{
+		{name: "wrong scheme", url: "http://localhost:6379", ttl: time.Minute},
+		{name: "invalid database", url: "redis://localhost:6379/first", ttl: time.Minute},
+		{name: "unparseable", url: "redis://local host:6379", ttl: time.Minute},
+		{name: "no ttl", url: "redis://localhost:6379"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedisCache(tt.url, RedisConfig{TTL: tt.ttl})
+			assert.Error(t, err)
+		})
+	}
+}
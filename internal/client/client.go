@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"geocoding-api/internal/models"
+)
+
+// Sentinel errors matched by APIError via errors.Is
+var (
+	ErrBadRequest = errors.New("client: bad request")
+	ErrNotFound   = errors.New("client: not found")
+	ErrServer     = errors.New("client: server error")
+)
+
+// APIError is returned when the API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: api returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code onto the sentinel errors so callers can use errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// Options holds optional per-call settings
+type Options struct {
+	// Timeout bounds the whole call; zero means only the context deadline applies
+	Timeout time.Duration
+}
+
+// Client is a typed client for the geocoding API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new API client; a nil httpClient uses http.DefaultClient
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Geocode searches for locations matching the address text
+func (c *Client) Geocode(ctx context.Context, query string, opts *Options) ([]models.Location, error) {
+	params := url.Values{}
+	params.Set("q", query)
+
+	var locations []models.Location
+	if err := c.get(ctx, "/geocode", params, opts, &locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// ReverseGeocode finds the nearest address to the given coordinates
+func (c *Client) ReverseGeocode(ctx context.Context, lat, lon float64, opts *Options) (*models.Location, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+
+	var location models.Location
+	if err := c.get(ctx, "/reverse-geocode", params, opts, &location); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, params url.Values, opts *Options, out interface{}) error {
+	if opts != nil && opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("client: failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			apiErr.Message = body.Error
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Geocode(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		expected    []models.Location
+		expectedErr error
+	}{
+		{
+			name:   "successful geocoding",
+			status: http.StatusOK,
+			body:   `[{"id":1,"prefecture":"東京都","municipality":"千代田区","address1":"丸の内","latitude":35.681236,"longitude":139.767125}]`,
+			expected: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125},
+			},
+		},
+		{
+			name:        "bad request",
+			status:      http.StatusBadRequest,
+			body:        `{"error":"missing required query parameter 'q'"}`,
+			expectedErr: ErrBadRequest,
+		},
+		{
+			name:        "server error",
+			status:      http.StatusInternalServerError,
+			body:        `{"error":"internal server error"}`,
+			expectedErr: ErrServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotQuery string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotQuery = r.URL.Query().Get("q")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL+"/", nil)
+			result, err := c.Geocode(context.Background(), "東京都千代田区丸の内", nil)
+
+			assert.Equal(t, "/geocode", gotPath)
+			assert.Equal(t, "東京都千代田区丸の内", gotQuery)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestClient_ReverseGeocode(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		expected    *models.Location
+		expectedErr error
+		expectedMsg string
+	}{
+		{
+			name:     "successful reverse geocoding",
+			status:   http.StatusOK,
+			body:     `{"id":1,"prefecture":"東京都","municipality":"千代田区","address1":"丸の内","latitude":35.681236,"longitude":139.767125}`,
+			expected: &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125},
+		},
+		{
+			name:        "not found",
+			status:      http.StatusNotFound,
+			body:        `{"error":"no address found near the specified coordinates"}`,
+			expectedErr: ErrNotFound,
+			expectedMsg: "no address found near the specified coordinates",
+		},
+		{
+			name:        "error without json body",
+			status:      http.StatusBadGateway,
+			body:        `upstream unavailable`,
+			expectedErr: ErrServer,
+			expectedMsg: "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLat, gotLon string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotLat = r.URL.Query().Get("lat")
+				gotLon = r.URL.Query().Get("lon")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL, nil)
+			result, err := c.ReverseGeocode(context.Background(), 35.681236, 139.767125, nil)
+
+			assert.Equal(t, "35.681236", gotLat)
+			assert.Equal(t, "139.767125", gotLon)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				var apiErr *APIError
+				require.True(t, errors.As(err, &apiErr))
+				assert.Equal(t, tt.status, apiErr.StatusCode)
+				assert.Equal(t, tt.expectedMsg, apiErr.Message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, nil)
+	_, err := c.Geocode(context.Background(), "丸の内", &Options{Timeout: 10 * time.Millisecond})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -1,15 +1,171 @@
 package config
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"geocoding-api/internal/models"
+
 	"github.com/spf13/viper"
 )
 
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variable.
 type Config struct {
-	DBDriver   string `mapstructure:"DB_DRIVER"`
-	DBSource   string `mapstructure:"DB_SOURCE"`
-	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	DBDriver string `mapstructure:"DB_DRIVER"`
+	DBSource string `mapstructure:"DB_SOURCE"`
+	// DBConnectTimeout is how long the API retries reaching the database at startup before
+	// exiting, for deployments that start it together with the database; 0 tries once
+	DBConnectTimeout time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	ServerAddress    string        `mapstructure:"SERVER_ADDRESS"`
+	// DefaultLanguage is the error message language used when Accept-Language names no supported language ("en" or "ja")
+	DefaultLanguage string `mapstructure:"DEFAULT_LANGUAGE"`
+	// MinQueryLength is the minimum number of letters/digits accepted by /geocode; 0 disables the check
+	MinQueryLength int `mapstructure:"MIN_QUERY_LENGTH"`
+	// MaxQueryTerms is the maximum number of words in a /geocode query, bounding the size of its
+	// tsquery; 0 disables the check
+	MaxQueryTerms int `mapstructure:"MAX_QUERY_TERMS"`
+	// TextSearchConfig is the Postgres text search configuration shared by the importer and the API
+	TextSearchConfig string `mapstructure:"TEXT_SEARCH_CONFIG"`
+	// TextSearchFallback falls back to the built-in "simple" configuration when TextSearchConfig is not installed
+	TextSearchFallback bool `mapstructure:"TEXT_SEARCH_FALLBACK"`
+	// TextSearchRetryConfigs are text search configurations /geocode's full-text search retries
+	// with, in order, when TextSearchConfig finds fewer than GeocodeStrategyMinResults results;
+	// e.g. ["simple"] recovers tokens the "japanese" dictionary drops. Ones not installed are skipped.
+	TextSearchRetryConfigs []string `mapstructure:"TEXT_SEARCH_RETRY_CONFIGS"`
+	// AddressNormalization canonicalizes address numbers ("1丁目2番3号" -> "1-2-3") in /geocode
+	// queries. The importer then creates new tables as with --app-search-text and normalizes their
+	// search text the same way, keeping the address columns as the dataset gives them; changing it
+	// requires re-importing so stored data matches queries
+	AddressNormalization bool `mapstructure:"ADDRESS_NORMALIZATION"`
+	// BlockedQueryPatterns are regular expressions; a /geocode query matching any of them is
+	// rejected with a 400 before it reaches the database. The API reloads them on SIGHUP.
+	BlockedQueryPatterns []string `mapstructure:"BLOCKED_QUERY_PATTERNS"`
+	// RejectControlChars answers /geocode queries containing NUL or other control characters with
+	// a 400; by default the characters are stripped before searching
+	RejectControlChars bool `mapstructure:"REJECT_CONTROL_CHARS"`
+	// StripBuildingNames drops a trailing building name ("○○ビル") from /geocode queries before searching
+	StripBuildingNames bool `mapstructure:"STRIP_BUILDING_NAMES"`
+	// SlowQueryThreshold logs repository queries slower than this (e.g. "500ms"); 0 disables it
+	SlowQueryThreshold time.Duration `mapstructure:"SLOW_QUERY_THRESHOLD"`
+	// DebugQueryPlans re-runs /geocode searches slower than SlowQueryThreshold under EXPLAIN
+	// (ANALYZE, BUFFERS), logging the plan and returning it with debug=true. EXPLAIN ANALYZE
+	// executes the query again, so keep it off in production.
+	DebugQueryPlans bool `mapstructure:"DEBUG_QUERY_PLANS"`
+	// GeocodeCacheTTL is how long /geocode results are cached in memory; 0 disables the cache
+	GeocodeCacheTTL time.Duration `mapstructure:"GEOCODE_CACHE_TTL"`
+	// GeocodeCacheSize is the maximum number of cached /geocode results
+	GeocodeCacheSize int `mapstructure:"GEOCODE_CACHE_SIZE"`
+	// PaginationSnapshotTTL keeps the first 1000 ranked results of a paginated /geocode query in
+	// memory this long, serving its offset pages from them so pages stay consistent while imports
+	// change the data; 0 searches every page live. Each instance keeps its own snapshots, and a
+	// snapshot can hold 1000 locations, so memory grows with PAGINATION_SNAPSHOT_SIZE times that.
+	// Keep it at least GEOCODE_CACHE_TTL.
+	PaginationSnapshotTTL time.Duration `mapstructure:"PAGINATION_SNAPSHOT_TTL"`
+	// PaginationSnapshotSize is the maximum number of queries with a pagination snapshot
+	PaginationSnapshotSize int `mapstructure:"PAGINATION_SNAPSHOT_SIZE"`
+	// GeocodeStrategies are the search strategies /geocode tries in order ("exact", "fulltext",
+	// "fuzzy", "interpolated", "normalized", "kana"), stopping at the first that finds GeocodeStrategyMinResults
+	// results. "normalized" needs data imported with --normalized-key, "kana" data with kana reading
+	// columns; it only searches queries written in kana. "fulltext,fuzzy" falls back to trigram
+	// similarity when full-text search finds nothing, catching typos; /geocode?fuzzy=false skips it.
+	GeocodeStrategies []string `mapstructure:"GEOCODE_STRATEGIES"`
+	// GeocodeStrategyMinResults is the number of results that satisfies a strategy; 0 means 1
+	GeocodeStrategyMinResults int `mapstructure:"GEOCODE_STRATEGY_MIN_RESULTS"`
+	// DisambiguationThreshold flags a /geocode query as ambiguous when results within this fraction
+	// of the best score (0.1 = 10%) are in more than one prefecture, so /geocode?disambiguate=true
+	// returns their areas to pick from; 0 disables the check
+	DisambiguationThreshold float64 `mapstructure:"DISAMBIGUATION_THRESHOLD"`
+	// DensityRadiusMeters is the radius /geocode?include_density=true counts locations within
+	// around each result (default 100)
+	DensityRadiusMeters float64 `mapstructure:"DENSITY_RADIUS_METERS"`
+	// DensityMaxResults caps the results of /geocode?include_density=true, which costs a spatial
+	// count per result; larger radii in dense areas make each count slower (default 20)
+	DensityMaxResults int `mapstructure:"DENSITY_MAX_RESULTS"`
+	// RedisURL stores the /geocode cache in Redis (redis://[user:password@]host:port/db) instead of
+	// memory, so every API instance shares it; empty keeps the in-memory cache
+	RedisURL string `mapstructure:"REDIS_URL"`
+	// RedisTimeout bounds each cache lookup in Redis; a slower one falls through to the database
+	RedisTimeout time.Duration `mapstructure:"REDIS_TIMEOUT"`
+	// MaxSpatialRadiusMeters caps the search radius of every spatial query (default 10000)
+	MaxSpatialRadiusMeters float64 `mapstructure:"MAX_SPATIAL_RADIUS_METERS"`
+	// NearbyDefaultLimit and NearbyMaxLimit are the default and largest number of /nearby results
+	// (defaults 10 and 100); larger requested limits are clamped
+	NearbyDefaultLimit int `mapstructure:"NEARBY_DEFAULT_LIMIT"`
+	NearbyMaxLimit     int `mapstructure:"NEARBY_MAX_LIMIT"`
+	// WithinDefaultLimit and WithinMaxLimit are the default and largest number of /within results
+	// (defaults 100 and 1000); larger requested limits are clamped, and a box holding more
+	// addresses is answered with truncated set
+	WithinDefaultLimit int `mapstructure:"WITHIN_DEFAULT_LIMIT"`
+	WithinMaxLimit     int `mapstructure:"WITHIN_MAX_LIMIT"`
+	// AddressRanges makes /reverse-geocode fall back to interpolating along the nearest address
+	// range segment when no address point is in range; it needs the address_segments table
+	AddressRanges bool `mapstructure:"ADDRESS_RANGES"`
+	// ColocationMeters is the distance within which /reverse-geocode?include_colocated=true groups
+	// addresses with the nearest one as units of the same building (default 5)
+	ColocationMeters float64 `mapstructure:"COLOCATION_METERS"`
+	// ReverseTieMeters is how much farther than the nearest address a /reverse-geocode candidate may
+	// be and still be reordered by the sort parameter's preference (default 1)
+	ReverseTieMeters float64 `mapstructure:"REVERSE_TIE_METERS"`
+	// MunicipalityBoundaries enables /reverse-geocode?prefer=admin, which resolves the point
+	// against municipality polygons; it needs the municipality_boundaries table
+	MunicipalityBoundaries bool `mapstructure:"MUNICIPALITY_BOUNDARIES"`
+	// SpatialWarmup runs a spatial query around SpatialWarmupLat/Lon at startup, so the first
+	// reverse geocode after a cold start doesn't wait for the index to be read from disk
+	SpatialWarmup    bool    `mapstructure:"SPATIAL_WARMUP"`
+	SpatialWarmupLat float64 `mapstructure:"SPATIAL_WARMUP_LAT"`
+	SpatialWarmupLon float64 `mapstructure:"SPATIAL_WARMUP_LON"`
+	// SpatialWarmupTimeout bounds the warmup query, which is skipped when it runs out; 0 leaves it unbounded
+	SpatialWarmupTimeout time.Duration `mapstructure:"SPATIAL_WARMUP_TIMEOUT"`
+	// QueryTimeout bounds how long a request's database queries may run; 0 leaves them unbounded
+	QueryTimeout time.Duration `mapstructure:"QUERY_TIMEOUT"`
+	// Per-endpoint query timeouts, so cheap lookups and expensive scans can be bounded differently;
+	// 0 falls back to QueryTimeout
+	GeocodeTimeout        time.Duration `mapstructure:"GEOCODE_TIMEOUT"`
+	ReverseGeocodeTimeout time.Duration `mapstructure:"REVERSE_GEOCODE_TIMEOUT"`
+	LocationsTimeout      time.Duration `mapstructure:"LOCATIONS_TIMEOUT"`
+	DistanceMatrixTimeout time.Duration `mapstructure:"DISTANCE_MATRIX_TIMEOUT"`
+	// DBBreakerFailures opens the database circuit breaker after this many consecutive queries
+	// fail with connection, resource or timeout errors; while it is open every request needing
+	// the database fails right away with a 503 instead of waiting for its timeout. 0 disables it.
+	DBBreakerFailures int `mapstructure:"DB_BREAKER_FAILURES"`
+	// DBBreakerCooldown is how long the breaker stays open before one query probes the database
+	// again, closing it on success (default 30s)
+	DBBreakerCooldown time.Duration `mapstructure:"DB_BREAKER_COOLDOWN"`
+	// StatementTimeout has Postgres itself cancel the expensive text and spatial queries that run
+	// longer (SET LOCAL statement_timeout), as a backstop to the timeouts above; 0 disables it
+	StatementTimeout time.Duration `mapstructure:"STATEMENT_TIMEOUT"`
+	// BatchWorkers is the number of background workers running /geocode/batch jobs; 0 leaves
+	// queued jobs to other API instances
+	BatchWorkers int `mapstructure:"BATCH_WORKERS"`
+	// BatchPollInterval is how often an idle batch worker checks for queued jobs
+	BatchPollInterval time.Duration `mapstructure:"BATCH_POLL_INTERVAL"`
+	// BatchStaleAfter reclaims a running batch job that made no progress for this long, e.g. after a crash
+	BatchStaleAfter time.Duration `mapstructure:"BATCH_STALE_AFTER"`
+	// APIKeys require every API request to carry one of these keys in X-API-Key and count it against
+	// the key's quota; an entry "key:daily:monthly" overrides the default quotas for that key, e.g. for
+	// a partner tier. Empty leaves the API open.
+	APIKeys []string `mapstructure:"API_KEYS"`
+	// APIKeyDailyQuota and APIKeyMonthlyQuota are the requests a key may make per UTC day and month,
+	// unless its API_KEYS entry sets its own; 0 leaves the period unlimited
+	APIKeyDailyQuota   int64 `mapstructure:"API_KEY_DAILY_QUOTA"`
+	APIKeyMonthlyQuota int64 `mapstructure:"API_KEY_MONTHLY_QUOTA"`
+	// GeocodeFormatLimits override the largest /geocode limit (100) per response format, as
+	// "format:max" entries; e.g. "ndjson:100000" lets streamed responses hold far more results
+	// than a JSON array should
+	GeocodeFormatLimits []string `mapstructure:"GEOCODE_FORMAT_LIMITS"`
+	// ExportFlushEvery flushes /admin/export to the client after this many locations, so large
+	// exports arrive as they are read instead of in buffer-sized bursts (default 1000)
+	ExportFlushEvery int `mapstructure:"EXPORT_FLUSH_EVERY"`
+	// AdminToken is the bearer token required by the admin/diagnostic endpoints; empty disables them
+	AdminToken string `mapstructure:"ADMIN_TOKEN"`
+	// FrameOptions is the X-Frame-Options header sent with every response; empty omits it
+	FrameOptions string `mapstructure:"FRAME_OPTIONS"`
+	// ImportTables lists extra tables the importer may load into besides "locations"
+	ImportTables []string `mapstructure:"IMPORT_TABLES"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -28,3 +184,84 @@ func LoadConfig(path string) (config Config, err error) {
 	err = viper.Unmarshal(&config)
 	return
 }
+
+// Timeouts holds the query timeout applied to each endpoint
+type Timeouts struct {
+	Geocode        time.Duration
+	ReverseGeocode time.Duration
+	Locations      time.Duration
+	DistanceMatrix time.Duration
+}
+
+// Timeouts returns each endpoint's query timeout, using QueryTimeout for endpoints without their own
+func (c Config) Timeouts() Timeouts {
+	orDefault := func(d time.Duration) time.Duration {
+		if d == 0 {
+			return c.QueryTimeout
+		}
+		return d
+	}
+	return Timeouts{
+		Geocode:        orDefault(c.GeocodeTimeout),
+		ReverseGeocode: orDefault(c.ReverseGeocodeTimeout),
+		Locations:      orDefault(c.LocationsTimeout),
+		DistanceMatrix: orDefault(c.DistanceMatrixTimeout),
+	}
+}
+
+// BlockedQueries compiles BlockedQueryPatterns, failing on the first invalid pattern
+func (c Config) BlockedQueries() ([]*regexp.Regexp, error) {
+	blocked := make([]*regexp.Regexp, 0, len(c.BlockedQueryPatterns))
+	for _, pattern := range c.BlockedQueryPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BLOCKED_QUERY_PATTERNS entry %q: %w", pattern, err)
+		}
+		blocked = append(blocked, re)
+	}
+	return blocked, nil
+}
+
+// GeocodeFormatMaxLimits parses GeocodeFormatLimits into each format's largest limit, failing on
+// the first malformed entry
+func (c Config) GeocodeFormatMaxLimits() (map[string]int, error) {
+	limits := make(map[string]int, len(c.GeocodeFormatLimits))
+	for _, entry := range c.GeocodeFormatLimits {
+		format, maxStr, _ := strings.Cut(entry, ":")
+		max, err := strconv.Atoi(maxStr)
+		if format == "" || err != nil || max < 1 {
+			return nil, fmt.Errorf("invalid GEOCODE_FORMAT_LIMITS entry %q: must be \"format:max\" with a positive max", entry)
+		}
+		if _, ok := limits[format]; ok {
+			return nil, fmt.Errorf("GEOCODE_FORMAT_LIMITS lists format %q twice", format)
+		}
+		limits[format] = max
+	}
+	return limits, nil
+}
+
+// APIKeyQuotas parses APIKeys into each key's quota, failing on the first malformed entry
+func (c Config) APIKeyQuotas() (map[string]models.Quota, error) {
+	quotas := make(map[string]models.Quota, len(c.APIKeys))
+	for _, entry := range c.APIKeys {
+		key, limits, custom := strings.Cut(entry, ":")
+		if key == "" {
+			return nil, fmt.Errorf("invalid API_KEYS entry: empty key")
+		}
+		quota := models.Quota{Daily: c.APIKeyDailyQuota, Monthly: c.APIKeyMonthlyQuota}
+		if custom {
+			daily, monthly, ok := strings.Cut(limits, ":")
+			var dailyErr, monthlyErr error
+			quota.Daily, dailyErr = strconv.ParseInt(daily, 10, 64)
+			quota.Monthly, monthlyErr = strconv.ParseInt(monthly, 10, 64)
+			if !ok || dailyErr != nil || monthlyErr != nil || quota.Daily < 0 || quota.Monthly < 0 {
+				return nil, fmt.Errorf("invalid API_KEYS entry for key %q: quotas must be \"key:daily:monthly\" with non-negative numbers", redactKey(key))
+			}
+		}
+		if _, ok := quotas[key]; ok {
+			return nil, fmt.Errorf("API_KEYS lists key %q twice", redactKey(key))
+		}
+		quotas[key] = quota
+	}
+	return quotas, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Timeouts(t *testing.T) {
+	cfg := Config{
+		QueryTimeout:          5 * time.Second,
+		ReverseGeocodeTimeout: 500 * time.Millisecond,
+		DistanceMatrixTimeout: 20 * time.Second,
+	}
+
+	assert.Equal(t, Timeouts{
+		Geocode:        5 * time.Second,
+		ReverseGeocode: 500 * time.Millisecond,
+		Locations:      5 * time.Second,
+		DistanceMatrix: 20 * time.Second,
+	}, cfg.Timeouts())
+}
+
+func TestConfig_BlockedQueries(t *testing.T) {
+	blocked, err := Config{BlockedQueryPatterns: []string{`^\pP+$`, `(?i)select\s.+\sfrom`}}.BlockedQueries()
+	require.NoError(t, err)
+	require.Len(t, blocked, 2)
+	assert.True(t, blocked[0].MatchString("。"))
+	assert.True(t, blocked[1].MatchString("1 UNION SELECT password FROM users"))
+
+	blocked, err = Config{}.BlockedQueries()
+	require.NoError(t, err)
+	assert.Empty(t, blocked)
+
+	_, err = Config{BlockedQueryPatterns: []string{"("}}.BlockedQueries()
+	assert.ErrorContains(t, err, `invalid BLOCKED_QUERY_PATTERNS entry "("`)
+}
+
+func TestConfig_GeocodeFormatMaxLimits(t *testing.T) {
+	limits, err := Config{GeocodeFormatLimits: []string{"json:50", "ndjson:100000"}}.GeocodeFormatMaxLimits()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"json": 50, "ndjson": 100000}, limits)
+
+	limits, err = Config{}.GeocodeFormatMaxLimits()
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, entries := range [][]string{{"ndjson"}, {":100"}, {"ndjson:0"}, {"ndjson:many"}, {"json:1", "json:2"}} {
+		_, err = Config{GeocodeFormatLimits: entries}.GeocodeFormatMaxLimits()
+		assert.Error(t, err, entries)
+	}
+}
+
+func TestConfig_APIKeyQuotas(t *testing.T) {
+	quotas, err := Config{
+		APIKeys:            []string{"open-key", "partner-key:10000:0"},
+		APIKeyDailyQuota:   100,
+		APIKeyMonthlyQuota: 2000,
+	}.APIKeyQuotas()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.Quota{
+		"open-key":    {Daily: 100, Monthly: 2000},
+		"partner-key": {Daily: 10000, Monthly: 0},
+	}, quotas)
+
+	quotas, err = Config{}.APIKeyQuotas()
+	require.NoError(t, err)
+	assert.Empty(t, quotas)
+
+	for _, entries := range [][]string{{":1:1"}, {"partner-key:10000"}, {"partner-key:-1:0"}, {"partner-key:many:0"}, {"k", "k"}} {
+		_, err = Config{APIKeys: entries}.APIKeyQuotas()
+		assert.Error(t, err, entries)
+	}
+
+	_, err = Config{APIKeys: []string{"partner-key:10000"}}.APIKeyQuotas()
+	assert.NotContains(t, err.Error(), "partner-key")
+}
@@ -0,0 +1,64 @@
+// Package geojson serializes locations as GeoJSON (RFC 7946), which mapping libraries such as
+// Leaflet and Mapbox consume directly.
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"geocoding-api/internal/models"
+)
+
+// FeatureCollection is a GeoJSON feature collection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a location as a GeoJSON feature: its position is the geometry and its other JSON
+// fields are the properties
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         int                    `json:"id,omitempty"`
+	Geometry   Point                  `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Point is a GeoJSON point geometry. Coordinates are [longitude, latitude], the GeoJSON order,
+// which is the reverse of how addresses are usually written.
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// FromLocations builds a feature collection with a point feature per location, in order. The
+// feature ID is the location ID, omitted for interpolated locations which have none; the
+// coordinates are only in the geometry, not repeated in the properties.
+func FromLocations(locations []models.Location) (FeatureCollection, error) {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(locations))}
+	for _, loc := range locations {
+		data, err := json.Marshal(loc)
+		if err != nil {
+			return FeatureCollection{}, err
+		}
+
+		// Decode numbers as json.Number so the properties are written back verbatim
+		var properties map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&properties); err != nil {
+			return FeatureCollection{}, err
+		}
+		delete(properties, "id")
+		delete(properties, "latitude")
+		delete(properties, "longitude")
+
+		fc.Features = append(fc.Features, Feature{
+			Type:       "Feature",
+			ID:         loc.ID,
+			Geometry:   Point{Type: "Point", Coordinates: [2]float64{loc.Longitude, loc.Latitude}},
+			Properties: properties,
+		})
+	}
+	return fc, nil
+}
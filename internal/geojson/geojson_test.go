@@ -0,0 +1,57 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromLocations(t *testing.T) {
+	fc, err := FromLocations([]models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Address2: "一丁目", Latitude: 35.681236, Longitude: 139.767125, Score: 0.5},
+		{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.68, Longitude: 139.76, Interpolated: true},
+	})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(fc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"id": 1,
+				"geometry": {"type": "Point", "coordinates": [139.767125, 35.681236]},
+				"properties": {"prefecture": "東京都", "municipality": "千代田区", "address1": "丸の内", "address2": "一丁目", "block_lot": "", "score": 0.5}
+			},
+			{
+				"type": "Feature",
+				"geometry": {"type": "Point", "coordinates": [139.76, 35.68]},
+				"properties": {"prefecture": "東京都", "municipality": "千代田区", "address1": "丸の内", "address2": "", "block_lot": "", "interpolated": true}
+			}
+		]
+	}`, string(data))
+}
+
+func TestFromLocations_CoordinateOrder(t *testing.T) {
+	fc, err := FromLocations([]models.Location{{ID: 1, Latitude: 35.0, Longitude: 139.0}})
+	require.NoError(t, err)
+
+	require.Len(t, fc.Features, 1)
+	assert.Equal(t, [2]float64{139.0, 35.0}, fc.Features[0].Geometry.Coordinates)
+}
+
+func TestFromLocations_Empty(t *testing.T) {
+	for _, locations := range [][]models.Location{nil, {}} {
+		fc, err := FromLocations(locations)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(fc)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, string(data))
+	}
+}
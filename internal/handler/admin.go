@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly restricts a route to requests carrying "Authorization: Bearer <token>". An empty
+// token disables the route entirely, answering it like an unknown route.
+func AdminOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			respondError(c, http.StatusNotFound, i18n.MsgNotFound)
+			c.Abort()
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			respondError(c, http.StatusUnauthorized, i18n.MsgUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		token          string
+		authorization  string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid token",
+			token:          "s3cret",
+			authorization:  "Bearer s3cret",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok"}`,
+		},
+		{
+			name:           "wrong token",
+			token:          "s3cret",
+			authorization:  "Bearer guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"admin token required"}`,
+		},
+		{
+			name:           "missing token",
+			token:          "s3cret",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"admin token required"}`,
+		},
+		{
+			name:           "not bearer",
+			token:          "s3cret",
+			authorization:  "s3cret",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"admin token required"}`,
+		},
+		{
+			name:           "disabled",
+			token:          "",
+			authorization:  "Bearer ",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/admin", AdminOnly(tt.token), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
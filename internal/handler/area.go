@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of /nearby and /within used when none are configured
+const (
+	defaultNearbyLimit = 10
+	maxNearbyLimit     = 100
+	defaultWithinLimit = 100
+	maxWithinLimit     = 1000
+)
+
+// AreaHandler handles listings of the locations around a point or inside a bounding box
+type AreaHandler struct {
+	service AreaService
+	config  AreaConfig
+}
+
+// AreaConfig holds the result limits of the area listings, kept apart from text search since
+// dense areas hold far more addresses than a query matches. A requested limit above the
+// maximum is clamped to it; each zero field takes its default.
+type AreaConfig struct {
+	// NearbyDefaultLimit and NearbyMaxLimit bound /nearby (defaults 10 and 100)
+	NearbyDefaultLimit int
+	NearbyMaxLimit     int
+	// WithinDefaultLimit and WithinMaxLimit bound /within (defaults 100 and 1000)
+	WithinDefaultLimit int
+	WithinMaxLimit     int
+}
+
+// AreaService interface for dependency injection
+type AreaService interface {
+	Nearby(ctx context.Context, lat, lon, radius float64, limit int) ([]models.Location, error)
+	Within(ctx context.Context, bbox models.BoundingBox, limit int) (*models.WithinResult, error)
+}
+
+// NewAreaHandler creates a new area handler
+func NewAreaHandler(svc AreaService, cfg AreaConfig) *AreaHandler {
+	if cfg.NearbyMaxLimit <= 0 {
+		cfg.NearbyMaxLimit = maxNearbyLimit
+	}
+	if cfg.NearbyDefaultLimit <= 0 {
+		cfg.NearbyDefaultLimit = defaultNearbyLimit
+	}
+	cfg.NearbyDefaultLimit = min(cfg.NearbyDefaultLimit, cfg.NearbyMaxLimit)
+	if cfg.WithinMaxLimit <= 0 {
+		cfg.WithinMaxLimit = maxWithinLimit
+	}
+	if cfg.WithinDefaultLimit <= 0 {
+		cfg.WithinDefaultLimit = defaultWithinLimit
+	}
+	cfg.WithinDefaultLimit = min(cfg.WithinDefaultLimit, cfg.WithinMaxLimit)
+	return &AreaHandler{service: svc, config: cfg}
+}
+
+// Nearby godoc
+// @Summary List the addresses around a point
+// @Description List the addresses within a radius of the coordinates, nearest first, each with distance_meters
+// @Tags geocoding
+// @Accept json
+// @Produce json,application/geo+json
+// @Param lat query number true "Latitude"
+// @Param lon query number true "Longitude"
+// @Param radius query number false "Search radius in meters (default and max MAX_SPATIAL_RADIUS_METERS)"
+// @Param limit query int false "Maximum number of results (default NEARBY_DEFAULT_LIMIT, 10); values above NEARBY_MAX_LIMIT (100) are clamped to it"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default) or geojson, a GeoJSON FeatureCollection of Point features ([lon, lat]) with the other fields as properties"
+// @Success 200 {array} models.Location
+// @Failure 400 {object} map[string]string "error":"missing required query parameters 'lat' and 'lon'" or "invalid latitude format" or "invalid longitude format" or "invalid radius" or "invalid limit" or "invalid coords_as_string value"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /nearby [get]
+func (h *AreaHandler) Nearby(c *gin.Context) {
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatGeoJSON)
+	if !ok {
+		return
+	}
+
+	latStr := c.Query("lat")
+	lonStr := c.Query("lon")
+	if latStr == "" || lonStr == "" {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingCoordinates)
+		return
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidLatitude)
+		return
+	}
+
+	lon, err := strconv.ParseFloat(lonStr, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidLongitude)
+		return
+	}
+
+	// 0 searches the service's maximum radius, which also bounds an explicit one
+	radius := 0.0
+	if radiusStr := c.Query("radius"); radiusStr != "" {
+		radius, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidRadius)
+			return
+		}
+	}
+
+	limit := h.config.NearbyDefaultLimit
+	if !bindLimit(c, &limit, h.config.NearbyMaxLimit) {
+		return
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	locations, err := h.service.Nearby(c.Request.Context(), lat, lon, radius, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if outputFormat == formatGeoJSON {
+		respondGeoJSON(c, locations, format)
+		return
+	}
+	respondLocations(c, locations, format)
+}
+
+// Within godoc
+// @Summary List the addresses inside a bounding box
+// @Description List the addresses inside the box, ordered by ID. When the box holds more than the limit, truncated is true and only the first ones are returned, so clients know to zoom in.
+// @Tags geocoding
+// @Accept json
+// @Produce json,application/geo+json
+// @Param bbox query string true "Box as min_lon,min_lat,max_lon,max_lat in WGS84 degrees, e.g. the map viewport"
+// @Param limit query int false "Maximum number of results (default WITHIN_DEFAULT_LIMIT, 100); values above WITHIN_MAX_LIMIT (1000) are clamped to it"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default) or geojson, a GeoJSON FeatureCollection of Point features ([lon, lat]) with the other fields as properties; it carries the truncation in the X-Truncated header only"
+// @Success 200 {object} models.WithinResult
+// @Header 200 {bool} X-Truncated "true when the box holds more addresses than the limit"
+// @Failure 400 {object} map[string]string "error":"missing required query parameter 'bbox'" or "invalid bbox" or "invalid limit" or "invalid coords_as_string value"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /within [get]
+func (h *AreaHandler) Within(c *gin.Context) {
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatGeoJSON)
+	if !ok {
+		return
+	}
+
+	var opts models.SearchOptions
+	if !bindBBox(c, &opts) {
+		return
+	}
+	if opts.BBox == nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingBBox)
+		return
+	}
+
+	limit := h.config.WithinDefaultLimit
+	if !bindLimit(c, &limit, h.config.WithinMaxLimit) {
+		return
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	result, err := h.service.Within(c.Request.Context(), *opts.BBox, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Header("X-Truncated", strconv.FormatBool(result.Truncated))
+	if outputFormat == formatGeoJSON {
+		respondGeoJSON(c, result.Results, format)
+		return
+	}
+	respondLocations(c, result, format)
+}
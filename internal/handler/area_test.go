@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAreaService is a mock implementation of the AreaService interface
+type MockAreaService struct {
+	mock.Mock
+}
+
+func (m *MockAreaService) Nearby(ctx context.Context, lat, lon, radius float64, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+func (m *MockAreaService) Within(ctx context.Context, bbox models.BoundingBox, limit int) (*models.WithinResult, error) {
+	args := m.Called(ctx, bbox, limit)
+	result, _ := args.Get(0).(*models.WithinResult)
+	return result, args.Error(1)
+}
+
+func TestAreaHandler_Nearby(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nearby := []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Distance: 3.5}}
+
+	tests := []struct {
+		name           string
+		query          string
+		config         AreaConfig
+		expectedRadius float64
+		expectedLimit  int
+		mockLocations  []models.Location
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "default limit",
+			query:          "lat=35.68&lon=139.76",
+			expectedLimit:  defaultNearbyLimit,
+			mockLocations:  nearby,
+			expectedStatus: http.StatusOK,
+			expectedBody:   nearby,
+		},
+		{
+			name:           "radius and limit",
+			query:          "lat=35.68&lon=139.76&radius=200&limit=5",
+			expectedRadius: 200,
+			expectedLimit:  5,
+			mockLocations:  nearby,
+			expectedStatus: http.StatusOK,
+			expectedBody:   nearby,
+		},
+		{
+			name:           "limit clamped to configured maximum",
+			query:          "lat=35.68&lon=139.76&limit=500",
+			config:         AreaConfig{NearbyMaxLimit: 50},
+			expectedLimit:  50,
+			mockLocations:  []models.Location{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "configured default",
+			query:          "lat=35.68&lon=139.76",
+			config:         AreaConfig{NearbyDefaultLimit: 25},
+			expectedLimit:  25,
+			mockLocations:  []models.Location{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "missing coordinates",
+			query:          "lat=35.68",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameters 'lat' and 'lon'"},
+		},
+		{
+			name:           "invalid radius",
+			query:          "lat=35.68&lon=139.76&radius=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
+		{
+			name:           "negative limit",
+			query:          "lat=35.68&lon=139.76&limit=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid limit: must be a non-negative integer; 0 uses the default and values above 100 are clamped to it"},
+		},
+		{
+			name:           "service error",
+			query:          "lat=35.68&lon=139.76",
+			expectedLimit:  defaultNearbyLimit,
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockAreaService)
+			handler := NewAreaHandler(mockSvc, tt.config)
+
+			if tt.expectedLimit != 0 {
+				mockSvc.On("Nearby", mock.Anything, 35.68, 139.76, tt.expectedRadius, tt.expectedLimit).Return(tt.mockLocations, tt.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/nearby?"+tt.query, nil)
+
+			handler.Nearby(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAreaHandler_Within(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bbox := models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+	locations := []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区"}}
+
+	tests := []struct {
+		name              string
+		query             string
+		config            AreaConfig
+		expectedLimit     int
+		mockResult        *models.WithinResult
+		mockError         error
+		expectedStatus    int
+		expectedTruncated string
+		expectedBody      interface{}
+	}{
+		{
+			name:              "default limit",
+			query:             "bbox=139.7,35.6,139.8,35.7",
+			expectedLimit:     defaultWithinLimit,
+			mockResult:        &models.WithinResult{Results: locations},
+			expectedStatus:    http.StatusOK,
+			expectedTruncated: "false",
+			expectedBody:      models.WithinResult{Results: locations},
+		},
+		{
+			name:              "truncated",
+			query:             "bbox=139.7,35.6,139.8,35.7&limit=1",
+			expectedLimit:     1,
+			mockResult:        &models.WithinResult{Results: locations, Truncated: true},
+			expectedStatus:    http.StatusOK,
+			expectedTruncated: "true",
+			expectedBody:      models.WithinResult{Results: locations, Truncated: true},
+		},
+		{
+			name:              "limit clamped to configured maximum",
+			query:             "bbox=139.7,35.6,139.8,35.7&limit=5000",
+			config:            AreaConfig{WithinMaxLimit: 2000},
+			expectedLimit:     2000,
+			mockResult:        &models.WithinResult{Results: locations},
+			expectedStatus:    http.StatusOK,
+			expectedTruncated: "false",
+			expectedBody:      models.WithinResult{Results: locations},
+		},
+		{
+			name:           "missing bbox",
+			query:          "limit=5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameter 'bbox'"},
+		},
+		{
+			name:           "malformed bbox",
+			query:          "bbox=139.7,35.6",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid bbox: expected min_lon,min_lat,max_lon,max_lat with longitudes between -180 and 180, latitudes between -90 and 90 and each minimum below its maximum"},
+		},
+		{
+			name:           "service error",
+			query:          "bbox=139.7,35.6,139.8,35.7",
+			expectedLimit:  defaultWithinLimit,
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockAreaService)
+			handler := NewAreaHandler(mockSvc, tt.config)
+
+			if tt.expectedLimit != 0 {
+				mockSvc.On("Within", mock.Anything, bbox, tt.expectedLimit).Return(tt.mockResult, tt.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/within?"+tt.query, nil)
+
+			handler.Within(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedTruncated, w.Header().Get("X-Truncated"))
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
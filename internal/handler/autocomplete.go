@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AutocompleteHandler handles search-as-you-type requests
+type AutocompleteHandler struct {
+	service AutocompleteService
+}
+
+// AutocompleteService interface for dependency injection
+type AutocompleteService interface {
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// NewAutocompleteHandler creates a new autocomplete handler
+func NewAutocompleteHandler(svc AutocompleteService) *AutocompleteHandler {
+	return &AutocompleteHandler{service: svc}
+}
+
+// Autocomplete godoc
+// @Summary Complete a partial address
+// @Description Suggest the municipalities and towns whose address starts with the typed text, for search-as-you-type; completions that start with the text as typed (including the prefecture) first, then shorter ones
+// @Tags geocoding
+// @Produce json
+// @Param q query string true "Start of the address, with or without the prefecture, e.g. 千代"
+// @Param limit query int false "Maximum number of suggestions (default 10); values above 20 are clamped to 20"
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {array} string
+// @Failure 400 {object} map[string]string "error":"missing required query parameter 'q'" or "invalid limit"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /autocomplete [get]
+func (h *AutocompleteHandler) Autocomplete(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	prefix := c.Query("q")
+	if prefix == "" {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingQuery)
+		return
+	}
+
+	limit := 0
+	if !bindLimit(c, &limit, service.MaxAutocompleteLimit) {
+		return
+	}
+
+	suggestions, err := h.service.Autocomplete(c.Request.Context(), prefix, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAutocompleteService is a mock implementation of the AutocompleteService interface
+type MockAutocompleteService struct {
+	mock.Mock
+}
+
+func (m *MockAutocompleteService) Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error) {
+	args := m.Called(ctx, prefix, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestAutocompleteHandler_Autocomplete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	suggestions := []string{"東京都千代田区", "東京都千代田区丸の内"}
+
+	tests := []struct {
+		name            string
+		query           url.Values
+		expectedLimit   *int
+		mockSuggestions []string
+		mockError       error
+		expectedStatus  int
+		expectedBody    interface{}
+	}{
+		{
+			name:            "suggestions",
+			query:           url.Values{"q": {"千代"}},
+			expectedLimit:   new(int),
+			mockSuggestions: suggestions,
+			expectedStatus:  http.StatusOK,
+			expectedBody:    suggestions,
+		},
+		{
+			name:            "no suggestions",
+			query:           url.Values{"q": {"存在しない"}},
+			expectedLimit:   new(int),
+			mockSuggestions: []string{},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    []string{},
+		},
+		{
+			name:            "explicit limit",
+			query:           url.Values{"q": {"千代"}, "limit": {"1"}},
+			expectedLimit:   func() *int { n := 1; return &n }(),
+			mockSuggestions: suggestions[:1],
+			expectedStatus:  http.StatusOK,
+			expectedBody:    suggestions[:1],
+		},
+		{
+			name:           "missing query",
+			query:          url.Values{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameter 'q'"},
+		},
+		{
+			name:            "limit clamped to maximum",
+			query:           url.Values{"q": {"千代"}, "limit": {"21"}},
+			expectedLimit:   func() *int { n := 20; return &n }(),
+			mockSuggestions: suggestions,
+			expectedStatus:  http.StatusOK,
+			expectedBody:    suggestions,
+		},
+		{
+			name:           "negative limit",
+			query:          url.Values{"q": {"千代"}, "limit": {"-1"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid limit: must be a non-negative integer; 0 uses the default and values above 20 are clamped to it"},
+		},
+		{
+			name:           "whitespace only query",
+			query:          url.Values{"q": {" "}},
+			expectedLimit:  new(int),
+			mockError:      service.ErrEmptyQuery,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameter 'q'"},
+		},
+		{
+			name:           "service error",
+			query:          url.Values{"q": {"千代"}},
+			expectedLimit:  new(int),
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockAutocompleteService)
+			handler := NewAutocompleteHandler(mockSvc)
+			if tt.expectedLimit != nil {
+				mockSvc.On("Autocomplete", mock.Anything, tt.query.Get("q"), *tt.expectedLimit).Return(tt.mockSuggestions, tt.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/autocomplete?"+tt.query.Encode(), nil)
+
+			// Execute
+			handler.Autocomplete(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
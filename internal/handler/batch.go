@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchHandler handles asynchronous batch geocoding requests
+type BatchHandler struct {
+	service BatchService
+	config  GeoCodeConfig
+}
+
+// BatchService interface for dependency injection
+type BatchService interface {
+	SubmitBatch(context.Context, []string) (*models.BatchJob, error)
+	GeocodeBatch(context.Context, []string) ([]models.BatchResult, error)
+	BatchJob(context.Context, string) (*models.BatchJob, error)
+	BatchJobResults(context.Context, string) ([]models.BatchResult, error)
+}
+
+// NewBatchHandler creates a new batch handler. Addresses are prepared the same way as
+// /geocode queries according to cfg.
+func NewBatchHandler(svc BatchService, cfg GeoCodeConfig) *BatchHandler {
+	return &BatchHandler{service: svc, config: cfg}
+}
+
+// Batch godoc
+// @Summary Geocode a batch of addresses
+// @Description With mode=async (the default), queue up to 10000 addresses to be geocoded in the background and return 202 with the job; poll its status at /jobs/{id} and fetch its results from results_url once it has succeeded. With mode=sync, geocode up to 100 addresses within the request and return 200 with one entry per address, in request order; an empty address gets an error in its entry instead of failing the batch.
+// @Tags geocoding
+// @Accept json
+// @Produce json
+// @Param request body models.BatchGeocodeRequest true "Addresses to geocode"
+// @Param mode query string false "async (default) to submit a job, or sync to geocode within the request"
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {array} models.BatchResult "mode=sync"
+// @Success 202 {object} models.BatchJob "mode=async"
+// @Header 202 {string} Location "Status URL of the job"
+// @Failure 400 {object} map[string]string "error":"invalid request body" or "between 1 and 10000 addresses are required" or "between 1 and 100 addresses are required" or "invalid mode value"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /geocode/batch [post]
+func (h *BatchHandler) Batch(c *gin.Context) {
+	switch c.Query("mode") {
+	case "", "async":
+		h.SubmitBatch(c)
+	case "sync":
+		h.GeocodeBatch(c)
+	default:
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBatchMode)
+	}
+}
+
+// SubmitBatch queues up to 10000 addresses as a job geocoded in the background and responds
+// 202 with it; Batch serves it for mode=async.
+func (h *BatchHandler) SubmitBatch(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	var req models.BatchGeocodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBody)
+		return
+	}
+
+	if len(req.Addresses) == 0 || len(req.Addresses) > service.MaxBatchAddresses {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBatchSize, service.MaxBatchAddresses)
+		return
+	}
+
+	addresses := make([]string, len(req.Addresses))
+	for i, address := range req.Addresses {
+		addresses[i], _ = h.config.prepareQuery(address)
+	}
+
+	job, err := h.service.SubmitBatch(c.Request.Context(), addresses)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GeocodeBatch geocodes up to 100 addresses within the request and responds with one entry per
+// address, in request order; an empty address gets an error in its entry instead of failing the
+// batch. Batch serves it for mode=sync.
+func (h *BatchHandler) GeocodeBatch(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	var req models.BatchGeocodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBody)
+		return
+	}
+
+	if len(req.Addresses) == 0 || len(req.Addresses) > service.MaxSyncBatchAddresses {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBatchSize, service.MaxSyncBatchAddresses)
+		return
+	}
+
+	addresses := make([]string, len(req.Addresses))
+	for i, address := range req.Addresses {
+		addresses[i], _ = h.config.prepareQuery(address)
+	}
+
+	results, err := h.service.GeocodeBatch(c.Request.Context(), addresses)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// BatchJob godoc
+// @Summary Get a batch geocoding job
+// @Description Report the status of a batch job: queued, running, succeeded or failed. results_url is set once it has succeeded.
+// @Tags geocoding
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} models.BatchJob
+// @Failure 404 {object} map[string]string "error":"job not found"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /jobs/{id} [get]
+func (h *BatchHandler) BatchJob(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	job, err := h.service.BatchJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if job == nil {
+		respondError(c, http.StatusNotFound, i18n.MsgJobNotFound)
+		return
+	}
+
+	if job.Status == models.BatchJobSucceeded {
+		job.ResultsURL = "/jobs/" + job.ID + "/results"
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// BatchJobResults godoc
+// @Summary Get the results of a batch geocoding job
+// @Description Return one entry per submitted address, in submission order, once the job has succeeded
+// @Tags geocoding
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {array} models.BatchResult
+// @Failure 404 {object} map[string]string "error":"job not found"
+// @Failure 409 {object} map[string]string "error":"job has not succeeded"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /jobs/{id}/results [get]
+func (h *BatchHandler) BatchJobResults(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	id := c.Param("id")
+	job, err := h.service.BatchJob(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if job == nil {
+		respondError(c, http.StatusNotFound, i18n.MsgJobNotFound)
+		return
+	}
+	if job.Status != models.BatchJobSucceeded {
+		respondError(c, http.StatusConflict, i18n.MsgJobNotFinished, job.Status)
+		return
+	}
+
+	results, err := h.service.BatchJobResults(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBatchService is a mock implementation of the BatchService interface
+type MockBatchService struct {
+	mock.Mock
+}
+
+func (m *MockBatchService) SubmitBatch(ctx context.Context, addresses []string) (*models.BatchJob, error) {
+	args := m.Called(ctx, addresses)
+	return args.Get(0).(*models.BatchJob), args.Error(1)
+}
+
+func (m *MockBatchService) GeocodeBatch(ctx context.Context, addresses []string) ([]models.BatchResult, error) {
+	args := m.Called(ctx, addresses)
+	return args.Get(0).([]models.BatchResult), args.Error(1)
+}
+
+func (m *MockBatchService) BatchJob(ctx context.Context, id string) (*models.BatchJob, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.BatchJob), args.Error(1)
+}
+
+func (m *MockBatchService) BatchJobResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]models.BatchResult), args.Error(1)
+}
+
+func TestBatchHandler_SubmitBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	job := &models.BatchJob{ID: "job-1", Status: models.BatchJobQueued, Total: 2, CreatedAt: created, UpdatedAt: created}
+
+	tests := []struct {
+		name              string
+		mode              string
+		body              string
+		expectedAddresses []string
+		mockError         error
+		expectedStatus    int
+		expectedLocation  string
+		expectedBody      interface{}
+	}{
+		{
+			name:           "malformed body",
+			body:           `{"addresses": [`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid request body"},
+		},
+		{
+			name:           "no addresses",
+			body:           `{"addresses": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "between 1 and 10000 addresses are required"},
+		},
+		{
+			name:              "queued with prepared addresses",
+			body:              `{"addresses": ["東京都千代田区丸の内1丁目1番 丸の内ビル", "港区赤坂"]}`,
+			expectedAddresses: []string{"東京都千代田区丸の内1-1", "港区赤坂"},
+			expectedStatus:    http.StatusAccepted,
+			expectedLocation:  "/jobs/job-1",
+			expectedBody:      job,
+		},
+		{
+			name:              "explicit async mode",
+			mode:              "async",
+			body:              `{"addresses": ["港区赤坂"]}`,
+			expectedAddresses: []string{"港区赤坂"},
+			expectedStatus:    http.StatusAccepted,
+			expectedLocation:  "/jobs/job-1",
+			expectedBody:      job,
+		},
+		{
+			name:           "unknown mode",
+			mode:           "later",
+			body:           `{"addresses": ["港区赤坂"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid mode value: must be async or sync"},
+		},
+		{
+			name:              "service error",
+			body:              `{"addresses": ["港区赤坂"]}`,
+			expectedAddresses: []string{"港区赤坂"},
+			mockError:         assert.AnError,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBody:      gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockBatchService)
+			handler := NewBatchHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true, StripBuildingNames: true})
+
+			if tt.expectedAddresses != nil {
+				mockSvc.On("SubmitBatch", mock.Anything, tt.expectedAddresses).Return(job, tt.mockError)
+			}
+
+			// Create request
+			url := "/geocode/batch"
+			if tt.mode != "" {
+				url += "?mode=" + tt.mode
+			}
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.Batch(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchHandler_GeocodeBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	results := []models.BatchResult{
+		{Query: "東京都千代田区丸の内1-1", Results: []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"}}},
+		{Query: "", Results: []models.Location{}, Error: "address cannot be empty"},
+	}
+	tooMany := `{"addresses": [` + strings.TrimSuffix(strings.Repeat(`"港区",`, 101), ",") + `]}`
+
+	tests := []struct {
+		name              string
+		body              string
+		expectedAddresses []string
+		mockResults       []models.BatchResult
+		mockError         error
+		expectedStatus    int
+		expectedBody      interface{}
+	}{
+		{
+			name:           "malformed body",
+			body:           `{"addresses": [`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid request body"},
+		},
+		{
+			name:           "no addresses",
+			body:           `{"addresses": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "between 1 and 100 addresses are required"},
+		},
+		{
+			name:           "too many addresses",
+			body:           tooMany,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "between 1 and 100 addresses are required"},
+		},
+		{
+			name:              "results in request order",
+			body:              `{"addresses": ["東京都千代田区丸の内1丁目1番 丸の内ビル", ""]}`,
+			expectedAddresses: []string{"東京都千代田区丸の内1-1", ""},
+			mockResults:       results,
+			expectedStatus:    http.StatusOK,
+			expectedBody:      results,
+		},
+		{
+			name:              "service error",
+			body:              `{"addresses": ["港区赤坂"]}`,
+			expectedAddresses: []string{"港区赤坂"},
+			mockError:         assert.AnError,
+			expectedStatus:    http.StatusInternalServerError,
+			expectedBody:      gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockBatchService)
+			handler := NewBatchHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true, StripBuildingNames: true})
+
+			if tt.expectedAddresses != nil {
+				mockSvc.On("GeocodeBatch", mock.Anything, tt.expectedAddresses).Return(tt.mockResults, tt.mockError)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodPost, "/geocode/batch?mode=sync", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.Batch(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchHandler_BatchJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	results := []models.BatchResult{
+		{Query: "丸の内", Results: []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125}}},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		mockJob        *models.BatchJob
+		mockError      error
+		callsResults   bool
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "running job",
+			path:           "/jobs/job-1",
+			mockJob:        &models.BatchJob{ID: "job-1", Status: models.BatchJobRunning, Total: 1, CreatedAt: created, UpdatedAt: created},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"id": "job-1", "status": "running", "total": 1, "processed": 0,
+				"created_at": "2024-04-01T09:00:00Z", "updated_at": "2024-04-01T09:00:00Z",
+			},
+		},
+		{
+			name:           "succeeded job links its results",
+			path:           "/jobs/job-1",
+			mockJob:        &models.BatchJob{ID: "job-1", Status: models.BatchJobSucceeded, Total: 1, Processed: 1, CreatedAt: created, UpdatedAt: created, FinishedAt: &created},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"id": "job-1", "status": "succeeded", "total": 1, "processed": 1,
+				"created_at": "2024-04-01T09:00:00Z", "updated_at": "2024-04-01T09:00:00Z", "finished_at": "2024-04-01T09:00:00Z",
+				"results_url": "/jobs/job-1/results",
+			},
+		},
+		{
+			name:           "unknown job",
+			path:           "/jobs/job-1",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "job not found"},
+		},
+		{
+			name:           "service error",
+			path:           "/jobs/job-1",
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+		{
+			name:           "results of a succeeded job",
+			path:           "/jobs/job-1/results",
+			mockJob:        &models.BatchJob{ID: "job-1", Status: models.BatchJobSucceeded},
+			callsResults:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   results,
+		},
+		{
+			name:           "results of a queued job",
+			path:           "/jobs/job-1/results",
+			mockJob:        &models.BatchJob{ID: "job-1", Status: models.BatchJobQueued},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   gin.H{"error": "job has not succeeded (status: queued)"},
+		},
+		{
+			name:           "results of an unknown job",
+			path:           "/jobs/job-1/results",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "job not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockBatchService)
+			handler := NewBatchHandler(mockSvc, GeoCodeConfig{})
+
+			mockSvc.On("BatchJob", mock.Anything, "job-1").Return(tt.mockJob, tt.mockError)
+			if tt.callsResults {
+				mockSvc.On("BatchJobResults", mock.Anything, "job-1").Return(results, nil)
+			}
+
+			router := gin.New()
+			router.GET("/jobs/:id", handler.BatchJob)
+			router.GET("/jobs/:id/results", handler.BatchJobResults)
+
+			// Execute
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindBBox parses the optional bbox query parameter, min_lon,min_lat,max_lon,max_lat, into opts.
+// The ranges are validated by the service; on anything but four numbers it writes a 400
+// response and returns false.
+func bindBBox(c *gin.Context, opts *models.SearchOptions) bool {
+	bboxStr := c.Query("bbox")
+	if bboxStr == "" {
+		return true
+	}
+
+	parts := strings.Split(bboxStr, ",")
+	if len(parts) != 4 {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBBox)
+		return false
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidBBox)
+			return false
+		}
+		values[i] = v
+	}
+
+	opts.BBox = &models.BoundingBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	return true
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"regexp"
+	"sync/atomic"
+)
+
+// QueryBlocklist holds the patterns of the queries /geocode rejects. Store swaps the whole list
+// at once, so reloading it (on SIGHUP) takes effect for the next request while requests in flight
+// finish checking against the list they started with.
+type QueryBlocklist struct {
+	patterns atomic.Pointer[[]*regexp.Regexp]
+}
+
+// NewQueryBlocklist creates a blocklist holding patterns
+func NewQueryBlocklist(patterns []*regexp.Regexp) *QueryBlocklist {
+	b := &QueryBlocklist{}
+	b.Store(patterns)
+	return b
+}
+
+// Store replaces the patterns
+func (b *QueryBlocklist) Store(patterns []*regexp.Regexp) {
+	b.patterns.Store(&patterns)
+}
+
+// Blocked reports whether query matches any of the patterns; a nil blocklist blocks nothing
+func (b *QueryBlocklist) Blocked(query string) bool {
+	if b == nil {
+		return false
+	}
+	patterns := b.patterns.Load()
+	if patterns == nil {
+		return false
+	}
+	for _, re := range *patterns {
+		if re.MatchString(query) {
+			return true
+		}
+	}
+	return false
+}
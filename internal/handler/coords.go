@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"geocoding-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coordStringDecimals is the fixed precision of coordinates written as strings. 7 decimals
+// is about 1cm, finer than any source data, so the string parses back to the stored value.
+const coordStringDecimals = 7
+
+// bindCoordsAsString parses the optional coords_as_string query parameter. On invalid input it
+// writes a 400 response and returns false.
+func bindCoordsAsString(c *gin.Context, asString *bool) bool {
+	if s := c.Query("coords_as_string"); s != "" {
+		var err error
+		*asString, err = strconv.ParseBool(s)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidCoordFormat)
+			return false
+		}
+	}
+	return true
+}
+
+// responseFormat holds the per-request output options of location responses
+type responseFormat struct {
+	// coordsAsString writes every latitude and longitude as a fixed-precision string, for
+	// clients whose JSON parsers lose precision on numbers
+	coordsAsString bool
+	// fields drops the models.LocationFields not listed from every location; empty keeps them all
+	fields []string
+}
+
+// respondLocations writes v as a 200 JSON response in the requested format; the parts of the
+// response the format doesn't cover are unchanged.
+func respondLocations(c *gin.Context, v interface{}, format responseFormat) {
+	tree, err := format.apply(v)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// apply returns v as it is written in the format: unchanged without output options, otherwise
+// as a decoded JSON tree with the coordinates and fields rewritten
+func (format responseFormat) apply(v interface{}) (interface{}, error) {
+	if !format.coordsAsString && len(format.fields) == 0 {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode numbers as json.Number so everything but the coordinates is written back verbatim
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	if format.coordsAsString {
+		tree = stringifyCoords(tree)
+	}
+	if len(format.fields) > 0 {
+		tree = selectFields(tree, format.fields)
+	}
+	return tree, nil
+}
+
+// stringifyCoords replaces the numeric latitude and longitude fields anywhere in a decoded JSON value
+func stringifyCoords(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			n, isNumber := value.(json.Number)
+			if isNumber && (key == "latitude" || key == "longitude") {
+				f, err := n.Float64()
+				if err == nil {
+					v[key] = strconv.FormatFloat(f, 'f', coordStringDecimals, 64)
+				}
+				continue
+			}
+			v[key] = stringifyCoords(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringifyCoords(value)
+		}
+	}
+	return v
+}
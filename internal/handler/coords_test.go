@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRespondLocations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := models.Location{ID: 1, Prefecture: "東京都", Latitude: 35.681236, Longitude: 139.767125}
+	withDistance := location
+	withDistance.Distance = 3.25
+
+	tests := []struct {
+		name         string
+		value        interface{}
+		asString     bool
+		expectedBody string
+	}{
+		{
+			name:         "numeric by default",
+			value:        []models.Location{location},
+			expectedBody: `[{"id":1,"prefecture":"東京都","municipality":"","address1":"","address2":"","block_lot":"","latitude":35.681236,"longitude":139.767125}]`,
+		},
+		{
+			name:         "array as strings",
+			value:        []models.Location{location},
+			asString:     true,
+			expectedBody: `[{"id":1,"prefecture":"東京都","municipality":"","address1":"","address2":"","block_lot":"","latitude":"35.6812360","longitude":"139.7671250"}]`,
+		},
+		{
+			name: "nested locations keep other numbers",
+			value: models.ReverseGeocodeResult{
+				Location: withDistance,
+				Context:  []models.Location{},
+			},
+			asString:     true,
+			expectedBody: `{"location":{"id":1,"prefecture":"東京都","municipality":"","address1":"","address2":"","block_lot":"","latitude":"35.6812360","longitude":"139.7671250","distance_meters":3.25},"context":[]}`,
+		},
+		{
+			name:         "hierarchy",
+			value:        location.Hierarchy(),
+			asString:     true,
+			expectedBody: `{"id":1,"latitude":"35.6812360","longitude":"139.7671250","prefecture":{"name":"東京都","municipality":{"name":"","district":{"name":""}}}}`,
+		},
+		{
+			name:         "rounded to fixed precision",
+			value:        models.Location{Latitude: 35.12345678901234, Longitude: -0.0000001},
+			asString:     true,
+			expectedBody: `{"id":0,"prefecture":"","municipality":"","address1":"","address2":"","block_lot":"","latitude":"35.1234568","longitude":"-0.0000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			respondLocations(c, tt.value, responseFormat{coordsAsString: tt.asString})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_CoordsAsString(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		coordsAsString string
+		callService    bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "strings",
+			coordsAsString: "true",
+			callService:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"id":1,"prefecture":"東京都","municipality":"","address1":"","address2":"","block_lot":"","latitude":"35.6812360","longitude":"139.7671250"}]`,
+		},
+		{
+			name:           "invalid value",
+			coordsAsString: "yes please",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid coords_as_string value: must be true or false"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.callService {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).Return(&models.GeocodeResult{
+					Results: []models.Location{{ID: 1, Prefecture: "東京都", Latitude: 35.681236, Longitude: 139.767125}},
+				}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("coords_as_string", tt.coordsAsString)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DistanceHandler handles distance matrix requests
+type DistanceHandler struct {
+	service DistanceService
+}
+
+// DistanceService interface for dependency injection
+type DistanceService interface {
+	DistanceMatrix(context.Context, []models.Point) (*models.DistanceMatrix, error)
+}
+
+// NewDistanceHandler creates a new distance handler
+func NewDistanceHandler(svc DistanceService) *DistanceHandler {
+	return &DistanceHandler{service: svc}
+}
+
+// DistanceMatrix godoc
+// @Summary Distance matrix
+// @Description Compute the geodesic distance in meters between every pair of points; distances_m[i][j] is from point i to point j
+// @Tags geocoding
+// @Accept json
+// @Produce json
+// @Param request body models.DistanceMatrixRequest true "Points (max 25)"
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} models.DistanceMatrix
+// @Failure 400 {object} map[string]string "error":"invalid request body" or "between 1 and 25 points are required" or "point has out-of-range coordinates"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /distance-matrix [post]
+func (h *DistanceHandler) DistanceMatrix(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	var req models.DistanceMatrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidBody)
+		return
+	}
+
+	if len(req.Points) == 0 || len(req.Points) > service.MaxMatrixPoints {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidPointCount, service.MaxMatrixPoints)
+		return
+	}
+
+	for i, p := range req.Points {
+		if !service.ValidPoint(p) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidPoint, i)
+			return
+		}
+	}
+
+	matrix, err := h.service.DistanceMatrix(c.Request.Context(), req.Points)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, matrix)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDistanceService is a mock implementation of the DistanceService interface
+type MockDistanceService struct {
+	mock.Mock
+}
+
+func (m *MockDistanceService) DistanceMatrix(ctx context.Context, points []models.Point) (*models.DistanceMatrix, error) {
+	args := m.Called(ctx, points)
+	return args.Get(0).(*models.DistanceMatrix), args.Error(1)
+}
+
+func TestDistanceHandler_DistanceMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	points := []models.Point{{Lat: 35.681236, Lon: 139.767125}, {Lat: 35.658034, Lon: 139.701636}}
+	matrix := &models.DistanceMatrix{Points: points, Distances: [][]float64{{0, 6420.5}, {6420.5, 0}}}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedPoints []models.Point
+		mockMatrix     *models.DistanceMatrix
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "malformed body",
+			body:           `{"points": [`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid request body"},
+		},
+		{
+			name:           "no points",
+			body:           `{"points": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "between 1 and 25 points are required"},
+		},
+		{
+			name:           "out of range point",
+			body:           `{"points": [{"lat": 35.68, "lon": 139.76}, {"lat": 35.68, "lon": 200}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "point 1 has out-of-range coordinates"},
+		},
+		{
+			name:           "successful matrix",
+			body:           `{"points": [{"lat": 35.681236, "lon": 139.767125}, {"lat": 35.658034, "lon": 139.701636}]}`,
+			expectedPoints: points,
+			mockMatrix:     matrix,
+			expectedStatus: http.StatusOK,
+			expectedBody:   matrix,
+		},
+		{
+			name:           "service error",
+			body:           `{"points": [{"lat": 35.681236, "lon": 139.767125}, {"lat": 35.658034, "lon": 139.701636}]}`,
+			expectedPoints: points,
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockDistanceService)
+			handler := NewDistanceHandler(mockSvc)
+
+			if tt.expectedPoints != nil {
+				mockSvc.On("DistanceMatrix", mock.Anything, tt.expectedPoints).Return(tt.mockMatrix, tt.mockError)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodPost, "/distance-matrix", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.DistanceMatrix(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// languageKey is the gin context key holding the negotiated response language
+const languageKey = "language"
+
+// Language negotiates the response language from Accept-Language, falling back to defaultLang
+func Language(defaultLang string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(languageKey, i18n.Negotiate(c.GetHeader("Accept-Language"), defaultLang))
+		c.Next()
+	}
+}
+
+// responseLanguage returns the language chosen by the Language middleware
+func responseLanguage(c *gin.Context) string {
+	lang := c.GetString(languageKey)
+	if lang == "" {
+		return i18n.DefaultLanguage
+	}
+	return lang
+}
+
+// respondError writes a localized {"error": ...} body with the given status
+func respondError(c *gin.Context, status int, key i18n.MessageKey, args ...interface{}) {
+	c.JSON(status, gin.H{"error": i18n.Message(responseLanguage(c), key, args...)})
+}
+
+// serviceErrors maps the services' validation errors to the message they are reported with as a 400
+var serviceErrors = []struct {
+	err  error
+	key  i18n.MessageKey
+	args []interface{}
+}{
+	{service.ErrEmptyQuery, i18n.MsgMissingQuery, nil},
+	{service.ErrInvalidCoordinates, i18n.MsgCoordsOutOfRange, nil},
+	{service.ErrInvalidLimit, i18n.MsgInvalidLimit, []interface{}{models.MaxSearchLimit}},
+	{service.ErrInvalidOffset, i18n.MsgInvalidOffset, nil},
+	{service.ErrCursorWithOffset, i18n.MsgCursorWithOffset, nil},
+	{service.ErrInvalidOrderBy, i18n.MsgInvalidOrderBy, nil},
+	{service.ErrCursorWithOrder, i18n.MsgCursorWithOrder, nil},
+	{service.ErrUnsupportedSRID, i18n.MsgInvalidSRID, nil},
+	{service.ErrUnknownField, i18n.MsgInvalidFields, []interface{}{strings.Join(models.LocationFields, ", ")}},
+	{service.ErrEmptyIDs, i18n.MsgMissingIDs, nil},
+	{service.ErrTooManyIDs, i18n.MsgTooManyIDs, []interface{}{service.MaxLocationIDs}},
+	{service.ErrMissingArea, i18n.MsgMissingArea, nil},
+	{service.ErrInvalidContext, i18n.MsgInvalidContext, []interface{}{service.MaxContextLocations}},
+	{service.ErrTooManyExcluded, i18n.MsgTooManyExcluded, []interface{}{service.MaxExcludedIDs}},
+	{service.ErrInvalidRadius, i18n.MsgInvalidRadius, nil},
+	{service.ErrBoundariesUnavailable, i18n.MsgNoBoundaries, nil},
+	{service.ErrInvalidSort, i18n.MsgInvalidSort, []interface{}{strings.Join(models.SortPreferenceKeys, ", ")}},
+	{service.ErrInvalidPointCount, i18n.MsgInvalidPointCount, []interface{}{service.MaxMatrixPoints}},
+	{service.ErrInvalidBBox, i18n.MsgInvalidBBox, nil},
+	{service.ErrInvalidBatchSize, i18n.MsgInvalidBatchSize, []interface{}{service.MaxBatchAddresses}},
+}
+
+// respondServiceError reports a service validation error as a 400 with its localized message,
+// a query cut off by the request's timeout as a 504, a query the database circuit breaker
+// rejected as a 503 and anything else as a 500
+func respondServiceError(c *gin.Context, err error) {
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			respondError(c, http.StatusBadRequest, e.key, e.args...)
+			return
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		respondError(c, http.StatusGatewayTimeout, i18n.MsgQueryTimeout)
+		return
+	}
+	if errors.Is(err, breaker.ErrOpen) {
+		respondError(c, http.StatusServiceUnavailable, i18n.MsgDBUnavailable)
+		return
+	}
+	respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+}
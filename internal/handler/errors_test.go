@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLanguage_LocalizesErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		defaultLang    string
+		acceptLanguage string
+		expectedBody   string
+	}{
+		{
+			name:           "accept-language selects japanese",
+			defaultLang:    i18n.English,
+			acceptLanguage: "ja-JP,ja;q=0.9,en;q=0.8",
+			expectedBody:   `{"error":"必須のクエリパラメータ 'q' が指定されていません"}`,
+		},
+		{
+			name:           "config default applies without header",
+			defaultLang:    i18n.Japanese,
+			acceptLanguage: "",
+			expectedBody:   `{"error":"必須のクエリパラメータ 'q' が指定されていません"}`,
+		},
+		{
+			name:           "accept-language overrides config default",
+			defaultLang:    i18n.Japanese,
+			acceptLanguage: "en-US",
+			expectedBody:   `{"error":"missing required query parameter 'q'"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Language(tt.defaultLang))
+			r.GET("/geocode", NewGeoCodeHandler(new(MockGeoCodeService), GeoCodeConfig{}).GeoCode)
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestRespondServiceError_BreakerOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	mockSvc.On("Geocode", mock.Anything, mock.Anything).Return((*models.GeocodeResult)(nil), fmt.Errorf("service: failed to search locations: %w", breaker.ErrOpen))
+
+	r := gin.New()
+	r.GET("/geocode", NewGeoCodeHandler(mockSvc, GeoCodeConfig{}).GeoCode)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/geocode?q=丸の内", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"the database is temporarily unavailable; try again later"}`, w.Body.String())
+	mockSvc.AssertExpectations(t)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// defaultExportFlushEvery is the number of exported locations written between flushes to the
+// client when none is configured
+const defaultExportFlushEvery = 1000
+
+// ExportHandler streams the whole dataset
+type ExportHandler struct {
+	service ExportService
+	config  ExportConfig
+}
+
+// ExportConfig holds the export handler's streaming settings
+type ExportConfig struct {
+	// FlushEvery flushes the response to the client after this many locations, besides after the
+	// first one; without flushing the client waits for the server's write buffer to fill.
+	// Defaults to 1000.
+	FlushEvery int
+}
+
+// ExportService interface for dependency injection
+type ExportService interface {
+	ExportLocations(ctx context.Context, fn func(models.Location) error) error
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(svc ExportService, cfg ExportConfig) *ExportHandler {
+	if cfg.FlushEvery <= 0 {
+		cfg.FlushEvery = defaultExportFlushEvery
+	}
+	return &ExportHandler{service: svc, config: cfg}
+}
+
+// Export godoc
+// @Summary Export all locations
+// @Description Stream every location as newline-delimited JSON in ID order, for bulk export and downstream reindexing; only served with ADMIN_TOKEN set. An error after the first location ends the stream early.
+// @Tags admin
+// @Produce application/x-ndjson
+// @Security AdminToken
+// @Param format query string false "Response format, taking priority over the Accept header (ndjson)"
+// @Success 200 {object} models.Location "one per line"
+// @Failure 401 {object} map[string]string "error":"unauthorized"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Router /admin/export [get]
+func (h *ExportHandler) Export(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatNDJSON); !ok {
+		return
+	}
+
+	// The status is only sent with the first location, so a failure before it is still a
+	// proper error response
+	started, written := false, 0
+	encoder := json.NewEncoder(c.Writer)
+	err := h.service.ExportLocations(c.Request.Context(), func(loc models.Location) error {
+		if !started {
+			c.Header("Content-Type", formatMediaTypes[formatNDJSON])
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(loc); err != nil {
+			return err
+		}
+		written++
+		// The first location is flushed right away, so the client sees the export start
+		if written == 1 || written%h.config.FlushEvery == 0 {
+			flush(c.Writer)
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil && !started:
+		respondServiceError(c, err)
+	case err != nil:
+		zerolog.Ctx(c.Request.Context()).Error().Err(err).Int("exported", written).Msg("export ended early")
+	case !started:
+		c.Data(http.StatusOK, formatMediaTypes[formatNDJSON], nil)
+	}
+}
+
+// flush sends what was written so far to the client. Gin's writer panics when the writer it
+// wraps can't flush, as with some wrapping middleware, so such a response is left to buffer.
+func flush(w gin.ResponseWriter) {
+	if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+		if _, ok := u.Unwrap().(http.Flusher); !ok {
+			return
+		}
+	}
+	w.Flush()
+}
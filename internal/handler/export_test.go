@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportService is a mock implementation of the ExportService interface
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) ExportLocations(ctx context.Context, fn func(models.Location) error) error {
+	args := m.Called(ctx)
+	for _, loc := range args.Get(0).([]models.Location) {
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestExportHandler_Export(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	locations := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125},
+		{ID: 2, Prefecture: "東京都", Municipality: "中央区", Address1: "銀座4", BlockLot: "6", Latitude: 35.671989, Longitude: 139.765},
+	}
+	lines := `{"id":1,"prefecture":"東京都","municipality":"千代田区","address1":"丸の内1","address2":"","block_lot":"1","latitude":35.681236,"longitude":139.767125}
+{"id":2,"prefecture":"東京都","municipality":"中央区","address1":"銀座4","address2":"","block_lot":"6","latitude":35.671989,"longitude":139.765}
+`
+
+	tests := []struct {
+		name                string
+		accept              string
+		mockLocations       []models.Location
+		mockError           error
+		callService         bool
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "one location per line",
+			mockLocations:       locations,
+			callService:         true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        lines,
+		},
+		{
+			name:                "empty table",
+			mockLocations:       []models.Location{},
+			callService:         true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+		},
+		{
+			name:                "error before the first location",
+			mockLocations:       []models.Location{},
+			mockError:           assert.AnError,
+			callService:         true,
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"internal server error"}`,
+		},
+		{
+			name:                "error after the first location ends the stream",
+			mockLocations:       locations,
+			mockError:           assert.AnError,
+			callService:         true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        lines,
+		},
+		{
+			name:                "json not acceptable",
+			accept:              "application/json",
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"error":"none of the accepted media types can be produced (available: application/x-ndjson)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockExportService)
+			handler := NewExportHandler(mockSvc, ExportConfig{})
+			if tt.callService {
+				mockSvc.On("ExportLocations", mock.Anything).Return(tt.mockLocations, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.Export(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+// flushRecorder records the body delivered to the client at each flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.String())
+	r.ResponseRecorder.Flush()
+}
+
+// plainWriter hides the recorder's Flush method
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestExportHandler_Export_Flushing(t *testing.T) {
+	locations := make([]models.Location, 5)
+	lines := make([]string, len(locations))
+	for i := range locations {
+		locations[i] = models.Location{ID: i + 1, Prefecture: "東京都"}
+		b, _ := json.Marshal(locations[i])
+		lines[i] = string(b) + "\n"
+	}
+	upTo := func(n int) string { return strings.Join(lines[:n], "") }
+
+	t.Run("flushes the first location and every FlushEvery after it", func(t *testing.T) {
+		mockSvc := new(MockExportService)
+		mockSvc.On("ExportLocations", mock.Anything).Return(locations, nil)
+		handler := NewExportHandler(mockSvc, ExportConfig{FlushEvery: 2})
+
+		w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+
+		handler.Export(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{upTo(1), upTo(2), upTo(4)}, w.flushed)
+		assert.Equal(t, upTo(5), w.Body.String())
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("writer that cannot flush", func(t *testing.T) {
+		mockSvc := new(MockExportService)
+		mockSvc.On("ExportLocations", mock.Anything).Return(locations, nil)
+		handler := NewExportHandler(mockSvc, ExportConfig{FlushEvery: 1})
+
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(plainWriter{rec})
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+
+		assert.NotPanics(t, func() { handler.Export(c) })
+		assert.Equal(t, upTo(5), rec.Body.String())
+	})
+}
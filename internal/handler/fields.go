@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindFields parses the optional comma-separated fields query parameter into fields. On an
+// unknown field it writes a 400 response and returns false.
+func bindFields(c *gin.Context, fields *[]string) bool {
+	fieldsStr := c.Query("fields")
+	if fieldsStr == "" {
+		return true
+	}
+
+	for _, field := range strings.Split(fieldsStr, ",") {
+		field = strings.TrimSpace(field)
+		if !models.IsLocationField(field) {
+			respondError(c, http.StatusBadRequest, i18n.MsgUnknownField, field, strings.Join(models.LocationFields, ", "))
+			return false
+		}
+		*fields = append(*fields, field)
+	}
+	return true
+}
+
+// selectFields drops the models.LocationFields not in fields from every object in a decoded
+// JSON value; other keys, such as bbox or projected, are kept
+func selectFields(v interface{}, fields []string) interface{} {
+	keep := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if models.IsLocationField(key) && !keep[key] {
+					delete(v, key)
+					continue
+				}
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(v)
+	return v
+}
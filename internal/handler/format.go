@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response formats an endpoint can produce, named as in the format query parameter
+const (
+	formatJSON    = "json"
+	formatJSONAPI = "jsonapi"
+	formatGeoJSON = "geojson"
+	formatNDJSON  = "ndjson"
+)
+
+// formatMediaTypes are the media types that select each format in an Accept header
+var formatMediaTypes = map[string]string{
+	formatJSON:    "application/json",
+	formatJSONAPI: "application/vnd.api+json",
+	formatGeoJSON: "application/geo+json",
+	formatNDJSON:  "application/x-ndjson",
+}
+
+// negotiateFormat resolves the response format of a request among the formats its endpoint
+// supports, the first of which is the default. The format query parameter takes priority over
+// the Accept header; without either the default is used. When neither names a supported format
+// it writes a 406 response and returns false.
+func negotiateFormat(c *gin.Context, supported ...string) (string, bool) {
+	if format := c.Query("format"); format != "" {
+		for _, f := range supported {
+			if strings.EqualFold(format, f) {
+				return f, true
+			}
+		}
+		respondError(c, http.StatusNotAcceptable, i18n.MsgUnsupportedFormat, format, strings.Join(supported, ", "))
+		return "", false
+	}
+
+	accept := c.GetHeader("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return supported[0], true
+	}
+	if format, ok := acceptedFormat(accept, supported); ok {
+		return format, true
+	}
+
+	mediaTypes := make([]string, len(supported))
+	for i, f := range supported {
+		mediaTypes[i] = formatMediaTypes[f]
+	}
+	respondError(c, http.StatusNotAcceptable, i18n.MsgNotAcceptable, strings.Join(mediaTypes, ", "))
+	return "", false
+}
+
+// acceptedFormat picks the supported format with the highest weight in an Accept header. A
+// wildcard range matches the first supported format it covers.
+func acceptedFormat(accept string, supported []string) (string, bool) {
+	type candidate struct {
+		mediaRange string
+		q          float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+		if mediaRange == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					q = 0
+				} else {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{mediaRange: mediaRange, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if c.q <= 0 {
+			continue
+		}
+		for _, f := range supported {
+			if mediaRangeMatches(c.mediaRange, formatMediaTypes[f]) {
+				return f, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mediaRangeMatches reports whether an Accept media range such as "*/*", "application/*" or
+// "application/json" covers mediaType
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	rangeType, subtype, _ := strings.Cut(mediaRange, "/")
+	typ, _, _ := strings.Cut(mediaType, "/")
+	return subtype == "*" && rangeType == typ
+}
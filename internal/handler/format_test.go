@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		supported      []string
+		query          string
+		accept         string
+		expected       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "default without preferences", supported: []string{formatJSON, formatGeoJSON}, expected: formatJSON},
+		{name: "format parameter", supported: []string{formatJSON, formatGeoJSON}, query: "format=geojson", expected: formatGeoJSON},
+		{name: "format parameter is case-insensitive", supported: []string{formatJSON, formatGeoJSON}, query: "format=GeoJSON", expected: formatGeoJSON},
+		{name: "format parameter beats Accept", supported: []string{formatJSON, formatGeoJSON}, query: "format=json", accept: "application/geo+json", expected: formatJSON},
+		{name: "exact media type", supported: []string{formatJSON, formatGeoJSON}, accept: "application/geo+json", expected: formatGeoJSON},
+		{name: "media type parameters ignored", supported: []string{formatJSON, formatNDJSON}, accept: "application/x-ndjson; charset=utf-8", expected: formatNDJSON},
+		{name: "highest weight wins", supported: []string{formatJSON, formatGeoJSON}, accept: "application/json;q=0.5, application/geo+json;q=0.9", expected: formatGeoJSON},
+		{name: "any type", supported: []string{formatJSON, formatGeoJSON}, accept: "text/html, */*;q=0.8", expected: formatJSON},
+		{name: "subtype wildcard", supported: []string{formatGeoJSON, formatNDJSON}, accept: "text/html, application/*;q=0.5", expected: formatGeoJSON},
+		{name: "zero weight excluded", supported: []string{formatJSON, formatGeoJSON}, accept: "application/geo+json;q=0, application/json;q=0.1", expected: formatJSON},
+		{
+			name:           "unsupported format parameter",
+			supported:      []string{formatJSON},
+			query:          "format=xml",
+			expectedStatus: http.StatusNotAcceptable,
+			expectedBody:   `{"error":"unsupported format \"xml\" (available: json)"}`,
+		},
+		{
+			name:           "nothing acceptable",
+			supported:      []string{formatJSON, formatGeoJSON},
+			accept:         "text/csv, application/xml;q=0.9",
+			expectedStatus: http.StatusNotAcceptable,
+			expectedBody:   `{"error":"none of the accepted media types can be produced (available: application/json, application/geo+json)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			format, ok := negotiateFormat(c, tt.supported...)
+
+			assert.Equal(t, tt.expected, format)
+			if tt.expectedStatus != 0 {
+				assert.False(t, ok)
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+				return
+			}
+			assert.True(t, ok)
+			assert.Empty(t, w.Body.String())
+		})
+	}
+}
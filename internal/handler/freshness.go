@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FreshnessHandler handles data freshness requests
+type FreshnessHandler struct {
+	service FreshnessService
+}
+
+// FreshnessService interface for dependency injection
+type FreshnessService interface {
+	Freshness(context.Context) (*models.DataFreshness, error)
+}
+
+// NewFreshnessHandler creates a new freshness handler
+func NewFreshnessHandler(svc FreshnessService) *FreshnessHandler {
+	return &FreshnessHandler{service: svc}
+}
+
+// Freshness godoc
+// @Summary Data freshness
+// @Description Report when data was last imported and how many locations are loaded; last_imported_at is null if nothing was ever imported
+// @Tags data
+// @Produce json
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} models.DataFreshness
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /data/freshness [get]
+func (h *FreshnessHandler) Freshness(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	freshness, err := h.service.Freshness(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, freshness)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFreshnessService is a mock implementation of the FreshnessService interface
+type MockFreshnessService struct {
+	mock.Mock
+}
+
+func (m *MockFreshnessService) Freshness(ctx context.Context) (*models.DataFreshness, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.DataFreshness), args.Error(1)
+}
+
+func TestFreshnessHandler_Freshness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	imported := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		mockFreshness  *models.DataFreshness
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "imported data",
+			mockFreshness:  &models.DataFreshness{LastImportedAt: &imported, RecordCount: 42},
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"last_imported_at": "2024-04-01T09:00:00Z", "record_count": 42},
+		},
+		{
+			name:           "never imported",
+			mockFreshness:  &models.DataFreshness{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"last_imported_at": nil, "record_count": 0},
+		},
+		{
+			name:           "service error",
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockFreshnessService)
+			handler := NewFreshnessHandler(mockSvc)
+			mockSvc.On("Freshness", mock.Anything).Return(tt.mockFreshness, tt.mockError)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/data/freshness", nil)
+
+			// Execute
+			handler.Freshness(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
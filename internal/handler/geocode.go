@@ -3,8 +3,15 @@ package handler
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"geocoding-api/internal/i18n"
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/normalize"
+	"geocoding-api/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,16 +19,42 @@ import (
 // GeocodeHandler handles geocoding requests
 type GeoCodeHandler struct {
 	service GeoCodeService
+	config  GeoCodeConfig
+}
+
+// GeoCodeConfig holds the request guards applied before a query reaches the service
+type GeoCodeConfig struct {
+	// MinQueryLength is the minimum number of letters/digits a query must contain; 0 disables the check
+	MinQueryLength int
+	// MaxQueryTerms is the maximum number of terms (see queryTerms) a query may have after it is
+	// prepared; 0 disables the check
+	MaxQueryTerms int
+	// NormalizeAddresses rewrites the query with normalize.Address, matching how the importer stored the data
+	NormalizeAddresses bool
+	// BlockedQueries rejects queries matching any of its patterns, e.g. known scraper junk; it
+	// can be reloaded while serving
+	BlockedQueries *QueryBlocklist
+	// StripBuildingNames removes a trailing building name (normalize.SplitBuilding) before searching
+	StripBuildingNames bool
+	// RejectControlChars answers a query containing NUL or another control character (see
+	// isDisallowedControl) with a 400 instead of silently stripping the characters
+	RejectControlChars bool
+	// QueryPlans lets debug=true return the plans of slow searches; the repository must be
+	// configured to capture them
+	QueryPlans bool
+	// FormatMaxLimits overrides models.MaxSearchLimit for the response formats it names, e.g.
+	// {"ndjson": 100000} so streamed responses can hold far more results than a JSON array
+	FormatMaxLimits map[string]int
 }
 
 // Service interface for dependency injection
 type GeoCodeService interface {
-	Geocode(context.Context, string) ([]models.Location, error)
+	Geocode(context.Context, models.SearchOptions) (*models.GeocodeResult, error)
 }
 
 // NewGeocodeHandler creates a new geocode handler
-func NewGeoCodeHandler(svc GeoCodeService) *GeoCodeHandler {
-	return &GeoCodeHandler{service: svc}
+func NewGeoCodeHandler(svc GeoCodeService, cfg GeoCodeConfig) *GeoCodeHandler {
+	return &GeoCodeHandler{service: svc, config: cfg}
 }
 
 // Geocode godoc
@@ -29,24 +62,349 @@ func NewGeoCodeHandler(svc GeoCodeService) *GeoCodeHandler {
 // @Description Convert an address string to geographic coordinates
 // @Tags geocoding
 // @Accept json
-// @Produce json
+// @Produce json,application/vnd.api+json,application/geo+json,application/x-ndjson
 // @Param q query string true "Address to geocode"
+// @Param limit query int false "Maximum number of results (default 10); values above the format's maximum are clamped to it, 100 unless GEOCODE_FORMAT_LIMITS raises it (e.g. to 100000 for ndjson)"
+// @Param offset query int false "Number of results to skip, for paging; an offset past the last result returns an empty array"
+// @Param include_total query bool false "Count every match of the query and return it in the X-Total-Count header (and as total in wrapped responses); costs a second query and is only available for full-text results"
+// @Param fuzzy query bool false "false skips the fuzzy (trigram similarity) strategy, which GEOCODE_STRATEGIES may run when the strategies before it find nothing, e.g. to tolerate typos (default true)"
+// @Param bbox query string false "Only return addresses inside this box, given as min_lon,min_lat,max_lon,max_lat in WGS84 degrees, e.g. to keep results in the map viewport"
+// @Param suggest query bool false "Wrap the response as {results, suggestions} and suggest similar addresses when nothing matches"
+// @Param verbose query bool false "Wrap the response as {results, query, count, took_ms, cache}, echoing the normalized query with the result count, server time in milliseconds and cache status"
+// @Param include_bbox query bool false "Attach each result's municipality extent as bbox [min_lon, min_lat, max_lon, max_lat]"
+// @Param include_density query bool false "Attach each result's density, the number of locations within DENSITY_RADIUS_METERS of it (itself included); costly, so results are capped at DENSITY_MAX_RESULTS"
+// @Param srid query int false "Also return coordinates projected to this SRID (4326, 3857 or 6668) as projected {srid, x, y}"
+// @Param order_by query string false "Result order: relevance (default) or importance, which puts the most prominent addresses (e.g. by population) first and breaks ties by relevance; importance can't be combined with cursor"
+// @Param cursor query string false "Continue after the page that returned this next_cursor (keyset pagination; stable while data changes, unlike offset)"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param fields query string false "Comma-separated fields to return for each result (id, external_id, prefecture, municipality, address1, address2, block_lot, latitude, longitude, altitude, prefecture_kana, municipality_kana, address1_kana, address2_kana); default all"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default); jsonapi, a JSON:API document of locations resources whose meta carries next_cursor, strategy, suggestions, building and the disambiguation fields, plus the verbose fields with verbose=true; geojson, a GeoJSON FeatureCollection of Point features ([lon, lat]) with the other fields as properties and nothing else of the wrapped responses (the headers still apply); or ndjson, one result per line, likewise without the wrapped responses"
+// @Param disambiguate query bool false "Wrap the response as {results, needs_disambiguation, disambiguation_options}; when the top results are in several prefectures with close scores, needs_disambiguation is true and the options list their distinct prefecture/municipality pairs for the user to pick from (only when DISAMBIGUATION_THRESHOLD is set)"
+// @Param debug query bool false "Implies verbose and adds query_plans, the EXPLAIN ANALYZE plans of searches slower than the slow query threshold; only when query plan capture is enabled"
 // @Success 200 {array} models.Location
-// @Failure 400 {object} map[string]string "error":"missing required query parameter 'q'"
+// @Header 200 {string} X-Next-Cursor "Cursor for the next page, omitted on the last page"
+// @Header 200 {int} X-Total-Count "Number of results across all pages; only with include_total=true for full-text results"
+// @Header 200 {string} X-Cache "HIT when the result was served from the result cache, MISS otherwise; omitted when caching is disabled"
+// @Header 200 {string} X-Search-Strategy "Search strategy that found the results (exact, fulltext, fuzzy, interpolated or normalized); omitted when nothing was found"
+// @Header 200 {string} X-Text-Search-Config "Text search configuration that found full-text results, e.g. simple when TEXT_SEARCH_RETRY_CONFIGS was retried; omitted for other strategies"
+// @Success 200 {object} models.GeocodeResult "when suggest=true or disambiguate=true; includes any building name stripped from the query"
+// @Success 200 {object} models.VerboseGeocodeResult "when verbose=true"
+// @Failure 400 {object} map[string]string "error":"missing required query parameter 'q'" or "query is too short" or "query contains control characters" or "query is not allowed" or "query has too many terms" or "invalid limit" or "invalid offset" or "cursor cannot be combined with offset" or "invalid include_total value" or "invalid fuzzy value" or "invalid bbox" or "invalid suggest value" or "invalid verbose value" or "invalid debug value" or "invalid disambiguate value" or "invalid include_bbox value" or "invalid include_density value" or "unsupported srid" or "invalid order_by value" or "invalid cursor" or "cursor cannot be combined with order_by=importance" or "invalid coords_as_string value" or "unknown field"
 // @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
 // @Router /geocode [get]
 func (h *GeoCodeHandler) GeoCode(c *gin.Context) {
+	start := time.Now()
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatJSONAPI, formatGeoJSON, formatNDJSON)
+	if !ok {
+		return
+	}
+
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required query parameter 'q'"})
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingQuery)
+		return
+	}
+
+	if h.config.RejectControlChars && strings.IndexFunc(query, isDisallowedControl) >= 0 {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidQueryChars)
+		return
+	}
+
+	if queryLength(query) < h.config.MinQueryLength {
+		respondError(c, http.StatusBadRequest, i18n.MsgQueryTooShort, h.config.MinQueryLength)
+		return
+	}
+
+	if h.config.BlockedQueries.Blocked(query) {
+		respondError(c, http.StatusBadRequest, i18n.MsgQueryNotAllowed)
+		return
+	}
+
+	query, building := h.config.prepareQuery(query)
+
+	if h.config.MaxQueryTerms > 0 && queryTerms(query) > h.config.MaxQueryTerms {
+		respondError(c, http.StatusBadRequest, i18n.MsgTooManyTerms, h.config.MaxQueryTerms)
 		return
 	}
 
-	locations, err := h.service.Geocode(c.Request.Context(), query)
+	opts := models.SearchOptions{Query: query}
+	if suggestStr := c.Query("suggest"); suggestStr != "" {
+		var err error
+		opts.Suggest, err = strconv.ParseBool(suggestStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidSuggest)
+			return
+		}
+	}
+
+	var verbose bool
+	if verboseStr := c.Query("verbose"); verboseStr != "" {
+		var err error
+		verbose, err = strconv.ParseBool(verboseStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidVerbose)
+			return
+		}
+	}
+
+	var disambiguate bool
+	if disambiguateStr := c.Query("disambiguate"); disambiguateStr != "" {
+		var err error
+		disambiguate, err = strconv.ParseBool(disambiguateStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidDisambig)
+			return
+		}
+	}
+
+	var debug bool
+	if debugStr := c.Query("debug"); debugStr != "" {
+		var err error
+		debug, err = strconv.ParseBool(debugStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidDebug)
+			return
+		}
+		// Validated either way, but only honored while plans are captured
+		debug = debug && h.config.QueryPlans
+		verbose = verbose || debug
+	}
+
+	if bboxStr := c.Query("include_bbox"); bboxStr != "" {
+		var err error
+		opts.IncludeBBox, err = strconv.ParseBool(bboxStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidIncludeBBox)
+			return
+		}
+	}
+
+	if densityStr := c.Query("include_density"); densityStr != "" {
+		var err error
+		opts.IncludeDensity, err = strconv.ParseBool(densityStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidDensity)
+			return
+		}
+	}
+
+	if sridStr := c.Query("srid"); sridStr != "" {
+		srid, err := strconv.Atoi(sridStr)
+		if err != nil || !service.SupportedSRID(srid) {
+			respondError(c, http.StatusBadRequest, i18n.MsgUnsupportedSRID, sridStr)
+			return
+		}
+		opts.SRID = srid
+	}
+
+	// Capped here for the format, so the service accepts what the handler lets through
+	maxLimit := h.config.maxLimit(outputFormat)
+	if maxLimit != models.MaxSearchLimit {
+		opts.MaxLimit = maxLimit
+	}
+	if !bindPagination(c, &opts, maxLimit) || !bindBBox(c, &opts) {
+		return
+	}
+
+	if fuzzyStr := c.Query("fuzzy"); fuzzyStr != "" {
+		fuzzy, err := strconv.ParseBool(fuzzyStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidFuzzy)
+			return
+		}
+		opts.NoFuzzy = !fuzzy
+	}
+
+	if totalStr := c.Query("include_total"); totalStr != "" {
+		var err error
+		opts.IncludeTotal, err = strconv.ParseBool(totalStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidTotal)
+			return
+		}
+	}
+
+	// Validated by the service along with the other options
+	opts.OrderBy = c.Query("order_by")
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := models.ParseSearchCursor(cursor)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidCursor)
+			return
+		}
+		opts.After = after
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	if !bindFields(c, &opts.Fields) {
+		return
+	}
+	format.fields = opts.Fields
+
+	ctx := c.Request.Context()
+	var plans *models.QueryPlans
+	if debug {
+		ctx, plans = models.WithQueryPlans(ctx)
+	}
+
+	result, err := h.service.Geocode(ctx, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		respondServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, locations)
+	// The bare array response has nowhere to carry the cursor, so it is also sent as a header
+	if result.NextCursor != "" {
+		c.Header("X-Next-Cursor", result.NextCursor)
+	}
+	if result.Total != nil {
+		c.Header("X-Total-Count", strconv.Itoa(*result.Total))
+	}
+	if result.Cache != "" {
+		c.Header("X-Cache", result.Cache)
+	}
+	if result.Strategy != "" {
+		c.Header("X-Search-Strategy", result.Strategy)
+	}
+	if result.TextSearchConfig != "" {
+		c.Header("X-Text-Search-Config", result.TextSearchConfig)
+	}
+
+	if outputFormat == formatJSONAPI {
+		respondJSONAPI(c, result.Results, format, geocodeMeta(result, building, verbose, query, start, plans))
+		return
+	}
+	if outputFormat == formatGeoJSON {
+		respondGeoJSON(c, result.Results, format)
+		return
+	}
+	if outputFormat == formatNDJSON {
+		respondNDJSON(c, result.Results, format)
+		return
+	}
+
+	if opts.Suggest || verbose || disambiguate {
+		// Copy before adding the building, the service may share result with its cache
+		wrapped := *result
+		wrapped.Building = building
+		if verbose {
+			respondLocations(c, models.VerboseGeocodeResult{
+				GeocodeResult: wrapped,
+				Query:         query,
+				Count:         len(wrapped.Results),
+				TookMs:        float64(time.Since(start).Microseconds()) / 1000,
+				Cache:         wrapped.Cache,
+				QueryPlans:    plans.All(),
+			}, format)
+			return
+		}
+		respondLocations(c, wrapped, format)
+		return
+	}
+
+	respondLocations(c, result.Results, format)
+}
+
+// geocodeMeta collects the parts of a geocode response other than its results, for the meta
+// member of a JSON:API document; the verbose fields are only included when verbose is set
+func geocodeMeta(result *models.GeocodeResult, building string, verbose bool, query string, start time.Time, plans *models.QueryPlans) map[string]interface{} {
+	meta := map[string]interface{}{}
+	if result.NextCursor != "" {
+		meta["next_cursor"] = result.NextCursor
+	}
+	if result.Total != nil {
+		meta["total"] = *result.Total
+	}
+	if result.Strategy != "" {
+		meta["strategy"] = result.Strategy
+	}
+	if result.TextSearchConfig != "" {
+		meta["text_search_config"] = result.TextSearchConfig
+	}
+	if len(result.Suggestions) > 0 {
+		meta["suggestions"] = result.Suggestions
+	}
+	if building != "" {
+		meta["building"] = building
+	}
+	if result.NeedsDisambiguation {
+		meta["needs_disambiguation"] = true
+		meta["disambiguation_options"] = result.DisambiguationOptions
+	}
+	if verbose {
+		meta["query"] = query
+		meta["count"] = len(result.Results)
+		meta["took_ms"] = float64(time.Since(start).Microseconds()) / 1000
+		if result.Cache != "" {
+			meta["cache"] = result.Cache
+		}
+		if all := plans.All(); len(all) > 0 {
+			meta["query_plans"] = all
+		}
+	}
+	return meta
+}
+
+// maxLimit returns the largest limit served in format: its FormatMaxLimits entry, or
+// models.MaxSearchLimit
+func (cfg GeoCodeConfig) maxLimit(format string) int {
+	if max := cfg.FormatMaxLimits[format]; max > 0 {
+		return max
+	}
+	return models.MaxSearchLimit
+}
+
+// prepareQuery removes control characters, strips the building name from and normalizes a
+// query as configured, returning the query to search for and the stripped building name
+func (cfg GeoCodeConfig) prepareQuery(query string) (string, string) {
+	query = strings.Map(func(r rune) rune {
+		if isDisallowedControl(r) {
+			return -1
+		}
+		return r
+	}, query)
+
+	var building string
+	if cfg.StripBuildingNames {
+		query, building = normalize.SplitBuilding(query)
+	}
+
+	if cfg.NormalizeAddresses {
+		query = normalize.Address(query)
+	}
+	return query, building
+}
+
+// queryLength counts the letters and digits in a query. Each CJK character counts as one,
+// while whitespace and punctuation are ignored so "丸 。" doesn't pass as three characters.
+func queryLength(query string) int {
+	n := 0
+	for _, r := range query {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// isDisallowedControl reports whether r is a control character that can't reach the database:
+// NUL, which Postgres text rejects with a cryptic error, and the other C0/C1 controls, which
+// never appear in addresses. Tabs and line breaks are allowed, they just separate words.
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
+
+// queryTerms counts the words of a query the way the text search parser splits the query text
+// handed to to_tsquery: at every character that is neither a letter nor a digit. Each term
+// becomes a node of the tsquery, so the count bounds how expensive it is to plan and match.
+func queryTerms(query string) int {
+	return len(strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
 }
@@ -3,11 +3,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -19,9 +22,9 @@ type MockGeoCodeService struct {
 	mock.Mock
 }
 
-func (m *MockGeoCodeService) Geocode(ctx context.Context, address string) ([]models.Location, error) {
-	args := m.Called(ctx, address)
-	return args.Get(0).([]models.Location), args.Error(1)
+func (m *MockGeoCodeService) Geocode(ctx context.Context, opts models.SearchOptions) (*models.GeocodeResult, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*models.GeocodeResult), args.Error(1)
 }
 
 func TestGeoCodeHandler_Geocode(t *testing.T) {
@@ -52,18 +55,22 @@ func TestGeoCodeHandler_Geocode(t *testing.T) {
 					Address1:     "丸の内",
 					Latitude:     35.681236,
 					Longitude:    139.767125,
+					Score:        0.6079271,
 				},
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedBody: []models.Location{
-				{
-					ID:           1,
-					Prefecture:   "東京都",
-					Municipality: "千代田区",
-					Address1:     "丸の内",
-					Latitude:     35.681236,
-					Longitude:    139.767125,
+			expectedBody: []interface{}{
+				gin.H{
+					"id":           1,
+					"prefecture":   "東京都",
+					"municipality": "千代田区",
+					"address1":     "丸の内",
+					"address2":     "",
+					"block_lot":    "",
+					"latitude":     35.681236,
+					"longitude":    139.767125,
+					"score":        0.6079271,
 				},
 			},
 		},
@@ -89,10 +96,14 @@ func TestGeoCodeHandler_Geocode(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup
 			mockSvc := new(MockGeoCodeService)
-			handler := NewGeoCodeHandler(mockSvc)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
 
 			if tt.query != "" {
-				mockSvc.On("Geocode", mock.Anything, tt.query).Return(tt.mockLocations, tt.mockError)
+				var result *models.GeocodeResult
+				if tt.mockError == nil {
+					result = &models.GeocodeResult{Results: tt.mockLocations}
+				}
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: tt.query}).Return(result, tt.mockError)
 			}
 
 			// Create request
@@ -114,10 +125,9 @@ func TestGeoCodeHandler_Geocode(t *testing.T) {
 			// Assert
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
-			var actualBody interface{}
-			err := json.Unmarshal(w.Body.Bytes(), &actualBody)
+			expectedBody, err := json.Marshal(tt.expectedBody)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedBody, actualBody)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
 
 			if tt.query != "" {
 				mockSvc.AssertExpectations(t)
@@ -125,3 +135,1658 @@ func TestGeoCodeHandler_Geocode(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoCodeHandler_Geocode_Suggest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		suggest        string
+		mockResult     *models.GeocodeResult
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid suggest value",
+			suggest:        "maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid suggest value"},
+		},
+		{
+			name:    "suggest wraps response with suggestions",
+			suggest: "true",
+			mockResult: &models.GeocodeResult{
+				Results:     []models.Location{},
+				Suggestions: []string{"東京都千代田区丸の内"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"results":     []models.Location{},
+				"suggestions": []string{"東京都千代田区丸の内"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.mockResult != nil {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "東京都千代田区丸之内", Suggest: true}).Return(tt.mockResult, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "東京都千代田区丸之内")
+			q.Add("suggest", tt.suggest)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_Disambiguate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	options := []models.Area{
+		{Prefecture: "東京都", Municipality: "府中市"},
+		{Prefecture: "広島県", Municipality: "府中市"},
+	}
+	locations := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "府中市"},
+		{ID: 2, Prefecture: "広島県", Municipality: "府中市"},
+	}
+
+	tests := []struct {
+		name           string
+		disambiguate   string
+		mockResult     *models.GeocodeResult
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid disambiguate value",
+			disambiguate:   "maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid disambiguate value"},
+		},
+		{
+			name:         "ambiguous query wraps response with options",
+			disambiguate: "true",
+			mockResult: &models.GeocodeResult{
+				Results:               locations,
+				NeedsDisambiguation:   true,
+				DisambiguationOptions: options,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"results":                locations,
+				"needs_disambiguation":   true,
+				"disambiguation_options": options,
+			},
+		},
+		{
+			name:           "unambiguous query",
+			disambiguate:   "true",
+			mockResult:     &models.GeocodeResult{Results: locations[:1]},
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"results": locations[:1]},
+		},
+		{
+			name:           "bare array without disambiguate",
+			disambiguate:   "false",
+			mockResult:     &models.GeocodeResult{Results: locations, NeedsDisambiguation: true, DisambiguationOptions: options},
+			expectedStatus: http.StatusOK,
+			expectedBody:   locations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.mockResult != nil {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "府中市"}).Return(tt.mockResult, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "府中市")
+			q.Add("disambiguate", tt.disambiguate)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_BlockedQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectSearch   bool
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "punctuation only",
+			query:          "!!!",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query is not allowed"},
+		},
+		{
+			name:           "sql-ish junk",
+			query:          "' OR 1=1 --",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query is not allowed"},
+		},
+		{
+			name:           "address allowed",
+			query:          "千代田区丸の内",
+			expectSearch:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+	}
+
+	blocked := []*regexp.Regexp{regexp.MustCompile(`^\pP+$`), regexp.MustCompile(`(?i)'\s*or\s`)}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{BlockedQueries: NewQueryBlocklist(blocked)})
+
+			if tt.expectSearch {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: tt.query}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", tt.query)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			// Blocked queries never reach the service
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_ReloadedBlockedQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	blocklist := NewQueryBlocklist(nil)
+	handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{BlockedQueries: blocklist})
+	mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "spam spam"}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil).Once()
+
+	geocode := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/geocode?q=spam+spam", nil)
+		handler.GeoCode(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, geocode().Code)
+
+	// As on SIGHUP: the reloaded pattern applies to the next request
+	blocklist.Store([]*regexp.Regexp{regexp.MustCompile(`^spam\b`)})
+	w := geocode()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"query is not allowed"}`, w.Body.String())
+
+	blocklist.Store(nil)
+	mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "spam spam"}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil).Once()
+	assert.Equal(t, http.StatusOK, geocode().Code)
+	mockSvc.AssertExpectations(t)
+}
+func TestGeoCodeHandler_Geocode_Verbose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125}
+
+	tests := []struct {
+		name           string
+		params         map[string]string
+		expectedOpts   *models.SearchOptions
+		mockResult     *models.GeocodeResult
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "envelope with normalized query",
+			params:         map[string]string{"q": "丸の内1丁目1", "verbose": "true"},
+			expectedOpts:   &models.SearchOptions{Query: "丸の内1-1"},
+			mockResult:     &models.GeocodeResult{Results: []models.Location{location}},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"results": []gin.H{{"id": 1, "prefecture": "東京都", "municipality": "千代田区", "address1": "丸の内1", "address2": "", "block_lot": "1",
+					"latitude": 35.681236, "longitude": 139.767125}},
+				"query": "丸の内1-1",
+				"count": 1,
+			},
+		},
+		{
+			name:           "combined with suggest and fields",
+			params:         map[string]string{"q": "丸之内", "verbose": "true", "suggest": "true", "fields": "id"},
+			expectedOpts:   &models.SearchOptions{Query: "丸之内", Suggest: true, Fields: []string{"id"}},
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}, Suggestions: []string{"丸の内"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"results": []gin.H{}, "suggestions": []string{"丸の内"}, "query": "丸之内", "count": 0},
+		},
+		{
+			name:           "verbose false keeps the bare array",
+			params:         map[string]string{"q": "丸の内", "verbose": "false"},
+			expectedOpts:   &models.SearchOptions{Query: "丸の内"},
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []gin.H{},
+		},
+		{
+			name:           "invalid verbose value",
+			params:         map[string]string{"q": "丸の内", "verbose": "loud"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid verbose value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true})
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(tt.mockResult, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			for key, value := range tt.params {
+				q.Add(key, value)
+			}
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			// The server time varies, so only check that it is reported
+			var body interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if envelope, ok := body.(map[string]interface{}); ok && tt.expectedStatus == http.StatusOK {
+				assert.GreaterOrEqual(t, envelope["took_ms"], 0.0)
+				delete(envelope, "took_ms")
+			}
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			actualBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), string(actualBody))
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_DebugQueryPlans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	plan := models.QueryPlan{Query: "SearchLocationsByText", Plan: "Limit  (actual time=812.4..812.4 rows=0 loops=1)"}
+
+	tests := []struct {
+		name           string
+		queryPlans     bool
+		debug          string
+		expectSearch   bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "plans returned in the verbose envelope",
+			queryPlans:     true,
+			debug:          "true",
+			expectSearch:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"results":[],"query":"丸の内","count":0,"query_plans":[{"query":"SearchLocationsByText","plan":"Limit  (actual time=812.4..812.4 rows=0 loops=1)"}]}`,
+		},
+		{
+			name:           "ignored when capture is disabled",
+			debug:          "true",
+			expectSearch:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "invalid debug value",
+			queryPlans:     true,
+			debug:          "plans",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid debug value"}`,
+		},
+		{
+			name:           "invalid debug value when capture is disabled",
+			debug:          "plans",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid debug value"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{QueryPlans: tt.queryPlans})
+			if tt.expectSearch {
+				// Stands in for the repository capturing the plan of a slow search
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).Run(func(args mock.Arguments) {
+					if plans := models.QueryPlansFromContext(args.Get(0).(context.Context)); plans != nil {
+						plans.Add(plan)
+					}
+				}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&debug="+tt.debug, nil)
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var body interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if envelope, ok := body.(map[string]interface{}); ok {
+				delete(envelope, "took_ms")
+			}
+			actualBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expectedBody, string(actualBody))
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_Strategy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		params         string
+		mockResult     *models.GeocodeResult
+		expectedHeader string
+		expectedBody   interface{}
+	}{
+		{
+			name:           "bare results",
+			params:         "q=丸の内",
+			mockResult:     &models.GeocodeResult{Results: []models.Location{{ID: 1}}, Strategy: "exact"},
+			expectedHeader: "exact",
+			expectedBody:   []gin.H{{"id": 1, "prefecture": "", "municipality": "", "address1": "", "address2": "", "block_lot": "", "latitude": 0, "longitude": 0}},
+		},
+		{
+			name:           "strategy in the verbose envelope",
+			params:         "q=丸の内&verbose=true",
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}, Strategy: "fuzzy"},
+			expectedHeader: "fuzzy",
+			expectedBody:   gin.H{"results": []gin.H{}, "query": "丸の内", "count": 0, "strategy": "fuzzy"},
+		},
+		{
+			name:           "text search configuration in the verbose envelope",
+			params:         "q=丸の内&verbose=true",
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}, Strategy: "fulltext", TextSearchConfig: "simple"},
+			expectedHeader: "fulltext",
+			expectedBody:   gin.H{"results": []gin.H{}, "query": "丸の内", "count": 0, "strategy": "fulltext", "text_search_config": "simple"},
+		},
+		{
+			name:         "nothing found",
+			params:       "q=丸の内&verbose=true",
+			mockResult:   &models.GeocodeResult{Results: []models.Location{}},
+			expectedBody: gin.H{"results": []gin.H{}, "query": "丸の内", "count": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+			mockSvc.On("Geocode", mock.Anything, mock.Anything).Return(tt.mockResult, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode?"+tt.params, nil)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedHeader, w.Header().Get("X-Search-Strategy"))
+			assert.Equal(t, tt.mockResult.TextSearchConfig, w.Header().Get("X-Text-Search-Config"))
+
+			var body interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if envelope, ok := body.(map[string]interface{}); ok {
+				delete(envelope, "took_ms")
+			}
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			actualBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), string(actualBody))
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_NotAcceptable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/geocode?q=丸の内", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.GeoCode(c)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.JSONEq(t, `{"error":"none of the accepted media types can be produced (available: application/json, application/vnd.api+json, application/geo+json, application/x-ndjson)"}`, w.Body.String())
+	mockSvc.AssertNotCalled(t, "Geocode", mock.Anything, mock.Anything)
+}
+
+func TestGeoCodeHandler_Geocode_CacheStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		params         string
+		mockResult     *models.GeocodeResult
+		expectedHeader string
+		expectedBody   interface{}
+	}{
+		{
+			name:           "served from cache",
+			params:         "q=丸の内",
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}, Cache: models.CacheHit},
+			expectedHeader: "HIT",
+			expectedBody:   []gin.H{},
+		},
+		{
+			name:           "cache status in the verbose envelope",
+			params:         "q=丸の内&verbose=true",
+			mockResult:     &models.GeocodeResult{Results: []models.Location{}, Cache: models.CacheMiss},
+			expectedHeader: "MISS",
+			expectedBody:   gin.H{"results": []gin.H{}, "query": "丸の内", "count": 0, "cache": "MISS"},
+		},
+		{
+			name:         "caching disabled",
+			params:       "q=丸の内&verbose=true",
+			mockResult:   &models.GeocodeResult{Results: []models.Location{}},
+			expectedBody: gin.H{"results": []gin.H{}, "query": "丸の内", "count": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+			mockSvc.On("Geocode", mock.Anything, mock.Anything).Return(tt.mockResult, nil)
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode?"+tt.params, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedHeader, w.Header().Get("X-Cache"))
+
+			var body interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if envelope, ok := body.(map[string]interface{}); ok {
+				delete(envelope, "took_ms")
+			}
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			actualBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), string(actualBody))
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_ControlChars(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		config         GeoCodeConfig
+		query          string
+		expectedQuery  string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "nul stripped by default",
+			query:          "東京都\x00千代田区",
+			expectedQuery:  "東京都千代田区",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "other controls stripped, whitespace kept",
+			query:          "東京都\x1b[0m\t千代田区\u009b",
+			expectedQuery:  "東京都[0m\t千代田区",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "nul rejected",
+			config:         GeoCodeConfig{RejectControlChars: true},
+			query:          "東京都\x00千代田区",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query contains control characters"},
+		},
+		{
+			name:           "whitespace accepted when rejecting",
+			config:         GeoCodeConfig{RejectControlChars: true},
+			query:          "東京都\n千代田区",
+			expectedQuery:  "東京都\n千代田区",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, tt.config)
+
+			if tt.expectedQuery != "" {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: tt.expectedQuery}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", tt.query)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_MaxQueryTerms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedQuery  string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "within the limit",
+			query:          "東京都 千代田区 丸の内",
+			expectedQuery:  "東京都 千代田区 丸の内",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "too many terms",
+			query:          "東京都 千代田区 丸の内 一丁目",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query has too many terms (max 3)"},
+		},
+		{
+			name:           "operators split terms",
+			query:          "東京都&千代田区|丸の内!一丁目",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query has too many terms (max 3)"},
+		},
+		{
+			name:           "counted after normalization",
+			query:          "丸の内1丁目9番",
+			expectedQuery:  "丸の内1-9",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{MaxQueryTerms: 3, NormalizeAddresses: true})
+
+			if tt.expectedQuery != "" {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: tt.expectedQuery}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", tt.query)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			// Rejected queries never reach the service
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_MinQueryLength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectSearch   bool
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "single kanji rejected",
+			query:          "東",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query is too short: use at least 2 characters, e.g. include the municipality"},
+		},
+		{
+			name:           "punctuation and spaces not counted",
+			query:          "丸 。",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "query is too short: use at least 2 characters, e.g. include the municipality"},
+		},
+		{
+			name:           "two kanji accepted",
+			query:          "東京",
+			expectSearch:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{MinQueryLength: 2})
+
+			if tt.expectSearch {
+				mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: tt.query}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", tt.query)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_IncludeBBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	located := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", BBox: []float64{139.73, 35.66, 139.78, 35.70}},
+	}
+
+	tests := []struct {
+		name           string
+		includeBBox    string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid include_bbox",
+			includeBBox:    "maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid include_bbox value"},
+		},
+		{
+			name:           "include_bbox passed to service",
+			includeBBox:    "true",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", IncludeBBox: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: located}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("include_bbox", tt.includeBBox)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_Fuzzy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	located := []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"}}
+
+	tests := []struct {
+		name           string
+		fuzzy          string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid fuzzy",
+			fuzzy:          "sometimes",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid fuzzy value"},
+		},
+		{
+			name:           "fuzzy disabled",
+			fuzzy:          "false",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", NoFuzzy: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+		{
+			name:           "fuzzy enabled is the default",
+			fuzzy:          "true",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: located}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("fuzzy", tt.fuzzy)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_BBox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	located := []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"}}
+	invalidBBox := gin.H{"error": "invalid bbox: expected min_lon,min_lat,max_lon,max_lat with longitudes between -180 and 180, latitudes between -90 and 90 and each minimum below its maximum"}
+
+	tests := []struct {
+		name           string
+		bbox           string
+		expectedOpts   *models.SearchOptions
+		mockErr        error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "bounding box",
+			bbox:           "139.7,35.6,139.8,35.7",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+		{
+			name:           "spaces around the values",
+			bbox:           "139.7, 35.6, 139.8, 35.7",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+		{
+			name:           "not a number",
+			bbox:           "139.7,35.6,east,35.7",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidBBox,
+		},
+		{
+			name:           "too few values",
+			bbox:           "139.7,35.6,139.8",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidBBox,
+		},
+		{
+			name:           "minimum above maximum",
+			bbox:           "139.8,35.6,139.7,35.7",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.8, MinLat: 35.6, MaxLon: 139.7, MaxLat: 35.7}},
+			mockErr:        service.ErrInvalidBBox,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidBBox,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				var result *models.GeocodeResult
+				if tt.mockErr == nil {
+					result = &models.GeocodeResult{Results: located}
+				}
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(result, tt.mockErr)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("bbox", tt.bbox)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_IncludeDensity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	density := 42
+	located := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Density: &density},
+	}
+
+	tests := []struct {
+		name           string
+		includeDensity string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid include_density",
+			includeDensity: "maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid include_density value"},
+		},
+		{
+			name:           "include_density passed to service",
+			includeDensity: "true",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", IncludeDensity: true},
+			expectedStatus: http.StatusOK,
+			expectedBody:   located,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: located}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("include_density", tt.includeDensity)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_SRID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	projected := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Latitude: 35.681236, Longitude: 139.767125,
+			Projected: &models.ProjectedPoint{SRID: 3857, X: 15558907.3, Y: 4256463.9}},
+	}
+
+	tests := []struct {
+		name           string
+		srid           string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "non-numeric srid",
+			srid:           "mercator",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": `unsupported srid: "mercator" (supported: 4326, 3857, 6668)`},
+		},
+		{
+			name:           "srid not in allowlist",
+			srid:           "2193",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": `unsupported srid: "2193" (supported: 4326, 3857, 6668)`},
+		},
+		{
+			name:           "web mercator",
+			srid:           "3857",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", SRID: 3857},
+			expectedStatus: http.StatusOK,
+			expectedBody:   projected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: projected}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("srid", tt.srid)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_NormalizeAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true})
+	mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内1-9-1"}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+	q := req.URL.Query()
+	q.Add("q", "丸の内一丁目９番１号")
+	req.URL.RawQuery = q.Encode()
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.GeoCode(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockSvc.AssertExpectations(t)
+}
+
+func TestGeoCodeHandler_Geocode_StripBuildingNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true, StripBuildingNames: true})
+	mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内1-9-1", Suggest: true}).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+	q := req.URL.Query()
+	q.Add("q", "丸の内一丁目9番1号 丸の内ビル 3F")
+	q.Add("suggest", "true")
+	req.URL.RawQuery = q.Encode()
+	w := httptest.NewRecorder()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.GeoCode(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"results":[],"building":"丸の内ビル 3F"}`, w.Body.String())
+	mockSvc.AssertExpectations(t)
+}
+
+func TestGeoCodeHandler_Geocode_Cursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	after := models.SearchCursor{Rank: 0.5, ID: 42}
+	next := models.SearchCursor{Rank: 0.25, ID: 7}.Encode()
+	page := []models.Location{{ID: 7, Prefecture: "東京都", Municipality: "千代田区"}}
+
+	tests := []struct {
+		name               string
+		cursor             string
+		expectedOpts       *models.SearchOptions
+		expectedStatus     int
+		expectedBody       interface{}
+		expectedNextCursor string
+	}{
+		{
+			name:           "malformed cursor",
+			cursor:         "not-a-cursor",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid cursor"},
+		},
+		{
+			name:               "valid cursor",
+			cursor:             after.Encode(),
+			expectedOpts:       &models.SearchOptions{Query: "丸の内", After: &after},
+			expectedStatus:     http.StatusOK,
+			expectedBody:       page,
+			expectedNextCursor: next,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: page, NextCursor: next}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("cursor", tt.cursor)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedNextCursor, w.Header().Get("X-Next-Cursor"))
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_OrderBy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	page := []models.Location{{ID: 7, Prefecture: "北海道", Municipality: "札幌市中央区"}}
+
+	tests := []struct {
+		name           string
+		orderBy        string
+		expectedOpts   models.SearchOptions
+		mockErr        error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "importance",
+			orderBy:        "importance",
+			expectedOpts:   models.SearchOptions{Query: "中央", OrderBy: models.OrderByImportance},
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+		},
+		{
+			name:           "unknown order",
+			orderBy:        "population",
+			expectedOpts:   models.SearchOptions{Query: "中央", OrderBy: "population"},
+			mockErr:        fmt.Errorf("%w: %q", service.ErrInvalidOrderBy, "population"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid order_by value: must be relevance or importance"},
+		},
+		{
+			name:           "importance with cursor",
+			orderBy:        "importance",
+			expectedOpts:   models.SearchOptions{Query: "中央", OrderBy: models.OrderByImportance},
+			mockErr:        service.ErrCursorWithOrder,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "cursor cannot be combined with order_by=importance, use offset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.mockErr != nil {
+				mockSvc.On("Geocode", mock.Anything, tt.expectedOpts).Return((*models.GeocodeResult)(nil), tt.mockErr)
+			} else {
+				mockSvc.On("Geocode", mock.Anything, tt.expectedOpts).Return(&models.GeocodeResult{Results: page}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "中央")
+			q.Add("order_by", tt.orderBy)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_Fields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	results := []models.Location{
+		{ID: 1, Municipality: "千代田区", Latitude: 35.681236, Longitude: 139.767125, BBox: []float64{139.7, 35.6, 139.8, 35.7}},
+	}
+
+	tests := []struct {
+		name           string
+		fields         string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "selected fields only",
+			fields:         "latitude, longitude",
+			expectedOpts:   &models.SearchOptions{Query: "丸の内", IncludeBBox: true, Fields: []string{"latitude", "longitude"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"latitude":35.681236,"longitude":139.767125,"bbox":[139.7,35.6,139.8,35.7]}]`,
+		},
+		{
+			name:           "unknown field",
+			fields:         "latitude,geom",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unknown field \"geom\" (available: id, external_id, prefecture, municipality, address1, address2, block_lot, latitude, longitude, altitude, prefecture_kana, municipality_kana, address1_kana, address2_kana)"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: results}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "丸の内")
+			q.Add("include_bbox", "true")
+			q.Add("fields", tt.fields)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_Limit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	page := []models.Location{{ID: 7, Prefecture: "東京都", Municipality: "千代田区"}}
+
+	tests := []struct {
+		name           string
+		limit          string
+		expectedOpts   *models.SearchOptions
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "within range",
+			limit:          "25",
+			expectedOpts:   &models.SearchOptions{Query: "東京", Limit: 25},
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+		},
+		{
+			name:           "clamped to maximum",
+			limit:          "1000",
+			expectedOpts:   &models.SearchOptions{Query: "東京", Limit: models.MaxSearchLimit},
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+		},
+		{
+			name:           "zero uses default",
+			limit:          "0",
+			expectedOpts:   &models.SearchOptions{Query: "東京"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+		},
+		{
+			name:           "negative",
+			limit:          "-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid limit: must be a non-negative integer; 0 uses the default and values above 100 are clamped to it"},
+		},
+		{
+			name:           "non-numeric",
+			limit:          "ten",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid limit: must be a non-negative integer; 0 uses the default and values above 100 are clamped to it"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: page}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "東京")
+			q.Add("limit", tt.limit)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_FormatMaxLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := GeoCodeConfig{FormatMaxLimits: map[string]int{formatNDJSON: 100000, formatGeoJSON: 20}}
+
+	tests := []struct {
+		name         string
+		format       string
+		limit        string
+		expectedOpts models.SearchOptions
+	}{
+		{
+			name:         "raised maximum",
+			format:       formatNDJSON,
+			limit:        "50000",
+			expectedOpts: models.SearchOptions{Query: "東京", Limit: 50000, MaxLimit: 100000},
+		},
+		{
+			name:         "clamped to raised maximum",
+			format:       formatNDJSON,
+			limit:        "200000",
+			expectedOpts: models.SearchOptions{Query: "東京", Limit: 100000, MaxLimit: 100000},
+		},
+		{
+			name:         "clamped to lowered maximum",
+			format:       formatGeoJSON,
+			limit:        "50",
+			expectedOpts: models.SearchOptions{Query: "東京", Limit: 20, MaxLimit: 20},
+		},
+		{
+			name:         "format without override",
+			format:       formatJSON,
+			limit:        "1000",
+			expectedOpts: models.SearchOptions{Query: "東京", Limit: models.MaxSearchLimit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, config)
+			mockSvc.On("Geocode", mock.Anything, tt.expectedOpts).Return(&models.GeocodeResult{Results: []models.Location{}}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "東京")
+			q.Add("limit", tt.limit)
+			q.Add("format", tt.format)
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_OffsetAndTotal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	page := []models.Location{{ID: 7, Prefecture: "東京都", Municipality: "千代田区"}}
+	total := 42
+
+	tests := []struct {
+		name           string
+		params         map[string]string
+		expectedOpts   *models.SearchOptions
+		mockTotal      *int
+		expectedStatus int
+		expectedBody   interface{}
+		expectedTotal  string
+	}{
+		{
+			name:           "offset",
+			params:         map[string]string{"offset": "20", "limit": "5"},
+			expectedOpts:   &models.SearchOptions{Query: "東京", Limit: 5, Offset: 20},
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+		},
+		{
+			name:           "total header",
+			params:         map[string]string{"offset": "20", "include_total": "true"},
+			expectedOpts:   &models.SearchOptions{Query: "東京", Offset: 20, IncludeTotal: true},
+			mockTotal:      &total,
+			expectedStatus: http.StatusOK,
+			expectedBody:   page,
+			expectedTotal:  "42",
+		},
+		{
+			name:           "negative offset",
+			params:         map[string]string{"offset": "-1"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid offset: must be a non-negative integer"},
+		},
+		{
+			name:           "invalid include_total",
+			params:         map[string]string{"include_total": "maybe"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid include_total value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("Geocode", mock.Anything, *tt.expectedOpts).Return(&models.GeocodeResult{Results: page, Total: tt.mockTotal}, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			q := req.URL.Query()
+			q.Add("q", "東京")
+			for k, v := range tt.params {
+				q.Add(k, v)
+			}
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GeoCode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedTotal, w.Header().Get("X-Total-Count"))
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"geocoding-api/internal/geojson"
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondGeoJSON writes locations as a 200 GeoJSON feature collection. fields selects the
+// properties like the plain response; coords_as_string doesn't apply, GeoJSON positions are numbers.
+func respondGeoJSON(c *gin.Context, locations []models.Location, format responseFormat) {
+	fc, err := geojson.FromLocations(locations)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+	if len(format.fields) > 0 {
+		for _, feature := range fc.Features {
+			selectFields(feature.Properties, format.fields)
+		}
+	}
+
+	data, err := json.Marshal(fc)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+	c.Data(http.StatusOK, formatMediaTypes[formatGeoJSON], data)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGeoCodeHandler_Geocode_GeoJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125}
+
+	tests := []struct {
+		name         string
+		url          string
+		accept       string
+		mockOpts     models.SearchOptions
+		mockResults  []models.Location
+		expectedBody string
+	}{
+		{
+			name:        "feature collection",
+			url:         "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85",
+			accept:      "application/geo+json",
+			mockOpts:    models.SearchOptions{Query: "丸の内"},
+			mockResults: []models.Location{location},
+			expectedBody: `{"type":"FeatureCollection","features":[{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[139.767125,35.681236]},
+				"properties":{"prefecture":"東京都","municipality":"千代田区","address1":"丸の内1","address2":"","block_lot":"1"}}]}`,
+		},
+		{
+			name:        "selected fields",
+			url:         "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&fields=municipality",
+			accept:      "application/geo+json",
+			mockOpts:    models.SearchOptions{Query: "丸の内", Fields: []string{"municipality"}},
+			mockResults: []models.Location{location},
+			expectedBody: `{"type":"FeatureCollection","features":[{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[139.767125,35.681236]},
+				"properties":{"municipality":"千代田区"}}]}`,
+		},
+		{
+			name:         "no results",
+			url:          "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&format=geojson",
+			mockOpts:     models.SearchOptions{Query: "丸の内"},
+			mockResults:  []models.Location{},
+			expectedBody: `{"type":"FeatureCollection","features":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+			mockSvc.On("Geocode", mock.Anything, tt.mockOpts).Return(&models.GeocodeResult{Results: tt.mockResults}, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_GeoJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nearest := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125, Distance: 3.2}
+	next := models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "大手町", Latitude: 35.6852, Longitude: 139.7638}
+	nearestFeature := `{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[139.767125,35.681236]},
+		"properties":{"prefecture":"東京都","municipality":"千代田区","address1":"丸の内","address2":"","block_lot":"","distance_meters":3.2}}`
+
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*MockReverseGeoCodeService)
+		expectedBody string
+	}{
+		{
+			name:  "nearest address",
+			query: "",
+			setupMock: func(m *MockReverseGeoCodeService) {
+				m.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil)).Return(&nearest, nil)
+			},
+			expectedBody: `{"type":"FeatureCollection","features":[` + nearestFeature + `]}`,
+		},
+		{
+			name:  "nearest addresses",
+			query: "&limit=2",
+			setupMock: func(m *MockReverseGeoCodeService) {
+				withDistance := next
+				withDistance.Distance = 420.5
+				m.On("ReverseGeocodeNearest", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil), 2).Return([]models.Location{nearest, withDistance}, nil)
+			},
+			expectedBody: `{"type":"FeatureCollection","features":[` + nearestFeature + `,{"type":"Feature","id":2,"geometry":{"type":"Point","coordinates":[139.7638,35.6852]},
+				"properties":{"prefecture":"東京都","municipality":"千代田区","address1":"大手町","address2":"","block_lot":"","distance_meters":420.5}}]}`,
+		},
+		{
+			name:  "context follows the nearest address",
+			query: "&context=1",
+			setupMock: func(m *MockReverseGeoCodeService) {
+				withDistance := next
+				withDistance.Distance = 420.5
+				m.On("ReverseGeocodeWithContext", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil), 1).Return(&models.ReverseGeocodeResult{
+					Location: nearest,
+					Context:  []models.Location{withDistance},
+				}, nil)
+			},
+			expectedBody: `{"type":"FeatureCollection","features":[` + nearestFeature + `,{"type":"Feature","id":2,"geometry":{"type":"Point","coordinates":[139.7638,35.6852]},
+				"properties":{"prefecture":"東京都","municipality":"千代田区","address1":"大手町","address2":"","block_lot":"","distance_meters":420.5}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+			tt.setupMock(mockSvc)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			c.Request.Header.Set("Accept", "application/geo+json")
+
+			handler.ReverseGeocode(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
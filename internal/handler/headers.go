@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHeaders sets baseline hardening headers on every response. frameOptions is sent as
+// X-Frame-Options (e.g. "DENY" or "SAMEORIGIN"); an empty value omits the header.
+func SecurityHeaders(frameOptions string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		if frameOptions != "" {
+			c.Header("X-Frame-Options", frameOptions)
+		}
+		c.Next()
+	}
+}
+
+// NotFound answers unknown routes with a JSON error instead of gin's plain-text default,
+// so every API response is application/json; charset=utf-8
+func NotFound(c *gin.Context) {
+	respondError(c, http.StatusNotFound, i18n.MsgNotFound)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name                string
+		frameOptions        string
+		path                string
+		expectedStatus      int
+		expectedFrameOption string
+		expectedBody        string
+	}{
+		{
+			name:                "json response",
+			frameOptions:        "DENY",
+			path:                "/geocode",
+			expectedStatus:      http.StatusBadRequest,
+			expectedFrameOption: "DENY",
+			expectedBody:        `{"error":"missing required query parameter 'q'"}`,
+		},
+		{
+			name:           "frame options disabled",
+			frameOptions:   "",
+			path:           "/geocode",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"missing required query parameter 'q'"}`,
+		},
+		{
+			name:                "unknown route is json",
+			frameOptions:        "SAMEORIGIN",
+			path:                "/nope",
+			expectedStatus:      http.StatusNotFound,
+			expectedFrameOption: "SAMEORIGIN",
+			expectedBody:        `{"error":"not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(SecurityHeaders(tt.frameOptions))
+			r.NoRoute(NotFound)
+			r.GET("/geocode", NewGeoCodeHandler(new(MockGeoCodeService), GeoCodeConfig{}).GeoCode)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, tt.expectedFrameOption, w.Header().Get("X-Frame-Options"))
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
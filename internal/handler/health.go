@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler handles readiness checks
+type HealthHandler struct {
+	service HealthService
+}
+
+// HealthService interface for dependency injection
+type HealthService interface {
+	CheckPostGIS(context.Context) (string, error)
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(svc HealthService) *HealthHandler {
+	return &HealthHandler{service: svc}
+}
+
+// Readyz godoc
+// @Summary Readiness check
+// @Description Report whether the database is ready to serve spatial queries, including the detected PostGIS version
+// @Tags health
+// @Produce json
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} map[string]string "status":"ready","postgis_version":"3.4"
+// @Failure 503 {object} map[string]string "status":"not ready","error":"PostGIS extension is not installed"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	version, err := h.service.CheckPostGIS(c.Request.Context())
+	if err != nil {
+		msg := i18n.Message(responseLanguage(c), i18n.MsgPostGISUnavailable)
+		if errors.Is(err, service.ErrPostGISTooOld) {
+			msg = i18n.Message(responseLanguage(c), i18n.MsgPostGISTooOld, version, service.MinPostGISVersion)
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":          "not ready",
+			"postgis_version": version,
+			"error":           msg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "ready",
+		"postgis_version": version,
+	})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHealthService is a mock implementation of the HealthService interface
+type MockHealthService struct {
+	mock.Mock
+}
+
+func (m *MockHealthService) CheckPostGIS(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		mockVersion    string
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "ready",
+			mockVersion:    "3.4",
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"status": "ready", "postgis_version": "3.4"},
+		},
+		{
+			name:           "postgis missing",
+			mockError:      fmt.Errorf("%w: %v", service.ErrPostGISUnavailable, assert.AnError),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: gin.H{
+				"status":          "not ready",
+				"postgis_version": "",
+				"error":           "PostGIS extension is not installed; run CREATE EXTENSION postgis",
+			},
+		},
+		{
+			name:           "postgis too old",
+			mockVersion:    "2.5",
+			mockError:      service.ErrPostGISTooOld,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: gin.H{
+				"status":          "not ready",
+				"postgis_version": "2.5",
+				"error":           "PostGIS 2.5 is too old; 3.0 or newer is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockHealthService)
+			handler := NewHealthHandler(mockSvc)
+			mockSvc.On("CheckPostGIS", mock.Anything).Return(tt.mockVersion, tt.mockError)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			// Execute
+			handler.Readyz(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonAPILocationType is the JSON:API resource type of a location
+const jsonAPILocationType = "locations"
+
+// jsonAPIDocument is a JSON:API top-level document whose primary data is a collection of locations
+type jsonAPIDocument struct {
+	Data []jsonAPIResource      `json:"data"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// jsonAPIResource is a location as a JSON:API resource object: its ID is lifted out of the
+// attributes, which hold everything else
+type jsonAPIResource struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// newJSONAPIDocument serializes locations as JSON:API resources. The attributes are the
+// location's JSON fields, formatted like the plain response (coords_as_string, fields), except
+// the id, which JSON:API requires on every resource whatever the selected fields.
+func newJSONAPIDocument(locations []models.Location, format responseFormat, meta map[string]interface{}) (jsonAPIDocument, error) {
+	doc := jsonAPIDocument{Data: make([]jsonAPIResource, 0, len(locations)), Meta: meta}
+	for _, loc := range locations {
+		data, err := json.Marshal(loc)
+		if err != nil {
+			return jsonAPIDocument{}, err
+		}
+
+		// Decode numbers as json.Number so the attributes are written back verbatim
+		var attributes map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&attributes); err != nil {
+			return jsonAPIDocument{}, err
+		}
+
+		if format.coordsAsString {
+			stringifyCoords(attributes)
+		}
+		if len(format.fields) > 0 {
+			selectFields(attributes, format.fields)
+		}
+		delete(attributes, "id")
+
+		doc.Data = append(doc.Data, jsonAPIResource{
+			Type:       jsonAPILocationType,
+			ID:         strconv.Itoa(loc.ID),
+			Attributes: attributes,
+		})
+	}
+	return doc, nil
+}
+
+// respondJSONAPI writes locations as a 200 JSON:API document, with meta as its top-level meta
+// member when not empty
+func respondJSONAPI(c *gin.Context, locations []models.Location, format responseFormat, meta map[string]interface{}) {
+	doc, err := newJSONAPIDocument(locations, format, meta)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+		return
+	}
+	c.Data(http.StatusOK, formatMediaTypes[formatJSONAPI], data)
+}
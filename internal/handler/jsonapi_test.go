@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewJSONAPIDocument(t *testing.T) {
+	location := models.Location{ID: 7, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125, Source: "tokyo.csv"}
+
+	tests := []struct {
+		name      string
+		locations []models.Location
+		format    responseFormat
+		meta      map[string]interface{}
+		expected  string
+	}{
+		{
+			name:      "resources with the id lifted out of the attributes",
+			locations: []models.Location{location},
+			expected: `{"data":[{"type":"locations","id":"7","attributes":{"prefecture":"東京都","municipality":"千代田区","address1":"丸の内1","address2":"","block_lot":"1",
+				"latitude":35.681236,"longitude":139.767125,"source":"tokyo.csv"}}]}`,
+		},
+		{
+			name:      "selected fields keep the id",
+			locations: []models.Location{location},
+			format:    responseFormat{fields: []string{"latitude", "longitude"}},
+			expected:  `{"data":[{"type":"locations","id":"7","attributes":{"latitude":35.681236,"longitude":139.767125,"source":"tokyo.csv"}}]}`,
+		},
+		{
+			name:      "coordinates as strings",
+			locations: []models.Location{location},
+			format:    responseFormat{coordsAsString: true, fields: []string{"latitude", "longitude"}},
+			expected:  `{"data":[{"type":"locations","id":"7","attributes":{"latitude":"35.6812360","longitude":"139.7671250","source":"tokyo.csv"}}]}`,
+		},
+		{
+			name:     "empty collection with meta",
+			meta:     map[string]interface{}{"strategy": "fulltext"},
+			expected: `{"data":[],"meta":{"strategy":"fulltext"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := newJSONAPIDocument(tt.locations, tt.format, tt.meta)
+
+			assert.NoError(t, err)
+			data, err := json.Marshal(doc)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestGeoCodeHandler_Geocode_JSONAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125}
+
+	mockSvc := new(MockGeoCodeService)
+	handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+	mockSvc.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内", Fields: []string{"municipality"}}).
+		Return(&models.GeocodeResult{Results: []models.Location{location}, Strategy: "fulltext", NextCursor: "abc"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&fields=municipality", nil)
+	c.Request.Header.Set("Accept", "application/vnd.api+json")
+
+	handler.GeoCode(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"data":[{"type":"locations","id":"1","attributes":{"municipality":"千代田区"}}],"meta":{"next_cursor":"abc","strategy":"fulltext"}}`, w.Body.String())
+	mockSvc.AssertExpectations(t)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocationHandler handles lookups of locations by ID
+type LocationHandler struct {
+	service LocationService
+}
+
+// LocationService interface for dependency injection
+type LocationService interface {
+	GetLocationsByIDs(context.Context, []int) ([]models.Location, error)
+	GetLocationsInArea(context.Context, models.SearchOptions) ([]models.Location, error)
+}
+
+// NewLocationHandler creates a new location handler
+func NewLocationHandler(svc LocationService) *LocationHandler {
+	return &LocationHandler{service: svc}
+}
+
+// GetLocations godoc
+// @Summary Get locations by IDs
+// @Description Fetch several locations at once by their IDs, returned in request order
+// @Tags locations
+// @Accept json
+// @Produce json,application/vnd.api+json
+// @Param ids query string true "Comma-separated location IDs (max 100)"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default) or jsonapi, a JSON:API document of locations resources"
+// @Success 200 {array} models.Location
+// @Failure 400 {object} map[string]string "error":"missing required query parameter 'ids'" or "invalid id" or "too many ids" or "invalid coords_as_string value"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /locations [get]
+func (h *LocationHandler) GetLocations(c *gin.Context) {
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatJSONAPI)
+	if !ok {
+		return
+	}
+
+	idsStr := c.Query("ids")
+	if idsStr == "" {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingIDs)
+		return
+	}
+
+	parts := strings.Split(idsStr, ",")
+	if len(parts) > service.MaxLocationIDs {
+		respondError(c, http.StatusBadRequest, i18n.MsgTooManyIDs, service.MaxLocationIDs)
+		return
+	}
+
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidID, part)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	locations, err := h.service.GetLocationsByIDs(c.Request.Context(), ids)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if outputFormat == formatJSONAPI {
+		respondJSONAPI(c, locations, format, nil)
+		return
+	}
+	respondLocations(c, locations, format)
+}
+
+// GetLocationsInArea godoc
+// @Summary Browse locations in an administrative area
+// @Description List the addresses in a prefecture and/or municipality, matched exactly rather than by free-text search
+// @Tags locations
+// @Accept json
+// @Produce json,application/vnd.api+json
+// @Param prefecture query string false "Prefecture, e.g. 東京都"
+// @Param municipality query string false "Municipality, e.g. 渋谷区"
+// @Param limit query int false "Maximum number of results (default 10); values above 100 are clamped to 100"
+// @Param offset query int false "Number of results to skip"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default) or jsonapi, a JSON:API document of locations resources"
+// @Success 200 {array} models.Location
+// @Failure 400 {object} map[string]string "error":"at least one of 'prefecture' or 'municipality' is required" or "invalid limit" or "invalid offset" or "invalid coords_as_string value"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /locations/in [get]
+func (h *LocationHandler) GetLocationsInArea(c *gin.Context) {
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatJSONAPI)
+	if !ok {
+		return
+	}
+
+	opts := models.SearchOptions{
+		Prefecture:   strings.TrimSpace(c.Query("prefecture")),
+		Municipality: strings.TrimSpace(c.Query("municipality")),
+	}
+	if opts.Prefecture == "" && opts.Municipality == "" {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingArea)
+		return
+	}
+
+	if !bindPagination(c, &opts, models.MaxSearchLimit) {
+		return
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	locations, err := h.service.GetLocationsInArea(c.Request.Context(), opts)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	if outputFormat == formatJSONAPI {
+		respondJSONAPI(c, locations, format, nil)
+		return
+	}
+	respondLocations(c, locations, format)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLocationService is a mock implementation of the LocationService interface
+type MockLocationService struct {
+	mock.Mock
+}
+
+func (m *MockLocationService) GetLocationsByIDs(ctx context.Context, ids []int) ([]models.Location, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+func (m *MockLocationService) GetLocationsInArea(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+func TestLocationHandler_GetLocations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := strings.Repeat("1,", 100) + "1"
+
+	tests := []struct {
+		name           string
+		ids            string
+		expectedIDs    []int
+		mockLocations  []models.Location
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "missing ids parameter",
+			ids:            "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameter 'ids'"},
+		},
+		{
+			name:           "non-integer id",
+			ids:            "1,abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": `invalid id: "abc"`},
+		},
+		{
+			name:           "too many ids",
+			ids:            tooMany,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "too many ids (max 100)"},
+		},
+		{
+			name:        "successful lookup in request order",
+			ids:         "2, 1",
+			expectedIDs: []int{2, 1},
+			mockLocations: []models.Location{
+				{ID: 2, Prefecture: "東京都", Municipality: "港区", Address1: "赤坂"},
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []models.Location{
+				{ID: 2, Prefecture: "東京都", Municipality: "港区", Address1: "赤坂"},
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+			},
+		},
+		{
+			name:           "service error",
+			ids:            "1",
+			expectedIDs:    []int{1},
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockLocationService)
+			handler := NewLocationHandler(mockSvc)
+
+			if tt.expectedIDs != nil {
+				mockSvc.On("GetLocationsByIDs", mock.Anything, tt.expectedIDs).Return(tt.mockLocations, tt.mockError)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/locations", nil)
+			if tt.ids != "" {
+				q := req.URL.Query()
+				q.Add("ids", tt.ids)
+				req.URL.RawQuery = q.Encode()
+			}
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GetLocations(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLocationHandler_GetLocationsInArea(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedOpts   *models.SearchOptions
+		mockLocations  []models.Location
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "missing area",
+			query:          "limit=5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "at least one of 'prefecture' or 'municipality' is required"},
+		},
+		{
+			name:           "limit clamped to maximum",
+			query:          "municipality=渋谷区&limit=500",
+			expectedOpts:   &models.SearchOptions{Municipality: "渋谷区", Limit: models.MaxSearchLimit},
+			mockLocations:  []models.Location{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "zero limit uses default",
+			query:          "municipality=渋谷区&limit=0",
+			expectedOpts:   &models.SearchOptions{Municipality: "渋谷区"},
+			mockLocations:  []models.Location{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []models.Location{},
+		},
+		{
+			name:           "invalid limit",
+			query:          "municipality=渋谷区&limit=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid limit: must be a non-negative integer; 0 uses the default and values above 100 are clamped to it"},
+		},
+		{
+			name:           "invalid offset",
+			query:          "municipality=渋谷区&offset=-5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid offset: must be a non-negative integer"},
+		},
+		{
+			name:         "municipality with pagination",
+			query:        "prefecture=東京都&municipality=渋谷区&limit=2&offset=4",
+			expectedOpts: &models.SearchOptions{Prefecture: "東京都", Municipality: "渋谷区", Limit: 2, Offset: 4},
+			mockLocations: []models.Location{
+				{ID: 5, Prefecture: "東京都", Municipality: "渋谷区", Address1: "道玄坂"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: []models.Location{
+				{ID: 5, Prefecture: "東京都", Municipality: "渋谷区", Address1: "道玄坂"},
+			},
+		},
+		{
+			name:           "service error",
+			query:          "prefecture=東京都",
+			expectedOpts:   &models.SearchOptions{Prefecture: "東京都"},
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockLocationService)
+			handler := NewLocationHandler(mockSvc)
+
+			if tt.expectedOpts != nil {
+				mockSvc.On("GetLocationsInArea", mock.Anything, *tt.expectedOpts).Return(tt.mockLocations, tt.mockError)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/locations/in?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.GetLocationsInArea(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondNDJSON writes locations as a 200 newline-delimited JSON response, one location per
+// line, with the output options of the plain response applied to each
+func respondNDJSON(c *gin.Context, locations []models.Location, format responseFormat) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, loc := range locations {
+		line, err := format.apply(loc)
+		if err == nil {
+			err = encoder.Encode(line)
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, i18n.MsgInternalError)
+			return
+		}
+	}
+	c.Data(http.StatusOK, formatMediaTypes[formatNDJSON], buf.Bytes())
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGeoCodeHandler_Geocode_NDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	locations := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", Latitude: 35.681236, Longitude: 139.767125},
+		{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内2", Latitude: 35.6795, Longitude: 139.7645},
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		accept       string
+		mockOpts     models.SearchOptions
+		mockResults  []models.Location
+		expectedBody string
+	}{
+		{
+			name:        "one location per line",
+			url:         "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85",
+			accept:      "application/x-ndjson",
+			mockOpts:    models.SearchOptions{Query: "丸の内"},
+			mockResults: locations,
+			expectedBody: `{"id":1,"prefecture":"東京都","municipality":"千代田区","address1":"丸の内1","address2":"","block_lot":"","latitude":35.681236,"longitude":139.767125}
+{"id":2,"prefecture":"東京都","municipality":"千代田区","address1":"丸の内2","address2":"","block_lot":"","latitude":35.6795,"longitude":139.7645}
+`,
+		},
+		{
+			name:        "selected fields and string coordinates",
+			url:         "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&format=ndjson&fields=id,latitude&coords_as_string=true",
+			mockOpts:    models.SearchOptions{Query: "丸の内", Fields: []string{"id", "latitude"}},
+			mockResults: locations[:1],
+			expectedBody: `{"id":1,"latitude":"35.6812360"}
+`,
+		},
+		{
+			name:         "no results",
+			url:          "/geocode?q=%E4%B8%B8%E3%81%AE%E5%86%85&format=ndjson",
+			mockOpts:     models.SearchOptions{Query: "丸の内"},
+			mockResults:  []models.Location{},
+			expectedBody: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockGeoCodeService)
+			handler := NewGeoCodeHandler(mockSvc, GeoCodeConfig{})
+			mockSvc.On("Geocode", mock.Anything, tt.mockOpts).Return(&models.GeocodeResult{Results: tt.mockResults}, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			handler.GeoCode(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
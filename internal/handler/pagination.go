@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindPagination parses the optional limit and offset query parameters into opts, clamping the
+// limit to max (see bindLimit). On invalid input it writes a 400 response and returns false.
+func bindPagination(c *gin.Context, opts *models.SearchOptions, max int) bool {
+	return bindLimit(c, &opts.Limit, max) && bindOffset(c, opts)
+}
+
+// bindOffset parses the optional offset query parameter into opts. On a negative or non-numeric
+// offset it writes a 400 response and returns false.
+func bindOffset(c *gin.Context, opts *models.SearchOptions) bool {
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidOffset)
+			return false
+		}
+		opts.Offset = offset
+	}
+
+	return true
+}
+
+// bindLimit parses the optional limit query parameter into limit, which keeps its value when the
+// parameter is absent or 0. A limit above max is clamped to it. On a negative or non-numeric
+// limit it writes a 400 response and returns false.
+func bindLimit(c *gin.Context, limit *int, max int) bool {
+	limitStr := c.Query("limit")
+	if limitStr == "" {
+		return true
+	}
+
+	n, err := strconv.Atoi(limitStr)
+	if err != nil || n < 0 {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidLimit, max)
+		return false
+	}
+	if n > 0 {
+		*limit = min(n, max)
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// QuotaService interface for dependency injection
+type QuotaService interface {
+	Consume(ctx context.Context, key string) (*models.QuotaUsage, error)
+}
+
+// APIKeyQuota requires every request to carry a configured API key in X-API-Key and counts it
+// against the key's quota. A limited key's standing is reported in X-Quota-Limit,
+// X-Quota-Remaining and X-Quota-Reset (Unix seconds); once the quota is exceeded requests are
+// answered with a 429 and Retry-After until it resets. When the usage can't be counted the
+// request is let through rather than failing with the quota store.
+func APIKeyQuota(quotas QuotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			respondError(c, http.StatusUnauthorized, i18n.MsgInvalidAPIKey)
+			c.Abort()
+			return
+		}
+
+		usage, err := quotas.Consume(c.Request.Context(), key)
+		if errors.Is(err, service.ErrUnknownAPIKey) {
+			respondError(c, http.StatusUnauthorized, i18n.MsgInvalidAPIKey)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			zerolog.Ctx(c.Request.Context()).Error().Err(err).Msg("cannot count api key usage, not enforcing its quota")
+			c.Next()
+			return
+		}
+
+		if usage.Limit > 0 {
+			c.Header("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(usage.Remaining, 10))
+			c.Header("X-Quota-Reset", strconv.FormatInt(usage.Reset.Unix(), 10))
+		}
+		if usage.Exceeded {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(usage.Reset).Seconds())+1))
+			respondError(c, http.StatusTooManyRequests, i18n.MsgQuotaExceeded, usage.Reset.Format(time.RFC3339))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQuotaService is a mock implementation of the QuotaService interface
+type MockQuotaService struct {
+	mock.Mock
+}
+
+func (m *MockQuotaService) Consume(ctx context.Context, key string) (*models.QuotaUsage, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.QuotaUsage), args.Error(1)
+}
+
+func TestAPIKeyQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name              string
+		key               string
+		mockUsage         *models.QuotaUsage
+		mockError         error
+		expectedStatus    int
+		expectedBody      string
+		expectedRemaining string
+		expectRetryAfter  bool
+	}{
+		{
+			name:              "within quota",
+			key:               "partner-key",
+			mockUsage:         &models.QuotaUsage{Limit: 100, Remaining: 99, Reset: reset},
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `{"status":"ok"}`,
+			expectedRemaining: "99",
+		},
+		{
+			name:              "last request of the quota",
+			key:               "partner-key",
+			mockUsage:         &models.QuotaUsage{Limit: 100, Remaining: 0, Reset: reset},
+			expectedStatus:    http.StatusOK,
+			expectedBody:      `{"status":"ok"}`,
+			expectedRemaining: "0",
+		},
+		{
+			name:              "quota exhausted",
+			key:               "partner-key",
+			mockUsage:         &models.QuotaUsage{Limit: 100, Remaining: 0, Reset: reset, Exceeded: true},
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedBody:      `{"error":"request quota exceeded; it resets at ` + reset.Format(time.RFC3339) + `"}`,
+			expectedRemaining: "0",
+			expectRetryAfter:  true,
+		},
+		{
+			name:           "unlimited key",
+			key:            "partner-key",
+			mockUsage:      &models.QuotaUsage{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok"}`,
+		},
+		{
+			name:           "missing key",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a valid API key is required in the X-API-Key header"}`,
+		},
+		{
+			name:           "unknown key",
+			key:            "guess",
+			mockError:      service.ErrUnknownAPIKey,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a valid API key is required in the X-API-Key header"}`,
+		},
+		{
+			name:           "usage store failing lets the request through",
+			key:            "partner-key",
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockQuotaService)
+			if tt.key != "" {
+				mockSvc.On("Consume", mock.Anything, tt.key).Return(tt.mockUsage, tt.mockError)
+			}
+
+			r := gin.New()
+			r.GET("/geocode", APIKeyQuota(mockSvc), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/geocode", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, tt.expectedRemaining, w.Header().Get("X-Quota-Remaining"))
+			if tt.expectedRemaining != "" {
+				assert.Equal(t, "100", w.Header().Get("X-Quota-Limit"))
+				assert.Equal(t, strconv.FormatInt(reset.Unix(), 10), w.Header().Get("X-Quota-Reset"))
+			}
+			assert.Equal(t, tt.expectRetryAfter, w.Header().Get("Retry-After") != "")
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// requestIDHeader carries the correlation ID on requests and responses
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits caller-supplied request IDs to short, log-safe tokens
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID propagates the caller's X-Request-ID, or generates one, echoes it on the response and
+// attaches a logger carrying it to the request context so downstream logs can be correlated
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Header(requestIDHeader, id)
+
+		logger := log.With().Str("request_id", id).Logger()
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "propagates caller id", incoming: "abc-123", keep: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "replaces unsafe id", incoming: "bad id\nwith newline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxLogger *zerolog.Logger
+
+			r := gin.New()
+			r.Use(RequestID())
+			r.GET("/ping", func(c *gin.Context) {
+				ctxLogger = zerolog.Ctx(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get("X-Request-ID")
+			if tt.keep {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.Len(t, id, 16)
+			}
+			assert.NotEqual(t, zerolog.Disabled, ctxLogger.GetLevel())
+		})
+	}
+}
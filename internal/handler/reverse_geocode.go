@@ -2,10 +2,14 @@ package handler
 
 import (
 	"context"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"geocoding-api/internal/i18n"
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,9 +21,22 @@ type ReverseGeocodeHandler struct {
 
 // Service interface for dependency injection
 type GeoCodingService interface {
-	ReverseGeocode(context.Context, float64, float64) (*models.Location, error)
+	ReverseGeocode(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error)
+	ReverseGeocodeWithContext(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int) (*models.ReverseGeocodeResult, error)
+	ReverseGeocodeColocated(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.ColocatedLocation, error)
+	ReverseGeocodeAdmin(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error)
+	ReverseGeocodeSorted(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int, pref models.SortPreference) (*models.ReverseGeocodeResult, error)
+	ReverseGeocodeNearest(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error)
 }
 
+// Values of the reverse geocode prefer parameter
+const (
+	// preferNearest returns the nearest address, the default
+	preferNearest = "nearest"
+	// preferAdmin returns the nearest address in the municipality containing the point
+	preferAdmin = "admin"
+)
+
 // NewReverseGeocodeHandler creates a new reverse geocode handler
 func NewReverseGeocodeHandler(svc GeoCodingService) *ReverseGeocodeHandler {
 	return &ReverseGeocodeHandler{service: svc}
@@ -30,45 +47,241 @@ func NewReverseGeocodeHandler(svc GeoCodingService) *ReverseGeocodeHandler {
 // @Description Convert geographic coordinates to an address
 // @Tags geocoding
 // @Accept json
-// @Produce json
+// @Produce json,application/geo+json
 // @Param lat query number true "Latitude"
 // @Param lon query number true "Longitude"
-// @Success 200 {object} models.Location
-// @Failure 400 {object} map[string]string "error":"missing required query parameters 'lat' and 'lon'" or "invalid latitude format" or "invalid longitude format"
+// @Param radius query number false "Search radius in meters (default and maximum: MAX_SPATIAL_RADIUS_METERS, 10000 unless configured)"
+// @Param source query string false "Only return addresses from this dataset, as reported in source"
+// @Param exclude query string false "Comma-separated location IDs to skip, e.g. to get the next nearest address when the nearest was wrong (max 100)"
+// @Param context query int false "Also return up to N further nearby addresses with distances (max 10)"
+// @Param limit query int false "Return up to this many nearest addresses (max 100) as an array ordered by distance, each with distance_meters; 1 (default) returns the single nearest address as an object. Above 1 it cannot be combined with context, hierarchy, include_colocated, prefer=admin or sort"
+// @Param hierarchy query bool false "Nest the address components by administrative level (prefecture → municipality → district); cannot be combined with context"
+// @Param prefer query string false "nearest (default) returns the nearest address; admin returns the nearest one in the municipality whose boundary contains the point, falling back to the nearest when no boundary contains it or that municipality has no address in range. admin needs municipality boundary data (MUNICIPALITY_BOUNDARIES) and cannot be combined with context or include_colocated"
+// @Param sort query string false "Sort preference breaking ties among addresses within a meter or so (REVERSE_TIE_METERS) of the nearest: distance (default) keeps the distance order; block_lot prefers addresses with a block/lot number; municipality prefers addresses in the municipality parameter. Cannot be combined with prefer=admin or include_colocated"
+// @Param municipality query string false "Municipality preferred by sort=municipality, e.g. 千代田区"
+// @Param include_colocated query bool false "Also return as colocated the addresses within a few meters of the nearest one, such as the other units of its building (max 100); cannot be combined with context or hierarchy"
+// @Param coords_as_string query bool false "Write latitude/longitude as strings with 7 fixed decimals, for clients whose JSON parsers lose precision"
+// @Param format query string false "Response format, taking priority over the Accept header: json (default) or geojson, a GeoJSON FeatureCollection of Point features ([lon, lat]) with the other fields as properties; it lists the nearest address first, then any context or colocated addresses, and ignores hierarchy"
+// @Success 200 {object} models.Location "with distance_meters, its distance from the point"
+// @Success 200 {array} models.Location "when limit > 1"
+// @Success 200 {object} models.ReverseGeocodeResult "when context > 0"
+// @Success 200 {object} models.LocationHierarchy "when hierarchy=true"
+// @Success 200 {object} models.ColocatedLocation "when include_colocated=true"
+// @Failure 400 {object} map[string]string "error":"missing required query parameters 'lat' and 'lon'" or "invalid latitude format" or "invalid longitude format" or "invalid radius" or "invalid id" or "too many excluded ids" or "invalid context" or "invalid limit" or "invalid hierarchy value" or "invalid include_colocated value" or "invalid prefer value" or "invalid sort value" or "prefer=admin is not available" or "invalid coords_as_string value"
 // @Failure 404 {object} map[string]string "error":"no address found near the specified coordinates"
 // @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
 // @Router /reverse-geocode [get]
 func (h *ReverseGeocodeHandler) ReverseGeocode(c *gin.Context) {
+	outputFormat, ok := negotiateFormat(c, formatJSON, formatGeoJSON)
+	if !ok {
+		return
+	}
+
 	latStr := c.Query("lat")
 	lonStr := c.Query("lon")
 
 	if latStr == "" || lonStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required query parameters 'lat' and 'lon'"})
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingCoordinates)
 		return
 	}
 
 	lat, err := strconv.ParseFloat(latStr, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid latitude format"})
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidLatitude)
 		return
 	}
 
 	lon, err := strconv.ParseFloat(lonStr, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid longitude format"})
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidLongitude)
+		return
+	}
+
+	contextSize := 0
+	if contextStr := c.Query("context"); contextStr != "" {
+		contextSize, err = strconv.Atoi(contextStr)
+		if err != nil || contextSize < 0 || contextSize > service.MaxContextLocations {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidContext, service.MaxContextLocations)
+			return
+		}
+	}
+
+	hierarchy := false
+	if hierarchyStr := c.Query("hierarchy"); hierarchyStr != "" {
+		hierarchy, err = strconv.ParseBool(hierarchyStr)
+		if err != nil || (hierarchy && contextSize > 0) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidHierarchy)
+			return
+		}
+	}
+
+	colocated := false
+	if colocatedStr := c.Query("include_colocated"); colocatedStr != "" {
+		colocated, err = strconv.ParseBool(colocatedStr)
+		if err != nil || (colocated && (contextSize > 0 || hierarchy)) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidColocated)
+			return
+		}
+	}
+
+	prefer := c.DefaultQuery("prefer", preferNearest)
+	if (prefer != preferNearest && prefer != preferAdmin) || (prefer == preferAdmin && (contextSize > 0 || colocated)) {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidPrefer)
+		return
+	}
+
+	pref := models.SortPreference{Key: c.Query("sort"), Municipality: c.Query("municipality")}
+	sorted := pref.Key != "" && pref.Key != models.SortByDistance
+	if sorted && (prefer == preferAdmin || colocated) {
+		respondError(c, http.StatusBadRequest, i18n.MsgInvalidSort, strings.Join(models.SortPreferenceKeys, ", "))
+		return
+	}
+
+	limit := 1
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > models.MaxSearchLimit ||
+			(limit > 1 && (contextSize > 0 || hierarchy || colocated || prefer == preferAdmin || sorted)) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidNearest, models.MaxSearchLimit)
+			return
+		}
+	}
+
+	var exclude []int
+	if excludeStr := c.Query("exclude"); excludeStr != "" {
+		parts := strings.Split(excludeStr, ",")
+		if len(parts) > service.MaxExcludedIDs {
+			respondError(c, http.StatusBadRequest, i18n.MsgTooManyExcluded, service.MaxExcludedIDs)
+			return
+		}
+		for _, part := range parts {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				respondError(c, http.StatusBadRequest, i18n.MsgInvalidID, part)
+				return
+			}
+			exclude = append(exclude, id)
+		}
+	}
+
+	var format responseFormat
+	if !bindCoordsAsString(c, &format.coordsAsString) {
+		return
+	}
+
+	// 0 searches the service's maximum radius, which also bounds an explicit one
+	radius := 0.0
+	if radiusStr := c.Query("radius"); radiusStr != "" {
+		radius, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+			respondError(c, http.StatusBadRequest, i18n.MsgInvalidRadius)
+			return
+		}
+	}
+	source := c.Query("source")
+
+	if contextSize > 0 {
+		var result *models.ReverseGeocodeResult
+		if sorted {
+			result, err = h.service.ReverseGeocodeSorted(c.Request.Context(), lat, lon, radius, source, exclude, contextSize, pref)
+		} else {
+			result, err = h.service.ReverseGeocodeWithContext(c.Request.Context(), lat, lon, radius, source, exclude, contextSize)
+		}
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		if result == nil {
+			respondError(c, http.StatusNotFound, i18n.MsgNoAddressFound)
+			return
+		}
+
+		if outputFormat == formatGeoJSON {
+			respondGeoJSON(c, append([]models.Location{result.Location}, result.Context...), format)
+			return
+		}
+
+		respondLocations(c, result, format)
+		return
+	}
+
+	if limit > 1 {
+		locations, err := h.service.ReverseGeocodeNearest(c.Request.Context(), lat, lon, radius, source, exclude, limit)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		if len(locations) == 0 {
+			respondError(c, http.StatusNotFound, i18n.MsgNoAddressFound)
+			return
+		}
+
+		if outputFormat == formatGeoJSON {
+			respondGeoJSON(c, locations, format)
+			return
+		}
+
+		respondLocations(c, locations, format)
+		return
+	}
+
+	if colocated {
+		result, err := h.service.ReverseGeocodeColocated(c.Request.Context(), lat, lon, radius, source, exclude)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		if result == nil {
+			respondError(c, http.StatusNotFound, i18n.MsgNoAddressFound)
+			return
+		}
+
+		if outputFormat == formatGeoJSON {
+			respondGeoJSON(c, append([]models.Location{result.Location}, result.Colocated...), format)
+			return
+		}
+
+		respondLocations(c, result, format)
 		return
 	}
 
-	location, err := h.service.ReverseGeocode(c.Request.Context(), lat, lon)
+	reverseGeocode := h.service.ReverseGeocode
+	if prefer == preferAdmin {
+		reverseGeocode = h.service.ReverseGeocodeAdmin
+	}
+	if sorted {
+		reverseGeocode = func(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+			result, err := h.service.ReverseGeocodeSorted(ctx, lat, lon, radius, source, exclude, 0, pref)
+			if err != nil || result == nil {
+				return nil, err
+			}
+			return &result.Location, nil
+		}
+	}
+	location, err := reverseGeocode(c.Request.Context(), lat, lon, radius, source, exclude)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		respondServiceError(c, err)
 		return
 	}
 
 	if location == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "no address found near the specified coordinates"})
+		respondError(c, http.StatusNotFound, i18n.MsgNoAddressFound)
+		return
+	}
+
+	if outputFormat == formatGeoJSON {
+		respondGeoJSON(c, []models.Location{*location}, format)
+		return
+	}
+
+	if hierarchy {
+		respondLocations(c, location.Hierarchy(), format)
 		return
 	}
 
-	c.JSON(http.StatusOK, location)
+	respondLocations(c, location, format)
 }
@@ -3,12 +3,16 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,11 +24,36 @@ type MockReverseGeoCodeService struct {
 	mock.Mock
 }
 
-func (m *MockReverseGeoCodeService) ReverseGeocode(ctx context.Context, lat float64, lon float64) (*models.Location, error) {
-	args := m.Called(ctx, lat, lon)
+func (m *MockReverseGeoCodeService) ReverseGeocode(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
 	return args.Get(0).(*models.Location), args.Error(1)
 }
 
+func (m *MockReverseGeoCodeService) ReverseGeocodeWithContext(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int) (*models.ReverseGeocodeResult, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude, n)
+	return args.Get(0).(*models.ReverseGeocodeResult), args.Error(1)
+}
+
+func (m *MockReverseGeoCodeService) ReverseGeocodeColocated(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.ColocatedLocation, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
+	return args.Get(0).(*models.ColocatedLocation), args.Error(1)
+}
+
+func (m *MockReverseGeoCodeService) ReverseGeocodeAdmin(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
+	return args.Get(0).(*models.Location), args.Error(1)
+}
+
+func (m *MockReverseGeoCodeService) ReverseGeocodeSorted(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int, pref models.SortPreference) (*models.ReverseGeocodeResult, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude, n, pref)
+	return args.Get(0).(*models.ReverseGeocodeResult), args.Error(1)
+}
+
+func (m *MockReverseGeoCodeService) ReverseGeocodeNearest(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
 func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -42,7 +71,7 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 			lat:            0,
 			lon:            0,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   gin.H{"error": "missing required query parameter 'q'"},
+			expectedBody:   gin.H{"error": "missing required query parameters 'lat' and 'lon'"},
 		},
 		{
 			name: "successful geocoding with results",
@@ -55,16 +84,20 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 				Address1:     "丸の内",
 				Latitude:     35.681236,
 				Longitude:    139.767125,
+				Distance:     4.2,
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
-			expectedBody: models.Location{
-				ID:           1,
-				Prefecture:   "東京都",
-				Municipality: "千代田区",
-				Address1:     "丸の内",
-				Latitude:     35.681236,
-				Longitude:    139.767125,
+			expectedBody: gin.H{
+				"id":              1,
+				"prefecture":      "東京都",
+				"municipality":    "千代田区",
+				"address1":        "丸の内",
+				"address2":        "",
+				"block_lot":       "",
+				"latitude":        35.681236,
+				"longitude":       139.767125,
+				"distance_meters": 4.2,
 			},
 		},
 		{
@@ -76,6 +109,24 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
 		},
+		{
+			name:           "coordinates out of range",
+			lat:            95,
+			lon:            139.767125,
+			mockLocation:   nil,
+			mockError:      fmt.Errorf("%w: latitude 95", service.ErrInvalidCoordinates),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "coordinates out of range: latitude must be within ±90 and longitude within ±180"},
+		},
+		{
+			name:           "radius above the maximum",
+			lat:            35.681236,
+			lon:            139.767125,
+			mockLocation:   nil,
+			mockError:      fmt.Errorf("%w: 20000 must be between 0 and 10000 meters", service.ErrInvalidRadius),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
 		{
 			name:           "service error",
 			lat:            35.681236,
@@ -94,7 +145,7 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 			handler := NewReverseGeocodeHandler(mockSvc)
 
 			if tt.lat != 0 && tt.lon != 0 {
-				mockSvc.On("ReverseGeocode", mock.Anything, tt.lat, tt.lon).Return(tt.mockLocation, tt.mockError)
+				mockSvc.On("ReverseGeocode", mock.Anything, tt.lat, tt.lon, 0.0, "", []int(nil)).Return(tt.mockLocation, tt.mockError)
 			}
 
 			// Create request
@@ -117,10 +168,9 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 			// Assert
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
-			var actualBody interface{}
-			err := json.Unmarshal(w.Body.Bytes(), &actualBody)
+			expectedBody, err := json.Marshal(tt.expectedBody)
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expectedBody, actualBody)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
 
 			if tt.lat != 0 && tt.lon != 0 {
 				mockSvc.AssertExpectations(t)
@@ -128,3 +178,715 @@ func TestReverseGeoCodeHandler_ReverseGeocode(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Context(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	result := &models.ReverseGeocodeResult{
+		Location: models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Distance: 3.2},
+		Context: []models.Location{
+			{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "大手町", Distance: 120.5},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		context        string
+		expectedN      int
+		mockResult     *models.ReverseGeocodeResult
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "invalid context",
+			context:        "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid context: must be between 0 and 10"},
+		},
+		{
+			name:           "context too large",
+			context:        "11",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid context: must be between 0 and 10"},
+		},
+		{
+			name:           "primary with context",
+			context:        "3",
+			expectedN:      3,
+			mockResult:     result,
+			expectedStatus: http.StatusOK,
+			expectedBody:   result,
+		},
+		{
+			name:           "nothing nearby",
+			context:        "3",
+			expectedN:      3,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			if tt.expectedN > 0 {
+				mockSvc.On("ReverseGeocodeWithContext", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil), tt.expectedN).Return(tt.mockResult, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125&context="+tt.context, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Hierarchy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := &models.Location{
+		ID:           1,
+		Prefecture:   "東京都",
+		Municipality: "千代田区",
+		Address1:     "丸の内一丁目",
+		BlockLot:     "9",
+		Latitude:     35.681236,
+		Longitude:    139.767125,
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		callService    bool
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "nested hierarchy",
+			query:          "&hierarchy=true",
+			callService:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"id":        1,
+				"latitude":  35.681236,
+				"longitude": 139.767125,
+				"prefecture": gin.H{
+					"name": "東京都",
+					"municipality": gin.H{
+						"name": "千代田区",
+						"district": gin.H{
+							"name":      "丸の内一丁目",
+							"block_lot": "9",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "flat by default",
+			query:          "&hierarchy=false",
+			callService:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   location,
+		},
+		{
+			name:           "invalid hierarchy",
+			query:          "&hierarchy=maybe",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid hierarchy value: must be true or false, and cannot be combined with context"},
+		},
+		{
+			name:           "combined with context",
+			query:          "&hierarchy=true&context=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid hierarchy value: must be true or false, and cannot be combined with context"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			if tt.callService {
+				mockSvc.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil)).Return(location, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Colocated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	result := &models.ColocatedLocation{
+		Location: models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "9-101", Latitude: 35.681236, Longitude: 139.767125},
+		Colocated: []models.Location{
+			{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "9-102", Latitude: 35.681251, Longitude: 139.767141},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockResult     *models.ColocatedLocation
+		callService    bool
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "nearest address with its units",
+			query:          "&include_colocated=true",
+			mockResult:     result,
+			callService:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   result,
+		},
+		{
+			name:           "nothing nearby",
+			query:          "&include_colocated=true",
+			mockResult:     nil,
+			callService:    true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
+		},
+		{
+			name:           "invalid include_colocated",
+			query:          "&include_colocated=some",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid include_colocated value: must be true or false, and cannot be combined with context or hierarchy"},
+		},
+		{
+			name:           "combined with hierarchy",
+			query:          "&include_colocated=true&hierarchy=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid include_colocated value: must be true or false, and cannot be combined with context or hierarchy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			if tt.callService {
+				mockSvc.On("ReverseGeocodeColocated", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil)).Return(tt.mockResult, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.ReverseGeocode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			mockSvc.AssertExpectations(t)
+			if !tt.callService {
+				mockSvc.AssertNotCalled(t, "ReverseGeocode", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Prefer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "9", Latitude: 35.681236, Longitude: 139.767125}
+	invalidPrefer := gin.H{"error": "invalid prefer value: must be nearest or admin, and admin cannot be combined with context or include_colocated"}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedMethod string
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "nearest by default",
+			expectedMethod: "ReverseGeocode",
+			expectedStatus: http.StatusOK,
+			expectedBody:   location,
+		},
+		{
+			name:           "administrative accuracy",
+			query:          "&prefer=admin",
+			expectedMethod: "ReverseGeocodeAdmin",
+			expectedStatus: http.StatusOK,
+			expectedBody:   location,
+		},
+		{
+			name:           "boundaries not loaded",
+			query:          "&prefer=admin",
+			expectedMethod: "ReverseGeocodeAdmin",
+			mockError:      service.ErrBoundariesUnavailable,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "prefer=admin is not available: no municipality boundaries are loaded"},
+		},
+		{
+			name:           "unknown preference",
+			query:          "&prefer=closest",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidPrefer,
+		},
+		{
+			name:           "admin with context",
+			query:          "&prefer=admin&context=3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidPrefer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			if tt.expectedMethod != "" {
+				result := location
+				if tt.mockError != nil {
+					result = nil
+				}
+				mockSvc.On(tt.expectedMethod, mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil)).Return(result, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.ReverseGeocode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Sort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nearest := models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "9", Latitude: 35.681236, Longitude: 139.767125, Distance: 3.5}
+	result := &models.ReverseGeocodeResult{
+		Location: nearest,
+		Context:  []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", Distance: 3.2}},
+	}
+	invalidSort := gin.H{"error": "invalid sort value (available: distance, block_lot, municipality); municipality needs the municipality parameter, and sort cannot be combined with prefer=admin or include_colocated"}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedN      int
+		expectedPref   models.SortPreference
+		mockResult     *models.ReverseGeocodeResult
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "prefer a block/lot number",
+			query:          "&sort=block_lot",
+			expectedPref:   models.SortPreference{Key: models.SortByBlockLot},
+			mockResult:     result,
+			expectedStatus: http.StatusOK,
+			expectedBody:   nearest,
+		},
+		{
+			name:           "prefer a municipality with context",
+			query:          "&sort=municipality&municipality=千代田区&context=1",
+			expectedN:      1,
+			expectedPref:   models.SortPreference{Key: models.SortByMunicipality, Municipality: "千代田区"},
+			mockResult:     result,
+			expectedStatus: http.StatusOK,
+			expectedBody:   result,
+		},
+		{
+			name:           "nothing in range",
+			query:          "&sort=block_lot",
+			expectedPref:   models.SortPreference{Key: models.SortByBlockLot},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
+		},
+		{
+			name:           "municipality without a municipality",
+			query:          "&sort=municipality",
+			expectedPref:   models.SortPreference{Key: models.SortByMunicipality},
+			mockError:      service.ErrInvalidSort,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidSort,
+		},
+		{
+			name:           "sort with prefer=admin",
+			query:          "&sort=block_lot&prefer=admin",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidSort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			if tt.expectedPref.Key != "" {
+				mockSvc.On("ReverseGeocodeSorted", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil), tt.expectedN, tt.expectedPref).Return(tt.mockResult, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125", nil)
+			q := req.URL.Query()
+			for _, param := range strings.Split(strings.TrimPrefix(tt.query, "&"), "&") {
+				name, value, _ := strings.Cut(param, "=")
+				q.Add(name, value)
+			}
+			req.URL.RawQuery = q.Encode()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler.ReverseGeocode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Source(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := &models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125, Source: "data/trusted.csv"}
+	nearby := &models.ReverseGeocodeResult{Location: *location, Context: []models.Location{}}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody interface{}
+	}{
+		{name: "nearest from source", query: "&source=data/trusted.csv", expectedBody: location},
+		{name: "context from source", query: "&source=data/trusted.csv&context=2", expectedBody: nearby},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+
+			mockSvc.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, 0.0, "data/trusted.csv", []int(nil)).Return(location, nil).Maybe()
+			mockSvc.On("ReverseGeocodeWithContext", mock.Anything, 35.681236, 139.767125, 0.0, "data/trusted.csv", []int(nil), 2).Return(nearby, nil).Maybe()
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+			assert.Contains(t, w.Body.String(), `"source":"data/trusted.csv"`)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Exclude(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := &models.Location{ID: 3, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.6813, Longitude: 139.7672}
+	tooMany := strings.TrimSuffix(strings.Repeat("1,", 101), ",")
+
+	tests := []struct {
+		name            string
+		exclude         string
+		expectedExclude []int
+		expectedStatus  int
+		expectedBody    interface{}
+	}{
+		{
+			name:            "skips excluded ids",
+			exclude:         "1, 2",
+			expectedExclude: []int{1, 2},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    location,
+		},
+		{
+			name:           "invalid id",
+			exclude:        "1,two",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": `invalid id: "two"`},
+		},
+		{
+			name:           "too many ids",
+			exclude:        tooMany,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "too many excluded ids (max 100)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+			if tt.expectedExclude != nil {
+				mockSvc.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, 0.0, "", tt.expectedExclude).Return(location, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125&exclude="+url.QueryEscape(tt.exclude), nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Radius(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	location := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125}
+
+	tests := []struct {
+		name           string
+		radius         string
+		expectedRadius float64
+		mockLocation   *models.Location
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "within range",
+			radius:         "300",
+			expectedRadius: 300,
+			mockLocation:   location,
+			expectedStatus: http.StatusOK,
+			expectedBody:   location,
+		},
+		{
+			name:           "nothing within radius",
+			radius:         "50",
+			expectedRadius: 50,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
+		},
+		{
+			name:           "above the maximum",
+			radius:         "1e9",
+			expectedRadius: 1e9,
+			mockError:      fmt.Errorf("%w: 1e+09 must be between 0 and 10000 meters", service.ErrInvalidRadius),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
+		{
+			name:           "zero",
+			radius:         "0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
+		{
+			name:           "negative",
+			radius:         "-100",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
+		{
+			name:           "not a number",
+			radius:         "NaN",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "invalid radius: must be positive and no larger than the maximum search radius"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+			if tt.expectedRadius != 0 {
+				mockSvc.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, tt.expectedRadius, "", []int(nil)).Return(tt.mockLocation, tt.mockError)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125&radius="+tt.radius, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeHandler_ReverseGeocode_Limit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nearest := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Distance: 3.2}
+	locations := []models.Location{*nearest, {ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "大手町", Distance: 120.5}}
+	invalidLimit := gin.H{"error": "invalid limit: must be between 1 and 100, and a limit above 1 cannot be combined with context, hierarchy, include_colocated, prefer=admin or sort"}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedLimit  int
+		mockLocations  []models.Location
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "nearest addresses as an array",
+			query:          "&limit=2",
+			expectedLimit:  2,
+			mockLocations:  locations,
+			expectedStatus: http.StatusOK,
+			expectedBody:   locations,
+		},
+		{
+			name:           "limit 1 keeps the single object",
+			query:          "&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   nearest,
+		},
+		{
+			name:           "nothing in range",
+			query:          "&limit=5",
+			expectedLimit:  5,
+			mockLocations:  []models.Location{},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   gin.H{"error": "no address found near the specified coordinates"},
+		},
+		{
+			name:           "limit too large",
+			query:          "&limit=101",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidLimit,
+		},
+		{
+			name:           "not a number",
+			query:          "&limit=two",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidLimit,
+		},
+		{
+			name:           "combined with context",
+			query:          "&limit=3&context=2",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   invalidLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockReverseGeoCodeService)
+			handler := NewReverseGeocodeHandler(mockSvc)
+			if tt.expectedLimit > 1 {
+				mockSvc.On("ReverseGeocodeNearest", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil), tt.expectedLimit).Return(tt.mockLocations, nil)
+			} else if tt.expectedStatus == http.StatusOK {
+				mockSvc.On("ReverseGeocode", mock.Anything, 35.681236, 139.767125, 0.0, "", []int(nil)).Return(nearest, nil)
+			}
+
+			// Create request
+			req := httptest.NewRequest(http.MethodGet, "/reverse-geocode?lat=35.681236&lon=139.767125"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			// Create Gin context
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			// Execute
+			handler.ReverseGeocode(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"geocoding-api/internal/i18n"
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoundTripHandler handles round trip validation requests
+type RoundTripHandler struct {
+	service RoundTripService
+	config  GeoCodeConfig
+}
+
+// RoundTripService interface for dependency injection
+type RoundTripService interface {
+	RoundTrip(context.Context, string) (*models.RoundTripResult, error)
+}
+
+// NewRoundTripHandler creates a new round trip handler. Queries are prepared the same way as
+// /geocode queries according to cfg.
+func NewRoundTripHandler(svc RoundTripService, cfg GeoCodeConfig) *RoundTripHandler {
+	return &RoundTripHandler{service: svc, config: cfg}
+}
+
+// RoundTrip godoc
+// @Summary Validate an address round trip
+// @Description Geocode the query, reverse geocode the top match's coordinates and report whether the address components agree. A diagnostic for data quality, only served when ADMIN_TOKEN is set.
+// @Tags admin
+// @Produce json
+// @Param q query string true "Address to check"
+// @Security AdminToken
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} models.RoundTripResult
+// @Failure 400 {object} map[string]string "error":"missing required query parameter 'q'"
+// @Failure 401 {object} map[string]string "error":"admin token required"
+// @Failure 500 {object} map[string]string "error":"internal server error"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /validate/roundtrip [get]
+func (h *RoundTripHandler) RoundTrip(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		respondError(c, http.StatusBadRequest, i18n.MsgMissingQuery)
+		return
+	}
+	query, _ = h.config.prepareQuery(query)
+
+	result, err := h.service.RoundTrip(c.Request.Context(), query)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoundTripService is a mock implementation of the RoundTripService interface
+type MockRoundTripService struct {
+	mock.Mock
+}
+
+func (m *MockRoundTripService) RoundTrip(ctx context.Context, query string) (*models.RoundTripResult, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(*models.RoundTripResult), args.Error(1)
+}
+
+func TestRoundTripHandler_RoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	geocoded := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125}
+	reversed := &models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "2", Latitude: 35.6813, Longitude: 139.7672}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedQuery  string
+		mockResult     *models.RoundTripResult
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "mismatch",
+			query:          "丸の内1丁目1",
+			expectedQuery:  "丸の内1-1",
+			mockResult:     &models.RoundTripResult{Query: "丸の内1-1", Geocoded: geocoded, Reversed: reversed, Mismatches: []string{"block_lot"}},
+			expectedStatus: http.StatusOK,
+			expectedBody: gin.H{
+				"query": "丸の内1-1",
+				"geocoded": gin.H{"id": 1, "prefecture": "東京都", "municipality": "千代田区", "address1": "丸の内1", "address2": "", "block_lot": "1",
+					"latitude": 35.681236, "longitude": 139.767125},
+				"reversed": gin.H{"id": 2, "prefecture": "東京都", "municipality": "千代田区", "address1": "丸の内1", "address2": "", "block_lot": "2",
+					"latitude": 35.6813, "longitude": 139.7672},
+				"match":      false,
+				"mismatches": []string{"block_lot"},
+			},
+		},
+		{
+			name:           "no match",
+			query:          "存在しない",
+			expectedQuery:  "存在しない",
+			mockResult:     &models.RoundTripResult{Query: "存在しない", Mismatches: []string{}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   gin.H{"query": "存在しない", "geocoded": nil, "reversed": nil, "match": false, "mismatches": []string{}},
+		},
+		{
+			name:           "missing query",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   gin.H{"error": "missing required query parameter 'q'"},
+		},
+		{
+			name:           "service error",
+			query:          "丸の内",
+			expectedQuery:  "丸の内",
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   gin.H{"error": "internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockSvc := new(MockRoundTripService)
+			handler := NewRoundTripHandler(mockSvc, GeoCodeConfig{NormalizeAddresses: true})
+			if tt.expectedQuery != "" {
+				mockSvc.On("RoundTrip", mock.Anything, tt.expectedQuery).Return(tt.mockResult, tt.mockError)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/validate/roundtrip", nil)
+			q := c.Request.URL.Query()
+			q.Set("q", tt.query)
+			c.Request.URL.RawQuery = q.Encode()
+
+			// Execute
+			handler.RoundTrip(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			expectedBody, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedBody), w.Body.String())
+
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
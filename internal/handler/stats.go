@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"geocoding-api/internal/stats"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stats counts every request in counters: in flight while it is handled, then by the status
+// it was answered with. A panicking handler counts as a server error, the status the recovery
+// middleware answers it with.
+func Stats(counters *stats.Counters) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counters.RequestStarted()
+		defer func() {
+			if err := recover(); err != nil {
+				counters.RequestFinished(http.StatusInternalServerError)
+				panic(err)
+			}
+			counters.RequestFinished(c.Writer.Status())
+		}()
+		c.Next()
+	}
+}
+
+// StatsHandler handles runtime stats requests
+type StatsHandler struct {
+	counters *stats.Counters
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(counters *stats.Counters) *StatsHandler {
+	return &StatsHandler{counters: counters}
+}
+
+// Runtime godoc
+// @Summary Runtime stats
+// @Description Report this instance's request and geocode cache counters since it started, and the database circuit breaker's state when DB_BREAKER_FAILURES enables it, a lightweight alternative to scraping metrics; only served with ADMIN_TOKEN set
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param format query string false "Response format, taking priority over the Accept header (json)"
+// @Success 200 {object} models.RuntimeStats
+// @Failure 401 {object} map[string]string "error":"unauthorized"
+// @Failure 406 {object} map[string]string "error":"unsupported format"
+// @Router /admin/stats/runtime [get]
+func (h *StatsHandler) Runtime(c *gin.Context) {
+	if _, ok := negotiateFormat(c, formatJSON); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.counters.Snapshot())
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/stats"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	counters := stats.New()
+	var inFlight int64
+
+	r := gin.New()
+	r.Use(gin.Recovery(), Stats(counters))
+	r.GET("/ok", func(c *gin.Context) {
+		inFlight = counters.Snapshot().Requests.InFlight
+		c.Status(http.StatusOK)
+	})
+	r.GET("/bad", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/admin/stats/runtime", NewStatsHandler(counters).Runtime)
+
+	for _, path := range []string{"/ok", "/ok", "/bad", "/panic", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	counters.CacheHit()
+	counters.CacheMiss()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/stats/runtime", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(1), inFlight)
+
+	var snapshot models.RuntimeStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	// The stats request itself is still in flight while the snapshot is taken
+	assert.Equal(t, models.RequestStats{InFlight: 1, Total: 5, ClientErrors: 2, ServerErrors: 1}, snapshot.Requests)
+	assert.Equal(t, models.CacheStats{Hits: 1, Misses: 1, HitRatio: 0.5}, snapshot.Cache)
+}
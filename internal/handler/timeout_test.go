@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	budgets := map[string]time.Duration{}
+	record := func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if ok {
+			budgets[c.FullPath()] = time.Until(deadline)
+		}
+		c.Status(http.StatusNoContent)
+	}
+	r.GET("/geocode", Timeout(5*time.Second), record)
+	r.GET("/reverse-geocode", Timeout(500*time.Millisecond), record)
+	r.GET("/locations", Timeout(0), record)
+
+	tests := []struct {
+		name        string
+		path        string
+		expected    time.Duration
+		hasDeadline bool
+	}{
+		{name: "geocode budget", path: "/geocode", expected: 5 * time.Second, hasDeadline: true},
+		{name: "reverse geocode budget", path: "/reverse-geocode", expected: 500 * time.Millisecond, hasDeadline: true},
+		{name: "no timeout", path: "/locations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			budget, ok := budgets[tt.path]
+			assert.Equal(t, tt.hasDeadline, ok)
+			if tt.hasDeadline {
+				assert.InDelta(t, tt.expected, budget, float64(100*time.Millisecond))
+			}
+		})
+	}
+}
+
+func TestTimeout_QueryCutOff(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockSvc := new(MockGeoCodeService)
+	mockSvc.On("Geocode", mock.Anything, mock.Anything).Return((*models.GeocodeResult)(nil), context.DeadlineExceeded)
+
+	r := gin.New()
+	r.GET("/geocode", Timeout(time.Millisecond), NewGeoCodeHandler(mockSvc, GeoCodeConfig{}).GeoCode)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/geocode?q=丸の内", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.JSONEq(t, `{"error":"the query took too long; try a more specific request"}`, w.Body.String())
+	mockSvc.AssertExpectations(t)
+}
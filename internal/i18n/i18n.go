@@ -0,0 +1,277 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages
+const (
+	English  = "en"
+	Japanese = "ja"
+)
+
+// DefaultLanguage is used when neither the request nor the config selects a supported language
+const DefaultLanguage = English
+
+// MessageKey identifies a user-facing message in the catalogs
+type MessageKey string
+
+// Message keys for API error responses
+const (
+	MsgInternalError      MessageKey = "internal_error"
+	MsgMissingQuery       MessageKey = "missing_query"
+	MsgQueryTooShort      MessageKey = "query_too_short"
+	MsgInvalidSuggest     MessageKey = "invalid_suggest"
+	MsgMissingCoordinates MessageKey = "missing_coordinates"
+	MsgInvalidLatitude    MessageKey = "invalid_latitude"
+	MsgInvalidLongitude   MessageKey = "invalid_longitude"
+	MsgNoAddressFound     MessageKey = "no_address_found"
+	MsgMissingIDs         MessageKey = "missing_ids"
+	MsgInvalidID          MessageKey = "invalid_id"
+	MsgTooManyIDs         MessageKey = "too_many_ids"
+	MsgPostGISUnavailable MessageKey = "postgis_unavailable"
+	MsgPostGISTooOld      MessageKey = "postgis_too_old"
+	MsgMissingArea        MessageKey = "missing_area"
+	MsgInvalidLimit       MessageKey = "invalid_limit"
+	MsgInvalidOffset      MessageKey = "invalid_offset"
+	MsgInvalidContext     MessageKey = "invalid_context"
+	MsgNotFound           MessageKey = "not_found"
+	MsgInvalidBody        MessageKey = "invalid_body"
+	MsgInvalidPointCount  MessageKey = "invalid_point_count"
+	MsgInvalidPoint       MessageKey = "invalid_point"
+	MsgInvalidIncludeBBox MessageKey = "invalid_include_bbox"
+	MsgInvalidDensity     MessageKey = "invalid_include_density"
+	MsgUnsupportedSRID    MessageKey = "unsupported_srid"
+	MsgInvalidSRID        MessageKey = "invalid_srid"
+	MsgCoordsOutOfRange   MessageKey = "coordinates_out_of_range"
+	MsgInvalidCursor      MessageKey = "invalid_cursor"
+	MsgCursorWithOffset   MessageKey = "cursor_with_offset"
+	MsgInvalidOrderBy     MessageKey = "invalid_order_by"
+	MsgCursorWithOrder    MessageKey = "cursor_with_order"
+	MsgQueryTimeout       MessageKey = "query_timeout"
+	MsgDBUnavailable      MessageKey = "database_unavailable"
+	MsgInvalidHierarchy   MessageKey = "invalid_hierarchy"
+	MsgInvalidColocated   MessageKey = "invalid_include_colocated"
+	MsgInvalidPrefer      MessageKey = "invalid_prefer"
+	MsgNoBoundaries       MessageKey = "boundaries_unavailable"
+	MsgInvalidCoordFormat MessageKey = "invalid_coords_as_string"
+	MsgInvalidRadius      MessageKey = "invalid_radius"
+	MsgUnknownField       MessageKey = "unknown_field"
+	MsgInvalidFields      MessageKey = "invalid_fields"
+	MsgInvalidBatchSize   MessageKey = "invalid_batch_size"
+	MsgInvalidBatchMode   MessageKey = "invalid_batch_mode"
+	MsgJobNotFound        MessageKey = "job_not_found"
+	MsgJobNotFinished     MessageKey = "job_not_finished"
+	MsgQueryNotAllowed    MessageKey = "query_not_allowed"
+	MsgUnauthorized       MessageKey = "unauthorized"
+	MsgTooManyTerms       MessageKey = "too_many_terms"
+	MsgInvalidQueryChars  MessageKey = "invalid_query_chars"
+	MsgInvalidVerbose     MessageKey = "invalid_verbose"
+	MsgInvalidDebug       MessageKey = "invalid_debug"
+	MsgInvalidDisambig    MessageKey = "invalid_disambiguate"
+	MsgTooManyExcluded    MessageKey = "too_many_excluded"
+	MsgUnsupportedFormat  MessageKey = "unsupported_format"
+	MsgNotAcceptable      MessageKey = "not_acceptable"
+	MsgInvalidAPIKey      MessageKey = "invalid_api_key"
+	MsgQuotaExceeded      MessageKey = "quota_exceeded"
+	MsgInvalidSort        MessageKey = "invalid_sort"
+	MsgInvalidTotal       MessageKey = "invalid_include_total"
+	MsgInvalidNearest     MessageKey = "invalid_nearest_limit"
+	MsgInvalidFuzzy       MessageKey = "invalid_fuzzy"
+	MsgInvalidBBox        MessageKey = "invalid_bbox"
+	MsgMissingBBox        MessageKey = "missing_bbox"
+)
+
+var catalogs = map[string]map[MessageKey]string{
+	English: {
+		MsgInternalError:      "internal server error",
+		MsgMissingQuery:       "missing required query parameter 'q'",
+		MsgQueryTooShort:      "query is too short: use at least %d characters, e.g. include the municipality",
+		MsgInvalidSuggest:     "invalid suggest value",
+		MsgMissingCoordinates: "missing required query parameters 'lat' and 'lon'",
+		MsgInvalidLatitude:    "invalid latitude format",
+		MsgInvalidLongitude:   "invalid longitude format",
+		MsgNoAddressFound:     "no address found near the specified coordinates",
+		MsgMissingIDs:         "missing required query parameter 'ids'",
+		MsgInvalidID:          "invalid id: %q",
+		MsgTooManyIDs:         "too many ids (max %d)",
+		MsgPostGISUnavailable: "PostGIS extension is not installed; run CREATE EXTENSION postgis",
+		MsgPostGISTooOld:      "PostGIS %s is too old; %s or newer is required",
+		MsgMissingArea:        "at least one of 'prefecture' or 'municipality' is required",
+		MsgInvalidLimit:       "invalid limit: must be a non-negative integer; 0 uses the default and values above %d are clamped to it",
+		MsgInvalidOffset:      "invalid offset: must be a non-negative integer",
+		MsgInvalidContext:     "invalid context: must be between 0 and %d",
+		MsgNotFound:           "not found",
+		MsgInvalidBody:        "invalid request body",
+		MsgInvalidPointCount:  "between 1 and %d points are required",
+		MsgInvalidPoint:       "point %d has out-of-range coordinates",
+		MsgInvalidIncludeBBox: "invalid include_bbox value",
+		MsgInvalidDensity:     "invalid include_density value",
+		MsgUnsupportedSRID:    "unsupported srid: %q (supported: 4326, 3857, 6668)",
+		MsgInvalidSRID:        "unsupported srid (supported: 4326, 3857, 6668)",
+		MsgCoordsOutOfRange:   "coordinates out of range: latitude must be within ±90 and longitude within ±180",
+		MsgInvalidCursor:      "invalid cursor",
+		MsgCursorWithOffset:   "cursor cannot be combined with offset",
+		MsgInvalidOrderBy:     "invalid order_by value: must be relevance or importance",
+		MsgCursorWithOrder:    "cursor cannot be combined with order_by=importance, use offset",
+		MsgQueryTimeout:       "the query took too long; try a more specific request",
+		MsgDBUnavailable:      "the database is temporarily unavailable; try again later",
+		MsgInvalidHierarchy:   "invalid hierarchy value: must be true or false, and cannot be combined with context",
+		MsgInvalidColocated:   "invalid include_colocated value: must be true or false, and cannot be combined with context or hierarchy",
+		MsgInvalidPrefer:      "invalid prefer value: must be nearest or admin, and admin cannot be combined with context or include_colocated",
+		MsgNoBoundaries:       "prefer=admin is not available: no municipality boundaries are loaded",
+		MsgInvalidCoordFormat: "invalid coords_as_string value: must be true or false",
+		MsgInvalidRadius:      "invalid radius: must be positive and no larger than the maximum search radius",
+		MsgUnknownField:       "unknown field %q (available: %s)",
+		MsgInvalidFields:      "invalid fields (available: %s)",
+		MsgInvalidBatchSize:   "between 1 and %d addresses are required",
+		MsgInvalidBatchMode:   "invalid mode value: must be async or sync",
+		MsgJobNotFound:        "job not found",
+		MsgJobNotFinished:     "job has not succeeded (status: %s)",
+		MsgQueryNotAllowed:    "query is not allowed",
+		MsgUnauthorized:       "admin token required",
+		MsgTooManyTerms:       "query has too many terms (max %d)",
+		MsgInvalidQueryChars:  "query contains control characters",
+		MsgInvalidVerbose:     "invalid verbose value",
+		MsgInvalidDebug:       "invalid debug value",
+		MsgInvalidDisambig:    "invalid disambiguate value",
+		MsgTooManyExcluded:    "too many excluded ids (max %d)",
+		MsgUnsupportedFormat:  "unsupported format %q (available: %s)",
+		MsgNotAcceptable:      "none of the accepted media types can be produced (available: %s)",
+		MsgInvalidAPIKey:      "a valid API key is required in the X-API-Key header",
+		MsgQuotaExceeded:      "request quota exceeded; it resets at %s",
+		MsgInvalidSort:        "invalid sort value (available: %s); municipality needs the municipality parameter, and sort cannot be combined with prefer=admin or include_colocated",
+		MsgInvalidTotal:       "invalid include_total value",
+		MsgInvalidNearest:     "invalid limit: must be between 1 and %d, and a limit above 1 cannot be combined with context, hierarchy, include_colocated, prefer=admin or sort",
+		MsgInvalidFuzzy:       "invalid fuzzy value",
+		MsgInvalidBBox:        "invalid bbox: expected min_lon,min_lat,max_lon,max_lat with longitudes between -180 and 180, latitudes between -90 and 90 and each minimum below its maximum",
+		MsgMissingBBox:        "missing required query parameter 'bbox'",
+	},
+	Japanese: {
+		MsgInternalError:      "サーバー内部でエラーが発生しました",
+		MsgMissingQuery:       "必須のクエリパラメータ 'q' が指定されていません",
+		MsgQueryTooShort:      "検索語が短すぎます。市区町村名などを含めて %d 文字以上で指定してください",
+		MsgInvalidSuggest:     "suggest の値が不正です",
+		MsgMissingCoordinates: "必須のクエリパラメータ 'lat' と 'lon' が指定されていません",
+		MsgInvalidLatitude:    "緯度の形式が不正です",
+		MsgInvalidLongitude:   "経度の形式が不正です",
+		MsgNoAddressFound:     "指定された座標の近くに住所が見つかりません",
+		MsgMissingIDs:         "必須のクエリパラメータ 'ids' が指定されていません",
+		MsgInvalidID:          "不正な ID です: %q",
+		MsgTooManyIDs:         "ID が多すぎます（最大 %d 件）",
+		MsgPostGISUnavailable: "PostGIS 拡張機能がインストールされていません。CREATE EXTENSION postgis を実行してください",
+		MsgPostGISTooOld:      "PostGIS %s は古すぎます。%s 以降が必要です",
+		MsgMissingArea:        "'prefecture' と 'municipality' の少なくとも一方を指定してください",
+		MsgInvalidLimit:       "limit の値が不正です。0 以上の整数で指定してください（0 は既定値、%d を超える値は %[1]d までに切り詰められます）",
+		MsgInvalidOffset:      "offset の値が不正です。0 以上の整数を指定してください",
+		MsgInvalidContext:     "context の値が不正です。0 から %d の範囲で指定してください",
+		MsgNotFound:           "見つかりません",
+		MsgInvalidBody:        "リクエストボディが不正です",
+		MsgInvalidPointCount:  "地点は 1 から %d 件の範囲で指定してください",
+		MsgInvalidPoint:       "地点 %d の座標が範囲外です",
+		MsgInvalidIncludeBBox: "include_bbox の値が不正です",
+		MsgInvalidDensity:     "include_density の値が不正です",
+		MsgUnsupportedSRID:    "対応していない srid です: %q（対応: 4326, 3857, 6668）",
+		MsgInvalidSRID:        "対応していない srid です（対応: 4326, 3857, 6668）",
+		MsgCoordsOutOfRange:   "座標が範囲外です。緯度は ±90、経度は ±180 の範囲で指定してください",
+		MsgInvalidCursor:      "cursor が不正です",
+		MsgCursorWithOffset:   "cursor と offset は同時に指定できません",
+		MsgInvalidOrderBy:     "order_by の値が不正です。relevance または importance を指定してください",
+		MsgCursorWithOrder:    "order_by=importance では cursor を指定できません。offset を使用してください",
+		MsgQueryTimeout:       "処理がタイムアウトしました。条件を絞り込んで再度お試しください",
+		MsgDBUnavailable:      "データベースが一時的に利用できません。しばらくしてから再度お試しください",
+		MsgInvalidHierarchy:   "hierarchy の値が不正です。true または false を指定してください（context とは併用できません）",
+		MsgInvalidColocated:   "include_colocated の値が不正です。true または false を指定してください（context、hierarchy とは併用できません）",
+		MsgInvalidPrefer:      "prefer の値が不正です。nearest または admin を指定してください（admin は context、include_colocated とは併用できません）",
+		MsgNoBoundaries:       "prefer=admin は利用できません。市区町村の境界データが読み込まれていません",
+		MsgInvalidCoordFormat: "coords_as_string の値が不正です。true または false を指定してください",
+		MsgInvalidRadius:      "radius が不正です。正の値で、最大検索半径以下を指定してください",
+		MsgUnknownField:       "不明なフィールドです: %q（指定可能: %s）",
+		MsgInvalidFields:      "fields が不正です（指定可能: %s）",
+		MsgInvalidBatchSize:   "住所は 1 から %d 件の範囲で指定してください",
+		MsgInvalidBatchMode:   "mode の値が不正です。async または sync を指定してください",
+		MsgJobNotFound:        "ジョブが見つかりません",
+		MsgJobNotFinished:     "ジョブは完了していません（状態: %s）",
+		MsgQueryNotAllowed:    "この検索語は使用できません",
+		MsgUnauthorized:       "管理者トークンが必要です",
+		MsgTooManyTerms:       "検索語が多すぎます（最大%d語）",
+		MsgInvalidQueryChars:  "検索語に制御文字が含まれています",
+		MsgInvalidVerbose:     "verbose の値が不正です",
+		MsgInvalidDebug:       "debug の値が不正です",
+		MsgInvalidDisambig:    "disambiguate の値が不正です",
+		MsgTooManyExcluded:    "除外 ID が多すぎます（最大 %d 件）",
+		MsgUnsupportedFormat:  "対応していない形式です: %q（指定可能: %s）",
+		MsgNotAcceptable:      "Accept で指定された形式では応答できません（指定可能: %s）",
+		MsgInvalidAPIKey:      "X-API-Key ヘッダーに有効な API キーを指定してください",
+		MsgQuotaExceeded:      "リクエスト数の上限に達しました。%s にリセットされます",
+		MsgInvalidSort:        "sort の値が不正です（指定可能: %s）。municipality には municipality パラメータが必要です（prefer=admin、include_colocated とは併用できません）",
+		MsgInvalidTotal:       "include_total の値が不正です",
+		MsgInvalidNearest:     "limit の値が不正です。1 から %d の範囲で指定してください（2 以上は context、hierarchy、include_colocated、prefer=admin、sort とは併用できません）",
+		MsgInvalidFuzzy:       "fuzzy の値が不正です",
+		MsgInvalidBBox:        "bbox の値が不正です。min_lon,min_lat,max_lon,max_lat の形式で、経度は -180 から 180、緯度は -90 から 90 の範囲で、最小値を最大値より小さく指定してください",
+		MsgMissingBBox:        "必須のクエリパラメータ 'bbox' が指定されていません",
+	},
+}
+
+// Supported reports whether a catalog exists for the language
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Message returns the message for key in lang formatted with args, falling back to English
+func Message(lang string, key MessageKey, args ...interface{}) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLanguage][key]
+		if !ok {
+			return string(key)
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Negotiate picks the supported language with the highest weight in an Accept-Language
+// header, falling back to fallback (or DefaultLanguage when that is unsupported too)
+func Negotiate(acceptLanguage, fallback string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(tag, "-")
+		candidates = append(candidates, candidate{lang: strings.ToLower(primary), q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if c.q > 0 && Supported(c.lang) {
+			return c.lang
+		}
+	}
+
+	if Supported(fallback) {
+		return fallback
+	}
+	return DefaultLanguage
+}
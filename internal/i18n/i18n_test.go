@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		fallback       string
+		expected       string
+	}{
+		{name: "empty header uses fallback", acceptLanguage: "", fallback: Japanese, expected: Japanese},
+		{name: "exact match", acceptLanguage: "ja", fallback: English, expected: Japanese},
+		{name: "region subtag", acceptLanguage: "ja-JP", fallback: English, expected: Japanese},
+		{name: "highest weight wins", acceptLanguage: "en;q=0.5, ja;q=0.9", fallback: English, expected: Japanese},
+		{name: "unsupported languages skipped", acceptLanguage: "fr-FR, de;q=0.9, en;q=0.1", fallback: Japanese, expected: English},
+		{name: "zero weight excluded", acceptLanguage: "ja;q=0", fallback: English, expected: English},
+		{name: "nothing supported uses fallback", acceptLanguage: "fr", fallback: Japanese, expected: Japanese},
+		{name: "unsupported fallback uses default", acceptLanguage: "fr", fallback: "de", expected: DefaultLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Negotiate(tt.acceptLanguage, tt.fallback))
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "missing required query parameter 'q'", Message(English, MsgMissingQuery))
+	assert.Equal(t, "必須のクエリパラメータ 'q' が指定されていません", Message(Japanese, MsgMissingQuery))
+	assert.Equal(t, "too many ids (max 100)", Message(English, MsgTooManyIDs, 100))
+	assert.Equal(t, "ID が多すぎます（最大 100 件）", Message(Japanese, MsgTooManyIDs, 100))
+	assert.Equal(t, "internal server error", Message("fr", MsgInternalError))
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for key := range catalogs[English] {
+		for lang, catalog := range catalogs {
+			_, ok := catalog[key]
+			assert.True(t, ok, "message %q missing from %q catalog", key, lang)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// BatchJobStatus is the lifecycle state of an asynchronous batch geocoding job. A job is queued
+// when submitted, running once a worker claims it, and ends as succeeded or failed. A running
+// job whose worker stops reporting progress goes back to being claimable, so no job is lost
+// when the API restarts mid-job.
+type BatchJobStatus string
+
+const (
+	// BatchJobQueued is a submitted job waiting for a worker
+	BatchJobQueued BatchJobStatus = "queued"
+	// BatchJobRunning is a job a worker is geocoding
+	BatchJobRunning BatchJobStatus = "running"
+	// BatchJobSucceeded is a job whose results are available
+	BatchJobSucceeded BatchJobStatus = "succeeded"
+	// BatchJobFailed is a job that stopped on an internal error; Error says why
+	BatchJobFailed BatchJobStatus = "failed"
+)
+
+// BatchGeocodeRequest is the body of a batch geocoding request
+type BatchGeocodeRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// BatchJob is the status of an asynchronous batch geocoding job
+type BatchJob struct {
+	ID         string         `json:"id"`
+	Status     BatchJobStatus `json:"status"`
+	Total      int            `json:"total"`
+	Processed  int            `json:"processed"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	// ResultsURL is where the results can be fetched once the job has succeeded
+	ResultsURL string `json:"results_url,omitempty"`
+	// Addresses are the queries to geocode; they are only loaded for the worker
+	Addresses []string `json:"-"`
+}
+
+// BatchResult is the outcome of geocoding one address of a batch job, in submission order
+type BatchResult struct {
+	Query   string     `json:"query"`
+	Results []Location `json:"results"`
+	// Error explains why the address could not be searched, e.g. because it is empty
+	Error string `json:"error,omitempty"`
+}
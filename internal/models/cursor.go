@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// SearchCursor marks the last geocode result of a page, so the next page can continue after it
+// with keyset pagination. Results are ordered by (Rank, ID) descending.
+//
+// Offset pagination is simpler and lets clients jump to any page, but it shifts when rows are
+// imported or removed between requests, repeating or skipping results. A cursor continues from
+// the exact row a page ended on, so it is the better choice for walking through long result
+// lists; use offset for small, random-access pages.
+type SearchCursor struct {
+	Rank float64 `json:"r"`
+	ID   int     `json:"i"`
+	// Config is the text search configuration the ranks were computed with
+	Config string `json:"c,omitempty"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseSearchCursor decodes a token produced by SearchCursor.Encode
+func ParseSearchCursor(token string) (*SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("models: malformed cursor")
+	}
+	var c SearchCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, errors.New("models: malformed cursor")
+	}
+	return &c, nil
+}
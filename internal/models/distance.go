@@ -0,0 +1,19 @@
+package models
+
+// Point is a WGS84 coordinate pair.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// DistanceMatrixRequest is the body of POST /distance-matrix.
+type DistanceMatrixRequest struct {
+	Points []Point `json:"points"`
+}
+
+// DistanceMatrix holds the geodesic distance in meters between every pair of points;
+// Distances[i][j] is the distance from Points[i] to Points[j].
+type DistanceMatrix struct {
+	Points    []Point     `json:"points"`
+	Distances [][]float64 `json:"distances_m"`
+}
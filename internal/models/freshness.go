@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// DataFreshness describes how recently the location data was imported; LastImportedAt is nil when nothing has been imported yet.
+type DataFreshness struct {
+	LastImportedAt *time.Time `json:"last_imported_at"`
+	RecordCount    int64      `json:"record_count"`
+}
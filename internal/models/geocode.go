@@ -0,0 +1,49 @@
+package models
+
+// Cache statuses of a geocode result, reported in the X-Cache header
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+// GeocodeResult wraps geocode matches together with "did you mean" suggestions offered when nothing matched.
+type GeocodeResult struct {
+	Results     []Location `json:"results"`
+	Suggestions []string   `json:"suggestions,omitempty"`
+	// Building is the building name removed from the query before searching, if any.
+	Building string `json:"building,omitempty"`
+	// NextCursor fetches the page after this one; it is empty when there are no more results.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of locations the query matches across all pages, set only when
+	// requested and the results were found by full-text search.
+	Total *int `json:"total,omitempty"`
+	// Strategy is the search strategy that found the results, e.g. "fulltext"; it is empty when
+	// nothing was found.
+	Strategy string `json:"strategy,omitempty"`
+	// TextSearchConfig is the text search configuration that found full-text results, e.g.
+	// "simple" when the primary configuration found too few; it is empty for other strategies.
+	TextSearchConfig string `json:"text_search_config,omitempty"`
+	// NeedsDisambiguation is set when the top results are spread over several prefectures with
+	// close scores, so a client should ask which area was meant before showing street-level results.
+	NeedsDisambiguation bool `json:"needs_disambiguation,omitempty"`
+	// DisambiguationOptions are the distinct areas of those top results, in result order; they are
+	// only set along with NeedsDisambiguation.
+	DisambiguationOptions []Area `json:"disambiguation_options,omitempty"`
+	// Cache is CacheHit when the result was served from the result cache and CacheMiss when it
+	// was searched for; it is empty when caching is disabled.
+	Cache string `json:"-"`
+}
+
+// VerboseGeocodeResult is the verbose geocode response: the wrapped results together with the
+// query as it was searched for (after normalization), the number of results and how long the
+// server took to answer.
+type VerboseGeocodeResult struct {
+	GeocodeResult
+	Query  string  `json:"query"`
+	Count  int     `json:"count"`
+	TookMs float64 `json:"took_ms"`
+	Cache  string  `json:"cache,omitempty"`
+	// QueryPlans are the plans of the searches that exceeded the slow query threshold, returned
+	// with debug=true when query plan capture is enabled.
+	QueryPlans []QueryPlan `json:"query_plans,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+// LocationHierarchy presents a location's address as its administrative chain, each level
+// nested under the one above it, for clients that render breadcrumb-style location info.
+type LocationHierarchy struct {
+	ID         int             `json:"id"`
+	Latitude   float64         `json:"latitude"`
+	Longitude  float64         `json:"longitude"`
+	Altitude   *float64        `json:"altitude,omitempty"`
+	Prefecture PrefectureLevel `json:"prefecture"`
+}
+
+// PrefectureLevel is the top level of the administrative chain (都道府県).
+type PrefectureLevel struct {
+	Name         string            `json:"name"`
+	Municipality MunicipalityLevel `json:"municipality"`
+}
+
+// MunicipalityLevel is a city, ward, town or village (市区町村).
+type MunicipalityLevel struct {
+	Name     string        `json:"name"`
+	District DistrictLevel `json:"district"`
+}
+
+// DistrictLevel is the 大字・丁目 with the 小字 and block/lot number below it, when present.
+type DistrictLevel struct {
+	Name        string `json:"name"`
+	SubDistrict string `json:"sub_district,omitempty"`
+	BlockLot    string `json:"block_lot,omitempty"`
+}
+
+// Hierarchy returns the location's address components nested by administrative level.
+func (l Location) Hierarchy() LocationHierarchy {
+	return LocationHierarchy{
+		ID:        l.ID,
+		Latitude:  l.Latitude,
+		Longitude: l.Longitude,
+		Altitude:  l.Altitude,
+		Prefecture: PrefectureLevel{
+			Name: l.Prefecture,
+			Municipality: MunicipalityLevel{
+				Name: l.Municipality,
+				District: DistrictLevel{
+					Name:        l.Address1,
+					SubDistrict: l.Address2,
+					BlockLot:    l.BlockLot,
+				},
+			},
+		},
+	}
+}
@@ -2,12 +2,58 @@ package models
 
 // Location represents a single addressable point, containing its decomposed Japanese address components and its precise geographic coordinates.
 type Location struct {
-	ID         int     `json:"id"`
-	Prefecture string  `json:"prefecture"`
+	ID           int     `json:"id"`
+	Prefecture   string  `json:"prefecture"`
+	Municipality string  `json:"municipality"`
+	Address1     string  `json:"address1"`
+	Address2     string  `json:"address2"`
+	BlockLot     string  `json:"block_lot"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	// Altitude is the elevation in meters, the Z of the location's point; set only for datasets
+	// imported with one
+	Altitude *float64 `json:"altitude,omitempty"`
+	// Source is the dataset (imported file) the location came from, set by reverse geocoding
+	Source string `json:"source,omitempty"`
+	// PrefectureKana, MunicipalityKana, Address1Kana and Address2Kana are the kana readings of the
+	// address parts, set only for datasets that have them
+	PrefectureKana   string `json:"prefecture_kana,omitempty"`
+	MunicipalityKana string `json:"municipality_kana,omitempty"`
+	Address1Kana     string `json:"address1_kana,omitempty"`
+	Address2Kana     string `json:"address2_kana,omitempty"`
+	// Distance is how far the location is from the queried point in meters, set by reverse
+	// geocoding so clients can tell when the nearest address is suspiciously far away
+	Distance float64 `json:"distance_meters,omitempty"`
+	// ExternalID is the stable ID the source dataset gave the address, set only for datasets that have one
+	ExternalID string `json:"external_id,omitempty"`
+	// BBox is the extent of the location's municipality as [min_lon, min_lat, max_lon, max_lat], set only when requested
+	BBox []float64 `json:"bbox,omitempty"`
+	// Density is the number of locations within the density radius of the location, itself
+	// included, set only when requested
+	Density *int `json:"density,omitempty"`
+	// Projected holds the coordinates transformed to the requested output SRID, set only when one is requested
+	Projected *ProjectedPoint `json:"projected,omitempty"`
+	// Interpolated is set when the position was interpolated along an address range segment
+	// instead of read from a stored address point; such locations have no ID
+	Interpolated bool `json:"interpolated,omitempty"`
+	// Score is the relevance of a geocode match as ranked by the strategy that found it (ts_rank
+	// for full-text search), so clients can judge how confident a match is; it also builds the
+	// pagination cursors. It is zero for reverse geocode and ID lookups, which aren't ranked.
+	Score float64 `json:"score,omitempty"`
+	// TextSearchConfig is the text search configuration of the full-text search that found the
+	// location; cursors carry it so the next page searches with the same one
+	TextSearchConfig string `json:"-"`
+}
+
+// ProjectedPoint is a location's position in a projected or alternative coordinate system.
+type ProjectedPoint struct {
+	SRID int     `json:"srid"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// Area identifies a municipality within a prefecture.
+type Area struct {
+	Prefecture   string `json:"prefecture"`
 	Municipality string `json:"municipality"`
-	Address1   string  `json:"address1"`
-	Address2   string  `json:"address2"`
-	BlockLot   string  `json:"block_lot"`
-	Latitude   float64 `json:"latitude"`
-	Longitude  float64 `json:"longitude"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"sync"
+)
+
+// QueryPlan is the EXPLAIN (ANALYZE, BUFFERS) output of a slow repository query.
+type QueryPlan struct {
+	Query string `json:"query"`
+	Plan  string `json:"plan"`
+}
+
+// QueryPlans collects the plans captured while serving one request, for responses that return
+// them as debug output. It is safe for concurrent use.
+type QueryPlans struct {
+	mu    sync.Mutex
+	plans []QueryPlan
+}
+
+type queryPlansKey struct{}
+
+// WithQueryPlans returns a context whose slow queries record their plans in the returned QueryPlans.
+func WithQueryPlans(ctx context.Context) (context.Context, *QueryPlans) {
+	plans := &QueryPlans{}
+	return context.WithValue(ctx, queryPlansKey{}, plans), plans
+}
+
+// QueryPlansFromContext returns the QueryPlans of ctx, or nil when the request didn't ask for plans.
+func QueryPlansFromContext(ctx context.Context) *QueryPlans {
+	plans, _ := ctx.Value(queryPlansKey{}).(*QueryPlans)
+	return plans
+}
+
+// Add records a plan.
+func (p *QueryPlans) Add(plan QueryPlan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.plans = append(p.plans, plan)
+}
+
+// All returns the recorded plans in the order they were captured; a nil QueryPlans has none.
+func (p *QueryPlans) All() []QueryPlan {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]QueryPlan(nil), p.plans...)
+}
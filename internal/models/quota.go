@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Quota is the number of requests an API key may make per UTC day and per UTC month; 0 leaves
+// that period unlimited.
+type Quota struct {
+	Daily   int64
+	Monthly int64
+}
+
+// QuotaUsage is where an API key stands after a request, in whichever of its quota periods has
+// the fewest requests left.
+type QuotaUsage struct {
+	// Limit is the quota of that period; it is 0 when the key has no quota
+	Limit int64
+	// Remaining is the number of requests left in that period
+	Remaining int64
+	// Reset is when that period ends and its count starts over
+	Reset time.Time
+	// Exceeded is set when the request went over the quota and must be refused
+	Exceeded bool
+}
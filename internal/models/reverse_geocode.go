@@ -0,0 +1,66 @@
+package models
+
+// DefaultMaxRadiusMeters is the largest search radius spatial queries accept when none is configured.
+const DefaultMaxRadiusMeters = 10000.0
+
+// DefaultColocationMeters is the distance within which reverse geocoding groups addresses into
+// one building when none is configured.
+const DefaultColocationMeters = 5.0
+
+// DefaultTieMeters is how much farther than the nearest address a reverse geocode candidate may
+// be and still count as equidistant for a SortPreference, when none is configured.
+const DefaultTieMeters = 1.0
+
+// Keys of the reverse geocode sort preferences
+const (
+	// SortByDistance orders candidates by distance alone, the default
+	SortByDistance = "distance"
+	// SortByBlockLot prefers candidates with a block/lot number over ones without
+	SortByBlockLot = "block_lot"
+	// SortByMunicipality prefers candidates in SortPreference.Municipality
+	SortByMunicipality = "municipality"
+)
+
+// SortPreferenceKeys are the valid SortPreference keys, in documentation order
+var SortPreferenceKeys = []string{SortByDistance, SortByBlockLot, SortByMunicipality}
+
+// SortPreference breaks ties among reverse geocode candidates about as near as the nearest one,
+// choosing which of several near-equidistant addresses comes first.
+type SortPreference struct {
+	// Key is one of SortPreferenceKeys; empty means SortByDistance
+	Key string
+	// Municipality is the municipality SortByMunicipality prefers
+	Municipality string
+}
+
+// Prefers reports whether loc is one of the candidates the preference puts first
+func (p SortPreference) Prefers(loc Location) bool {
+	switch p.Key {
+	case SortByBlockLot:
+		return loc.BlockLot != ""
+	case SortByMunicipality:
+		return loc.Municipality == p.Municipality
+	}
+	return false
+}
+
+// ReverseGeocodeResult is the nearest location plus the further neighbours returned as context
+// when requested, each with its distance from the queried point.
+type ReverseGeocodeResult struct {
+	Location Location   `json:"location"`
+	Context  []Location `json:"context"`
+}
+
+// WithinResult lists the locations inside a bounding box, up to a limit; Truncated reports that
+// the box holds more, so clients know to zoom in.
+type WithinResult struct {
+	Results   []Location `json:"results"`
+	Truncated bool       `json:"truncated"`
+}
+
+// ColocatedLocation is the nearest location together with the other addresses sharing its
+// building, such as the units of an apartment block, nearest to it first.
+type ColocatedLocation struct {
+	Location
+	Colocated []Location `json:"colocated"`
+}
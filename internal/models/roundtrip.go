@@ -0,0 +1,13 @@
+package models
+
+// RoundTripResult reports whether reverse geocoding an address's top geocode match leads back to
+// the same address. Geocoded is nil when the query matched nothing and Reversed when nothing was
+// found near the match; either way Match is false.
+type RoundTripResult struct {
+	Query    string    `json:"query"`
+	Geocoded *Location `json:"geocoded"`
+	Reversed *Location `json:"reversed"`
+	Match    bool      `json:"match"`
+	// Mismatches lists the address components (by JSON name) that differ between the two locations
+	Mismatches []string `json:"mismatches"`
+}
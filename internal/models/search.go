@@ -0,0 +1,123 @@
+package models
+
+import "encoding/json"
+
+const (
+	// DefaultSRID is WGS84 lat/lon, the SRID results are always returned in.
+	DefaultSRID = 4326
+
+	// DefaultSearchLimit is the number of geocode results returned when SearchOptions.Limit is unset.
+	DefaultSearchLimit = 10
+	// MaxSearchLimit is the largest SearchOptions.Limit accepted.
+	MaxSearchLimit = 100
+)
+
+// Result orders accepted by SearchOptions.OrderBy.
+const (
+	// OrderByRelevance ranks results by how well they match the query (ts_rank); the default.
+	OrderByRelevance = "relevance"
+	// OrderByImportance ranks results by the importance the importer stored for each address
+	// (e.g. population), most important first, breaking ties by relevance. Addresses without an
+	// importance come after those with one.
+	OrderByImportance = "importance"
+)
+
+// SearchOptions carries the parameters of a geocode query from the handler through the service to
+// the repository. Every field except Query is optional; its zero value selects the default.
+type SearchOptions struct {
+	Query   string
+	Suggest bool
+	Limit   int
+	Offset  int
+	// MaxLimit is the largest Limit accepted, raised for response formats that stream more
+	// results than a JSON array should hold; 0 keeps MaxSearchLimit.
+	MaxLimit int
+	// After continues a keyset-paginated search after the given result; it can't be combined with Offset.
+	After *SearchCursor
+
+	// IncludeBBox attaches each result's municipality extent.
+	IncludeBBox bool
+	// IncludeDensity attaches to each result the number of locations around it. It costs a
+	// spatial count per result, so the service caps Limit while it is set.
+	IncludeDensity bool
+	// IncludeTotal counts every location the query matches, for clients paging with Offset. It
+	// costs a second query, and only full-text results can be counted.
+	IncludeTotal bool
+	// SRID additionally returns each result's coordinates transformed to this SRID; 0 or
+	// DefaultSRID returns only latitude/longitude.
+	SRID int
+
+	// Prefecture and Municipality restrict results to exact administrative area matches.
+	Prefecture   string
+	Municipality string
+
+	// Fields limits each result to these LocationFields; empty returns every field.
+	Fields []string
+
+	// OrderBy is OrderByRelevance or OrderByImportance; empty orders by relevance.
+	OrderBy string
+
+	// NoFuzzy skips the fuzzy (trigram similarity) strategy of the search pipeline, for callers
+	// that only want matches of the query as written.
+	NoFuzzy bool
+
+	// BBox restricts results to locations inside the box, e.g. a map viewport; nil searches everywhere.
+	BBox *BoundingBox
+}
+
+// BoundingBox is a WGS84 rectangle, written min_lon,min_lat,max_lon,max_lat like a GeoJSON bbox.
+type BoundingBox struct {
+	MinLon float64 `json:"min_lon"`
+	MinLat float64 `json:"min_lat"`
+	MaxLon float64 `json:"max_lon"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// Valid reports whether the box's coordinates are in range and each minimum is below its maximum.
+func (b BoundingBox) Valid() bool {
+	return b.MinLon >= -180 && b.MaxLon <= 180 && b.MinLat >= -90 && b.MaxLat <= 90 &&
+		b.MinLon < b.MaxLon && b.MinLat < b.MaxLat
+}
+
+// Contains reports whether a point is inside the box. Like ST_Within, points on its edges are not.
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lon > b.MinLon && lon < b.MaxLon && lat > b.MinLat && lat < b.MaxLat
+}
+
+// LocationFields are the JSON names of the Location fields a search can be limited to with SearchOptions.Fields.
+var LocationFields = []string{"id", "external_id", "prefecture", "municipality", "address1", "address2", "block_lot", "latitude", "longitude", "altitude",
+	"prefecture_kana", "municipality_kana", "address1_kana", "address2_kana"}
+
+// IsLocationField reports whether name is one of LocationFields.
+func IsLocationField(name string) bool {
+	for _, f := range LocationFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitCap returns the largest Limit the options accept: MaxLimit, or MaxSearchLimit when it is unset.
+func (o SearchOptions) LimitCap() int {
+	if o.MaxLimit > 0 {
+		return o.MaxLimit
+	}
+	return MaxSearchLimit
+}
+
+// WithDefaults returns a copy of the options with unset fields replaced by their defaults.
+func (o SearchOptions) WithDefaults() SearchOptions {
+	if o.Limit == 0 {
+		o.Limit = DefaultSearchLimit
+	}
+	return o
+}
+
+// CacheKey returns a canonical serialization of every option after defaults are applied, so
+// requests that differ in any option (e.g. only in Offset) get different keys.
+func (o SearchOptions) CacheKey() string {
+	// Marshalling a struct of plain fields can't fail and always emits fields in declaration order
+	key, _ := json.Marshal(o.WithDefaults())
+	return string(key)
+}
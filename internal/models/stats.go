@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RuntimeStats is an in-process snapshot of one API instance's counters since it started
+type RuntimeStats struct {
+	StartedAt     time.Time    `json:"started_at"`
+	UptimeSeconds float64      `json:"uptime_seconds"`
+	Goroutines    int          `json:"goroutines"`
+	Requests      RequestStats `json:"requests"`
+	Cache         CacheStats   `json:"cache"`
+	// DatabaseBreaker is set only when the database circuit breaker is enabled
+	DatabaseBreaker *BreakerStats `json:"database_breaker,omitempty"`
+}
+
+// RequestStats counts the HTTP requests handled; Total counts finished requests, and the
+// error counts those answered with a 4xx or 5xx status
+type RequestStats struct {
+	InFlight     int64 `json:"in_flight"`
+	Total        int64 `json:"total"`
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+}
+
+// CacheStats counts geocode result cache lookups; HitRatio is 0 before the first lookup
+type CacheStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// BreakerStats reports the database circuit breaker: its state ("closed", "open" or "half_open"),
+// how often it opened and the queries it rejected while open
+type BreakerStats struct {
+	State    string `json:"state"`
+	Opens    int64  `json:"opens"`
+	Rejected int64  `json:"rejected"`
+}
@@ -0,0 +1,173 @@
+// Package normalize rewrites Japanese address text into a canonical form shared by the importer
+// and the API, so that differently written addresses tokenize identically.
+//
+// The importer stores what these rules produce (SearchText, SearchKey and Kana), so changing them
+// makes the stored text and keys stale: reimport the data, or recompute the columns, before
+// serving queries normalized by the new rules.
+package normalize
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// kanjiNumberPattern matches kanji numerals used as an address number, i.e. directly before a
+	// unit; Address only converts the bare 番 where blockNumberFollows
+	kanjiNumberPattern = regexp.MustCompile(`[〇一二三四五六七八九十百千]+(丁目|番地|番|号)`)
+	// kanjiBlockNumberPattern is kanjiNumberPattern without the bare 番, which also ends town
+	// names such as 三番町
+	kanjiBlockNumberPattern = regexp.MustCompile(`[〇一二三四五六七八九十百千]+(丁目|番地|号)`)
+	// hyphenPattern matches the dash variants seen between address numbers, and ASCII hyphens
+	// only when padded with spaces so the canonical "1-2" no longer matches
+	hyphenPattern = regexp.MustCompile(`(\d)(?:\s*[‐‑‒–—―−ーｰ－]\s*|\s+-\s*|-\s+)(\d)`)
+	// unitSeparatorPattern matches a unit that is followed by another number, e.g. 丁目 in "1丁目2"
+	unitSeparatorPattern = regexp.MustCompile(`(\d+)(?:丁目|番地|番|号)\s*(\d)`)
+	// unitSuffixPattern matches a trailing unit, e.g. 号 in "3号"
+	unitSuffixPattern = regexp.MustCompile(`(\d+)(?:丁目|番地|番|号)`)
+)
+
+// kanjiDigits maps kanji numerals to their values; 十, 百 and 千 are handled separately as multipliers
+var kanjiDigits = map[rune]int{
+	'〇': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// Address canonicalizes the numeric notation of a Japanese address: full-width digits, letters
+// and spaces become ASCII (HalfWidth), kanji numerals before 丁目/番地/番/号 become arabic, and
+// chome/banchi/go sequences and dash variants collapse to hyphens. "1丁目2番3号", "一丁目二番三号",
+// "１－２－３" and "1-2-3" all become "1-2-3"; "丸の内一丁目" becomes "丸の内1" and "百二十三番地"
+// becomes "123". Kanji numerals before a bare 番 are only converted after a chome or before
+// another number, since town names such as 三番町 and 麻布十番 end in it. Other text is left
+// unchanged.
+func Address(s string) string {
+	s = HalfWidth(s)
+	s = replaceKanjiNumbersFunc(s, kanjiNumberPattern, blockNumberFollows)
+
+	// Each pass rewrites every other separator, since adjacent matches share a digit
+	for _, pattern := range []*regexp.Regexp{hyphenPattern, unitSeparatorPattern} {
+		for {
+			replaced := pattern.ReplaceAllString(s, "$1-$2")
+			if replaced == s {
+				break
+			}
+			s = replaced
+		}
+	}
+
+	return unitSuffixPattern.ReplaceAllString(s, "$1")
+}
+
+// KanjiToArabic rewrites the kanji numerals directly before 丁目, 番地 or 号 as arabic numerals,
+// keeping the unit: "丸の内一丁目" becomes "丸の内1丁目" and "二十三番地" becomes "23番地". Kanji
+// numerals elsewhere are part of place names and are left unchanged, like 四谷, 八丁堀 and 三番町.
+func KanjiToArabic(s string) string {
+	return replaceKanjiNumbers(s, kanjiBlockNumberPattern)
+}
+
+// replaceKanjiNumbers rewrites the kanji numerals of every match of pattern, whose first group
+// is the unit following them, as arabic numerals; matches that don't parse are kept
+func replaceKanjiNumbers(s string, pattern *regexp.Regexp) string {
+	return replaceKanjiNumbersFunc(s, pattern, func(string, int, int) bool { return true })
+}
+
+// replaceKanjiNumbersFunc is replaceKanjiNumbers converting only the matches s[start:end] for
+// which convert reports true
+func replaceKanjiNumbersFunc(s string, pattern *regexp.Regexp, convert func(s string, start, end int) bool) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		unit := s[loc[2]:loc[3]]
+		n, ok := parseKanjiNumber(s[start:loc[2]])
+		if !ok || !convert(s, start, end) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(strconv.Itoa(n) + unit)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// blockNumberFollows reports whether the kanji number s[start:end] is an address number: always
+// unless its unit is the bare 番, which also ends town names (三番町, 麻布十番) and so only counts
+// after a chome ("一丁目二番") or before another number that isn't a chome ("二番三号", "二番 3",
+// but not "麻布十番二丁目")
+func blockNumberFollows(s string, start, end int) bool {
+	if !strings.HasSuffix(s[start:end], "番") {
+		return true
+	}
+	if strings.HasSuffix(s[:start], "丁目") {
+		return true
+	}
+	rest := strings.TrimLeft(s[end:], " ")
+	number := strings.TrimLeftFunc(rest, isNumeral)
+	return len(number) < len(rest) && !strings.HasPrefix(number, "丁目")
+}
+
+// isNumeral reports whether r is an ASCII digit or a kanji numeral
+func isNumeral(r rune) bool {
+	_, kanji := kanjiDigits[r]
+	return kanji || strings.ContainsRune("0123456789十百千", r)
+}
+
+// kanjiMultipliers are the kanji numerals that multiply the digit before them, largest first
+var kanjiMultipliers = []struct {
+	numeral string
+	value   int
+}{
+	{"千", 1000}, {"百", 100}, {"十", 10},
+}
+
+// parseKanjiNumber parses kanji numerals below 10000, written either positionally ("二〇",
+// "一〇五") or with 十, 百 and 千 ("二十", "十五", "百二十三", "千二百"). A multiplier without a
+// digit before it counts once, and each may appear at most once, in decreasing order.
+func parseKanjiNumber(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if !strings.ContainsAny(s, "十百千") {
+		return parseKanjiDigits(s)
+	}
+
+	n := 0
+	for _, m := range kanjiMultipliers {
+		head, tail, ok := strings.Cut(s, m.numeral)
+		if !ok {
+			continue
+		}
+		digit := 1
+		if head != "" {
+			v, ok := parseKanjiDigits(head)
+			if !ok || v > 9 {
+				return 0, false
+			}
+			digit = v
+		}
+		n += digit * m.value
+		s = tail
+	}
+	if s != "" {
+		v, ok := parseKanjiDigits(s)
+		if !ok || v > 9 {
+			return 0, false
+		}
+		n += v
+	}
+	return n, true
+}
+
+// parseKanjiDigits parses positional kanji digits such as "一二" (12)
+func parseKanjiDigits(s string) (int, bool) {
+	n := 0
+	for _, r := range s {
+		d, ok := kanjiDigits[r]
+		if !ok {
+			return 0, false
+		}
+		n = n*10 + d
+	}
+	return n, true
+}
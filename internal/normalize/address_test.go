@@ -0,0 +1,105 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "already canonical", input: "1-2-3", expected: "1-2-3"},
+		{name: "chome ban go", input: "1丁目2番3号", expected: "1-2-3"},
+		{name: "chome banchi", input: "1丁目2番地", expected: "1-2"},
+		{name: "banchi go", input: "2番地3号", expected: "2-3"},
+		{name: "kanji numerals", input: "一丁目二番三号", expected: "1-2-3"},
+		{name: "kanji ten", input: "十丁目", expected: "10"},
+		{name: "kanji tens and ones", input: "二十三番地", expected: "23"},
+		{name: "kanji teens", input: "十五番三号", expected: "15-3"},
+		{name: "kanji positional", input: "二〇番一号", expected: "20-1"},
+		{name: "kanji positional hundreds", input: "一〇五番地", expected: "105"},
+		{name: "kanji hundred", input: "百番地", expected: "100"},
+		{name: "kanji hundreds tens and ones", input: "百二十三番地", expected: "123"},
+		{name: "kanji hundreds and ones", input: "二百五番一号", expected: "205-1"},
+		{name: "kanji thousands", input: "千二百番地", expected: "1200"},
+		{name: "kanji thousands full", input: "三千四百五十六番地", expected: "3456"},
+		{name: "kanji multipliers out of order kept", input: "十百番地", expected: "十百番地"},
+		{name: "kanji repeated multiplier kept", input: "十二十番", expected: "十二十番"},
+		{name: "full-width digits and hyphens", input: "１－２－３", expected: "1-2-3"},
+		{name: "full-width letters and ideographic spaces", input: "Ａ棟　１　－　２", expected: "A棟 1-2"},
+		{name: "katakana long vowel as dash", input: "1ー2ー3", expected: "1-2-3"},
+		{name: "unicode minus and hyphen", input: "1−2‐3", expected: "1-2-3"},
+		{name: "spaces around dashes", input: "1 - 2 - 3", expected: "1-2-3"},
+		{name: "mixed notation", input: "1丁目2-3", expected: "1-2-3"},
+		{name: "with town name", input: "丸の内一丁目", expected: "丸の内1"},
+		{name: "full address", input: "東京都千代田区丸の内一丁目9番1号", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "kanji ban after chome", input: "一丁目十五番", expected: "1-15"},
+		{name: "town name with ban kept", input: "千代田区三番町", expected: "千代田区三番町"},
+		{name: "town name with ban before chome", input: "仙台市青葉区一番町四丁目", expected: "仙台市青葉区一番町4"},
+		{name: "town name with hundreds and ban kept", input: "千代田区五番町", expected: "千代田区五番町"},
+		{name: "town name ending in ban kept", input: "港区麻布十番", expected: "港区麻布十番"},
+		{name: "town name ending in ban before chome", input: "港区麻布十番二丁目", expected: "港区麻布十番2"},
+		{name: "town name with hundred and ban kept", input: "百番町", expected: "百番町"},
+		{name: "town name with thousand and ban kept", input: "千番町", expected: "千番町"},
+		{name: "kanji not followed by unit kept", input: "三田", expected: "三田"},
+		{name: "katakana long vowel in words kept", input: "センター1", expected: "センター1"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Address(tt.input))
+		})
+	}
+}
+
+func TestKanjiToArabic(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "chome", input: "丸の内一丁目", expected: "丸の内1丁目"},
+		{name: "ten", input: "十丁目", expected: "10丁目"},
+		{name: "teens", input: "十一丁目", expected: "11丁目"},
+		{name: "tens and ones", input: "二十三番地", expected: "23番地"},
+		{name: "hundreds", input: "百二十三号", expected: "123号"},
+		{name: "full address", input: "東京都千代田区丸の内一丁目九番地一号", expected: "東京都千代田区丸の内1丁目9番地1号"},
+		{name: "place name before the number", input: "四谷三丁目", expected: "四谷3丁目"},
+		{name: "place name alone", input: "四谷", expected: "四谷"},
+		{name: "place name ending in 丁", input: "八丁堀二丁目", expected: "八丁堀2丁目"},
+		{name: "place name ending in 番", input: "三番町五番地", expected: "三番町5番地"},
+		{name: "place name full of numerals", input: "八王子市千人町", expected: "八王子市千人町"},
+		{name: "unparsable numerals kept", input: "十百番地", expected: "十百番地"},
+		{name: "arabic numerals unchanged", input: "丸の内1丁目", expected: "丸の内1丁目"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, KanjiToArabic(tt.input))
+		})
+	}
+}
+
+func TestAddress_EquivalentNotations(t *testing.T) {
+	notations := []string{"1-2-3", "1丁目2番3号", "一丁目二番三号", "１－２－３", "1丁目2番地3号", "１丁目２－３"}
+	for _, n := range notations {
+		assert.Equal(t, Address(notations[0]), Address(n), n)
+	}
+
+	// Town names keep their kanji, only the numbers before units are rewritten
+	notations = []string{"三田3丁目12番地", "三田三丁目十二番地", "三田３丁目１２番地", "三田三丁目一二番地", "三田3-12"}
+	for _, n := range notations {
+		assert.Equal(t, "三田3-12", Address(n), n)
+	}
+
+	notations = []string{"大手町1丁目123番", "大手町一丁目百二十三番", "大手町一丁目一二三番", "大手町１－１２３"}
+	for _, n := range notations {
+		assert.Equal(t, "大手町1-123", Address(n), n)
+	}
+}
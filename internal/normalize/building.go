@@ -0,0 +1,48 @@
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// buildingKeywordPattern matches words that mark a building name. Single-kanji suffixes such
+	// as 荘 or 館 are left out since they also occur in place names (館山市, 本荘).
+	buildingKeywordPattern = regexp.MustCompile(`ビル|マンション|ハイツ|コーポ|アパート|レジデンス|タワー|ヒルズ`)
+	// addressNumberPattern matches an address number, e.g. "1", "9番" or "一丁目"
+	addressNumberPattern = regexp.MustCompile(`[0-9０-９]+(?:丁目|番地|番|号)?|[〇一二三四五六七八九十百千]+(?:丁目|番地|番|号)`)
+	// addressNumberEndPattern matches text ending in an address number
+	addressNumberEndPattern = regexp.MustCompile(`(?:[0-9０-９]+(?:丁目|番地|番|号)?|[〇一二三四五六七八九十百千]+(?:丁目|番地|番|号))$`)
+)
+
+// SplitBuilding separates a trailing building name from an address query, since building names
+// aren't in the address data and only drag down the match: "東京都千代田区丸の内1-1 ○○ビル 3F"
+// becomes ("東京都千代田区丸の内1-1", "○○ビル 3F"). A building is only split off when it contains
+// a building word (ビル, マンション, ...) and follows an address number, so names like
+// "六本木ヒルズ" without a preceding number are left alone and building is empty.
+func SplitBuilding(s string) (address, building string) {
+	keyword := buildingKeywordPattern.FindStringIndex(s)
+	if keyword == nil {
+		return s, ""
+	}
+	head := s[:keyword[0]]
+
+	// Prefer the last space before the building word, so "1-1 第2ビル" keeps 第2 with the building
+	if space := strings.LastIndexFunc(head, unicode.IsSpace); space >= 0 {
+		candidate := strings.TrimRightFunc(head[:space], unicode.IsSpace)
+		if addressNumberEndPattern.MatchString(candidate) {
+			_, size := utf8.DecodeRuneInString(head[space:])
+			return candidate, strings.TrimSpace(s[space+size:])
+		}
+	}
+
+	// Otherwise the building starts right after the last address number
+	numbers := addressNumberPattern.FindAllStringIndex(head, -1)
+	if len(numbers) == 0 {
+		return s, ""
+	}
+	end := numbers[len(numbers)-1][1]
+	return strings.TrimSpace(head[:end]), strings.TrimSpace(s[end:])
+}
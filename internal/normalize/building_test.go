@@ -0,0 +1,37 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBuilding(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedAddress  string
+		expectedBuilding string
+	}{
+		{name: "building after space", input: "東京都千代田区丸の内1-1 丸の内ビル", expectedAddress: "東京都千代田区丸の内1-1", expectedBuilding: "丸の内ビル"},
+		{name: "full-width space", input: "丸の内1-1　丸の内ビル", expectedAddress: "丸の内1-1", expectedBuilding: "丸の内ビル"},
+		{name: "no space", input: "丸の内1-1丸の内ビル", expectedAddress: "丸の内1-1", expectedBuilding: "丸の内ビル"},
+		{name: "floor and room kept with building", input: "赤坂1-2-3 赤坂マンション 501", expectedAddress: "赤坂1-2-3", expectedBuilding: "赤坂マンション 501"},
+		{name: "numbered building", input: "丸の内1-1 第2ビル", expectedAddress: "丸の内1-1", expectedBuilding: "第2ビル"},
+		{name: "chome ban go", input: "丸の内一丁目9番1号 グランドタワー", expectedAddress: "丸の内一丁目9番1号", expectedBuilding: "グランドタワー"},
+		{name: "kanji chome only", input: "丸の内一丁目丸の内ハイツ", expectedAddress: "丸の内一丁目", expectedBuilding: "丸の内ハイツ"},
+		{name: "kanji hundreds", input: "大手町百二十番地大手町タワー", expectedAddress: "大手町百二十番地", expectedBuilding: "大手町タワー"},
+		{name: "no building", input: "東京都千代田区丸の内1-1", expectedAddress: "東京都千代田区丸の内1-1"},
+		{name: "building word without address number", input: "六本木ヒルズ", expectedAddress: "六本木ヒルズ"},
+		{name: "place name with building kanji", input: "館山市北条1-1", expectedAddress: "館山市北条1-1"},
+		{name: "empty", input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, building := SplitBuilding(tt.input)
+			assert.Equal(t, tt.expectedAddress, address)
+			assert.Equal(t, tt.expectedBuilding, building)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package normalize
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Kana reduces a kana reading to the key stored in the locations.kana_key column and matched by
+// the "kana" search strategy: NFKC widens half-width katakana, hiragana becomes katakana and
+// whitespace and middle dots are removed. "ﾁﾖﾀﾞｸ", "ちよだく" and "チヨダ ク" share the key "チヨダク".
+func Kana(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r) || r == '・':
+			return -1
+		case r >= 'ぁ' && r <= 'ゖ' || r == 'ゝ' || r == 'ゞ':
+			// The hiragana block mirrors the katakana one 0x60 code points lower
+			return r + 0x60
+		}
+		return r
+	}, norm.NFKC.String(s))
+}
+
+// IsKana reports whether s is a kana reading, i.e. whether its Kana key is non-empty and made of
+// katakana and prolonged sound marks only
+func IsKana(s string) bool {
+	key := Kana(s)
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'ァ' && r <= 'ヺ') && r != 'ー' && r != 'ヽ' && r != 'ヾ' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKana(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "katakana unchanged", input: "チヨダク", expected: "チヨダク"},
+		{name: "hiragana", input: "ちよだく", expected: "チヨダク"},
+		{name: "half-width katakana", input: "ﾁﾖﾀﾞｸ", expected: "チヨダク"},
+		{name: "whitespace and middle dots removed", input: "トウキョウト　チヨダク・マルノウチ", expected: "トウキョウトチヨダクマルノウチ"},
+		{name: "prolonged sound mark kept", input: "せんたーみなみ", expected: "センターミナミ"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Kana(tt.input))
+		})
+	}
+}
+
+func TestIsKana(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{name: "katakana", input: "マルノウチ", expected: true},
+		{name: "hiragana with spaces", input: "ちよだく まるのうち", expected: true},
+		{name: "half-width katakana", input: "ﾏﾙﾉｳﾁ", expected: true},
+		{name: "kanji", input: "丸の内", expected: false},
+		{name: "kana and digits", input: "マルノウチ1", expected: false},
+		{name: "whitespace only", input: "　", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsKana(tt.input))
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package normalize
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SearchText rewrites address text into the form the importer's --app-search-text tokenizes:
+// NFKC folds full-width letters, digits and symbols and half-width katakana, a trailing building
+// name is dropped (SplitBuilding), the numeric notation is canonicalized (Address) and runs of
+// whitespace become single spaces. "丸の内一丁目９番１号　○○ビル" becomes "丸の内1-9-1".
+func SearchText(s string) string {
+	s = norm.NFKC.String(s)
+	s, _ = SplitBuilding(s)
+	return strings.Join(strings.Fields(Address(s)), " ")
+}
+
+// SearchKey reduces an address to the key stored in the locations.normalized_address column and
+// matched by the "normalized" search strategy: its SearchText with whitespace removed.
+// "東京都千代田区丸の内一丁目９番１号 ○○ビル" and "東京都 千代田区 丸の内1-9-1" share the key
+// "東京都千代田区丸の内1-9-1".
+func SearchKey(s string) string {
+	return strings.ReplaceAll(SearchText(s), " ", "")
+}
@@ -0,0 +1,51 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "already canonical", input: "東京都千代田区丸の内1-9-1", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "kanji numerals", input: "東京都千代田区丸の内一丁目9番1号", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "full-width digits", input: "東京都千代田区丸の内１－９－１", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "whitespace removed", input: "東京都 千代田区　丸の内 1-9-1", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "building stripped", input: "東京都千代田区丸の内1-9-1 ○○ビル 3F", expected: "東京都千代田区丸の内1-9-1"},
+		{name: "full-width letters", input: "ＡＢＣ町1-2", expected: "ABC町1-2"},
+		{name: "half-width katakana", input: "ｾﾝﾀｰ南1-2", expected: "センター南1-2"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SearchKey(tt.input))
+		})
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "already canonical", input: "丸の内1-9-1", expected: "丸の内1-9-1"},
+		{name: "kanji numerals and full-width digits", input: "丸の内一丁目９番１号", expected: "丸の内1-9-1"},
+		{name: "whitespace collapsed", input: "  丸の内　1-9-1  ", expected: "丸の内 1-9-1"},
+		{name: "building stripped", input: "丸の内1-9-1 ○○ビル 3F", expected: "丸の内1-9-1"},
+		{name: "half-width katakana", input: "ｾﾝﾀｰ南", expected: "センター南"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SearchText(tt.input))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package normalize
+
+import "strings"
+
+// HalfWidth converts full-width digits (０-９), latin letters (Ａ-Ｚ, ａ-ｚ) and ideographic spaces
+// to their ASCII equivalents, the way the imported data writes them: "丸の内１丁目" becomes
+// "丸の内1丁目" and "ＪＲ　東京駅" becomes "JR 東京駅". Other text, including full-width
+// punctuation and kana, is left unchanged.
+func HalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+			// The full-width forms mirror ASCII 0xFEE0 code points higher
+			return r - 0xFEE0
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, s)
+}
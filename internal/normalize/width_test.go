@@ -0,0 +1,30 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHalfWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "full-width digits", input: "東京都千代田区丸の内１丁目", expected: "東京都千代田区丸の内1丁目"},
+		{name: "full-width letters", input: "ＪＲｔｏｗｅｒ", expected: "JRtower"},
+		{name: "ideographic space", input: "千代田区　丸の内", expected: "千代田区 丸の内"},
+		{name: "punctuation and kana unchanged", input: "１－２ カタカナ ｶﾀｶﾅ", expected: "1－2 カタカナ ｶﾀｶﾅ"},
+		{name: "already half-width", input: "東京都千代田区丸の内1丁目", expected: "東京都千代田区丸の内1丁目"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HalfWidth(tt.input)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, got, HalfWidth(got), "converting twice changes nothing")
+		})
+	}
+}
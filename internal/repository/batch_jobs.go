@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateBatchJob stores a queued batch job for the addresses
+func (r *Repository) CreateBatchJob(ctx context.Context, id string, addresses []string) (*models.BatchJob, error) {
+	sql := `
+		INSERT INTO geocode_jobs (id, status, addresses, total)
+		VALUES ($1, 'queued', $2, $3)
+		RETURNING status, total, processed, created_at, updated_at
+	`
+
+	job := models.BatchJob{ID: id}
+	defer r.logSlowQuery(ctx, "CreateBatchJob", time.Now(), id, len(addresses))
+	err := r.db.QueryRow(ctx, sql, id, addresses, len(addresses)).Scan(
+		&job.Status,
+		&job.Total,
+		&job.Processed,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to create batch job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// GetBatchJob returns the status of the batch job, or nil when there is no job with that ID
+func (r *Repository) GetBatchJob(ctx context.Context, id string) (*models.BatchJob, error) {
+	sql := `
+		SELECT status, total, processed, coalesce(error, ''), created_at, updated_at, finished_at
+		FROM geocode_jobs
+		WHERE id = $1
+	`
+
+	job := models.BatchJob{ID: id}
+	defer r.logSlowQuery(ctx, "GetBatchJob", time.Now(), id)
+	err := r.db.QueryRow(ctx, sql, id).Scan(
+		&job.Status,
+		&job.Total,
+		&job.Processed,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to query batch job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// BatchJobResults returns the results of a succeeded batch job; they are nil until it succeeds
+func (r *Repository) BatchJobResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	sql := `
+		SELECT results
+		FROM geocode_jobs
+		WHERE id = $1
+	`
+
+	var results []models.BatchResult
+	defer r.logSlowQuery(ctx, "BatchJobResults", time.Now(), id)
+	err := r.db.QueryRow(ctx, sql, id).Scan(&results)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("repository: failed to query batch job results: %w", err)
+	}
+
+	return results, nil
+}
+
+// ClaimBatchJob marks the oldest queued job as running and returns it with its addresses, or
+// nil when there is nothing to do. A running job not updated for staleAfter is claimed again
+// from the start, since its worker has stopped. SKIP LOCKED lets several API instances claim
+// concurrently without handing the same job to two workers.
+func (r *Repository) ClaimBatchJob(ctx context.Context, staleAfter time.Duration) (*models.BatchJob, error) {
+	sql := `
+		UPDATE geocode_jobs
+		SET status = 'running', processed = 0, updated_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM geocode_jobs
+			WHERE status = 'queued'
+				OR (status = 'running' AND updated_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, status, addresses, total, processed, created_at, updated_at
+	`
+
+	var job models.BatchJob
+	defer r.logSlowQuery(ctx, "ClaimBatchJob", time.Now(), staleAfter)
+	err := r.db.QueryRow(ctx, sql, staleAfter.Seconds()).Scan(
+		&job.ID,
+		&job.Status,
+		&job.Addresses,
+		&job.Total,
+		&job.Processed,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to claim batch job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// UpdateBatchJobProgress records how many addresses of a running job have been geocoded. It
+// also refreshes updated_at, which keeps the job from being reclaimed as stale.
+func (r *Repository) UpdateBatchJobProgress(ctx context.Context, id string, processed int) error {
+	sql := `
+		UPDATE geocode_jobs
+		SET processed = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	defer r.logSlowQuery(ctx, "UpdateBatchJobProgress", time.Now(), id, processed)
+	if _, err := r.db.Exec(ctx, sql, id, processed); err != nil {
+		return fmt.Errorf("repository: failed to update batch job progress: %w", err)
+	}
+	return nil
+}
+
+// CompleteBatchJob stores the results of a running job and marks it as succeeded
+func (r *Repository) CompleteBatchJob(ctx context.Context, id string, results []models.BatchResult) error {
+	sql := `
+		UPDATE geocode_jobs
+		SET status = 'succeeded', results = $2, processed = total, updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	defer r.logSlowQuery(ctx, "CompleteBatchJob", time.Now(), id, len(results))
+	if _, err := r.db.Exec(ctx, sql, id, results); err != nil {
+		return fmt.Errorf("repository: failed to complete batch job: %w", err)
+	}
+	return nil
+}
+
+// FailBatchJob marks a running job as failed with the reason reported to its submitter
+func (r *Repository) FailBatchJob(ctx context.Context, id string, reason string) error {
+	sql := `
+		UPDATE geocode_jobs
+		SET status = 'failed', error = $2, updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	defer r.logSlowQuery(ctx, "FailBatchJob", time.Now(), id, reason)
+	if _, err := r.db.Exec(ctx, sql, id, reason); err != nil {
+		return fmt.Errorf("repository: failed to fail batch job: %w", err)
+	}
+	return nil
+}
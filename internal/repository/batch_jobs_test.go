@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_ClaimBatchJob(t *testing.T) {
+	created := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("claims the oldest claimable job", func(t *testing.T) {
+		db := &fakeQuerier{rows: [][]any{{"job-1", models.BatchJobRunning, []string{"東京都千代田区丸の内1"}, 1, 0, created, created}}}
+		repo := NewRepository(db, Config{})
+
+		job, err := repo.ClaimBatchJob(context.Background(), 5*time.Minute)
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.BatchJob{
+			ID:        "job-1",
+			Status:    models.BatchJobRunning,
+			Total:     1,
+			Addresses: []string{"東京都千代田区丸の内1"},
+			CreatedAt: created,
+			UpdatedAt: created,
+		}, job)
+		assert.Equal(t, []any{300.0}, db.args)
+		assert.Contains(t, db.sql, "FOR UPDATE SKIP LOCKED")
+		assert.Contains(t, db.sql, "status = 'running' AND updated_at < NOW() - make_interval(secs => $1)")
+	})
+
+	t.Run("empty queue", func(t *testing.T) {
+		repo := NewRepository(&fakeQuerier{}, Config{})
+
+		job, err := repo.ClaimBatchJob(context.Background(), 5*time.Minute)
+
+		require.NoError(t, err)
+		assert.Nil(t, job)
+	})
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FindNearestLocationInMunicipality is FindNearestLocation restricted to the municipality whose
+// boundary polygon contains the point, so an address just across a boundary doesn't win over a
+// slightly farther one on the point's side. It returns nil when no polygon contains the point or
+// no address of that municipality is within radius meters.
+func (r *Repository) FindNearestLocationInMunicipality(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (_ *models.Location, err error) {
+	sql := `
+		SELECT
+			l.id,
+			coalesce(l.external_id, '') as external_id,
+			l.prefecture,
+			l.municipality,
+			l.address_1,
+			l.address_2,
+			l.block_lot,
+			ST_Y(l.geom) as latitude,
+			ST_X(l.geom) as longitude,
+			coalesce(l.source_file, '') as source,
+			ST_Distance(l.geom, ST_SetSRID(ST_MakePoint($2, $1), 4326)) as distance,
+			ST_Z(l.geom) as altitude,
+			coalesce(l.prefecture_kana, '') as prefecture_kana,
+			coalesce(l.municipality_kana, '') as municipality_kana,
+			coalesce(l.address_1_kana, '') as address_1_kana,
+			coalesce(l.address_2_kana, '') as address_2_kana
+		FROM municipality_boundaries b
+		JOIN locations l ON l.prefecture = b.prefecture AND l.municipality = b.municipality
+		WHERE ST_Contains(b.geom, ST_SetSRID(ST_MakePoint($2, $1), 4326))
+			AND ST_DWithin(l.geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)
+			AND ($4 = '' OR l.source_file = $4)
+			AND l.id <> ALL($5)
+		ORDER BY l.geom <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)
+		LIMIT 1
+	`
+
+	radius = r.radius(radius)
+	exclude = excludedIDs(exclude)
+	var loc models.Location
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindNearestLocationInMunicipality", time.Now(), lat, lon, radius, source, exclude)
+	err = db.QueryRow(ctx, sql, lat, lon, radius, source, exclude).Scan(
+		&loc.ID,
+		&loc.ExternalID,
+		&loc.Prefecture,
+		&loc.Municipality,
+		&loc.Address1,
+		&loc.Address2,
+		&loc.BlockLot,
+		&loc.Latitude,
+		&loc.Longitude,
+		&loc.Source,
+		&loc.Distance,
+		&loc.Altitude,
+		&loc.PrefectureKana,
+		&loc.MunicipalityKana,
+		&loc.Address1Kana,
+		&loc.Address2Kana,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to execute boundary query: %w", err)
+	}
+
+	return &loc, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"geocoding-api/internal/breaker"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// breakerQuerier runs every call of a Querier through a circuit breaker, see Config.Breaker.
+// Transactions it begins run their statements through the same breaker.
+type breakerQuerier struct {
+	db      Querier
+	breaker *breaker.Breaker
+}
+
+func (q breakerQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := q.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	rows, err := q.db.Query(ctx, sql, args...)
+	if err != nil {
+		q.breaker.Done(isOutage(err))
+		return nil, err
+	}
+	return &breakerRows{Rows: rows, breaker: q.breaker}, nil
+}
+
+func (q breakerQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := q.breaker.Allow(); err != nil {
+		return errRow{err}
+	}
+	return breakerRow{row: q.db.QueryRow(ctx, sql, args...), breaker: q.breaker}
+}
+
+func (q breakerQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := q.breaker.Allow(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := q.db.Exec(ctx, sql, args...)
+	q.breaker.Done(isOutage(err))
+	return tag, err
+}
+
+func (q breakerQuerier) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := q.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	tx, err := q.db.Begin(ctx)
+	q.breaker.Done(isOutage(err))
+	if err != nil {
+		return nil, err
+	}
+	return breakerTx{Tx: tx, guarded: breakerQuerier{db: tx, breaker: q.breaker}}, nil
+}
+
+// breakerTx is a transaction whose statements run through its breakerQuerier's breaker
+type breakerTx struct {
+	pgx.Tx
+	guarded breakerQuerier
+}
+
+func (t breakerTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return t.guarded.Query(ctx, sql, args...)
+}
+
+func (t breakerTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return t.guarded.QueryRow(ctx, sql, args...)
+}
+
+func (t breakerTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return t.guarded.Exec(ctx, sql, args...)
+}
+
+// breakerRows records the outcome of a query when its rows are closed, since pgx reports most
+// query errors through Rows.Err rather than from Query
+type breakerRows struct {
+	pgx.Rows
+	breaker *breaker.Breaker
+	done    bool
+}
+
+func (r *breakerRows) Close() {
+	r.Rows.Close()
+	if !r.done {
+		r.done = true
+		r.breaker.Done(isOutage(r.Rows.Err()))
+	}
+}
+
+// breakerRow records the outcome of a single-row query when it is scanned
+type breakerRow struct {
+	row     pgx.Row
+	breaker *breaker.Breaker
+}
+
+func (r breakerRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.breaker.Done(isOutage(err))
+	return err
+}
+
+// errRow is the row of a query that was never run
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error { return r.err }
+
+// isOutage reports whether err suggests the database is unavailable or overloaded: it failed to
+// connect, ran out of resources or time, or is shutting down. Errors in the query or its results
+// and calls cancelled by a client that went away don't count against the database.
+func isOutage(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var scanErr pgx.ScanArgError
+	if errors.As(err, &scanErr) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Classes 08 connection exception, 53 insufficient resources, 57 operator intervention
+		// (including statement_timeout's query_canceled) and 58 system error
+		switch pgErr.Code[:2] {
+		case "08", "53", "57", "58":
+			return true
+		}
+		return false
+	}
+	// Network errors and timeouts
+	return true
+}
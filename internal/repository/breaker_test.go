@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingQuerier fails every query with err, counting the queries that reached it
+type failingQuerier struct {
+	fakeQuerier
+	err     error
+	queries int
+}
+
+func (q *failingQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.queries++
+	return nil, q.err
+}
+
+func (q *failingQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.queries++
+	return errRow{q.err}
+}
+
+func TestIsOutage(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "success", err: nil, expected: false},
+		{name: "no rows", err: fmt.Errorf("wrapped: %w", pgx.ErrNoRows), expected: false},
+		{name: "client went away", err: context.Canceled, expected: false},
+		{name: "timeout", err: context.DeadlineExceeded, expected: true},
+		{name: "network error", err: errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"), expected: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, expected: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, expected: true},
+		{name: "statement timeout", err: &pgconn.PgError{Code: queryCanceledCode}, expected: true},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, expected: false},
+		{name: "missing text search config", err: &pgconn.PgError{Code: "42704"}, expected: false},
+		{name: "scan error", err: pgx.ScanArgError{ColumnIndex: 0, Err: errors.New("bad type")}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isOutage(tt.err))
+		})
+	}
+}
+
+func TestRepository_Breaker(t *testing.T) {
+	areas := []models.Area{{Prefecture: "東京都", Municipality: "千代田区"}}
+
+	t.Run("outages open the circuit", func(t *testing.T) {
+		db := &failingQuerier{err: errors.New("connection refused")}
+		repo := NewRepository(db, Config{Breaker: breaker.New(breaker.Config{FailureThreshold: 2, Cooldown: time.Minute})})
+
+		for range 2 {
+			_, err := repo.MunicipalityBBoxes(context.Background(), areas)
+			require.ErrorIs(t, err, db.err)
+		}
+
+		// Open: queries fail fast without reaching the database
+		_, err := repo.MunicipalityBBoxes(context.Background(), areas)
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+		_, err = repo.DataFreshness(context.Background())
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+		assert.Equal(t, 2, db.queries)
+	})
+
+	t.Run("query errors don't", func(t *testing.T) {
+		db := &failingQuerier{err: &pgconn.PgError{Code: "42601"}}
+		b := breaker.New(breaker.Config{FailureThreshold: 2})
+		repo := NewRepository(db, Config{Breaker: b})
+
+		for range 3 {
+			_, err := repo.MunicipalityBBoxes(context.Background(), areas)
+			require.Error(t, err)
+			require.NotErrorIs(t, err, breaker.ErrOpen)
+		}
+		assert.Equal(t, breaker.Closed, b.State())
+	})
+
+	t.Run("statements in a bounded transaction count", func(t *testing.T) {
+		db := &fakeQuerier{tx: &fakeTx{queryErr: &pgconn.PgError{Code: queryCanceledCode}}}
+		b := breaker.New(breaker.Config{FailureThreshold: 1})
+		repo := NewRepository(db, Config{StatementTimeout: time.Second, Breaker: b})
+
+		_, err := repo.SuggestAddresses(context.Background(), "丸の内", 5)
+		require.ErrorIs(t, err, ErrStatementTimeout)
+		assert.Equal(t, breaker.Open, b.State())
+
+		_, err = repo.SuggestAddresses(context.Background(), "丸の内", 5)
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+	})
+
+	t.Run("successful queries pass through", func(t *testing.T) {
+		db := &fakeQuerier{rows: [][]any{{"東京都", "千代田区", 139.73, 35.66, 139.78, 35.70}}}
+		b := breaker.New(breaker.Config{FailureThreshold: 1})
+		repo := NewRepository(db, Config{Breaker: b})
+
+		bboxes, err := repo.MunicipalityBBoxes(context.Background(), areas)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{139.73, 35.66, 139.78, 35.70}, bboxes[areas[0]])
+		assert.Equal(t, breaker.Closed, b.State())
+	})
+}
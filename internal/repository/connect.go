@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Pinger is satisfied by both *pgx.Conn and *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ConnectRetry controls how long WaitForDatabase keeps trying to reach the database
+type ConnectRetry struct {
+	// Timeout is how long to keep retrying before giving up; 0 tries once
+	Timeout time.Duration
+	// InitialBackoff is the wait after the first failed attempt, doubling after each further
+	// failure up to MaxBackoff. Defaults to 500ms and 5s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AttemptTimeout bounds each attempt, so an unreachable host can't use up the whole
+	// Timeout in one try. Defaults to 5s.
+	AttemptTimeout time.Duration
+}
+
+// WaitForDatabase pings db until it answers or cfg.Timeout has passed, backing off between
+// attempts, so a database that is still starting up (e.g. under docker-compose) delays startup
+// instead of failing it. onRetry, when set, is called after each failed attempt with the
+// attempt number, the wait before the next one and the error.
+func WaitForDatabase(ctx context.Context, db Pinger, cfg ConnectRetry, onRetry func(attempt int, wait time.Duration, err error)) error {
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Second
+	}
+	if cfg.AttemptTimeout <= 0 {
+		cfg.AttemptTimeout = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(cfg.Timeout)
+	backoff := cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, cfg.AttemptTimeout)
+		err := db.Ping(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 || ctx.Err() != nil {
+			return fmt.Errorf("repository: database not reachable after %d attempts: %w", attempt, err)
+		}
+
+		wait := min(backoff, remaining)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("repository: database not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+}
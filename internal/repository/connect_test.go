@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePinger fails the given number of pings before succeeding
+type fakePinger struct {
+	failures int
+	pings    int
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	p.pings++
+	if p.pings <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWaitForDatabase(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		timeout       time.Duration
+		expectedPings int
+		expectedWaits []time.Duration
+		expectError   bool
+	}{
+		{
+			name:          "available right away",
+			timeout:       time.Minute,
+			expectedPings: 1,
+		},
+		{
+			name:          "available after retries",
+			failures:      4,
+			timeout:       time.Minute,
+			expectedPings: 5,
+			expectedWaits: []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond},
+		},
+		{
+			name:          "no timeout tries once",
+			failures:      1,
+			expectedPings: 1,
+			expectError:   true,
+		},
+		{
+			name:        "gives up after the timeout",
+			failures:    1000,
+			timeout:     20 * time.Millisecond,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakePinger{failures: tt.failures}
+			var waits []time.Duration
+
+			err := WaitForDatabase(context.Background(), db, ConnectRetry{
+				Timeout:        tt.timeout,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     4 * time.Millisecond,
+			}, func(attempt int, wait time.Duration, err error) {
+				assert.Equal(t, len(waits)+1, attempt)
+				waits = append(waits, wait)
+			})
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "connection refused")
+				assert.Less(t, db.pings, 1000)
+				if tt.expectedPings > 0 {
+					assert.Equal(t, tt.expectedPings, db.pings)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPings, db.pings)
+			assert.Equal(t, tt.expectedWaits, waits)
+		})
+	}
+}
+
+func TestWaitForDatabase_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &fakePinger{failures: 1000}
+
+	err := WaitForDatabase(ctx, db, ConnectRetry{Timeout: time.Minute, InitialBackoff: time.Hour}, func(int, time.Duration, error) {
+		cancel()
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, 1, db.pings)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"geocoding-api/internal/models"
+)
+
+// exportBatchSize is the number of locations StreamAllLocations reads per query
+const exportBatchSize = 1000
+
+// StreamAllLocations calls fn with every location in ID order, stopping at the first error fn
+// returns or when ctx is done. It pages through the table by ID, a batch at a time, and calls fn
+// only after each batch's rows are closed: a slow consumer, such as a client downloading an
+// export, then never holds a pool connection, and memory stays bounded by the batch size.
+func (r *Repository) StreamAllLocations(ctx context.Context, fn func(models.Location) error) error {
+	sql := `
+		SELECT
+			id,
+			coalesce(external_id, '') as external_id,
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			block_lot,
+			ST_Y(geom) as latitude,
+			ST_X(geom) as longitude,
+			coalesce(source_file, '') as source,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
+		FROM locations
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	afterID := 0
+	for {
+		batch, err := r.exportBatch(ctx, sql, afterID)
+		if err != nil {
+			return err
+		}
+
+		for _, loc := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(loc); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+}
+
+// exportBatch reads the batch of StreamAllLocations after afterID
+func (r *Repository) exportBatch(ctx context.Context, sql string, afterID int) ([]models.Location, error) {
+	defer r.logSlowQuery(ctx, "StreamAllLocations", time.Now(), afterID, exportBatchSize)
+	rows, err := r.db.Query(ctx, sql, afterID, exportBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute export query: %w", err)
+	}
+	defer rows.Close()
+
+	batch := make([]models.Location, 0, exportBatchSize)
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID,
+			&loc.ExternalID,
+			&loc.Prefecture,
+			&loc.Municipality,
+			&loc.Address1,
+			&loc.Address2,
+			&loc.BlockLot,
+			&loc.Latitude,
+			&loc.Longitude,
+			&loc.Source,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
+		}
+		batch = append(batch, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return batch, nil
+}
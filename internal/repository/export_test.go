@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagingQuerier answers each query with the next of its batches, recording the arguments
+type pagingQuerier struct {
+	fakeQuerier
+	batches [][][]any
+	calls   [][]any
+}
+
+func (q *pagingQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.sql = sql
+	q.calls = append(q.calls, args)
+	var rows [][]any
+	if len(q.batches) > 0 {
+		rows, q.batches = q.batches[0], q.batches[1:]
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func exportRow(id int) []any {
+	return []any{id, "", "東京都", "千代田区", "丸の内1", "", "1", 35.681236, 139.767125, "tokyo.csv", (*float64)(nil)}
+}
+
+func TestRepository_StreamAllLocations(t *testing.T) {
+	full := make([][]any, exportBatchSize)
+	for i := range full {
+		full[i] = exportRow(i + 1)
+	}
+	db := &pagingQuerier{batches: [][][]any{full, {exportRow(exportBatchSize + 1), exportRow(exportBatchSize + 2)}}}
+	repo := NewRepository(db, Config{})
+
+	var ids []int
+	err := repo.StreamAllLocations(context.Background(), func(loc models.Location) error {
+		ids = append(ids, loc.ID)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, ids, exportBatchSize+2)
+	assert.Equal(t, exportBatchSize+2, ids[len(ids)-1])
+	// Each batch continues after the last ID of the previous one; a short batch ends the stream
+	assert.Equal(t, [][]any{{0, exportBatchSize}, {exportBatchSize, exportBatchSize}}, db.calls)
+	assert.Contains(t, db.sql, "WHERE id > $1")
+	assert.Contains(t, db.sql, "ORDER BY id")
+}
+
+func TestRepository_StreamAllLocations_Stops(t *testing.T) {
+	stop := errors.New("client went away")
+
+	db := &pagingQuerier{batches: [][][]any{{exportRow(1), exportRow(2), exportRow(3)}}}
+	repo := NewRepository(db, Config{})
+	calls := 0
+	err := repo.StreamAllLocations(context.Background(), func(loc models.Location) error {
+		calls++
+		if loc.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 2, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db = &pagingQuerier{batches: [][][]any{{exportRow(1)}}}
+	repo = NewRepository(db, Config{})
+	err = repo.StreamAllLocations(ctx, func(loc models.Location) error {
+		t.Fatal("called after the context was canceled")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -2,44 +2,785 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"geocoding-api/internal/breaker"
 	"geocoding-api/internal/models"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// rankWeights are the ts_rank weights for the {D, C, B, A} labels of full_address_tsvector,
+// where A is the municipality, B the prefecture and C the street-level address
+const rankWeights = "{0.1, 0.2, 0.4, 1.0}"
+
+// Querier is the subset of *pgxpool.Pool the repository runs its queries through, so unit
+// tests can substitute a fake to check the generated SQL without a database
+type Querier interface {
+	RowQuerier
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	// Begin is only called with Config.StatementTimeout set, see bounded
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 // Repository implements the repository interface for PostgreSQL
 type Repository struct {
-	db *pgxpool.Pool
+	db     Querier
+	config Config
+}
+
+// Config holds query settings for the repository
+type Config struct {
+	// TextSearchConfig is the text search configuration passed to to_tsquery; it must match
+	// the one full_address_tsvector was generated with. Defaults to DefaultTextSearchConfig.
+	TextSearchConfig string
+	// TextSearchRetryConfigs are tried in order after TextSearchConfig when it finds fewer than
+	// TextSearchMinResults locations, e.g. "simple", which keeps the tokens a dictionary-based
+	// configuration drops. Entries repeating an earlier configuration are ignored.
+	TextSearchRetryConfigs []string
+	// TextSearchMinResults is the number of results that stops the retries; 0 means 1
+	TextSearchMinResults int
+	// SlowQueryThreshold logs a warning for queries that take longer; 0 disables slow query logging
+	SlowQueryThreshold time.Duration
+	// CaptureQueryPlans re-runs geocode searches slower than SlowQueryThreshold under EXPLAIN
+	// (ANALYZE, BUFFERS) and logs their plans; a debugging aid that doubles the cost of slow searches
+	CaptureQueryPlans bool
+	// MaxRadiusMeters caps the search radius of every spatial query, so no caller can turn one
+	// into a full-table scan. Defaults to models.DefaultMaxRadiusMeters.
+	MaxRadiusMeters float64
+	// StatementTimeout runs the expensive text and spatial queries in a transaction with this
+	// statement_timeout, so Postgres cancels them server-side; 0 leaves it to the Go-side timeouts
+	StatementTimeout time.Duration
+	// Breaker fails every query with breaker.ErrOpen while the database keeps failing, instead of
+	// letting each wait for its timeout; nil runs them all
+	Breaker *breaker.Breaker
 }
 
 // NewRepository creates a new PostgreSQL repository
-func NewRepository(db *pgxpool.Pool) *Repository {
-	return &Repository{db: db}
+func NewRepository(db Querier, cfg Config) *Repository {
+	if cfg.TextSearchConfig == "" {
+		cfg.TextSearchConfig = DefaultTextSearchConfig
+	}
+	if cfg.MaxRadiusMeters <= 0 {
+		cfg.MaxRadiusMeters = models.DefaultMaxRadiusMeters
+	}
+	retries := make([]string, 0, len(cfg.TextSearchRetryConfigs))
+	for _, config := range cfg.TextSearchRetryConfigs {
+		if config != cfg.TextSearchConfig && !slices.Contains(retries, config) {
+			retries = append(retries, config)
+		}
+	}
+	cfg.TextSearchRetryConfigs = retries
+	if cfg.Breaker != nil {
+		db = breakerQuerier{db: db, breaker: cfg.Breaker}
+	}
+	return &Repository{db: db, config: cfg}
+}
+
+// radius clamps a requested search radius to MaxRadiusMeters; 0 or less selects the maximum
+func (r *Repository) radius(meters float64) float64 {
+	if meters <= 0 || meters > r.config.MaxRadiusMeters {
+		return r.config.MaxRadiusMeters
+	}
+	return meters
+}
+
+// PostGISVersion returns the version reported by PostGIS_Version(), failing when the extension is not installed
+func (r *Repository) PostGISVersion(ctx context.Context) (string, error) {
+	var version string
+	err := r.db.QueryRow(ctx, "SELECT PostGIS_Version()").Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to query postgis version: %w", err)
+	}
+	return version, nil
+}
+
+// WarmSpatialIndex counts the locations within the maximum search radius of the point. Run at
+// startup, it reads the spatial index pages around a busy area into shared buffers, so the
+// first reverse geocode doesn't pay for loading them from disk.
+func (r *Repository) WarmSpatialIndex(ctx context.Context, lat, lon float64) (int, error) {
+	sql := `
+		SELECT COUNT(*)
+		FROM locations
+		WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)
+	`
+
+	var count int
+	radius := r.radius(0)
+	defer r.logSlowQuery(ctx, "WarmSpatialIndex", time.Now(), lat, lon, radius)
+	err := r.db.QueryRow(ctx, sql, lat, lon, radius).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to execute warmup query: %w", err)
+	}
+	return count, nil
+}
+
+// DataFreshness returns the most recent processed_files import time and the total number of locations
+func (r *Repository) DataFreshness(ctx context.Context) (*models.DataFreshness, error) {
+	sql := `
+		SELECT
+			(SELECT MAX(processed_at) FROM processed_files),
+			(SELECT COUNT(*) FROM locations)
+	`
+
+	var freshness models.DataFreshness
+	defer r.logSlowQuery(ctx, "DataFreshness", time.Now())
+	err := r.db.QueryRow(ctx, sql).Scan(&freshness.LastImportedAt, &freshness.RecordCount)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query data freshness: %w", err)
+	}
+
+	return &freshness, nil
+}
+
+// locationColumn is the select expression of one models.LocationFields field and its scan target
+type locationColumn struct {
+	sql  string
+	dest func(*models.Location) any
+}
+
+// locationColumns holds the column of every models.LocationFields name
+var locationColumns = map[string]locationColumn{
+	"id":                {"id", func(l *models.Location) any { return &l.ID }},
+	"external_id":       {"coalesce(external_id, '') as external_id", func(l *models.Location) any { return &l.ExternalID }},
+	"prefecture":        {"prefecture", func(l *models.Location) any { return &l.Prefecture }},
+	"municipality":      {"municipality", func(l *models.Location) any { return &l.Municipality }},
+	"address1":          {"address_1", func(l *models.Location) any { return &l.Address1 }},
+	"address2":          {"address_2", func(l *models.Location) any { return &l.Address2 }},
+	"block_lot":         {"block_lot", func(l *models.Location) any { return &l.BlockLot }},
+	"latitude":          {"ST_Y(geom) as latitude", func(l *models.Location) any { return &l.Latitude }},
+	"longitude":         {"ST_X(geom) as longitude", func(l *models.Location) any { return &l.Longitude }},
+	"altitude":          {"ST_Z(geom) as altitude", func(l *models.Location) any { return &l.Altitude }},
+	"prefecture_kana":   {"coalesce(prefecture_kana, '') as prefecture_kana", func(l *models.Location) any { return &l.PrefectureKana }},
+	"municipality_kana": {"coalesce(municipality_kana, '') as municipality_kana", func(l *models.Location) any { return &l.MunicipalityKana }},
+	"address1_kana":     {"coalesce(address_1_kana, '') as address_1_kana", func(l *models.Location) any { return &l.Address1Kana }},
+	"address2_kana":     {"coalesce(address_2_kana, '') as address_2_kana", func(l *models.Location) any { return &l.Address2Kana }},
+}
+
+// searchColumns returns the columns a search selects, in models.LocationFields order: the
+// requested fields, plus the id every cursor needs, the area that bounding boxes are looked
+// up by and the coordinates that densities are counted around. Without requested fields it selects them all.
+func searchColumns(opts models.SearchOptions) []locationColumn {
+	if len(opts.Fields) == 0 {
+		opts.Fields = models.LocationFields
+	}
+	wanted := map[string]bool{"id": true}
+	for _, f := range opts.Fields {
+		wanted[f] = true
+	}
+	if opts.IncludeBBox {
+		wanted["prefecture"] = true
+		wanted["municipality"] = true
+	}
+	if opts.IncludeDensity {
+		wanted["latitude"] = true
+		wanted["longitude"] = true
+	}
+
+	var columns []locationColumn
+	for _, f := range models.LocationFields {
+		if wanted[f] {
+			columns = append(columns, locationColumns[f])
+		}
+	}
+	return columns
+}
+
+// SearchLocationsByText performs a full-text search on the locations table. It searches with
+// each of textSearchConfigs in turn until one finds TextSearchMinResults locations, returning
+// the results of the last one run when none does; every location records the configuration
+// that found it in TextSearchConfig.
+func (r *Repository) SearchLocationsByText(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	var locations []models.Location
+	for _, config := range r.textSearchConfigs(opts.After) {
+		found, err := r.searchLocationsByText(ctx, opts, config)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			found[i].TextSearchConfig = config
+		}
+		locations = found
+		if len(found) >= max(r.config.TextSearchMinResults, 1) {
+			break
+		}
+	}
+	return locations, nil
+}
+
+// textSearchConfigs returns the text search configurations to try, in order. A cursor's ranks
+// only compare with those of the configuration its page was found with, so it continues with
+// that one alone, unless it names a configuration that isn't configured (any more).
+func (r *Repository) textSearchConfigs(after *models.SearchCursor) []string {
+	configs := append([]string{r.config.TextSearchConfig}, r.config.TextSearchRetryConfigs...)
+	if after != nil && slices.Contains(configs, after.Config) {
+		return []string{after.Config}
+	}
+	return configs
+}
+
+// searchLocationsByText runs one full-text search with the text search configuration config
+func (r *Repository) searchLocationsByText(ctx context.Context, opts models.SearchOptions, config string) (_ []models.Location, err error) {
+	opts = opts.WithDefaults()
+	project := opts.SRID != 0 && opts.SRID != models.DefaultSRID
+
+	columns := searchColumns(opts)
+	selectList := make([]string, len(columns))
+	for i, c := range columns {
+		selectList[i] = c.sql
+	}
+
+	rank := `ts_rank('` + rankWeights + `', full_address_tsvector, to_tsquery($2::regconfig, $1))`
+	args := []any{opts.Query, config, opts.Limit, opts.Offset}
+	projection := ""
+	if project {
+		args = append(args, opts.SRID)
+		projection = projectionColumns(len(args))
+	}
+	// Keyset pagination: continue strictly after the cursor's row in (rank, id) order. The rank
+	// is compared as real, the type ts_rank returns, so the cursor's row matches exactly.
+	after := ""
+	if opts.After != nil {
+		args = append(args, opts.After.Rank, opts.After.ID)
+		after = fmt.Sprintf(`
+			AND (%s, id) < ($%d::real, $%d)`, rank, len(args)-1, len(args))
+	}
+	var within string
+	within, args = bboxFilter(opts.BBox, args)
+
+	orderBy := "rank DESC, id DESC"
+	if opts.OrderBy == models.OrderByImportance {
+		orderBy = "importance DESC NULLS LAST, rank DESC, id DESC"
+	}
+
+	sql := `
+		SELECT
+			` + strings.Join(selectList, ",\n\t\t\t") + `,
+			` + rank + ` as rank` + projection + `
+		FROM locations
+		WHERE full_address_tsvector @@ to_tsquery($2::regconfig, $1)` + after + within + `
+		ORDER BY ` + orderBy + `
+		LIMIT $3 OFFSET $4
+	`
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.explainSlowQuery(ctx, "SearchLocationsByText", time.Now(), sql, args...)
+	defer r.logSlowQuery(ctx, "SearchLocationsByText", time.Now(), args...)
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute search query: %w", err)
+	}
+	return scanSearchRows(rows, columns, project, opts.SRID)
+}
+
+// CountLocationsByText returns the number of locations a full-text search for query matches with
+// the text search configuration config, or with TextSearchConfig when config is empty, inside
+// bbox unless it is nil; it is the total that offset pages of SearchLocationsByText are cut from
+func (r *Repository) CountLocationsByText(ctx context.Context, query, config string, bbox *models.BoundingBox) (_ int, err error) {
+	if config == "" {
+		config = r.config.TextSearchConfig
+	}
+
+	within, args := bboxFilter(bbox, []any{query, config})
+	sql := `
+		SELECT COUNT(*)
+		FROM locations
+		WHERE full_address_tsvector @@ to_tsquery($2::regconfig, $1)` + within + `
+	`
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer end(&err)
+
+	var count int
+	defer r.logSlowQuery(ctx, "CountLocationsByText", time.Now(), args...)
+	err = db.QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to execute count query: %w", err)
+	}
+	return count, nil
+}
+
+// bboxFilter returns the condition restricting a search to the locations inside bbox, appending
+// its coordinates to args, or no condition when bbox is nil
+func bboxFilter(bbox *models.BoundingBox, args []any) (string, []any) {
+	if bbox == nil {
+		return "", args
+	}
+	args = append(args, bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat)
+	n := len(args)
+	return fmt.Sprintf(`
+			AND ST_Within(geom::geometry, ST_MakeEnvelope($%d, $%d, $%d, $%d, 4326))`, n-3, n-2, n-1, n), args
+}
+
+// projectionColumns selects the x and y of each row transformed to the SRID bound as $arg
+func projectionColumns(arg int) string {
+	return fmt.Sprintf(`,
+			ST_X(ST_Transform(geom::geometry, $%[1]d)) as x,
+			ST_Y(ST_Transform(geom::geometry, $%[1]d)) as y`, arg)
+}
+
+// scanSearchRows scans the rows of a search selecting columns, then the rank, then the
+// projected coordinates when project is set
+func scanSearchRows(rows pgx.Rows, columns []locationColumn, project bool, srid int) ([]models.Location, error) {
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var loc models.Location
+		dest := make([]any, 0, len(columns)+3)
+		for _, c := range columns {
+			dest = append(dest, c.dest(&loc))
+		}
+		dest = append(dest, &loc.Score)
+		if project {
+			loc.Projected = &models.ProjectedPoint{SRID: srid}
+			dest = append(dest, &loc.Projected.X, &loc.Projected.Y)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// SuggestAddresses returns up to limit distinct full addresses that are similar to the query using pg_trgm
+func (r *Repository) SuggestAddresses(ctx context.Context, query string, limit int) (_ []string, err error) {
+	sql := `
+		SELECT address
+		FROM (
+			SELECT DISTINCT prefecture || municipality || address_1 || address_2 AS address
+			FROM locations
+			WHERE (prefecture || municipality || address_1 || address_2) % $1
+		) candidates
+		ORDER BY similarity(address, $1) DESC, address
+		LIMIT $2
+	`
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "SuggestAddresses", time.Now(), query, limit)
+	rows, err := db.Query(ctx, sql, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute suggestion query: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan suggestion: %w", err)
+		}
+		suggestions = append(suggestions, address)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in text matched literally, with the default backslash escape
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// The address prefixes SuggestLocations matches the typed text against. The importer builds a
+// text_pattern_ops index on each to serve the LIKE prefix matches, so the queried expressions
+// must stay identical to the indexed ones.
+const (
+	SuggestFullTown         = `(prefecture || municipality || address_1)`
+	SuggestMunicipalityTown = `(municipality || address_1)`
+	SuggestTown             = `(address_1)`
+	SuggestFullMunicipality = `(prefecture || municipality)`
+	SuggestMunicipality     = `(municipality)`
+)
+
+// SuggestLocations returns up to limit distinct address prefixes starting with prefix, for
+// search-as-you-type: municipalities (prefecture and municipality) and towns (with the first
+// address part). The prefix may leave out the prefecture, or the prefecture and municipality.
+// Completions that start with the prefix as typed come first, then shorter ones, so for "千代"
+// the municipality 東京都千代田区 precedes its towns.
+func (r *Repository) SuggestLocations(ctx context.Context, prefix string, limit int) (_ []string, err error) {
+	sql := `
+		SELECT suggestion
+		FROM (
+			SELECT DISTINCT ` + SuggestFullTown + ` AS suggestion
+			FROM locations
+			WHERE ` + SuggestFullTown + ` LIKE $1 || '%'
+				OR ` + SuggestMunicipalityTown + ` LIKE $1 || '%'
+				OR ` + SuggestTown + ` LIKE $1 || '%'
+			UNION
+			SELECT DISTINCT ` + SuggestFullMunicipality + `
+			FROM locations
+			WHERE ` + SuggestFullMunicipality + ` LIKE $1 || '%'
+				OR ` + SuggestMunicipality + ` LIKE $1 || '%'
+		) candidates
+		ORDER BY (suggestion LIKE $1 || '%') DESC, length(suggestion), suggestion
+		LIMIT $2
+	`
+	pattern := likeEscaper.Replace(prefix)
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "SuggestLocations", time.Now(), pattern, limit)
+	rows, err := db.Query(ctx, sql, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute autocomplete query: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []string{}
+	for rows.Next() {
+		var suggestion string
+		if err := rows.Scan(&suggestion); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan autocomplete suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// FullAddress is the concatenated address of a row, as matched by exact and fuzzy searches. The
+// importer indexes it for the fuzzy search, whose expression must match the index's to use it.
+const FullAddress = `(prefecture || municipality || address_1 || address_2)`
+
+// FindLocationsByAddress returns the locations whose full address, with or without the block/lot
+// number, is exactly the query once whitespace is removed. Every match ranks the same, so they are
+// ordered by ID unless opts orders by importance.
+func (r *Repository) FindLocationsByAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	return r.searchByAddress(ctx, "FindLocationsByAddress", opts, `1::float8`,
+		`regexp_replace($1, '\s', '', 'g') IN (`+FullAddress+`, `+FullAddress+` || block_lot)`)
+}
+
+// FindLocationsByNormalizedAddress returns the locations whose stored search key, computed by the
+// importer's --normalized-key, is exactly the query, which must already be a normalize.SearchKey.
+// Rows imported without the flag have no key and never match. Every match ranks the same, as in
+// FindLocationsByAddress.
+func (r *Repository) FindLocationsByNormalizedAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	return r.searchByAddress(ctx, "FindLocationsByNormalizedAddress", opts, `1::float8`, `normalized_address = $1`)
+}
+
+// FindLocationsByKana returns the locations whose stored kana key, computed by the importer from
+// the dataset's readings, contains the query, which must already be a normalize.Kana key. Matches
+// covering more of their key rank higher, so a full reading outranks a partial one. Rows imported
+// without readings have no key and never match.
+func (r *Repository) FindLocationsByKana(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	return r.searchByAddress(ctx, "FindLocationsByKana", opts, `length($1)::float8 / length(kana_key)`,
+		`kana_key LIKE '%' || $1 || '%'`)
+}
+
+// SearchLocationsByTrigram returns the locations whose full address is similar to the query using
+// pg_trgm, most similar first, catching typos that full-text search misses
+func (r *Repository) SearchLocationsByTrigram(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	return r.searchByAddress(ctx, "SearchLocationsByTrigram", opts, `similarity(`+FullAddress+`, $1)`,
+		FullAddress+` % $1`)
+}
+
+// searchByAddress runs a search of the full address against the query bound as $1: where selects
+// the matches, inside opts.BBox when set, and rank orders them, followed by ID so pages are stable
+func (r *Repository) searchByAddress(ctx context.Context, name string, opts models.SearchOptions, rank, where string) (_ []models.Location, err error) {
+	opts = opts.WithDefaults()
+	project := opts.SRID != 0 && opts.SRID != models.DefaultSRID
+
+	columns := searchColumns(opts)
+	selectList := make([]string, len(columns))
+	for i, c := range columns {
+		selectList[i] = c.sql
+	}
+
+	args := []any{opts.Query, opts.Limit, opts.Offset}
+	projection := ""
+	if project {
+		args = append(args, opts.SRID)
+		projection = projectionColumns(len(args))
+	}
+
+	var within string
+	within, args = bboxFilter(opts.BBox, args)
+
+	orderBy := "rank DESC, id"
+	if opts.OrderBy == models.OrderByImportance {
+		orderBy = "importance DESC NULLS LAST, rank DESC, id"
+	}
+
+	sql := `
+		SELECT
+			` + strings.Join(selectList, ",\n\t\t\t") + `,
+			` + rank + ` as rank` + projection + `
+		FROM locations
+		WHERE ` + where + within + `
+		ORDER BY ` + orderBy + `
+		LIMIT $2 OFFSET $3
+	`
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.explainSlowQuery(ctx, name, time.Now(), sql, args...)
+	defer r.logSlowQuery(ctx, name, time.Now(), args...)
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute search query: %w", err)
+	}
+	return scanSearchRows(rows, columns, project, opts.SRID)
+}
+
+// MunicipalityBBoxes returns the extent of the locations in each area as [min_lon, min_lat, max_lon, max_lat].
+// Areas without any locations are absent from the result.
+func (r *Repository) MunicipalityBBoxes(ctx context.Context, areas []models.Area) (_ map[models.Area][]float64, err error) {
+	sql := `
+		SELECT prefecture, municipality, ST_XMin(extent), ST_YMin(extent), ST_XMax(extent), ST_YMax(extent)
+		FROM (
+			SELECT prefecture, municipality, ST_Extent(geom::geometry) AS extent
+			FROM locations
+			WHERE (prefecture, municipality) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+			GROUP BY prefecture, municipality
+		) extents
+	`
+
+	prefectures := make([]string, len(areas))
+	municipalities := make([]string, len(areas))
+	for i, a := range areas {
+		prefectures[i] = a.Prefecture
+		municipalities[i] = a.Municipality
+	}
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "MunicipalityBBoxes", time.Now(), prefectures, municipalities)
+	rows, err := db.Query(ctx, sql, prefectures, municipalities)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute extent query: %w", err)
+	}
+	defer rows.Close()
+
+	bboxes := make(map[models.Area][]float64)
+	for rows.Next() {
+		var area models.Area
+		bbox := make([]float64, 4)
+		if err := rows.Scan(&area.Prefecture, &area.Municipality, &bbox[0], &bbox[1], &bbox[2], &bbox[3]); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan extent: %w", err)
+		}
+		bboxes[area] = bbox
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return bboxes, nil
+}
+
+// NeighborCounts returns the number of locations within radius meters of each point, in the
+// order of points. Each count is a separate index scan, so the cost grows with the number of
+// points and with how many locations each radius covers.
+func (r *Repository) NeighborCounts(ctx context.Context, points []models.Point, radius float64) (_ []int, err error) {
+	sql := `
+		SELECT (
+			SELECT COUNT(*)
+			FROM locations
+			WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint(p.lon, p.lat), 4326), $3)
+		)
+		FROM unnest($1::float8[], $2::float8[]) WITH ORDINALITY AS p(lat, lon, n)
+		ORDER BY p.n
+	`
+
+	lats := make([]float64, len(points))
+	lons := make([]float64, len(points))
+	for i, p := range points {
+		lats[i] = p.Lat
+		lons[i] = p.Lon
+	}
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "NeighborCounts", time.Now(), lats, lons, radius)
+	rows, err := db.Query(ctx, sql, lats, lons, radius)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute neighbor count query: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]int, 0, len(points))
+	for rows.Next() {
+		var count int
+		if err := rows.Scan(&count); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan neighbor count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// excludedIDs returns the IDs a nearest location search skips, never nil: pgx sends a nil slice
+// as NULL, and "id <> ALL(NULL)" would exclude every row
+func excludedIDs(exclude []int) []int {
+	if exclude == nil {
+		return []int{}
+	}
+	return exclude
 }
 
-// SearchLocationsByText performs a full-text search on the locations table
-func (r *Repository) SearchLocationsByText(ctx context.Context, query string) ([]models.Location, error) {
+// FindNearestLocation performs a spatial query to find the nearest location within radius meters of the given coordinates,
+// skipping the locations whose IDs are in exclude. The location's Distance is its distance from the point in meters.
+// A non-empty source only considers locations imported from that file.
+func (r *Repository) FindNearestLocation(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (_ *models.Location, err error) {
 	sql := `
 		SELECT
 			id,
+			coalesce(external_id, '') as external_id,
 			prefecture,
 			municipality,
 			address_1,
 			address_2,
 			block_lot,
 			ST_Y(geom) as latitude,
-			ST_X(geom) as longitude
+			ST_X(geom) as longitude,
+			coalesce(source_file, '') as source,
+			ST_Distance(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326)) as distance,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
 		FROM locations
-		WHERE full_address_tsvector @@ to_tsquery('japanese', $1)
-		ORDER BY ts_rank(full_address_tsvector, to_tsquery('japanese', $1)) DESC
-		LIMIT 10
+		WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)
+			AND ($4 = '' OR source_file = $4)
+			AND id <> ALL($5)
+		ORDER BY geom <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)
+		LIMIT 1
 	`
 
-	rows, err := r.db.Query(ctx, sql, query)
+	radius = r.radius(radius)
+	exclude = excludedIDs(exclude)
+	var loc models.Location
+	db, end, err := r.bounded(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("repository: failed to execute search query: %w", err)
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindNearestLocation", time.Now(), lat, lon, radius, source, exclude)
+	err = db.QueryRow(ctx, sql, lat, lon, radius, source, exclude).Scan(
+		&loc.ID,
+		&loc.ExternalID,
+		&loc.Prefecture,
+		&loc.Municipality,
+		&loc.Address1,
+		&loc.Address2,
+		&loc.BlockLot,
+		&loc.Latitude,
+		&loc.Longitude,
+		&loc.Source,
+		&loc.Distance,
+		&loc.Altitude,
+		&loc.PrefectureKana,
+		&loc.MunicipalityKana,
+		&loc.Address1Kana,
+		&loc.Address2Kana,
+	)
+
+	if err != nil {
+		// Nothing within range is not a failure; callers report it as not found
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to execute spatial query: %w", err)
+	}
+
+	return &loc, nil
+}
+
+// FindNearestLocations returns up to limit locations within radius meters of the point, nearest first, with their distances.
+// A non-empty source only considers locations imported from that file.
+func (r *Repository) FindNearestLocations(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) (_ []models.Location, err error) {
+	sql := `
+		SELECT
+			id,
+			coalesce(external_id, '') as external_id,
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			block_lot,
+			ST_Y(geom) as latitude,
+			ST_X(geom) as longitude,
+			coalesce(source_file, '') as source,
+			ST_Distance(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326)) as distance,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
+		FROM locations
+		WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)
+			AND ($4 = '' OR source_file = $4)
+			AND id <> ALL($5)
+		ORDER BY geom <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)
+		LIMIT $6
+	`
+
+	radius = r.radius(radius)
+	exclude = excludedIDs(exclude)
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindNearestLocations", time.Now(), lat, lon, radius, source, exclude, limit)
+	rows, err := db.Query(ctx, sql, lat, lon, radius, source, exclude, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute spatial query: %w", err)
 	}
 	defer rows.Close()
 
@@ -48,6 +789,87 @@ func (r *Repository) SearchLocationsByText(ctx context.Context, query string) ([
 		var loc models.Location
 		err := rows.Scan(
 			&loc.ID,
+			&loc.ExternalID,
+			&loc.Prefecture,
+			&loc.Municipality,
+			&loc.Address1,
+			&loc.Address2,
+			&loc.BlockLot,
+			&loc.Latitude,
+			&loc.Longitude,
+			&loc.Source,
+			&loc.Distance,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// FindColocatedLocations returns up to limit other locations within meters of the location
+// with the given ID, nearest to it first: the units sharing its building footprint, whose points
+// are often slightly offset from each other. Like the nearest location search, it only considers
+// locations from a non-empty source and skips the IDs in exclude.
+func (r *Repository) FindColocatedLocations(ctx context.Context, id int, meters float64, source string, exclude []int, limit int) (_ []models.Location, err error) {
+	sql := `
+		SELECT
+			l.id,
+			coalesce(l.external_id, '') as external_id,
+			l.prefecture,
+			l.municipality,
+			l.address_1,
+			l.address_2,
+			l.block_lot,
+			ST_Y(l.geom) as latitude,
+			ST_X(l.geom) as longitude,
+			coalesce(l.source_file, '') as source,
+			ST_Z(l.geom) as altitude,
+			coalesce(l.prefecture_kana, '') as prefecture_kana,
+			coalesce(l.municipality_kana, '') as municipality_kana,
+			coalesce(l.address_1_kana, '') as address_1_kana,
+			coalesce(l.address_2_kana, '') as address_2_kana
+		FROM locations anchor
+		JOIN locations l ON ST_DWithin(l.geom, anchor.geom, $2)
+		WHERE anchor.id = $1
+			AND l.id <> $1
+			AND ($3 = '' OR l.source_file = $3)
+			AND l.id <> ALL($4)
+		ORDER BY l.geom <-> anchor.geom, l.id
+		LIMIT $5
+	`
+
+	exclude = excludedIDs(exclude)
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindColocatedLocations", time.Now(), id, meters, source, exclude, limit)
+	rows, err := db.Query(ctx, sql, id, meters, source, exclude, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute spatial query: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []models.Location{}
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID,
+			&loc.ExternalID,
 			&loc.Prefecture,
 			&loc.Municipality,
 			&loc.Address1,
@@ -55,6 +877,12 @@ func (r *Repository) SearchLocationsByText(ctx context.Context, query string) ([
 			&loc.BlockLot,
 			&loc.Latitude,
 			&loc.Longitude,
+			&loc.Source,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
@@ -69,42 +897,265 @@ func (r *Repository) SearchLocationsByText(ctx context.Context, query string) ([
 	return locations, nil
 }
 
-// FindNearestLocation performs a spatial query to find the nearest location to the given coordinates
-func (r *Repository) FindNearestLocation(ctx context.Context, lat, lon float64) (*models.Location, error) {
+// FindLocationsByIDs fetches the locations with the given IDs, returned in the same order as the IDs
+func (r *Repository) FindLocationsByIDs(ctx context.Context, ids []int) (_ []models.Location, err error) {
 	sql := `
 		SELECT
 			id,
+			coalesce(external_id, '') as external_id,
 			prefecture,
 			municipality,
 			address_1,
 			address_2,
 			block_lot,
 			ST_Y(geom) as latitude,
-			ST_X(geom) as longitude
+			ST_X(geom) as longitude,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
 		FROM locations
-		WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), 10000) -- Within 10km
-		ORDER BY geom <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)
-		LIMIT 1
+		WHERE id = ANY($1)
+		ORDER BY array_position($1, id)
 	`
 
-	var loc models.Location
-	err := r.db.QueryRow(ctx, sql, lat, lon).Scan(
-		&loc.ID,
-		&loc.Prefecture,
-		&loc.Municipality,
-		&loc.Address1,
-		&loc.Address2,
-		&loc.BlockLot,
-		&loc.Latitude,
-		&loc.Longitude,
-	)
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
 
+	defer r.logSlowQuery(ctx, "FindLocationsByIDs", time.Now(), ids)
+	rows, err := db.Query(ctx, sql, ids)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, fmt.Errorf("repository: no location found near coordinates")
+		return nil, fmt.Errorf("repository: failed to execute id lookup query: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID,
+			&loc.ExternalID,
+			&loc.Prefecture,
+			&loc.Municipality,
+			&loc.Address1,
+			&loc.Address2,
+			&loc.BlockLot,
+			&loc.Latitude,
+			&loc.Longitude,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
 		}
-		return nil, fmt.Errorf("repository: failed to execute spatial query: %w", err)
+		locations = append(locations, loc)
 	}
 
-	return &loc, nil
-}
\ No newline at end of file
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// FindLocationsByArea returns the locations whose prefecture and/or municipality exactly match
+// opts, ordered by ID so pages are stable. Empty area fields are not filtered on.
+func (r *Repository) FindLocationsByArea(ctx context.Context, opts models.SearchOptions) (_ []models.Location, err error) {
+	opts = opts.WithDefaults()
+
+	sql := `
+		SELECT
+			id,
+			coalesce(external_id, '') as external_id,
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			block_lot,
+			ST_Y(geom) as latitude,
+			ST_X(geom) as longitude,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
+		FROM locations
+		WHERE ($1 = '' OR prefecture = $1)
+			AND ($2 = '' OR municipality = $2)
+		ORDER BY id
+		LIMIT $3 OFFSET $4
+	`
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindLocationsByArea", time.Now(), opts.Prefecture, opts.Municipality, opts.Limit, opts.Offset)
+	rows, err := db.Query(ctx, sql, opts.Prefecture, opts.Municipality, opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute area query: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID,
+			&loc.ExternalID,
+			&loc.Prefecture,
+			&loc.Municipality,
+			&loc.Address1,
+			&loc.Address2,
+			&loc.BlockLot,
+			&loc.Latitude,
+			&loc.Longitude,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// FindLocationsInBBox returns up to limit locations inside bbox, ordered by ID so the same box
+// always yields the same locations
+func (r *Repository) FindLocationsInBBox(ctx context.Context, bbox models.BoundingBox, limit int) (_ []models.Location, err error) {
+	sql := `
+		SELECT
+			id,
+			coalesce(external_id, '') as external_id,
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			block_lot,
+			ST_Y(geom) as latitude,
+			ST_X(geom) as longitude,
+			ST_Z(geom) as altitude,
+			coalesce(prefecture_kana, '') as prefecture_kana,
+			coalesce(municipality_kana, '') as municipality_kana,
+			coalesce(address_1_kana, '') as address_1_kana,
+			coalesce(address_2_kana, '') as address_2_kana
+		FROM locations
+		WHERE ST_Within(geom::geometry, ST_MakeEnvelope($1, $2, $3, $4, 4326))
+		ORDER BY id
+		LIMIT $5
+	`
+	args := []any{bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat, limit}
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindLocationsInBBox", time.Now(), args...)
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute bbox query: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []models.Location
+	for rows.Next() {
+		var loc models.Location
+		err := rows.Scan(
+			&loc.ID,
+			&loc.ExternalID,
+			&loc.Prefecture,
+			&loc.Municipality,
+			&loc.Address1,
+			&loc.Address2,
+			&loc.BlockLot,
+			&loc.Latitude,
+			&loc.Longitude,
+			&loc.Altitude,
+			&loc.PrefectureKana,
+			&loc.MunicipalityKana,
+			&loc.Address1Kana,
+			&loc.Address2Kana,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return locations, nil
+}
+
+// DistanceMatrix computes the geodesic distance in meters between every pair of points in a
+// single cross-joined query, returning one row per point in input order
+func (r *Repository) DistanceMatrix(ctx context.Context, points []models.Point) (_ [][]float64, err error) {
+	sql := `
+		WITH points AS (
+			SELECT ord, ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography AS geog
+			FROM unnest($1::float8[], $2::float8[]) WITH ORDINALITY AS p(lat, lon, ord)
+		)
+		SELECT array_agg(ST_Distance(a.geog, b.geog) ORDER BY b.ord)
+		FROM points a
+		CROSS JOIN points b
+		GROUP BY a.ord
+		ORDER BY a.ord
+	`
+
+	lats := make([]float64, len(points))
+	lons := make([]float64, len(points))
+	for i, p := range points {
+		lats[i] = p.Lat
+		lons[i] = p.Lon
+	}
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "DistanceMatrix", time.Now(), len(points))
+	rows, err := db.Query(ctx, sql, lats, lons)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to execute distance matrix query: %w", err)
+	}
+	defer rows.Close()
+
+	matrix := make([][]float64, 0, len(points))
+	for rows.Next() {
+		var row []float64
+		if err := rows.Scan(&row); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan distance row: %w", err)
+		}
+		matrix = append(matrix, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: error iterating rows: %w", err)
+	}
+
+	return matrix, nil
+}
@@ -4,7 +4,9 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"geocoding-api/internal/models"
 
@@ -60,6 +62,7 @@ func setupTestDatabase(t *testing.T) *pgxpool.Pool {
 	// Create test schema
 	_, err = pool.Exec(ctx, `
 		CREATE EXTENSION IF NOT EXISTS postgis;
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
 		CREATE TABLE locations (
 			id BIGSERIAL PRIMARY KEY,
@@ -68,8 +71,19 @@ func setupTestDatabase(t *testing.T) *pgxpool.Pool {
 			address_1 VARCHAR(255),
 			address_2 VARCHAR(255),
 			block_lot VARCHAR(255),
+			external_id TEXT,
+			source_file TEXT,
+			importance REAL,
+			normalized_address TEXT,
+			prefecture_kana TEXT,
+			municipality_kana TEXT,
+			address_1_kana TEXT,
+			address_2_kana TEXT,
+			kana_key TEXT,
 			full_address_tsvector TSVECTOR GENERATED ALWAYS AS (
-				to_tsvector('japanese', prefecture || municipality || address_1 || address_2)
+				setweight(to_tsvector('japanese', coalesce(municipality, '')), 'A') ||
+				setweight(to_tsvector('japanese', coalesce(prefecture, '')), 'B') ||
+				setweight(to_tsvector('japanese', coalesce(address_1, '') || ' ' || coalesce(address_2, '')), 'C')
 			) STORED,
 			geom GEOGRAPHY(POINT, 4326)
 		);
@@ -77,6 +91,33 @@ func setupTestDatabase(t *testing.T) *pgxpool.Pool {
 		CREATE INDEX locations_geom_idx ON locations USING GIST (geom);
 		CREATE INDEX locations_full_address_tsvector_idx ON locations USING GIN (full_address_tsvector);
 
+		CREATE TABLE geocode_jobs (
+			id TEXT PRIMARY KEY,
+			status TEXT NOT NULL DEFAULT 'queued',
+			addresses JSONB NOT NULL,
+			results JSONB,
+			error TEXT,
+			total INTEGER NOT NULL,
+			processed INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			finished_at TIMESTAMP WITH TIME ZONE
+		);
+
+		CREATE TABLE api_key_usage (
+			key_id TEXT NOT NULL,
+			day DATE NOT NULL,
+			requests BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id, day)
+		);
+
+		CREATE TABLE municipality_boundaries (
+			id BIGSERIAL PRIMARY KEY,
+			prefecture VARCHAR(255) NOT NULL,
+			municipality VARCHAR(255) NOT NULL,
+			geom GEOMETRY(MULTIPOLYGON, 4326) NOT NULL
+		);
+
 		-- Insert test data
 		INSERT INTO locations (prefecture, municipality, address_1, address_2, geom) VALUES
 		('東京都', '千代田区', '丸の内', '', ST_SetSRID(ST_MakePoint(139.767125, 35.681236), 4326)),
@@ -93,7 +134,7 @@ func TestPostgresRepository_SearchLocationsByText(t *testing.T) {
 	}
 
 	pool := setupTestDatabase(t)
-	repo := NewPostgresRepository(pool)
+	repo := NewRepository(pool, Config{})
 	ctx := context.Background()
 
 	tests := []struct {
@@ -140,9 +181,867 @@ func TestPostgresRepository_SearchLocationsByText(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			locations, err := repo.SearchLocationsByText(ctx, tt.query)
+			locations, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: tt.query})
 			require.NoError(t, err)
+			// The score depends on the text search configuration; only the ordering it produces matters here
+			for i := range locations {
+				assert.Positive(t, locations[i].Score)
+				locations[i].Score = 0
+			}
 			assert.Equal(t, tt.expected, locations)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestPostgresRepository_SearchLocationsByText_Cursor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	// Walk every match one row at a time; each page must continue after the previous one
+	opts := models.SearchOptions{Query: "東京都", Limit: 1}
+	var ids []int
+	for {
+		locations, err := repo.SearchLocationsByText(ctx, opts)
+		require.NoError(t, err)
+		if len(locations) == 0 {
+			break
+		}
+		require.Len(t, locations, 1)
+		last := locations[0]
+		ids = append(ids, last.ID)
+		opts.After = &models.SearchCursor{Rank: last.Score, ID: last.ID}
+	}
+
+	assert.ElementsMatch(t, []int{1, 2}, ids)
+}
+
+func TestPostgresRepository_SearchLocationsByText_Offset(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	// Identical addresses tie on ts_rank, so only the id tiebreak keeps pages from overlapping
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, geom) VALUES
+		('東京都', '中央区', '銀座', ST_SetSRID(ST_MakePoint(139.765, 35.671), 4326)),
+		('東京都', '中央区', '銀座', ST_SetSRID(ST_MakePoint(139.766, 35.672), 4326)),
+		('東京都', '中央区', '銀座', ST_SetSRID(ST_MakePoint(139.767, 35.673), 4326))
+	`)
+	require.NoError(t, err)
+
+	all, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: "東京都"})
+	require.NoError(t, err)
+	total, err := repo.CountLocationsByText(ctx, "東京都", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(all), total)
+
+	var paged []int
+	for offset := 0; offset < total; offset += 2 {
+		page, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: "東京都", Limit: 2, Offset: offset})
+		require.NoError(t, err)
+		for _, loc := range page {
+			paged = append(paged, loc.ID)
+		}
+	}
+	want := make([]int, len(all))
+	for i, loc := range all {
+		want[i] = loc.ID
+	}
+	assert.Equal(t, want, paged)
+
+	past, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: "東京都", Offset: total})
+	require.NoError(t, err)
+	assert.Empty(t, past)
+}
+
+func TestPostgresRepository_SearchLocationsByText_BBox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	// 丸の内 is at 139.767125, 35.681236 and 赤坂 at 139.732, 35.675; both match 東京都
+	tests := []struct {
+		name     string
+		bbox     *models.BoundingBox
+		expected []string
+	}{
+		{name: "no bounding box", expected: []string{"丸の内", "赤坂"}},
+		{name: "around 丸の内", bbox: &models.BoundingBox{MinLon: 139.76, MinLat: 35.678, MaxLon: 139.77, MaxLat: 35.684}, expected: []string{"丸の内"}},
+		{name: "around 赤坂", bbox: &models.BoundingBox{MinLon: 139.73, MinLat: 35.673, MaxLon: 139.735, MaxLat: 35.677}, expected: []string{"赤坂"}},
+		{name: "in Osaka", bbox: &models.BoundingBox{MinLon: 135.4, MinLat: 34.6, MaxLon: 135.6, MaxLat: 34.8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: "東京都", BBox: tt.bbox})
+			require.NoError(t, err)
+			var addresses []string
+			for _, loc := range locations {
+				addresses = append(addresses, loc.Address1)
+			}
+			assert.ElementsMatch(t, tt.expected, addresses)
+
+			total, err := repo.CountLocationsByText(ctx, "東京都", "", tt.bbox)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expected), total)
+		})
+	}
+}
+
+func TestPostgresRepository_FindLocationsInBBox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// 丸の内 is at 139.767125, 35.681236 and 赤坂 at 139.732, 35.675
+	tokyo := models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+	tests := []struct {
+		name     string
+		bbox     models.BoundingBox
+		limit    int
+		expected []string
+	}{
+		{name: "both inside", bbox: tokyo, limit: 10, expected: []string{"丸の内", "赤坂"}},
+		{name: "limited", bbox: tokyo, limit: 1, expected: []string{"丸の内"}},
+		{name: "around 赤坂", bbox: models.BoundingBox{MinLon: 139.73, MinLat: 35.673, MaxLon: 139.735, MaxLat: 35.677}, limit: 10, expected: []string{"赤坂"}},
+		{name: "in Osaka", bbox: models.BoundingBox{MinLon: 135.4, MinLat: 34.6, MaxLon: 135.6, MaxLat: 34.8}, limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := repo.FindLocationsInBBox(ctx, tt.bbox, tt.limit)
+			require.NoError(t, err)
+			var addresses []string
+			for _, loc := range locations {
+				addresses = append(addresses, loc.Address1)
+			}
+			assert.Equal(t, tt.expected, addresses)
+		})
+	}
+}
+
+func TestPostgresRepository_BatchJobLifecycle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	created, err := repo.CreateBatchJob(ctx, "job-1", []string{"丸の内", ""})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchJobQueued, created.Status)
+	assert.Equal(t, 2, created.Total)
+
+	claimed, err := repo.ClaimBatchJob(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, "job-1", claimed.ID)
+	assert.Equal(t, []string{"丸の内", ""}, claimed.Addresses)
+
+	// A running job that is still making progress isn't handed to another worker
+	none, err := repo.ClaimBatchJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	require.NoError(t, repo.UpdateBatchJobProgress(ctx, "job-1", 1))
+	results := []models.BatchResult{
+		{Query: "丸の内", Results: []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125}}},
+		{Query: "", Results: []models.Location{}, Error: "address cannot be empty"},
+	}
+	require.NoError(t, repo.CompleteBatchJob(ctx, "job-1", results))
+
+	job, err := repo.GetBatchJob(ctx, "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchJobSucceeded, job.Status)
+	assert.Equal(t, 2, job.Processed)
+	assert.NotNil(t, job.FinishedAt)
+
+	stored, err := repo.BatchJobResults(ctx, "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, results, stored)
+
+	missing, err := repo.GetBatchJob(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestPostgresRepository_IncrementAPIKeyUsage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// Earlier days of the month add to the monthly count, the previous month's don't
+	for _, day := range []time.Time{
+		time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		_, _, err := repo.IncrementAPIKeyUsage(ctx, "key-1", day)
+		require.NoError(t, err)
+	}
+
+	daily, monthly, err := repo.IncrementAPIKeyUsage(ctx, "key-1", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), daily)
+	assert.Equal(t, int64(3), monthly)
+
+	daily, monthly, err = repo.IncrementAPIKeyUsage(ctx, "key-2", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), daily)
+	assert.Equal(t, int64(1), monthly)
+}
+
+func TestPostgresRepository_ClaimBatchJob_Stale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	_, err := repo.CreateBatchJob(ctx, "job-1", []string{"丸の内"})
+	require.NoError(t, err)
+	_, err = repo.ClaimBatchJob(ctx, time.Minute)
+	require.NoError(t, err)
+
+	// Simulate a worker that died without reporting progress
+	_, err = pool.Exec(ctx, "UPDATE geocode_jobs SET updated_at = NOW() - interval '10 minutes', processed = 1")
+	require.NoError(t, err)
+
+	reclaimed, err := repo.ClaimBatchJob(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, "job-1", reclaimed.ID)
+	assert.Equal(t, 0, reclaimed.Processed)
+}
+
+func TestPostgresRepository_FindNearestLocation_Radius(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// About 110 meters north of the 丸の内 fixture
+	lat, lon := 35.6822, 139.767125
+
+	location, err := repo.FindNearestLocation(ctx, lat, lon, 50, "", nil)
+	require.NoError(t, err)
+	assert.Nil(t, location)
+
+	location, err = repo.FindNearestLocation(ctx, lat, lon, 500, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, "丸の内", location.Address1)
+	assert.InDelta(t, 107, location.Distance, 2)
+}
+
+func TestPostgresRepository_FindNearestLocation_Distance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// Queried at its exact coordinates, the 丸の内 fixture is where the point is
+	location, err := repo.FindNearestLocation(ctx, 35.681236, 139.767125, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, "丸の内", location.Address1)
+	assert.InDelta(t, 0, location.Distance, 0.01)
+}
+
+func TestPostgresRepository_FindNearestLocations_Order(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// Close to the 赤坂 fixture, a few kilometers from 丸の内
+	nearby, err := repo.FindNearestLocations(ctx, 35.6751, 139.7321, 10000, "", nil, 5)
+	require.NoError(t, err)
+	require.Len(t, nearby, 2)
+	assert.Equal(t, []string{"赤坂", "丸の内"}, []string{nearby[0].Address1, nearby[1].Address1})
+	assert.Less(t, nearby[0].Distance, nearby[1].Distance)
+
+	// The limit keeps the nearest
+	nearby, err = repo.FindNearestLocations(ctx, 35.6751, 139.7321, 10000, "", nil, 1)
+	require.NoError(t, err)
+	require.Len(t, nearby, 1)
+	assert.Equal(t, "赤坂", nearby[0].Address1)
+}
+
+func TestPostgresRepository_FindNearestLocation_Source(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// The same point imported from two datasets
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, source_file, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '', 'data/a.csv', ST_SetSRID(ST_MakePoint(139.7, 35.7), 4326)),
+		('東京都', '千代田区', '丸の内1', '', 'data/b.csv', ST_SetSRID(ST_MakePoint(139.7, 35.7), 4326))
+	`)
+	require.NoError(t, err)
+
+	for _, source := range []string{"data/a.csv", "data/b.csv"} {
+		t.Run(source, func(t *testing.T) {
+			location, err := repo.FindNearestLocation(ctx, 35.7, 139.7, 100, source, nil)
+			require.NoError(t, err)
+			require.NotNil(t, location)
+			assert.Equal(t, source, location.Source)
+
+			nearby, err := repo.FindNearestLocations(ctx, 35.7, 139.7, 100, source, nil, 5)
+			require.NoError(t, err)
+			require.Len(t, nearby, 1)
+			assert.Equal(t, source, nearby[0].Source)
+		})
+	}
+
+	// Without a filter both are candidates and the one returned reports its dataset
+	nearby, err := repo.FindNearestLocations(ctx, 35.7, 139.7, 100, "", nil, 5)
+	require.NoError(t, err)
+	require.Len(t, nearby, 2)
+	assert.ElementsMatch(t, []string{"data/a.csv", "data/b.csv"}, []string{nearby[0].Source, nearby[1].Source})
+
+	missing, err := repo.FindNearestLocation(ctx, 35.7, 139.7, 100, "data/c.csv", nil)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestPostgresRepository_FindNearestLocation_Exclude(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	var ids []int
+	rows, err := pool.Query(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '1', ST_SetSRID(ST_MakePoint(139.7, 35.7), 4326)),
+		('東京都', '千代田区', '丸の内一丁目', '2', ST_SetSRID(ST_MakePoint(139.7001, 35.7), 4326)),
+		('東京都', '千代田区', '丸の内一丁目', '3', ST_SetSRID(ST_MakePoint(139.7002, 35.7), 4326))
+		RETURNING id
+	`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, ids, 3)
+
+	// Each exclusion moves on to the next nearest address
+	location, err := repo.FindNearestLocation(ctx, 35.7, 139.7, 100, "", []int{ids[0]})
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, ids[1], location.ID)
+
+	nearby, err := repo.FindNearestLocations(ctx, 35.7, 139.7, 100, "", []int{ids[0], ids[1]}, 5)
+	require.NoError(t, err)
+	require.Len(t, nearby, 1)
+	assert.Equal(t, ids[2], nearby[0].ID)
+
+	missing, err := repo.FindNearestLocation(ctx, 35.7, 139.7, 100, "", ids)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestPostgresRepository_SearchLocationsByText_OrderByImportance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	// The same district name in three places, one without an importance
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, importance, geom) VALUES
+		('福島県', '伊達市', '中央', 60000, ST_SetSRID(ST_MakePoint(140.56, 37.82), 4326)),
+		('北海道', '札幌市中央区', '中央', 250000, ST_SetSRID(ST_MakePoint(141.35, 43.06), 4326)),
+		('長野県', '佐久市', '中央', NULL, ST_SetSRID(ST_MakePoint(138.43, 36.25), 4326))
+	`)
+	require.NoError(t, err)
+
+	locations, err := repo.SearchLocationsByText(ctx, models.SearchOptions{Query: "中央", OrderBy: models.OrderByImportance})
+	require.NoError(t, err)
+
+	municipalities := make([]string, len(locations))
+	for i, loc := range locations {
+		municipalities[i] = loc.Municipality
+	}
+	assert.Equal(t, []string{"札幌市中央区", "伊達市", "佐久市"}, municipalities)
+}
+
+func TestPostgresRepository_FindNearestLocation_Altitude(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	// A 2D point has no altitude
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, geom) VALUES
+		('東京都', '港区', '赤坂', ST_SetSRID(ST_MakePoint(139.732, 35.675), 4326))
+	`)
+	require.NoError(t, err)
+
+	location, err := repo.FindNearestLocation(ctx, 35.675, 139.732, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Nil(t, location.Altitude)
+
+	// A table imported with altitude stores it as the Z of a POINTZ geom
+	_, err = pool.Exec(ctx, `
+		TRUNCATE locations;
+		ALTER TABLE locations ALTER COLUMN geom TYPE GEOGRAPHY(POINTZ, 4326);
+		INSERT INTO locations (prefecture, municipality, address_1, geom) VALUES
+		('東京都', '千代田区', '丸の内', ST_SetSRID(ST_MakePoint(139.767125, 35.681236, 3.5), 4326))
+	`)
+	require.NoError(t, err)
+
+	location, err = repo.FindNearestLocation(ctx, 35.681236, 139.767125, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	require.NotNil(t, location.Altitude)
+	assert.InDelta(t, 3.5, *location.Altitude, 0.001)
+}
+
+func TestPostgresRepository_FindLocationsByAddress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, block_lot, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '', '1', ST_SetSRID(ST_MakePoint(139.767125, 35.681236), 4326)),
+		('東京都', '千代田区', '丸の内二丁目', '', '1', ST_SetSRID(ST_MakePoint(139.764, 35.68), 4326))
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{query: "東京都千代田区丸の内一丁目", expected: 1},
+		{query: "東京都 千代田区 丸の内一丁目1", expected: 1},
+		{query: "東京都千代田区丸の内", expected: 0},
+	}
+	for _, tt := range tests {
+		locations, err := repo.FindLocationsByAddress(ctx, models.SearchOptions{Query: tt.query})
+		require.NoError(t, err)
+		assert.Len(t, locations, tt.expected, tt.query)
+	}
+	// Only 丸の内二丁目 is inside the box
+	bbox := &models.BoundingBox{MinLon: 139.763, MinLat: 35.679, MaxLon: 139.765, MaxLat: 35.681}
+	locations, err := repo.FindLocationsByAddress(ctx, models.SearchOptions{Query: "東京都千代田区丸の内一丁目", BBox: bbox})
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+	locations, err = repo.FindLocationsByAddress(ctx, models.SearchOptions{Query: "東京都千代田区丸の内二丁目", BBox: bbox})
+	require.NoError(t, err)
+	assert.Len(t, locations, 1)
+}
+
+func TestPostgresRepository_SearchLocationsByTrigram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{TextSearchConfig: "japanese"})
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `CREATE INDEX locations_full_address_trgm_idx ON locations USING GIN (`+FullAddress+` gin_trgm_ops)`)
+	require.NoError(t, err)
+
+	// 丸の肉 is a typo of 丸の内 that full-text search can't match
+	opts := models.SearchOptions{Query: "東京都千代田区丸の肉"}
+	locations, err := repo.SearchLocationsByText(ctx, opts)
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+
+	locations, err = repo.SearchLocationsByTrigram(ctx, opts)
+	require.NoError(t, err)
+	require.NotEmpty(t, locations)
+	assert.Equal(t, "千代田区", locations[0].Municipality)
+	assert.Equal(t, "丸の内", locations[0].Address1)
+	assert.Greater(t, locations[0].Score, 0.3)
+}
+
+func TestPostgresRepository_SuggestLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{prefix: "千代", expected: []string{"東京都千代田区", "東京都千代田区丸の内"}},
+		{prefix: "東京都千代田区丸", expected: []string{"東京都千代田区丸の内"}},
+		{prefix: "東京都", expected: []string{"東京都港区", "東京都千代田区", "東京都港区赤坂", "東京都千代田区丸の内"}},
+		{prefix: "赤", expected: []string{"東京都港区赤坂"}},
+		{prefix: "%", expected: []string{}},
+	}
+	for _, tt := range tests {
+		suggestions, err := repo.SuggestLocations(ctx, tt.prefix, 10)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, suggestions, tt.prefix)
+	}
+
+	suggestions, err := repo.SuggestLocations(ctx, "東京都", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"東京都港区"}, suggestions)
+}
+
+func TestPostgresRepository_SuggestLocations_PrefixFirst(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, geom) VALUES
+		('北海道', '京極町', '', ST_SetSRID(ST_MakePoint(140.873, 42.858), 4326)),
+		('京都府', '京田辺市', '', ST_SetSRID(ST_MakePoint(135.768, 34.814), 4326))
+	`)
+	require.NoError(t, err)
+
+	// 京都府京田辺市 starts with what was typed, so it ranks above the shorter 北海道京極町,
+	// which only matches on its municipality
+	suggestions, err := repo.SuggestLocations(ctx, "京", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"京都府京田辺市", "北海道京極町"}, suggestions)
+}
+
+func TestPostgresRepository_SuggestLocations_Indexes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	ctx := context.Background()
+
+	conn, err := pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	// The table is too small for the planner to pick an index unless sequential scans are off
+	_, err = conn.Exec(ctx, "SET enable_seqscan = off")
+	require.NoError(t, err)
+
+	// Each prefix match must be served by the index the importer builds on its expression
+	for name, expr := range map[string]string{
+		"locations_suggest_full_town_idx":         SuggestFullTown,
+		"locations_suggest_municipality_town_idx": SuggestMunicipalityTown,
+		"locations_suggest_town_idx":              SuggestTown,
+		"locations_suggest_full_municipality_idx": SuggestFullMunicipality,
+		"locations_suggest_municipality_idx":      SuggestMunicipality,
+	} {
+		_, err := conn.Exec(ctx, `CREATE INDEX `+name+` ON locations (`+expr+` text_pattern_ops)`)
+		require.NoError(t, err)
+
+		var plan strings.Builder
+		rows, err := conn.Query(ctx, `EXPLAIN SELECT 1 FROM locations WHERE `+expr+` LIKE $1 || '%'`, "東京都")
+		require.NoError(t, err)
+		for rows.Next() {
+			var line string
+			require.NoError(t, rows.Scan(&line))
+			plan.WriteString(line + "\n")
+		}
+		require.NoError(t, rows.Err())
+		assert.Contains(t, plan.String(), name, expr)
+	}
+}
+
+func TestPostgresRepository_FindLocationsByNormalizedAddress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// The second row was imported without --normalized-key
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, block_lot, normalized_address, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '9番', '1号', '東京都千代田区丸の内1-9-1', ST_SetSRID(ST_MakePoint(139.767125, 35.681236), 4326)),
+		('東京都', '千代田区', '丸の内二丁目', '', '1', NULL, ST_SetSRID(ST_MakePoint(139.764, 35.68), 4326))
+	`)
+	require.NoError(t, err)
+
+	locations, err := repo.FindLocationsByNormalizedAddress(ctx, models.SearchOptions{Query: "東京都千代田区丸の内1-9-1"})
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	assert.Equal(t, "丸の内一丁目", locations[0].Address1)
+
+	locations, err = repo.FindLocationsByNormalizedAddress(ctx, models.SearchOptions{Query: "東京都千代田区丸の内2-1"})
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+}
+
+func TestPostgresRepository_FindLocationsByKana(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// The third row was imported from a dataset without readings
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, block_lot, prefecture_kana, municipality_kana, address_1_kana, kana_key, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '', '1', 'トウキョウト', 'チヨダク', 'マルノウチ1チョウメ', 'トウキョウトチヨダクマルノウチ1チョウメ', ST_SetSRID(ST_MakePoint(139.767125, 35.681236), 4326)),
+		('東京都', '千代田区', '丸の内', '', '1', 'トウキョウト', 'チヨダク', 'マルノウチ', 'トウキョウトチヨダクマルノウチ', ST_SetSRID(ST_MakePoint(139.764, 35.68), 4326)),
+		('東京都', '千代田区', '丸の内二丁目', '', '1', NULL, NULL, NULL, NULL, ST_SetSRID(ST_MakePoint(139.763, 35.679), 4326))
+	`)
+	require.NoError(t, err)
+
+	// The shorter key is covered more fully by the query, so it ranks first
+	locations, err := repo.FindLocationsByKana(ctx, models.SearchOptions{Query: "チヨダクマルノウチ"})
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	assert.Equal(t, "丸の内", locations[0].Address1)
+	assert.Equal(t, "トウキョウト", locations[0].PrefectureKana)
+	assert.Equal(t, "チヨダク", locations[0].MunicipalityKana)
+	assert.Equal(t, "マルノウチ", locations[0].Address1Kana)
+	assert.Empty(t, locations[0].Address2Kana)
+	assert.Equal(t, "丸の内一丁目", locations[1].Address1)
+
+	locations, err = repo.FindLocationsByKana(ctx, models.SearchOptions{Query: "ミナトク"})
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+}
+
+func TestPostgresRepository_Kana_AllLookups(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// 丸の内 (id 1) gets readings; 赤坂 (id 2) has none
+	_, err := pool.Exec(ctx, `UPDATE locations SET prefecture_kana = 'トウキョウト', municipality_kana = 'チヨダク', address_1_kana = 'マルノウチ' WHERE id = 1`)
+	require.NoError(t, err)
+
+	nearest, err := repo.FindNearestLocation(ctx, 35.681236, 139.767125, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, nearest)
+	assert.Equal(t, "マルノウチ", nearest.Address1Kana)
+
+	nearby, err := repo.FindNearestLocations(ctx, 35.681236, 139.767125, 10000, "", nil, 2)
+	require.NoError(t, err)
+	require.Len(t, nearby, 2)
+	assert.Equal(t, "チヨダク", nearby[0].MunicipalityKana)
+	assert.Empty(t, nearby[1].MunicipalityKana)
+
+	byID, err := repo.FindLocationsByIDs(ctx, []int{1, 2})
+	require.NoError(t, err)
+	require.Len(t, byID, 2)
+	assert.Equal(t, "トウキョウト", byID[0].PrefectureKana)
+	assert.Empty(t, byID[1].PrefectureKana)
+
+	var exported []models.Location
+	require.NoError(t, repo.StreamAllLocations(ctx, func(loc models.Location) error {
+		exported = append(exported, loc)
+		return nil
+	}))
+	require.Len(t, exported, 2)
+	assert.Equal(t, "マルノウチ", exported[0].Address1Kana)
+	assert.Empty(t, exported[1].Address1Kana)
+}
+
+func TestPostgresRepository_StatementTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{StatementTimeout: 100 * time.Millisecond})
+
+	// The context has no deadline, so only Postgres can cut the query short
+	slowQuery := func(ctx context.Context) (err error) {
+		db, end, err := repo.bounded(ctx)
+		if err != nil {
+			return err
+		}
+		defer end(&err)
+
+		_, err = db.Exec(ctx, "SELECT pg_sleep(5)")
+		return err
+	}
+
+	start := time.Now()
+	err := slowQuery(context.Background())
+
+	assert.ErrorIs(t, err, ErrStatementTimeout)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// The timeout is local to the query's transaction and doesn't stay on the pooled connection
+	var timeout string
+	require.NoError(t, pool.QueryRow(context.Background(), "SHOW statement_timeout").Scan(&timeout))
+	assert.Equal(t, "0", timeout)
+}
+
+func TestPostgresRepository_FindColocatedLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// Three units of one building about 2m apart, and the building next door about 18m away
+	var ids []int
+	rows, err := pool.Query(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, block_lot, geom) VALUES
+		('東京都', '千代田区', '丸の内一丁目', '1', '101', ST_SetSRID(ST_MakePoint(139.7, 35.7), 4326)),
+		('東京都', '千代田区', '丸の内一丁目', '1', '102', ST_SetSRID(ST_MakePoint(139.70002, 35.7), 4326)),
+		('東京都', '千代田区', '丸の内一丁目', '1', '201', ST_SetSRID(ST_MakePoint(139.7, 35.70002), 4326)),
+		('東京都', '千代田区', '丸の内一丁目', '2', '', ST_SetSRID(ST_MakePoint(139.7002, 35.7), 4326))
+		RETURNING id
+	`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, ids, 4)
+
+	colocated, err := repo.FindColocatedLocations(ctx, ids[0], 5, "", nil, 100)
+	require.NoError(t, err)
+	require.Len(t, colocated, 2)
+	assert.ElementsMatch(t, []int{ids[1], ids[2]}, []int{colocated[0].ID, colocated[1].ID})
+
+	// A wider distance takes in the building next door
+	colocated, err = repo.FindColocatedLocations(ctx, ids[0], 25, "", []int{ids[1]}, 100)
+	require.NoError(t, err)
+	require.Len(t, colocated, 2)
+	assert.Equal(t, []int{ids[2], ids[3]}, []int{colocated[0].ID, colocated[1].ID})
+}
+
+func TestPostgresRepository_StreamAllLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// More than one batch, so paging by ID is exercised
+	_, err := pool.Exec(ctx, `
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, geom)
+		SELECT '東京都', '千代田区', '丸の内一丁目', n::text, ST_SetSRID(ST_MakePoint(139.7, 35.7), 4326)
+		FROM generate_series(1, $1) n
+	`, exportBatchSize+5)
+	require.NoError(t, err)
+
+	var ids []int
+	err = repo.StreamAllLocations(ctx, func(loc models.Location) error {
+		ids = append(ids, loc.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	// Plus the two locations every test database starts with
+	require.Len(t, ids, exportBatchSize+7)
+	assert.IsIncreasing(t, ids)
+}
+
+func TestPostgresRepository_FindNearestLocationInMunicipality(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	pool := setupTestDatabase(t)
+	repo := NewRepository(pool, Config{})
+	ctx := context.Background()
+
+	// The boundary between the two wards runs along longitude 139.75; the point at 139.7499 lies
+	// in 千代田区, but the nearest address is just across the boundary in 中央区
+	_, err := pool.Exec(ctx, `
+		INSERT INTO municipality_boundaries (prefecture, municipality, geom) VALUES
+		('東京都', '千代田区', ST_Multi(ST_MakeEnvelope(139.74, 35.67, 139.75, 35.68, 4326))),
+		('東京都', '中央区', ST_Multi(ST_MakeEnvelope(139.75, 35.67, 139.76, 35.68, 4326)));
+
+		INSERT INTO locations (prefecture, municipality, address_1, address_2, geom) VALUES
+		('東京都', '中央区', '八重洲', '1', ST_SetSRID(ST_MakePoint(139.7501, 35.675), 4326)),
+		('東京都', '千代田区', '丸の内', '2', ST_SetSRID(ST_MakePoint(139.7496, 35.675), 4326));
+	`)
+	require.NoError(t, err)
+
+	nearest, err := repo.FindNearestLocation(ctx, 35.675, 139.7499, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, nearest)
+	assert.Equal(t, "中央区", nearest.Municipality)
+
+	location, err := repo.FindNearestLocationInMunicipality(ctx, 35.675, 139.7499, 100, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, "千代田区", location.Municipality)
+
+	// Outside every boundary
+	location, err = repo.FindNearestLocationInMunicipality(ctx, 35.69, 139.7499, 100, "", nil)
+	require.NoError(t, err)
+	assert.Nil(t, location)
+}
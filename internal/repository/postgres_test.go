@@ -0,0 +1,716 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRows serves fixed rows, assigning each value to the matching Scan destination
+type fakeRows struct {
+	rows [][]any
+	next int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) Values() ([]any, error)                       { return r.rows[r.next-1], nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, v := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// fakeQuerier records the last query and answers it with fixed rows
+type fakeQuerier struct {
+	rows [][]any
+	sql  string
+	args []any
+	tx   *fakeTx
+}
+
+func (q *fakeQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.sql, q.args = sql, args
+	return &fakeRows{rows: q.rows}, nil
+}
+
+func (q *fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.sql, q.args = sql, args
+	if len(q.rows) == 0 {
+		return noRow{}
+	}
+	return &fakeRows{rows: q.rows, next: 1}
+}
+
+// noRow is the result of a QueryRow that matched nothing
+type noRow struct{}
+
+func (noRow) Scan(dest ...any) error { return pgx.ErrNoRows }
+
+func (q *fakeQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	q.sql, q.args = sql, args
+	return pgconn.CommandTag{}, nil
+}
+
+// Begin starts q's fakeTx, which runs its queries through q
+func (q *fakeQuerier) Begin(ctx context.Context) (pgx.Tx, error) {
+	if q.tx == nil {
+		q.tx = &fakeTx{}
+	}
+	q.tx.q = q
+	return q.tx, nil
+}
+
+func TestRepository_SearchLocationsByText_SQL(t *testing.T) {
+	after := &models.SearchCursor{Rank: 0.5, ID: 42}
+	bbox := &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+
+	tests := []struct {
+		name            string
+		opts            models.SearchOptions
+		expectedArgs    []any
+		expectedSQL     []string
+		unexpectedSQL   []string
+		row             []any
+		expectedProject *models.ProjectedPoint
+	}{
+		{
+			name:          "defaults",
+			opts:          models.SearchOptions{Query: "丸の内"},
+			expectedArgs:  []any{"丸の内", "japanese", models.DefaultSearchLimit, 0},
+			expectedSQL:   []string{"ORDER BY rank DESC, id DESC", "LIMIT $3 OFFSET $4"},
+			unexpectedSQL: []string{"ST_Transform", "::real"},
+			row:           []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.5},
+		},
+		{
+			name:            "projected",
+			opts:            models.SearchOptions{Query: "丸の内", SRID: 3857},
+			expectedArgs:    []any{"丸の内", "japanese", models.DefaultSearchLimit, 0, 3857},
+			expectedSQL:     []string{"ST_X(ST_Transform(geom::geometry, $5))", "ST_Y(ST_Transform(geom::geometry, $5))"},
+			unexpectedSQL:   []string{"::real"},
+			row:             []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.5, 15558907.3, 4256463.9},
+			expectedProject: &models.ProjectedPoint{SRID: 3857, X: 15558907.3, Y: 4256463.9},
+		},
+		{
+			name:          "cursor",
+			opts:          models.SearchOptions{Query: "丸の内", Limit: 5, After: after},
+			expectedArgs:  []any{"丸の内", "japanese", 5, 0, 0.5, 42},
+			expectedSQL:   []string{"id) < ($5::real, $6)"},
+			unexpectedSQL: []string{"ST_Transform"},
+			row:           []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.25},
+		},
+		{
+			name:          "ordered by importance",
+			opts:          models.SearchOptions{Query: "丸の内", OrderBy: models.OrderByImportance},
+			expectedArgs:  []any{"丸の内", "japanese", models.DefaultSearchLimit, 0},
+			expectedSQL:   []string{"ORDER BY importance DESC NULLS LAST, rank DESC, id DESC"},
+			unexpectedSQL: []string{"::real"},
+			row:           []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.5},
+		},
+		{
+			name:            "projected with cursor",
+			opts:            models.SearchOptions{Query: "丸の内", SRID: 6668, After: after},
+			expectedArgs:    []any{"丸の内", "japanese", models.DefaultSearchLimit, 0, 6668, 0.5, 42},
+			expectedSQL:     []string{"ST_Transform(geom::geometry, $5)", "id) < ($6::real, $7)"},
+			row:             []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.25, 139.767125, 35.681236},
+			expectedProject: &models.ProjectedPoint{SRID: 6668, X: 139.767125, Y: 35.681236},
+		},
+		{
+			name:          "bounding box",
+			opts:          models.SearchOptions{Query: "丸の内", BBox: bbox},
+			expectedArgs:  []any{"丸の内", "japanese", models.DefaultSearchLimit, 0, 139.7, 35.6, 139.8, 35.7},
+			expectedSQL:   []string{"@@ to_tsquery($2::regconfig, $1)\n\t\t\tAND ST_Within(geom::geometry, ST_MakeEnvelope($5, $6, $7, $8, 4326))"},
+			unexpectedSQL: []string{"ST_Transform", "::real"},
+			row:           []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.5},
+		},
+		{
+			name:          "bounding box with cursor",
+			opts:          models.SearchOptions{Query: "丸の内", After: after, BBox: bbox},
+			expectedArgs:  []any{"丸の内", "japanese", models.DefaultSearchLimit, 0, 0.5, 42, 139.7, 35.6, 139.8, 35.7},
+			expectedSQL:   []string{"id) < ($5::real, $6)", "ST_MakeEnvelope($7, $8, $9, $10, 4326)"},
+			unexpectedSQL: []string{"ST_Transform"},
+			row:           []any{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{tt.row}}
+			repo := NewRepository(db, Config{})
+
+			locations, err := repo.SearchLocationsByText(context.Background(), tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedArgs, db.args)
+			for _, fragment := range tt.expectedSQL {
+				assert.Contains(t, db.sql, fragment)
+			}
+			for _, fragment := range tt.unexpectedSQL {
+				assert.NotContains(t, db.sql, fragment)
+			}
+			require.Len(t, locations, 1)
+			assert.Equal(t, tt.row[14], locations[0].Score)
+			assert.Equal(t, tt.expectedProject, locations[0].Projected)
+		})
+	}
+}
+
+func TestRepository_SearchByAddress_SQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		search        func(r *Repository, ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+		opts          models.SearchOptions
+		expectedArgs  []any
+		expectedSQL   []string
+		unexpectedSQL []string
+	}{
+		{
+			name:          "exact",
+			search:        (*Repository).FindLocationsByAddress,
+			opts:          models.SearchOptions{Query: "東京都千代田区丸の内 1"},
+			expectedArgs:  []any{"東京都千代田区丸の内 1", models.DefaultSearchLimit, 0},
+			expectedSQL:   []string{"regexp_replace($1, '\\s', '', 'g') IN (", "|| block_lot)", "ORDER BY rank DESC, id", "LIMIT $2 OFFSET $3"},
+			unexpectedSQL: []string{"ST_Transform", "to_tsquery"},
+		},
+		{
+			name:          "normalized",
+			search:        (*Repository).FindLocationsByNormalizedAddress,
+			opts:          models.SearchOptions{Query: "東京都千代田区丸の内1-9-1"},
+			expectedArgs:  []any{"東京都千代田区丸の内1-9-1", models.DefaultSearchLimit, 0},
+			expectedSQL:   []string{"WHERE normalized_address = $1", "ORDER BY rank DESC, id"},
+			unexpectedSQL: []string{"regexp_replace", "to_tsquery"},
+		},
+		{
+			name:          "kana",
+			search:        (*Repository).FindLocationsByKana,
+			opts:          models.SearchOptions{Query: "チヨダクマルノウチ"},
+			expectedArgs:  []any{"チヨダクマルノウチ", models.DefaultSearchLimit, 0},
+			expectedSQL:   []string{"WHERE kana_key LIKE '%' || $1 || '%'", "length($1)::float8 / length(kana_key) as rank", "ORDER BY rank DESC, id"},
+			unexpectedSQL: []string{"regexp_replace", "to_tsquery"},
+		},
+		{
+			name:          "fuzzy",
+			search:        (*Repository).SearchLocationsByTrigram,
+			opts:          models.SearchOptions{Query: "東京都千代田区丸之内", Offset: 20},
+			expectedArgs:  []any{"東京都千代田区丸之内", models.DefaultSearchLimit, 20},
+			expectedSQL:   []string{"address_2) % $1", "similarity((prefecture || municipality || address_1 || address_2), $1) as rank"},
+			unexpectedSQL: []string{"ST_Transform", "importance"},
+		},
+		{
+			name:         "projected and ordered by importance",
+			search:       (*Repository).SearchLocationsByTrigram,
+			opts:         models.SearchOptions{Query: "丸の内", SRID: 3857, OrderBy: models.OrderByImportance},
+			expectedArgs: []any{"丸の内", models.DefaultSearchLimit, 0, 3857},
+			expectedSQL:  []string{"ST_Transform(geom::geometry, $4)", "ORDER BY importance DESC NULLS LAST, rank DESC, id"},
+		},
+		{
+			name:          "fuzzy in a bounding box",
+			search:        (*Repository).SearchLocationsByTrigram,
+			opts:          models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+			expectedArgs:  []any{"丸の内", models.DefaultSearchLimit, 0, 139.7, 35.6, 139.8, 35.7},
+			expectedSQL:   []string{"address_2) % $1\n\t\t\tAND ST_Within(geom::geometry, ST_MakeEnvelope($4, $5, $6, $7, 4326))"},
+			unexpectedSQL: []string{"ST_Transform"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{}
+			repo := NewRepository(db, Config{})
+
+			locations, err := tt.search(repo, context.Background(), tt.opts)
+
+			require.NoError(t, err)
+			assert.Empty(t, locations)
+			assert.Equal(t, tt.expectedArgs, db.args)
+			for _, fragment := range tt.expectedSQL {
+				assert.Contains(t, db.sql, fragment)
+			}
+			for _, fragment := range tt.unexpectedSQL {
+				assert.NotContains(t, db.sql, fragment)
+			}
+		})
+	}
+}
+
+func TestRepository_FindLocationsByArea_SQL(t *testing.T) {
+	db := &fakeQuerier{}
+	repo := NewRepository(db, Config{})
+
+	locations, err := repo.FindLocationsByArea(context.Background(), models.SearchOptions{Municipality: "千代田区", Offset: 20})
+
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+	assert.Equal(t, []any{"", "千代田区", models.DefaultSearchLimit, 20}, db.args)
+	assert.Contains(t, db.sql, "ORDER BY id")
+}
+
+func TestRepository_FindLocationsInBBox_SQL(t *testing.T) {
+	db := &fakeQuerier{}
+	repo := NewRepository(db, Config{})
+
+	bbox := models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+	locations, err := repo.FindLocationsInBBox(context.Background(), bbox, 101)
+
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+	assert.Equal(t, []any{139.7, 35.6, 139.8, 35.7, 101}, db.args)
+	assert.Contains(t, db.sql, "ST_Within(geom::geometry, ST_MakeEnvelope($1, $2, $3, $4, 4326))")
+	assert.Contains(t, db.sql, "ORDER BY id")
+}
+
+func TestRepository_FindNearestLocations_Radius(t *testing.T) {
+	tests := []struct {
+		name           string
+		radius         float64
+		expectedRadius float64
+	}{
+		{name: "within the maximum", radius: 250, expectedRadius: 250},
+		{name: "clamped to the maximum", radius: 50000, expectedRadius: 1000},
+		{name: "unset uses the maximum", radius: 0, expectedRadius: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{}
+			repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+			_, err := repo.FindNearestLocations(context.Background(), 35.681236, 139.767125, tt.radius, "", nil, 3)
+
+			require.NoError(t, err)
+			assert.Equal(t, []any{35.681236, 139.767125, tt.expectedRadius, "", []int{}, 3}, db.args)
+			assert.Contains(t, db.sql, "ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)")
+		})
+	}
+}
+
+func TestRepository_FindNearestLocation_Exclude(t *testing.T) {
+	db := &fakeQuerier{}
+	repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+	location, err := repo.FindNearestLocation(context.Background(), 35.681236, 139.767125, 0, "", []int{1, 2})
+
+	require.NoError(t, err)
+	assert.Nil(t, location)
+	assert.Equal(t, []any{35.681236, 139.767125, 1000.0, "", []int{1, 2}}, db.args)
+	assert.Contains(t, db.sql, "id <> ALL($5)")
+}
+
+func TestRepository_FindNearestLocation_Altitude(t *testing.T) {
+	altitude := 3.5
+	tests := []struct {
+		name     string
+		altitude *float64
+	}{
+		{name: "3D point", altitude: &altitude},
+		{name: "2D point", altitude: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, "", 12.5, tt.altitude}}}
+			repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+			location, err := repo.FindNearestLocation(context.Background(), 35.681236, 139.767125, 0, "", nil)
+
+			require.NoError(t, err)
+			require.NotNil(t, location)
+			assert.Equal(t, tt.altitude, location.Altitude)
+			assert.Equal(t, 12.5, location.Distance)
+			assert.Contains(t, db.sql, "ST_Z(geom) as altitude")
+		})
+	}
+}
+
+func TestRepository_FindNearestLocation_Kana(t *testing.T) {
+	db := &fakeQuerier{rows: [][]any{{1, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, "", 12.5, (*float64)(nil), "トウキョウト", "チヨダク", "マルノウチ", ""}}}
+	repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+	location, err := repo.FindNearestLocation(context.Background(), 35.681236, 139.767125, 0, "", nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, "トウキョウト", location.PrefectureKana)
+	assert.Equal(t, "チヨダク", location.MunicipalityKana)
+	assert.Equal(t, "マルノウチ", location.Address1Kana)
+	assert.Empty(t, location.Address2Kana)
+	assert.Contains(t, db.sql, "coalesce(address_2_kana, '') as address_2_kana")
+}
+
+func TestRepository_FindColocatedLocations(t *testing.T) {
+	// Two units of the building around location 10, their points offset by a few meters
+	db := &fakeQuerier{rows: [][]any{
+		{11, "", "東京都", "千代田区", "丸の内1", "", "1-102", 35.681251, 139.767141, "tokyo.csv", (*float64)(nil)},
+		{12, "", "東京都", "千代田区", "丸の内1", "", "1-201", 35.681219, 139.767102, "tokyo.csv", (*float64)(nil)},
+	}}
+	repo := NewRepository(db, Config{})
+
+	locations, err := repo.FindColocatedLocations(context.Background(), 10, 5, "tokyo.csv", nil, 100)
+
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	assert.Equal(t, []int{11, 12}, []int{locations[0].ID, locations[1].ID})
+	assert.Equal(t, "1-201", locations[1].BlockLot)
+	assert.Equal(t, []any{10, 5.0, "tokyo.csv", []int{}, 100}, db.args)
+	assert.Contains(t, db.sql, "JOIN locations l ON ST_DWithin(l.geom, anchor.geom, $2)")
+	assert.Contains(t, db.sql, "l.id <> $1")
+	assert.Contains(t, db.sql, "ORDER BY l.geom <-> anchor.geom, l.id")
+}
+
+func TestRepository_FindNearestLocationInMunicipality(t *testing.T) {
+	db := &fakeQuerier{rows: [][]any{{1, "", "東京都", "千代田区", "丸の内1", "", "1", 35.681236, 139.767125, "", 0.0, (*float64)(nil)}}}
+	repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+	location, err := repo.FindNearestLocationInMunicipality(context.Background(), 35.681236, 139.767125, 0, "", nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	assert.Equal(t, "千代田区", location.Municipality)
+	assert.Equal(t, []any{35.681236, 139.767125, 1000.0, "", []int{}}, db.args)
+	assert.Contains(t, db.sql, "JOIN locations l ON l.prefecture = b.prefecture AND l.municipality = b.municipality")
+	assert.Contains(t, db.sql, "ST_Contains(b.geom, ST_SetSRID(ST_MakePoint($2, $1), 4326))")
+
+	// No boundary contains the point, or nothing of its municipality is in range
+	repo = NewRepository(&fakeQuerier{}, Config{})
+	location, err = repo.FindNearestLocationInMunicipality(context.Background(), 35.681236, 139.767125, 0, "", nil)
+	require.NoError(t, err)
+	assert.Nil(t, location)
+}
+
+func TestRepository_WarmSpatialIndex(t *testing.T) {
+	db := &fakeQuerier{rows: [][]any{{42}}}
+	repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+	count, err := repo.WarmSpatialIndex(context.Background(), 35.681236, 139.767125)
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+	assert.Equal(t, []any{35.681236, 139.767125, 1000.0}, db.args)
+	assert.Contains(t, db.sql, "ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)")
+}
+
+func TestRepository_CountLocationsByText(t *testing.T) {
+	bbox := &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+
+	tests := []struct {
+		name         string
+		config       string
+		bbox         *models.BoundingBox
+		expectedArgs []any
+		expectedSQL  string
+	}{
+		{name: "default configuration", expectedArgs: []any{"丸の内", "japanese"}},
+		{name: "explicit configuration", config: "simple", expectedArgs: []any{"丸の内", "simple"}},
+		{
+			name:         "inside a bounding box",
+			bbox:         bbox,
+			expectedArgs: []any{"丸の内", "japanese", 139.7, 35.6, 139.8, 35.7},
+			expectedSQL:  "AND ST_Within(geom::geometry, ST_MakeEnvelope($3, $4, $5, $6, 4326))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{{42}}}
+			repo := NewRepository(db, Config{TextSearchConfig: "japanese"})
+
+			count, err := repo.CountLocationsByText(context.Background(), "丸の内", tt.config, tt.bbox)
+
+			require.NoError(t, err)
+			assert.Equal(t, 42, count)
+			assert.Equal(t, tt.expectedArgs, db.args)
+			assert.Contains(t, db.sql, "full_address_tsvector @@ to_tsquery($2::regconfig, $1)")
+			if tt.expectedSQL != "" {
+				assert.Contains(t, db.sql, tt.expectedSQL)
+			} else {
+				assert.NotContains(t, db.sql, "ST_Within")
+			}
+		})
+	}
+}
+
+func TestRepository_SuggestLocations(t *testing.T) {
+	tests := []struct {
+		name            string
+		prefix          string
+		expectedPattern string
+	}{
+		{name: "plain prefix", prefix: "千代", expectedPattern: "千代"},
+		{name: "wildcards matched literally", prefix: `100%_\`, expectedPattern: `100\%\_\\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{{"東京都千代田区"}, {"東京都千代田区丸の内"}}}
+			repo := NewRepository(db, Config{})
+
+			suggestions, err := repo.SuggestLocations(context.Background(), tt.prefix, 10)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"東京都千代田区", "東京都千代田区丸の内"}, suggestions)
+			assert.Equal(t, []any{tt.expectedPattern, 10}, db.args)
+			assert.Contains(t, db.sql, "ORDER BY (suggestion LIKE $1 || '%') DESC, length(suggestion), suggestion")
+		})
+	}
+}
+
+func TestRepository_NeighborCounts(t *testing.T) {
+	db := &fakeQuerier{rows: [][]any{{12}, {0}}}
+	repo := NewRepository(db, Config{})
+
+	counts, err := repo.NeighborCounts(context.Background(), []models.Point{{Lat: 35.681236, Lon: 139.767125}, {Lat: 34.702485, Lon: 135.495951}}, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{12, 0}, counts)
+	assert.Equal(t, []any{[]float64{35.681236, 34.702485}, []float64{139.767125, 135.495951}, 100.0}, db.args)
+	assert.Contains(t, db.sql, "WITH ORDINALITY")
+}
+
+func TestRepository_SearchLocationsByText_Fields(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          models.SearchOptions
+		row           []any
+		expectedSQL   string
+		unexpectedSQL []string
+		expected      models.Location
+	}{
+		{
+			name:          "requested fields plus id",
+			opts:          models.SearchOptions{Query: "丸の内", Fields: []string{"latitude", "municipality"}},
+			row:           []any{1, "千代田区", 35.681236, 0.5},
+			expectedSQL:   "id,\n\t\t\tmunicipality,\n\t\t\tST_Y(geom) as latitude,\n\t\t\tts_rank(",
+			unexpectedSQL: []string{"prefecture,", "address_1", "ST_X(geom)"},
+			expected:      models.Location{ID: 1, Municipality: "千代田区", Latitude: 35.681236, Score: 0.5, TextSearchConfig: DefaultTextSearchConfig},
+		},
+		{
+			name:          "bbox needs the area",
+			opts:          models.SearchOptions{Query: "丸の内", Fields: []string{"id"}, IncludeBBox: true},
+			row:           []any{1, "東京都", "千代田区", 0.5},
+			expectedSQL:   "id,\n\t\t\tprefecture,\n\t\t\tmunicipality,\n\t\t\tts_rank(",
+			unexpectedSQL: []string{"ST_Y(geom)"},
+			expected:      models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Score: 0.5, TextSearchConfig: DefaultTextSearchConfig},
+		},
+		{
+			name:          "kana reading",
+			opts:          models.SearchOptions{Query: "チヨダク", Fields: []string{"municipality_kana"}},
+			row:           []any{1, "チヨダク", 0.5},
+			expectedSQL:   "id,\n\t\t\tcoalesce(municipality_kana, '') as municipality_kana,\n\t\t\tts_rank(",
+			unexpectedSQL: []string{"prefecture,", "prefecture_kana"},
+			expected:      models.Location{ID: 1, MunicipalityKana: "チヨダク", Score: 0.5, TextSearchConfig: DefaultTextSearchConfig},
+		},
+		{
+			name:          "density needs the coordinates",
+			opts:          models.SearchOptions{Query: "丸の内", Fields: []string{"id"}, IncludeDensity: true},
+			row:           []any{1, 35.681236, 139.767125, 0.5},
+			expectedSQL:   "id,\n\t\t\tST_Y(geom) as latitude,\n\t\t\tST_X(geom) as longitude,\n\t\t\tts_rank(",
+			unexpectedSQL: []string{"prefecture,"},
+			expected:      models.Location{ID: 1, Latitude: 35.681236, Longitude: 139.767125, Score: 0.5, TextSearchConfig: DefaultTextSearchConfig},
+		},
+		{
+			name:          "external id",
+			opts:          models.SearchOptions{Query: "丸の内", Fields: []string{"external_id"}},
+			row:           []any{1, "13101-000001", 0.5},
+			expectedSQL:   "id,\n\t\t\tcoalesce(external_id, '') as external_id,\n\t\t\tts_rank(",
+			unexpectedSQL: []string{"prefecture,"},
+			expected:      models.Location{ID: 1, ExternalID: "13101-000001", Score: 0.5, TextSearchConfig: DefaultTextSearchConfig},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{tt.row}}
+			repo := NewRepository(db, Config{})
+
+			locations, err := repo.SearchLocationsByText(context.Background(), tt.opts)
+
+			require.NoError(t, err)
+			assert.Contains(t, db.sql, tt.expectedSQL)
+			for _, fragment := range tt.unexpectedSQL {
+				assert.NotContains(t, db.sql, fragment)
+			}
+			assert.Equal(t, []models.Location{tt.expected}, locations)
+		})
+	}
+}
+
+// configQuerier answers each text search with the rows of the configuration bound as $2
+type configQuerier struct {
+	fakeQuerier
+	rows    map[string][][]any
+	configs []string
+}
+
+func (q *configQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	config := args[1].(string)
+	q.configs = append(q.configs, config)
+	return &fakeRows{rows: q.rows[config]}, nil
+}
+
+func TestRepository_SearchLocationsByText_RetryConfigs(t *testing.T) {
+	row := func(id int) []any {
+		return []any{id, "", "東京都", "千代田区", "丸の内", "", "", 35.681236, 139.767125, (*float64)(nil), "", "", "", "", 0.5}
+	}
+	rows := map[string][][]any{
+		"japanese": {row(1)},
+		"simple":   {row(2), row(3)},
+	}
+
+	tests := []struct {
+		name            string
+		config          Config
+		after           *models.SearchCursor
+		expectedConfigs []string
+		expectedIDs     []int
+		expectedConfig  string
+	}{
+		{
+			name:            "primary finds enough",
+			config:          Config{TextSearchRetryConfigs: []string{"simple"}},
+			expectedConfigs: []string{"japanese"},
+			expectedIDs:     []int{1},
+			expectedConfig:  "japanese",
+		},
+		{
+			name:            "retries with too few results",
+			config:          Config{TextSearchRetryConfigs: []string{"simple"}, TextSearchMinResults: 2},
+			expectedConfigs: []string{"japanese", "simple"},
+			expectedIDs:     []int{2, 3},
+			expectedConfig:  "simple",
+		},
+		{
+			name:            "returns the last results when none finds enough",
+			config:          Config{TextSearchRetryConfigs: []string{"simple", "english"}, TextSearchMinResults: 3},
+			expectedConfigs: []string{"japanese", "simple", "english"},
+			expectedIDs:     nil,
+			expectedConfig:  "english",
+		},
+		{
+			name:            "repeated configurations are tried once",
+			config:          Config{TextSearchRetryConfigs: []string{"japanese", "simple", "simple"}, TextSearchMinResults: 5},
+			expectedConfigs: []string{"japanese", "simple"},
+			expectedIDs:     []int{2, 3},
+			expectedConfig:  "simple",
+		},
+		{
+			name:            "cursor continues with its configuration",
+			config:          Config{TextSearchRetryConfigs: []string{"simple"}},
+			after:           &models.SearchCursor{Rank: 0.5, ID: 9, Config: "simple"},
+			expectedConfigs: []string{"simple"},
+			expectedIDs:     []int{2, 3},
+			expectedConfig:  "simple",
+		},
+		{
+			name:            "cursor of an unconfigured configuration tries them all",
+			config:          Config{TextSearchRetryConfigs: []string{"simple"}},
+			after:           &models.SearchCursor{Rank: 0.5, ID: 9, Config: "english"},
+			expectedConfigs: []string{"japanese"},
+			expectedIDs:     []int{1},
+			expectedConfig:  "japanese",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &configQuerier{rows: rows}
+			repo := NewRepository(db, tt.config)
+
+			locations, err := repo.SearchLocationsByText(context.Background(), models.SearchOptions{Query: "丸の内", After: tt.after})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedConfigs, db.configs)
+			var ids []int
+			for _, loc := range locations {
+				ids = append(ids, loc.ID)
+				assert.Equal(t, tt.expectedConfig, loc.TextSearchConfig)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestRepository_InterpolateAddress_SQL(t *testing.T) {
+	tests := []struct {
+		name            string
+		srid            int
+		row             []any
+		expectedArgs    []any
+		expectedProject *models.ProjectedPoint
+	}{
+		{
+			name:         "WGS84",
+			row:          []any{"東京都", "千代田区", "丸の内1", "", 35.681, 139.767},
+			expectedArgs: []any{"東京都千代田区丸の内1", 5},
+		},
+		{
+			name:            "projected",
+			srid:            3857,
+			row:             []any{"東京都", "千代田区", "丸の内1", "", 35.681, 139.767, 15558907.3, 4256463.9},
+			expectedArgs:    []any{"東京都千代田区丸の内1", 5, 3857},
+			expectedProject: &models.ProjectedPoint{SRID: 3857, X: 15558907.3, Y: 4256463.9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{rows: [][]any{tt.row}}
+			repo := NewRepository(db, Config{})
+
+			location, err := repo.InterpolateAddress(context.Background(), "東京都千代田区丸の内1", 5, tt.srid)
+
+			require.NoError(t, err)
+			require.NotNil(t, location)
+			assert.Equal(t, tt.expectedArgs, db.args)
+			assert.Contains(t, db.sql, "ST_LineInterpolatePoint(geom::geometry")
+			assert.Contains(t, db.sql, "$2 BETWEEN least(from_number, to_number) AND greatest(from_number, to_number)")
+			assert.Equal(t, "5", location.BlockLot)
+			assert.True(t, location.Interpolated)
+			assert.Zero(t, location.ID)
+			assert.Equal(t, tt.expectedProject, location.Projected)
+		})
+	}
+}
+
+func TestRepository_FindNearestSegmentAddress(t *testing.T) {
+	db := &fakeQuerier{rows: [][]any{{"東京都", "千代田区", "丸の内1", "", 7, 35.681, 139.767}}}
+	repo := NewRepository(db, Config{MaxRadiusMeters: 1000})
+
+	location, err := repo.FindNearestSegmentAddress(context.Background(), 35.681236, 139.767125, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.Location{
+		Prefecture:   "東京都",
+		Municipality: "千代田区",
+		Address1:     "丸の内1",
+		BlockLot:     "7",
+		Latitude:     35.681,
+		Longitude:    139.767,
+		Interpolated: true,
+	}, location)
+	assert.Equal(t, []any{35.681236, 139.767125, 1000.0}, db.args)
+	assert.Contains(t, db.sql, "ST_LineLocatePoint(geom::geometry")
+
+	// No segment in range
+	location, err = NewRepository(&fakeQuerier{}, Config{}).FindNearestSegmentAddress(context.Background(), 35.681236, 139.767125, 0)
+
+	require.NoError(t, err)
+	assert.Nil(t, location)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IncrementAPIKeyUsage counts one request by the API key identified by keyID on day and returns
+// the key's request counts for that day and for its month so far, including this request. The
+// rows of earlier days in the month stay as they are, so the month's count is their sum.
+func (r *Repository) IncrementAPIKeyUsage(ctx context.Context, keyID string, day time.Time) (daily, monthly int64, err error) {
+	sql := `
+		WITH used AS (
+			INSERT INTO api_key_usage (key_id, day, requests)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+			RETURNING requests
+		)
+		SELECT
+			used.requests,
+			used.requests + coalesce((
+				SELECT sum(requests)
+				FROM api_key_usage
+				WHERE key_id = $1 AND day >= date_trunc('month', $2::date) AND day < $2::date
+			), 0)::bigint
+		FROM used
+	`
+
+	defer r.logSlowQuery(ctx, "IncrementAPIKeyUsage", time.Now(), keyID, day)
+	err = r.db.QueryRow(ctx, sql, keyID, day).Scan(&daily, &monthly)
+	if err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to count api key usage: %w", err)
+	}
+	return daily, monthly, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// segmentFraction is the position of the house number expression number along a segment, from 0
+// at from_number to 1 at to_number; a segment holding a single number places it at the midpoint
+func segmentFraction(number string) string {
+	return `CASE WHEN to_number = from_number THEN 0.5
+			ELSE (` + number + ` - from_number)::float8 / (to_number - from_number) END`
+}
+
+// InterpolateAddress places a house number along the address segment of address (the full
+// address without the number) whose range contains it, returning nil when no segment does. The
+// narrowest matching range wins. The result has no stored location, so its ID is 0. A non-zero
+// srid other than models.DefaultSRID also projects the point.
+func (r *Repository) InterpolateAddress(ctx context.Context, address string, number int, srid int) (_ *models.Location, err error) {
+	project := srid != 0 && srid != models.DefaultSRID
+	args := []any{address, number}
+	projection := ""
+	if project {
+		args = append(args, srid)
+		projection = `,
+			ST_X(ST_Transform(point, $3)) as x,
+			ST_Y(ST_Transform(point, $3)) as y`
+	}
+
+	sql := `
+		SELECT
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			ST_Y(point) as latitude,
+			ST_X(point) as longitude` + projection + `
+		FROM (
+			SELECT
+				prefecture, municipality, address_1, address_2,
+				ST_LineInterpolatePoint(geom::geometry, ` + segmentFraction("$2") + `) as point
+			FROM address_segments
+			WHERE ` + FullAddress + ` = $1
+				AND $2 BETWEEN least(from_number, to_number) AND greatest(from_number, to_number)
+			ORDER BY abs(to_number - from_number), id
+			LIMIT 1
+		) segment
+	`
+
+	loc := models.Location{BlockLot: strconv.Itoa(number), Interpolated: true}
+	dest := []any{&loc.Prefecture, &loc.Municipality, &loc.Address1, &loc.Address2, &loc.Latitude, &loc.Longitude}
+	if project {
+		loc.Projected = &models.ProjectedPoint{SRID: srid}
+		dest = append(dest, &loc.Projected.X, &loc.Projected.Y)
+	}
+
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "InterpolateAddress", time.Now(), args...)
+	err = db.QueryRow(ctx, sql, args...).Scan(dest...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to interpolate address: %w", err)
+	}
+
+	return &loc, nil
+}
+
+// FindNearestSegmentAddress reverse geocodes against the address segments: it takes the nearest
+// segment within radius meters, estimates the house number at the point's position along it and
+// returns that number placed back on the segment, or nil when no segment is in range
+func (r *Repository) FindNearestSegmentAddress(ctx context.Context, lat, lon, radius float64) (_ *models.Location, err error) {
+	sql := `
+		SELECT
+			prefecture,
+			municipality,
+			address_1,
+			address_2,
+			number,
+			ST_Y(point) as latitude,
+			ST_X(point) as longitude
+		FROM (
+			SELECT
+				prefecture, municipality, address_1, address_2, from_number, to_number, geom,
+				round(from_number + ST_LineLocatePoint(geom::geometry, ST_SetSRID(ST_MakePoint($2, $1), 4326))
+					* (to_number - from_number))::int as number
+			FROM address_segments
+			WHERE ST_DWithin(geom, ST_SetSRID(ST_MakePoint($2, $1), 4326), $3)
+			ORDER BY geom <-> ST_SetSRID(ST_MakePoint($2, $1), 4326), id
+			LIMIT 1
+		) nearest,
+		LATERAL (SELECT ST_LineInterpolatePoint(geom::geometry, ` + segmentFraction("number") + `) as point) interpolated
+	`
+
+	radius = r.radius(radius)
+	var loc models.Location
+	var number int
+	db, end, err := r.bounded(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end(&err)
+
+	defer r.logSlowQuery(ctx, "FindNearestSegmentAddress", time.Now(), lat, lon, radius)
+	err = db.QueryRow(ctx, sql, lat, lon, radius).Scan(
+		&loc.Prefecture,
+		&loc.Municipality,
+		&loc.Address1,
+		&loc.Address2,
+		&number,
+		&loc.Latitude,
+		&loc.Longitude,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to find nearest address segment: %w", err)
+	}
+
+	loc.BlockLot = strconv.Itoa(number)
+	loc.Interpolated = true
+	return &loc, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/rs/zerolog"
+)
+
+// logSlowQuery warns when the query named name, started at start, ran longer than
+// Config.SlowQueryThreshold. It logs through the logger in ctx, so entries carry the
+// request ID set by the API's RequestID middleware. Use it as
+//
+//	defer r.logSlowQuery(ctx, "FindLocationsByIDs", time.Now(), ids)
+func (r *Repository) logSlowQuery(ctx context.Context, name string, start time.Time, args ...any) {
+	threshold := r.config.SlowQueryThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	elapsed := time.Since(start)
+	if elapsed < threshold {
+		return
+	}
+
+	zerolog.Ctx(ctx).Warn().
+		Str("query", name).
+		Interface("args", args).
+		Dur("duration", elapsed).
+		Dur("threshold", threshold).
+		Msg("slow query")
+}
+
+// explainSlowQuery captures the plan of a query that ran longer than Config.SlowQueryThreshold
+// when Config.CaptureQueryPlans is set, by running sql again under EXPLAIN (ANALYZE, BUFFERS).
+// The plan is logged and, when the request asked for plans (models.WithQueryPlans), recorded
+// for its response. EXPLAIN ANALYZE executes the query, so a captured query costs twice. Use it
+// before logSlowQuery, so the slow query warning isn't delayed by the EXPLAIN:
+//
+//	defer r.explainSlowQuery(ctx, "SearchLocationsByText", time.Now(), sql, args...)
+func (r *Repository) explainSlowQuery(ctx context.Context, name string, start time.Time, sql string, args ...any) {
+	threshold := r.config.SlowQueryThreshold
+	if !r.config.CaptureQueryPlans || threshold <= 0 || time.Since(start) < threshold {
+		return
+	}
+
+	logger := zerolog.Ctx(ctx)
+	plan, err := r.explain(ctx, sql, args...)
+	if err != nil {
+		logger.Warn().Err(err).Str("query", name).Msg("failed to capture query plan")
+		return
+	}
+
+	logger.Warn().Str("query", name).Str("plan", plan).Msg("slow query plan")
+	if plans := models.QueryPlansFromContext(ctx); plans != nil {
+		plans.Add(models.QueryPlan{Query: name, Plan: plan})
+	}
+}
+
+// explain runs sql under EXPLAIN (ANALYZE, BUFFERS) and returns the plan text
+func (r *Repository) explain(ctx context.Context, sql string, args ...any) (string, error) {
+	rows, err := r.db.Query(ctx, "EXPLAIN (ANALYZE, BUFFERS) "+sql, args...)
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", fmt.Errorf("repository: failed to scan query plan: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("repository: error iterating query plan: %w", err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
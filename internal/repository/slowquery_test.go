@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSlowQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		elapsed   time.Duration
+		expectLog bool
+	}{
+		{name: "disabled", threshold: 0, elapsed: time.Hour},
+		{name: "fast query", threshold: time.Second, elapsed: time.Millisecond},
+		{name: "slow query", threshold: time.Millisecond, elapsed: time.Second, expectLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := zerolog.New(&buf).With().Str("request_id", "req-1").Logger()
+			ctx := logger.WithContext(context.Background())
+
+			repo := &Repository{config: Config{SlowQueryThreshold: tt.threshold}}
+			repo.logSlowQuery(ctx, "FindLocationsByIDs", time.Now().Add(-tt.elapsed), []int{1, 2})
+
+			if !tt.expectLog {
+				assert.Empty(t, buf.String())
+				return
+			}
+			assert.Contains(t, buf.String(), `"query":"FindLocationsByIDs"`)
+			assert.Contains(t, buf.String(), `"args":[[1,2]]`)
+			assert.Contains(t, buf.String(), `"request_id":"req-1"`)
+		})
+	}
+}
+
+func TestExplainSlowQuery(t *testing.T) {
+	const sql = "SELECT id FROM locations WHERE full_address_tsvector @@ to_tsquery($1)"
+
+	tests := []struct {
+		name      string
+		capture   bool
+		threshold time.Duration
+		elapsed   time.Duration
+		expectRun bool
+	}{
+		{name: "capture disabled", threshold: time.Millisecond, elapsed: time.Second},
+		{name: "slow query logging disabled", capture: true, elapsed: time.Second},
+		{name: "fast query", capture: true, threshold: time.Second, elapsed: time.Millisecond},
+		{name: "slow query", capture: true, threshold: time.Millisecond, elapsed: time.Second, expectRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := zerolog.New(&buf).WithContext(context.Background())
+			ctx, plans := models.WithQueryPlans(ctx)
+
+			db := &fakeQuerier{rows: [][]any{{"Limit  (actual time=0.1..812.4 rows=20 loops=1)"}, {"  Buffers: shared hit=12 read=3400"}}}
+			repo := &Repository{db: db, config: Config{SlowQueryThreshold: tt.threshold, CaptureQueryPlans: tt.capture}}
+			repo.explainSlowQuery(ctx, "SearchLocationsByText", time.Now().Add(-tt.elapsed), sql, "丸の内")
+
+			if !tt.expectRun {
+				assert.Empty(t, db.sql)
+				assert.Empty(t, plans.All())
+				assert.Empty(t, buf.String())
+				return
+			}
+			assert.Equal(t, "EXPLAIN (ANALYZE, BUFFERS) "+sql, db.sql)
+			assert.Equal(t, []any{"丸の内"}, db.args)
+			expectedPlan := "Limit  (actual time=0.1..812.4 rows=20 loops=1)\n  Buffers: shared hit=12 read=3400"
+			assert.Equal(t, []models.QueryPlan{{Query: "SearchLocationsByText", Plan: expectedPlan}}, plans.All())
+			assert.Contains(t, buf.String(), `"message":"slow query plan"`)
+		})
+	}
+}
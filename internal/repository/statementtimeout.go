@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryCanceledCode is the SQLSTATE Postgres reports for a statement it cancelled, e.g. after
+// statement_timeout
+const queryCanceledCode = "57014"
+
+// ErrStatementTimeout is returned when Postgres cancels a query that ran longer than
+// Config.StatementTimeout. It wraps context.DeadlineExceeded, so callers treat it like a query
+// cut off by the request's own timeout.
+var ErrStatementTimeout = fmt.Errorf("repository: statement timeout: %w", context.DeadlineExceeded)
+
+// bounded returns the Querier to run an expensive query through. With Config.StatementTimeout set
+// it is a transaction that sets statement_timeout locally, so Postgres cancels a runaway query
+// itself, even when a gap in the Go-side context handling would let it run on. end must be
+// deferred with the method's error result; it ends the transaction and reports a cancelled
+// statement as ErrStatementTimeout. Use it as
+//
+//	db, end, err := r.bounded(ctx)
+//	if err != nil {
+//		return nil, err
+//	}
+//	defer end(&err)
+func (r *Repository) bounded(ctx context.Context) (db Querier, end func(*error), err error) {
+	timeout := r.config.StatementTimeout.Milliseconds()
+	if timeout <= 0 {
+		return r.db, func(*error) {}, nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	end = func(err *error) {
+		// The transaction only reads, so there is nothing to commit
+		_ = tx.Rollback(ctx)
+		var pgErr *pgconn.PgError
+		if *err != nil && errors.As(*err, &pgErr) && pgErr.Code == queryCanceledCode {
+			*err = fmt.Errorf("%w: %w", ErrStatementTimeout, *err)
+		}
+	}
+	// SET doesn't take parameters; timeout is an integer, so formatting it in is safe
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, nil, fmt.Errorf("repository: failed to set statement timeout: %w", err)
+	}
+	return tx, end, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx is the transaction of a fakeQuerier: queries go to the fakeQuerier, except that Query
+// fails with queryErr when it is set, and statements run through Exec are recorded separately
+type fakeTx struct {
+	pgx.Tx
+	q          *fakeQuerier
+	queryErr   error
+	execs      []string
+	rolledBack bool
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx.queryErr != nil {
+		return nil, tx.queryErr
+	}
+	return tx.q.Query(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tx.q.QueryRow(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.execs = append(tx.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.rolledBack = true
+	return nil
+}
+
+func TestRepository_StatementTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		timeout       time.Duration
+		queryErr      error
+		expectedExecs []string
+		expectedErr   error
+	}{
+		{
+			name: "disabled runs outside a transaction",
+		},
+		{
+			name:          "sets a local statement timeout",
+			timeout:       1500 * time.Millisecond,
+			expectedExecs: []string{"SET LOCAL statement_timeout = 1500"},
+		},
+		{
+			name:          "cancelled statement",
+			timeout:       time.Second,
+			queryErr:      &pgconn.PgError{Code: queryCanceledCode, Message: "canceling statement due to statement timeout"},
+			expectedExecs: []string{"SET LOCAL statement_timeout = 1000"},
+			expectedErr:   ErrStatementTimeout,
+		},
+		{
+			name:          "other errors pass through",
+			timeout:       time.Second,
+			queryErr:      assert.AnError,
+			expectedExecs: []string{"SET LOCAL statement_timeout = 1000"},
+			expectedErr:   assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{tx: &fakeTx{queryErr: tt.queryErr}}
+			repo := NewRepository(db, Config{StatementTimeout: tt.timeout})
+
+			_, err := repo.SearchLocationsByText(context.Background(), models.SearchOptions{Query: "丸の内"})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Contains(t, db.sql, "to_tsquery")
+			}
+			assert.Equal(t, tt.expectedExecs, db.tx.execs)
+			assert.Equal(t, tt.timeout > 0, db.tx.rolledBack)
+		})
+	}
+}
+
+func TestRepository_StatementTimeout_Lookups(t *testing.T) {
+	tests := []struct {
+		name   string
+		lookup func(*Repository) error
+	}{
+		{
+			name: "municipality bounding boxes",
+			lookup: func(r *Repository) error {
+				_, err := r.MunicipalityBBoxes(context.Background(), []models.Area{{Prefecture: "東京都", Municipality: "千代田区"}})
+				return err
+			},
+		},
+		{
+			name: "locations by id",
+			lookup: func(r *Repository) error {
+				_, err := r.FindLocationsByIDs(context.Background(), []int{1, 2})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQuerier{tx: &fakeTx{}}
+			repo := NewRepository(db, Config{StatementTimeout: time.Second})
+
+			require.NoError(t, tt.lookup(repo))
+			assert.Equal(t, []string{"SET LOCAL statement_timeout = 1000"}, db.tx.execs)
+			assert.True(t, db.tx.rolledBack)
+		})
+	}
+}
+
+func TestErrStatementTimeout_IsDeadlineExceeded(t *testing.T) {
+	assert.ErrorIs(t, ErrStatementTimeout, context.DeadlineExceeded)
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTextSearchConfig is the text search configuration used when none is configured
+const DefaultTextSearchConfig = "japanese"
+
+// FallbackTextSearchConfig is built into every Postgres installation
+const FallbackTextSearchConfig = "simple"
+
+// ErrTextSearchConfigMissing is returned when the configured text search configuration is not installed
+var ErrTextSearchConfigMissing = errors.New("repository: text search configuration is not installed")
+
+// textSearchConfigPattern restricts configuration names to plain identifiers, since the
+// importer has to embed them in DDL where they can't be bound as parameters
+var textSearchConfigPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// RowQuerier is satisfied by both *pgx.Conn and *pgxpool.Pool
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ResolveTextSearchConfig checks that the named text search configuration exists. When it
+// doesn't and fallback is set, FallbackTextSearchConfig is returned with fellBack true;
+// otherwise an error wrapping ErrTextSearchConfigMissing explains how to fix it.
+func ResolveTextSearchConfig(ctx context.Context, db RowQuerier, name string, fallback bool) (resolved string, fellBack bool, err error) {
+	if name == "" {
+		name = DefaultTextSearchConfig
+	}
+	if !textSearchConfigPattern.MatchString(name) {
+		return "", false, fmt.Errorf("repository: invalid text search configuration name %q", name)
+	}
+
+	var exists bool
+	err = db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_ts_config WHERE cfgname = $1)", name).Scan(&exists)
+	if err != nil {
+		return "", false, fmt.Errorf("repository: failed to look up text search configuration: %w", err)
+	}
+
+	if exists {
+		return name, false, nil
+	}
+	if fallback {
+		return FallbackTextSearchConfig, true, nil
+	}
+	return "", false, fmt.Errorf("%w: %q; install an extension that provides it (e.g. textsearch_ja for \"japanese\"), "+
+		"or set TEXT_SEARCH_CONFIG=%s or TEXT_SEARCH_FALLBACK=true", ErrTextSearchConfigMissing, name, FallbackTextSearchConfig)
+}
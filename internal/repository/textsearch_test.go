@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRow returns a fixed boolean or error from Scan
+type fakeRow struct {
+	exists bool
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*bool) = r.exists
+	return nil
+}
+
+// fakeRowQuerier records the looked up configuration name
+type fakeRowQuerier struct {
+	row  fakeRow
+	args []any
+}
+
+func (q *fakeRowQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.args = args
+	return q.row
+}
+
+func TestResolveTextSearchConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		configured       string
+		fallback         bool
+		row              fakeRow
+		expectedLookup   string
+		expected         string
+		expectedFellBack bool
+		expectedErr      error
+		expectError      bool
+	}{
+		{
+			name:           "installed configuration",
+			configured:     "japanese",
+			row:            fakeRow{exists: true},
+			expectedLookup: "japanese",
+			expected:       "japanese",
+		},
+		{
+			name:           "empty name uses default",
+			configured:     "",
+			row:            fakeRow{exists: true},
+			expectedLookup: DefaultTextSearchConfig,
+			expected:       DefaultTextSearchConfig,
+		},
+		{
+			name:             "missing configuration falls back",
+			configured:       "japanese",
+			fallback:         true,
+			row:              fakeRow{exists: false},
+			expectedLookup:   "japanese",
+			expected:         FallbackTextSearchConfig,
+			expectedFellBack: true,
+		},
+		{
+			name:           "missing configuration fails fast",
+			configured:     "japanese",
+			row:            fakeRow{exists: false},
+			expectedLookup: "japanese",
+			expectedErr:    ErrTextSearchConfigMissing,
+			expectError:    true,
+		},
+		{
+			name:        "invalid name rejected before querying",
+			configured:  "japanese'); DROP TABLE locations; --",
+			expectError: true,
+		},
+		{
+			name:           "query error",
+			configured:     "japanese",
+			row:            fakeRow{err: assert.AnError},
+			expectedLookup: "japanese",
+			expectedErr:    assert.AnError,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeRowQuerier{row: tt.row}
+
+			resolved, fellBack, err := ResolveTextSearchConfig(context.Background(), db, tt.configured, tt.fallback)
+
+			if tt.expectedLookup != "" {
+				assert.Equal(t, []any{tt.expectedLookup}, db.args)
+			} else {
+				assert.Nil(t, db.args)
+			}
+			if tt.expectError {
+				assert.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, resolved)
+			assert.Equal(t, tt.expectedFellBack, fellBack)
+		})
+	}
+}
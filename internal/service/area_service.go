@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"geocoding-api/internal/models"
+)
+
+// AreaService contains the business logic for listing the locations in an area: around a point
+// or inside a bounding box
+type AreaService struct {
+	repo   AreaRepository
+	config SpatialConfig
+}
+
+// AreaRepository interface for dependency injection
+type AreaRepository interface {
+	FindNearestLocations(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error)
+	FindLocationsInBBox(ctx context.Context, bbox models.BoundingBox, limit int) ([]models.Location, error)
+}
+
+// NewAreaService creates a new area service
+func NewAreaService(repo AreaRepository, cfg SpatialConfig) *AreaService {
+	if cfg.MaxRadiusMeters <= 0 {
+		cfg.MaxRadiusMeters = models.DefaultMaxRadiusMeters
+	}
+	return &AreaService{repo: repo, config: cfg}
+}
+
+// Nearby lists up to limit locations within radius meters (0 for the maximum) of the point,
+// nearest first, each with its Distance. It returns an empty slice when nothing is in range.
+func (s *AreaService) Nearby(ctx context.Context, lat, lon, radius float64, limit int) ([]models.Location, error) {
+	if lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("%w: latitude %f", ErrInvalidCoordinates, lat)
+	}
+	if lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("%w: longitude %f", ErrInvalidCoordinates, lon)
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidLimit)
+	}
+	radius, err := s.config.searchRadius(radius)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := s.repo.FindNearestLocations(ctx, lat, lon, radius, "", nil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find nearby locations: %w", err)
+	}
+	if locations == nil {
+		locations = []models.Location{}
+	}
+	return locations, nil
+}
+
+// Within lists up to limit locations inside bbox, ordered by ID. One more is fetched to tell
+// whether the box holds more than limit, which sets Truncated.
+func (s *AreaService) Within(ctx context.Context, bbox models.BoundingBox, limit int) (*models.WithinResult, error) {
+	if !bbox.Valid() {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidBBox, bbox)
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidLimit)
+	}
+
+	locations, err := s.repo.FindLocationsInBBox(ctx, bbox, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find locations in bbox: %w", err)
+	}
+
+	result := &models.WithinResult{Results: []models.Location{}}
+	if len(locations) > limit {
+		locations, result.Truncated = locations[:limit], true
+	}
+	result.Results = append(result.Results, locations...)
+	return result, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAreaRepository is a mock implementation of the AreaRepository interface
+type MockAreaRepository struct {
+	mock.Mock
+}
+
+// FindNearestLocations implements AreaRepository.
+func (m *MockAreaRepository) FindNearestLocations(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindLocationsInBBox implements AreaRepository.
+func (m *MockAreaRepository) FindLocationsInBBox(ctx context.Context, bbox models.BoundingBox, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, bbox, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+func TestAreaService_Nearby(t *testing.T) {
+	nearby := []models.Location{{ID: 1, Distance: 3.5}, {ID: 2, Distance: 12}}
+
+	tests := []struct {
+		name          string
+		lat, lon      float64
+		radius        float64
+		limit         int
+		callsRepo     bool
+		expectRadius  float64
+		mockLocations []models.Location
+		expected      []models.Location
+		expectedErr   error
+	}{
+		{
+			name:          "default radius",
+			lat:           35.68,
+			lon:           139.76,
+			limit:         10,
+			callsRepo:     true,
+			expectRadius:  models.DefaultMaxRadiusMeters,
+			mockLocations: nearby,
+			expected:      nearby,
+		},
+		{
+			name:          "nothing in range",
+			lat:           35.68,
+			lon:           139.76,
+			radius:        50,
+			limit:         10,
+			callsRepo:     true,
+			expectRadius:  50,
+			mockLocations: nil,
+			expected:      []models.Location{},
+		},
+		{
+			name:        "latitude out of range",
+			lat:         91,
+			lon:         139.76,
+			limit:       10,
+			expectedErr: ErrInvalidCoordinates,
+		},
+		{
+			name:        "radius above maximum",
+			lat:         35.68,
+			lon:         139.76,
+			radius:      models.DefaultMaxRadiusMeters + 1,
+			limit:       10,
+			expectedErr: ErrInvalidRadius,
+		},
+		{
+			name:        "zero limit",
+			lat:         35.68,
+			lon:         139.76,
+			expectedErr: ErrInvalidLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockAreaRepository)
+			svc := NewAreaService(mockRepo, SpatialConfig{})
+			if tt.callsRepo {
+				mockRepo.On("FindNearestLocations", mock.Anything, tt.lat, tt.lon, tt.expectRadius, "", []int(nil), tt.limit).Return(tt.mockLocations, nil)
+			}
+
+			locations, err := svc.Nearby(context.Background(), tt.lat, tt.lon, tt.radius, tt.limit)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, locations)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAreaService_Within(t *testing.T) {
+	bbox := models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}
+	three := []models.Location{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	tests := []struct {
+		name          string
+		bbox          models.BoundingBox
+		limit         int
+		callsRepo     bool
+		mockLocations []models.Location
+		mockError     error
+		expected      *models.WithinResult
+		expectedErr   error
+	}{
+		{
+			name:          "fewer than the limit",
+			bbox:          bbox,
+			limit:         3,
+			callsRepo:     true,
+			mockLocations: three,
+			expected:      &models.WithinResult{Results: three},
+		},
+		{
+			name:          "more than the limit is truncated",
+			bbox:          bbox,
+			limit:         2,
+			callsRepo:     true,
+			mockLocations: three,
+			expected:      &models.WithinResult{Results: three[:2], Truncated: true},
+		},
+		{
+			name:          "empty box",
+			bbox:          bbox,
+			limit:         2,
+			callsRepo:     true,
+			mockLocations: nil,
+			expected:      &models.WithinResult{Results: []models.Location{}},
+		},
+		{
+			name:        "minimum above maximum",
+			bbox:        models.BoundingBox{MinLon: 139.8, MinLat: 35.6, MaxLon: 139.7, MaxLat: 35.7},
+			limit:       2,
+			expectedErr: ErrInvalidBBox,
+		},
+		{
+			name:        "zero limit",
+			bbox:        bbox,
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:      "repository error",
+			bbox:      bbox,
+			limit:     2,
+			callsRepo: true,
+			mockError: errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockAreaRepository)
+			svc := NewAreaService(mockRepo, SpatialConfig{})
+			if tt.callsRepo {
+				mockRepo.On("FindLocationsInBBox", mock.Anything, tt.bbox, tt.limit+1).Return(tt.mockLocations, tt.mockError)
+			}
+
+			result, err := svc.Within(context.Background(), tt.bbox, tt.limit)
+
+			switch {
+			case tt.mockError != nil:
+				assert.ErrorContains(t, err, tt.mockError.Error())
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
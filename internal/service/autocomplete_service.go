@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"geocoding-api/internal/normalize"
+)
+
+// Autocomplete suggestion limits; type-ahead boxes only show a handful
+const (
+	DefaultAutocompleteLimit = 10
+	MaxAutocompleteLimit     = 20
+)
+
+// AutocompleteService completes partially typed addresses
+type AutocompleteService struct {
+	repo AutocompleteRepository
+}
+
+// AutocompleteRepository interface for dependency injection
+type AutocompleteRepository interface {
+	SuggestLocations(ctx context.Context, prefix string, limit int) ([]string, error)
+}
+
+// NewAutocompleteService creates a new autocomplete service
+func NewAutocompleteService(repo AutocompleteRepository) *AutocompleteService {
+	return &AutocompleteService{repo: repo}
+}
+
+// Autocomplete returns up to limit distinct address prefixes starting with prefix, DefaultAutocompleteLimit
+// when limit is 0. Whitespace is removed from the prefix and full-width characters made
+// half-width (normalize.HalfWidth), as stored addresses are written.
+func (s *AutocompleteService) Autocomplete(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.Join(strings.Fields(normalize.HalfWidth(prefix)), "")
+	if prefix == "" {
+		return nil, ErrEmptyQuery
+	}
+	if limit == 0 {
+		limit = DefaultAutocompleteLimit
+	}
+	if limit < 0 || limit > MaxAutocompleteLimit {
+		return nil, fmt.Errorf("%w: must be between 0 and %d (0 uses the default)", ErrInvalidLimit, MaxAutocompleteLimit)
+	}
+
+	suggestions, err := s.repo.SuggestLocations(ctx, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to suggest locations: %w", err)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAutocompleteRepository is a mock implementation of the AutocompleteRepository interface
+type MockAutocompleteRepository struct {
+	mock.Mock
+}
+
+// SuggestLocations implements AutocompleteRepository.
+func (m *MockAutocompleteRepository) SuggestLocations(ctx context.Context, prefix string, limit int) ([]string, error) {
+	args := m.Called(ctx, prefix, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestAutocompleteService_Autocomplete(t *testing.T) {
+	suggestions := []string{"東京都千代田区", "東京都千代田区丸の内"}
+
+	tests := []struct {
+		name           string
+		prefix         string
+		limit          int
+		expectedPrefix string
+		expectedLimit  int
+		mockError      error
+		expectedErr    error
+	}{
+		{name: "default limit", prefix: "千代", expectedPrefix: "千代", expectedLimit: DefaultAutocompleteLimit},
+		{name: "explicit limit", prefix: "千代", limit: 5, expectedPrefix: "千代", expectedLimit: 5},
+		{name: "whitespace and full-width removed", prefix: " 千代田区　丸の内１ ", expectedPrefix: "千代田区丸の内1", expectedLimit: DefaultAutocompleteLimit},
+		{name: "empty prefix", prefix: "", expectedErr: ErrEmptyQuery},
+		{name: "whitespace only", prefix: " 　", expectedErr: ErrEmptyQuery},
+		{name: "negative limit", prefix: "千代", limit: -1, expectedErr: ErrInvalidLimit},
+		{name: "limit too large", prefix: "千代", limit: MaxAutocompleteLimit + 1, expectedErr: ErrInvalidLimit},
+		{name: "repository error", prefix: "千代", expectedPrefix: "千代", expectedLimit: DefaultAutocompleteLimit, mockError: assert.AnError, expectedErr: assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockAutocompleteRepository)
+			service := NewAutocompleteService(mockRepo)
+			if tt.expectedPrefix != "" {
+				mockRepo.On("SuggestLocations", mock.Anything, tt.expectedPrefix, tt.expectedLimit).Return(suggestions, tt.mockError)
+			}
+
+			// Execute
+			result, err := service.Autocomplete(context.Background(), tt.prefix, tt.limit)
+
+			// Assert
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, suggestions, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
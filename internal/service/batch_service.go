@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/rs/zerolog"
+)
+
+// MaxBatchAddresses is the maximum number of addresses accepted in a single batch job
+const MaxBatchAddresses = 10000
+
+// MaxSyncBatchAddresses is the maximum number of addresses geocoded by a single GeocodeBatch
+// call, which answers within one request; larger batches must be submitted as jobs
+const MaxSyncBatchAddresses = 100
+
+// batchProgressInterval is the number of addresses a worker geocodes between progress updates
+const batchProgressInterval = 100
+
+// BatchRepository interface for dependency injection
+type BatchRepository interface {
+	CreateBatchJob(ctx context.Context, id string, addresses []string) (*models.BatchJob, error)
+	GetBatchJob(ctx context.Context, id string) (*models.BatchJob, error)
+	BatchJobResults(ctx context.Context, id string) ([]models.BatchResult, error)
+	ClaimBatchJob(ctx context.Context, staleAfter time.Duration) (*models.BatchJob, error)
+	UpdateBatchJobProgress(ctx context.Context, id string, processed int) error
+	CompleteBatchJob(ctx context.Context, id string, results []models.BatchResult) error
+	FailBatchJob(ctx context.Context, id string, reason string) error
+}
+
+// BatchGeocoder geocodes a single address of a batch job
+type BatchGeocoder interface {
+	Geocode(ctx context.Context, opts models.SearchOptions) (*models.GeocodeResult, error)
+}
+
+// BatchConfig holds the batch job worker settings
+type BatchConfig struct {
+	// PollInterval is how long an idle worker waits before looking for a queued job again
+	PollInterval time.Duration
+	// StaleAfter is how long a running job may go without progress before another worker
+	// reclaims it, e.g. after the API restarted mid-job
+	StaleAfter time.Duration
+}
+
+// BatchService runs batch geocoding jobs in the background
+type BatchService struct {
+	repo     BatchRepository
+	geocoder BatchGeocoder
+	config   BatchConfig
+}
+
+// NewBatchService creates a new batch service, geocoding each address with geocoder
+func NewBatchService(repo BatchRepository, geocoder BatchGeocoder, cfg BatchConfig) *BatchService {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 5 * time.Minute
+	}
+	return &BatchService{repo: repo, geocoder: geocoder, config: cfg}
+}
+
+// SubmitBatch queues a job geocoding the addresses and returns it without waiting for it to run
+func (s *BatchService) SubmitBatch(ctx context.Context, addresses []string) (*models.BatchJob, error) {
+	if len(addresses) == 0 || len(addresses) > MaxBatchAddresses {
+		return nil, fmt.Errorf("%w: %d addresses, must be between 1 and %d", ErrInvalidBatchSize, len(addresses), MaxBatchAddresses)
+	}
+
+	job, err := s.repo.CreateBatchJob(ctx, newBatchJobID(), addresses)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to submit batch job: %w", err)
+	}
+	return job, nil
+}
+
+// GeocodeBatch geocodes the addresses right away and returns one result per address, in order.
+// An address that isn't a valid query gets an error in its result instead of failing the batch.
+func (s *BatchService) GeocodeBatch(ctx context.Context, addresses []string) ([]models.BatchResult, error) {
+	if len(addresses) == 0 || len(addresses) > MaxSyncBatchAddresses {
+		return nil, fmt.Errorf("%w: %d addresses, must be between 1 and %d", ErrInvalidBatchSize, len(addresses), MaxSyncBatchAddresses)
+	}
+
+	results, err := s.geocodeAddresses(ctx, addresses, nil)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to geocode batch: %w", err)
+	}
+	return results, nil
+}
+
+// BatchJob returns the status of a job, or nil when it doesn't exist
+func (s *BatchService) BatchJob(ctx context.Context, id string) (*models.BatchJob, error) {
+	job, err := s.repo.GetBatchJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get batch job: %w", err)
+	}
+	return job, nil
+}
+
+// BatchJobResults returns the results of a succeeded job, in the order its addresses were submitted
+func (s *BatchService) BatchJobResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	results, err := s.repo.BatchJobResults(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get batch job results: %w", err)
+	}
+	return results, nil
+}
+
+// RunWorker processes queued jobs one at a time until ctx is cancelled, polling for new ones
+// every PollInterval while the queue is empty
+func (s *BatchService) RunWorker(ctx context.Context) {
+	for {
+		processed, err := s.ProcessNext(ctx)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("batch job worker failed")
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// ProcessNext claims the next queued job and runs it to completion, reporting whether there
+// was a job to run. A job that cannot be finished is marked as failed.
+func (s *BatchService) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := s.repo.ClaimBatchJob(ctx, s.config.StaleAfter)
+	if err != nil {
+		return false, fmt.Errorf("service: failed to claim batch job: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	results, err := s.geocodeBatch(ctx, job)
+	if err != nil {
+		// The submitter only learns that the job failed; the cause is logged by RunWorker
+		if failErr := s.repo.FailBatchJob(context.WithoutCancel(ctx), job.ID, "geocoding failed"); failErr != nil {
+			return true, fmt.Errorf("service: failed to mark batch job %s as failed: %w", job.ID, failErr)
+		}
+		return true, fmt.Errorf("service: batch job %s failed: %w", job.ID, err)
+	}
+
+	if err := s.repo.CompleteBatchJob(ctx, job.ID, results); err != nil {
+		return true, fmt.Errorf("service: failed to complete batch job %s: %w", job.ID, err)
+	}
+	return true, nil
+}
+
+// geocodeBatch geocodes each address of the job, recording progress as it goes
+func (s *BatchService) geocodeBatch(ctx context.Context, job *models.BatchJob) ([]models.BatchResult, error) {
+	return s.geocodeAddresses(ctx, job.Addresses, func(processed int) error {
+		return s.repo.UpdateBatchJobProgress(ctx, job.ID, processed)
+	})
+}
+
+// geocodeAddresses geocodes each address in order, calling progress (when not nil) after every
+// batchProgressInterval addresses. An address that isn't a valid query gets an error in its
+// result; any other error fails the whole batch.
+func (s *BatchService) geocodeAddresses(ctx context.Context, addresses []string, progress func(processed int) error) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(addresses))
+	for i, address := range addresses {
+		results[i] = models.BatchResult{Query: address, Results: []models.Location{}}
+
+		result, err := s.geocoder.Geocode(ctx, models.SearchOptions{Query: address})
+		switch {
+		case errors.Is(err, ErrEmptyQuery):
+			results[i].Error = "address cannot be empty"
+		case err != nil:
+			return nil, err
+		case result.Results != nil:
+			results[i].Results = result.Results
+		}
+
+		if progress != nil && (i+1)%batchProgressInterval == 0 {
+			if err := progress(i + 1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// newBatchJobID returns a random, unguessable job ID, so one client can't read another's results
+func newBatchJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
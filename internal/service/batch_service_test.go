@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBatchRepository is a mock implementation of the BatchRepository interface
+type MockBatchRepository struct {
+	mock.Mock
+}
+
+func (m *MockBatchRepository) CreateBatchJob(ctx context.Context, id string, addresses []string) (*models.BatchJob, error) {
+	args := m.Called(ctx, id, addresses)
+	return args.Get(0).(*models.BatchJob), args.Error(1)
+}
+
+func (m *MockBatchRepository) GetBatchJob(ctx context.Context, id string) (*models.BatchJob, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.BatchJob), args.Error(1)
+}
+
+func (m *MockBatchRepository) BatchJobResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]models.BatchResult), args.Error(1)
+}
+
+func (m *MockBatchRepository) ClaimBatchJob(ctx context.Context, staleAfter time.Duration) (*models.BatchJob, error) {
+	args := m.Called(ctx, staleAfter)
+	return args.Get(0).(*models.BatchJob), args.Error(1)
+}
+
+func (m *MockBatchRepository) UpdateBatchJobProgress(ctx context.Context, id string, processed int) error {
+	return m.Called(ctx, id, processed).Error(0)
+}
+
+func (m *MockBatchRepository) CompleteBatchJob(ctx context.Context, id string, results []models.BatchResult) error {
+	return m.Called(ctx, id, results).Error(0)
+}
+
+func (m *MockBatchRepository) FailBatchJob(ctx context.Context, id string, reason string) error {
+	return m.Called(ctx, id, reason).Error(0)
+}
+
+// MockBatchGeocoder is a mock implementation of the BatchGeocoder interface
+type MockBatchGeocoder struct {
+	mock.Mock
+}
+
+func (m *MockBatchGeocoder) Geocode(ctx context.Context, opts models.SearchOptions) (*models.GeocodeResult, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*models.GeocodeResult), args.Error(1)
+}
+
+func TestBatchService_SubmitBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		addresses   []string
+		callsRepo   bool
+		mockError   error
+		expectError error
+	}{
+		{
+			name:        "no addresses",
+			addresses:   []string{},
+			expectError: ErrInvalidBatchSize,
+		},
+		{
+			name:        "too many addresses",
+			addresses:   make([]string, MaxBatchAddresses+1),
+			expectError: ErrInvalidBatchSize,
+		},
+		{
+			name:      "queued",
+			addresses: []string{"丸の内", "赤坂"},
+			callsRepo: true,
+		},
+		{
+			name:        "repository error",
+			addresses:   []string{"丸の内"},
+			callsRepo:   true,
+			mockError:   assert.AnError,
+			expectError: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockBatchRepository)
+			service := NewBatchService(mockRepo, new(MockBatchGeocoder), BatchConfig{})
+
+			job := &models.BatchJob{Status: models.BatchJobQueued, Total: len(tt.addresses)}
+			if tt.callsRepo {
+				mockRepo.On("CreateBatchJob", mock.Anything, mock.MatchedBy(func(id string) bool { return len(id) == 32 }), tt.addresses).Return(job, tt.mockError)
+			}
+
+			result, err := service.SubmitBatch(context.Background(), tt.addresses)
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, job, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBatchService_GeocodeBatch(t *testing.T) {
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125}
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		service := NewBatchService(new(MockBatchRepository), new(MockBatchGeocoder), BatchConfig{})
+
+		for _, addresses := range [][]string{{}, make([]string, MaxSyncBatchAddresses+1)} {
+			results, err := service.GeocodeBatch(context.Background(), addresses)
+
+			assert.ErrorIs(t, err, ErrInvalidBatchSize)
+			assert.Nil(t, results)
+		}
+	})
+
+	t.Run("results in order", func(t *testing.T) {
+		mockRepo := new(MockBatchRepository)
+		mockGeocoder := new(MockBatchGeocoder)
+		service := NewBatchService(mockRepo, mockGeocoder, BatchConfig{})
+
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).
+			Return(&models.GeocodeResult{Results: []models.Location{location}}, nil)
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: ""}).
+			Return((*models.GeocodeResult)(nil), ErrEmptyQuery)
+
+		results, err := service.GeocodeBatch(context.Background(), []string{"丸の内", ""})
+
+		require.NoError(t, err)
+		assert.Equal(t, []models.BatchResult{
+			{Query: "丸の内", Results: []models.Location{location}},
+			{Query: "", Results: []models.Location{}, Error: "address cannot be empty"},
+		}, results)
+		mockGeocoder.AssertExpectations(t)
+		// Nothing is stored for a synchronous batch
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("geocoding error", func(t *testing.T) {
+		mockGeocoder := new(MockBatchGeocoder)
+		service := NewBatchService(new(MockBatchRepository), mockGeocoder, BatchConfig{})
+
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).
+			Return((*models.GeocodeResult)(nil), assert.AnError)
+
+		results, err := service.GeocodeBatch(context.Background(), []string{"丸の内"})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, results)
+	})
+}
+
+func TestBatchService_ProcessNext(t *testing.T) {
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Latitude: 35.681236, Longitude: 139.767125}
+	job := &models.BatchJob{ID: "job-1", Status: models.BatchJobRunning, Total: 3, Addresses: []string{"丸の内", "", "存在しない"}}
+
+	t.Run("empty queue", func(t *testing.T) {
+		mockRepo := new(MockBatchRepository)
+		service := NewBatchService(mockRepo, new(MockBatchGeocoder), BatchConfig{StaleAfter: time.Minute})
+		mockRepo.On("ClaimBatchJob", mock.Anything, time.Minute).Return((*models.BatchJob)(nil), nil)
+
+		processed, err := service.ProcessNext(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("completes the job", func(t *testing.T) {
+		mockRepo := new(MockBatchRepository)
+		mockGeocoder := new(MockBatchGeocoder)
+		service := NewBatchService(mockRepo, mockGeocoder, BatchConfig{})
+
+		mockRepo.On("ClaimBatchJob", mock.Anything, 5*time.Minute).Return(job, nil)
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).
+			Return(&models.GeocodeResult{Results: []models.Location{location}}, nil)
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: ""}).
+			Return((*models.GeocodeResult)(nil), ErrEmptyQuery)
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "存在しない"}).
+			Return(&models.GeocodeResult{}, nil)
+		mockRepo.On("CompleteBatchJob", mock.Anything, "job-1", []models.BatchResult{
+			{Query: "丸の内", Results: []models.Location{location}},
+			{Query: "", Results: []models.Location{}, Error: "address cannot be empty"},
+			{Query: "存在しない", Results: []models.Location{}},
+		}).Return(nil)
+
+		processed, err := service.ProcessNext(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		mockRepo.AssertExpectations(t)
+		mockGeocoder.AssertExpectations(t)
+	})
+
+	t.Run("fails the job on a geocoding error", func(t *testing.T) {
+		mockRepo := new(MockBatchRepository)
+		mockGeocoder := new(MockBatchGeocoder)
+		service := NewBatchService(mockRepo, mockGeocoder, BatchConfig{})
+
+		mockRepo.On("ClaimBatchJob", mock.Anything, 5*time.Minute).Return(job, nil)
+		mockGeocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内"}).
+			Return((*models.GeocodeResult)(nil), assert.AnError)
+		mockRepo.On("FailBatchJob", mock.Anything, "job-1", "geocoding failed").Return(nil)
+
+		processed, err := service.ProcessNext(context.Background())
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.True(t, processed)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "CompleteBatchJob", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
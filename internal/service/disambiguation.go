@@ -0,0 +1,37 @@
+package service
+
+import "geocoding-api/internal/models"
+
+// disambiguate returns the distinct areas of the close matches among locations when they span
+// more than one prefecture, and nil when they don't. A close match is a location whose rank is at
+// least the best rank less threshold times it, so with 0.1 every location within 10% of the best.
+// The best rank is searched for rather than taken from the first location, since order_by=importance
+// doesn't order by rank.
+func disambiguate(locations []models.Location, threshold float64) []models.Area {
+	if len(locations) < 2 {
+		return nil
+	}
+	best := locations[0].Score
+	for _, loc := range locations[1:] {
+		best = max(best, loc.Score)
+	}
+
+	var areas []models.Area
+	seen := make(map[models.Area]bool)
+	prefectures := make(map[string]bool)
+	for _, loc := range locations {
+		if loc.Score < best-threshold*best {
+			continue
+		}
+		area := models.Area{Prefecture: loc.Prefecture, Municipality: loc.Municipality}
+		if !seen[area] {
+			seen[area] = true
+			areas = append(areas, area)
+		}
+		prefectures[loc.Prefecture] = true
+	}
+	if len(prefectures) < 2 {
+		return nil
+	}
+	return areas
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisambiguate(t *testing.T) {
+	fuchu := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "府中市", Score: 0.9},
+		{ID: 2, Prefecture: "広島県", Municipality: "府中市", Score: 0.85},
+		{ID: 3, Prefecture: "東京都", Municipality: "府中市", Score: 0.8},
+		{ID: 4, Prefecture: "広島県", Municipality: "府中町", Score: 0.3},
+	}
+
+	tests := []struct {
+		name      string
+		locations []models.Location
+		threshold float64
+		expected  []models.Area
+	}{
+		{
+			name:      "close matches in several prefectures",
+			locations: fuchu,
+			threshold: 0.2,
+			expected: []models.Area{
+				{Prefecture: "東京都", Municipality: "府中市"},
+				{Prefecture: "広島県", Municipality: "府中市"},
+			},
+		},
+		{
+			name:      "wider threshold includes weaker matches",
+			locations: fuchu,
+			threshold: 0.8,
+			expected: []models.Area{
+				{Prefecture: "東京都", Municipality: "府中市"},
+				{Prefecture: "広島県", Municipality: "府中市"},
+				{Prefecture: "広島県", Municipality: "府中町"},
+			},
+		},
+		{
+			name:      "other prefecture not close enough",
+			locations: fuchu,
+			threshold: 0.05,
+		},
+		{
+			name: "best match not first",
+			locations: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "府中市", Score: 0.2},
+				{ID: 2, Prefecture: "広島県", Municipality: "府中市", Score: 0.9},
+			},
+			threshold: 0.5,
+		},
+		{
+			name: "several municipalities of one prefecture",
+			locations: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Score: 0.5},
+				{ID: 2, Prefecture: "東京都", Municipality: "港区", Score: 0.5},
+			},
+			threshold: 0.2,
+		},
+		{
+			name:      "single result",
+			locations: fuchu[:1],
+			threshold: 0.2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, disambiguate(tt.locations, tt.threshold))
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_Disambiguation(t *testing.T) {
+	locations := []models.Location{
+		{ID: 1, Prefecture: "東京都", Municipality: "府中市", Score: 0.9},
+		{ID: 2, Prefecture: "広島県", Municipality: "府中市", Score: 0.85},
+	}
+	expected := []models.Area{
+		{Prefecture: "東京都", Municipality: "府中市"},
+		{Prefecture: "広島県", Municipality: "府中市"},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		opts      models.SearchOptions
+		expected  []models.Area
+	}{
+		{
+			name:      "ambiguous",
+			threshold: 0.1,
+			opts:      models.SearchOptions{Query: "府中市"},
+			expected:  expected,
+		},
+		{
+			name: "disabled",
+			opts: models.SearchOptions{Query: "府中市"},
+		},
+		{
+			name:      "later page",
+			threshold: 0.1,
+			opts:      models.SearchOptions{Query: "府中市", Offset: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{DisambiguationThreshold: tt.threshold})
+
+			mockRepo.On("SearchLocationsByText", mock.Anything, tt.opts.WithDefaults()).Return(locations, nil)
+
+			result, err := service.Geocode(context.Background(), tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, locations, result.Results)
+			assert.Equal(t, tt.expected != nil, result.NeedsDisambiguation)
+			assert.Equal(t, tt.expected, result.DisambiguationOptions)
+		})
+	}
+}
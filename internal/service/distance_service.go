@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"geocoding-api/internal/models"
+)
+
+// MaxMatrixPoints bounds the distance matrix, whose size grows with the square of the points
+const MaxMatrixPoints = 25
+
+// DistanceService computes distances between points
+type DistanceService struct {
+	repo DistanceRepository
+}
+
+// DistanceRepository interface for dependency injection
+type DistanceRepository interface {
+	DistanceMatrix(ctx context.Context, points []models.Point) ([][]float64, error)
+}
+
+// NewDistanceService creates a new distance service
+func NewDistanceService(repo DistanceRepository) *DistanceService {
+	return &DistanceService{repo: repo}
+}
+
+// DistanceMatrix returns the NxN geodesic distance matrix between the points
+func (s *DistanceService) DistanceMatrix(ctx context.Context, points []models.Point) (*models.DistanceMatrix, error) {
+	if len(points) == 0 || len(points) > MaxMatrixPoints {
+		return nil, fmt.Errorf("%w: between 1 and %d points are required, got %d", ErrInvalidPointCount, MaxMatrixPoints, len(points))
+	}
+	for i, p := range points {
+		if !ValidPoint(p) {
+			return nil, fmt.Errorf("%w: point %d: %f,%f", ErrInvalidCoordinates, i, p.Lat, p.Lon)
+		}
+	}
+
+	distances, err := s.repo.DistanceMatrix(ctx, points)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to compute distance matrix: %w", err)
+	}
+
+	return &models.DistanceMatrix{Points: points, Distances: distances}, nil
+}
+
+// ValidPoint reports whether the point's latitude and longitude are within range
+func ValidPoint(p models.Point) bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDistanceRepository is a mock implementation of the DistanceRepository interface
+type MockDistanceRepository struct {
+	mock.Mock
+}
+
+// DistanceMatrix implements DistanceRepository.
+func (m *MockDistanceRepository) DistanceMatrix(ctx context.Context, points []models.Point) ([][]float64, error) {
+	args := m.Called(ctx, points)
+	return args.Get(0).([][]float64), args.Error(1)
+}
+
+func TestDistanceService_DistanceMatrix(t *testing.T) {
+	points := []models.Point{{Lat: 35.681236, Lon: 139.767125}, {Lat: 35.658034, Lon: 139.701636}}
+	distances := [][]float64{{0, 6420.5}, {6420.5, 0}}
+
+	tests := []struct {
+		name          string
+		points        []models.Point
+		callsRepo     bool
+		mockDistances [][]float64
+		mockError     error
+		expected      *models.DistanceMatrix
+		expectError   bool
+	}{
+		{
+			name:        "no points",
+			points:      []models.Point{},
+			expectError: true,
+		},
+		{
+			name:        "too many points",
+			points:      make([]models.Point, MaxMatrixPoints+1),
+			expectError: true,
+		},
+		{
+			name:        "out of range point",
+			points:      []models.Point{{Lat: 35.68, Lon: 139.76}, {Lat: 91, Lon: 0}},
+			expectError: true,
+		},
+		{
+			name:          "successful matrix",
+			points:        points,
+			callsRepo:     true,
+			mockDistances: distances,
+			expected:      &models.DistanceMatrix{Points: points, Distances: distances},
+		},
+		{
+			name:          "repository error",
+			points:        points,
+			callsRepo:     true,
+			mockDistances: [][]float64{},
+			mockError:     assert.AnError,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockDistanceRepository)
+			service := NewDistanceService(mockRepo)
+
+			if tt.callsRepo {
+				mockRepo.On("DistanceMatrix", mock.Anything, tt.points).Return(tt.mockDistances, tt.mockError)
+			}
+
+			// Execute
+			result, err := service.DistanceMatrix(context.Background(), tt.points)
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import "errors"
+
+// Validation errors returned by the services. They are wrapped with details, so compare with
+// errors.Is; handlers map them to 400 responses, while any other error is an internal failure.
+var (
+	// ErrEmptyQuery is returned when a search has no query text
+	ErrEmptyQuery = errors.New("service: address cannot be empty")
+	// ErrInvalidCoordinates is returned when a latitude or longitude is out of range
+	ErrInvalidCoordinates = errors.New("service: coordinates out of range")
+	// ErrInvalidLimit is returned when a result limit is negative or above the maximum of its
+	// lookup, e.g. models.MaxSearchLimit; 0 selects the default where the lookup has one
+	ErrInvalidLimit = errors.New("service: invalid limit")
+	// ErrInvalidOffset is returned for a negative result offset
+	ErrInvalidOffset = errors.New("service: invalid offset")
+	// ErrCursorWithOffset is returned when a search sets both a pagination cursor and an offset
+	ErrCursorWithOffset = errors.New("service: cursor cannot be combined with offset")
+	// ErrInvalidOrderBy is returned when a search asks for a result order other than models.OrderByRelevance or models.OrderByImportance
+	ErrInvalidOrderBy = errors.New("service: invalid order")
+	// ErrCursorWithOrder is returned when a search ordered by importance sets a pagination cursor,
+	// which only follows the relevance order
+	ErrCursorWithOrder = errors.New("service: cursor cannot be combined with order")
+	// ErrUnsupportedSRID is returned when results are requested in an SRID that isn't allowlisted
+	ErrUnsupportedSRID = errors.New("service: unsupported srid")
+	// ErrUnknownField is returned when a search is limited to a field that isn't in models.LocationFields
+	ErrUnknownField = errors.New("service: unknown field")
+	// ErrEmptyIDs is returned when an ID lookup has no IDs
+	ErrEmptyIDs = errors.New("service: ids cannot be empty")
+	// ErrTooManyIDs is returned when an ID lookup exceeds MaxLocationIDs
+	ErrTooManyIDs = errors.New("service: too many ids")
+	// ErrMissingArea is returned when an area lookup names neither a prefecture nor a municipality
+	ErrMissingArea = errors.New("service: prefecture or municipality is required")
+	// ErrInvalidContext is returned when more than MaxContextLocations context locations are requested
+	ErrInvalidContext = errors.New("service: invalid context size")
+	// ErrTooManyExcluded is returned when a reverse geocode excludes more than MaxExcludedIDs locations
+	ErrTooManyExcluded = errors.New("service: too many excluded ids")
+	// ErrBoundariesUnavailable is returned when a reverse geocode prefers administrative accuracy
+	// but no municipality boundaries are configured
+	ErrBoundariesUnavailable = errors.New("service: municipality boundaries are not available")
+	// ErrInvalidSort is returned for a reverse geocode sort preference that isn't one of
+	// models.SortPreferenceKeys, or that prefers a municipality without naming one
+	ErrInvalidSort = errors.New("service: invalid sort preference")
+	// ErrInvalidRadius is returned for a negative search radius or one above the configured maximum
+	ErrInvalidRadius = errors.New("service: invalid radius")
+	// ErrInvalidPointCount is returned when a distance matrix has no points or more than MaxMatrixPoints
+	ErrInvalidPointCount = errors.New("service: invalid number of points")
+	// ErrInvalidBBox is returned for a search bounding box with coordinates out of range or a
+	// minimum that isn't below its maximum
+	ErrInvalidBBox = errors.New("service: invalid bounding box")
+	// ErrInvalidBatchSize is returned when a batch job has no addresses or more than MaxBatchAddresses
+	ErrInvalidBatchSize = errors.New("service: invalid number of addresses")
+)
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"geocoding-api/internal/models"
+)
+
+// ExportService streams the whole dataset for bulk export and reindexing
+type ExportService struct {
+	repo ExportRepository
+}
+
+// ExportRepository interface for dependency injection
+type ExportRepository interface {
+	StreamAllLocations(ctx context.Context, fn func(models.Location) error) error
+}
+
+// NewExportService creates a new export service
+func NewExportService(repo ExportRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// ExportLocations calls fn with every location in ID order, stopping at the first error fn
+// returns, which is passed through unwrapped
+func (s *ExportService) ExportLocations(ctx context.Context, fn func(models.Location) error) error {
+	var fnErr error
+	err := s.repo.StreamAllLocations(ctx, func(loc models.Location) error {
+		fnErr = fn(loc)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return fmt.Errorf("service: failed to export locations: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportRepository is a mock implementation of the ExportRepository interface
+type MockExportRepository struct {
+	mock.Mock
+}
+
+// StreamAllLocations implements ExportRepository by passing the mocked locations to fn.
+func (m *MockExportRepository) StreamAllLocations(ctx context.Context, fn func(models.Location) error) error {
+	args := m.Called(ctx)
+	for _, loc := range args.Get(0).([]models.Location) {
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestExportService_ExportLocations(t *testing.T) {
+	locations := []models.Location{{ID: 1}, {ID: 2}}
+	stop := errors.New("client went away")
+
+	tests := []struct {
+		name        string
+		mockError   error
+		fnError     error
+		expectedIDs []int
+		expectedErr error
+	}{
+		{name: "every location", expectedIDs: []int{1, 2}},
+		{name: "repository error is wrapped", mockError: assert.AnError, expectedIDs: []int{1, 2}, expectedErr: assert.AnError},
+		{name: "callback error is passed through", fnError: stop, expectedIDs: []int{1}, expectedErr: stop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockExportRepository)
+			service := NewExportService(mockRepo)
+			mockRepo.On("StreamAllLocations", mock.Anything).Return(locations, tt.mockError)
+
+			var ids []int
+			err := service.ExportLocations(context.Background(), func(loc models.Location) error {
+				ids = append(ids, loc.ID)
+				return tt.fnError
+			})
+
+			assert.Equal(t, tt.expectedIDs, ids)
+			if tt.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.fnError != nil {
+				assert.Equal(t, tt.fnError, err)
+			} else {
+				assert.ErrorContains(t, err, "service: failed to export locations")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"geocoding-api/internal/models"
+)
+
+// freshnessCacheTTL is how long a freshness result is reused; the COUNT(*) behind it is not free
+const freshnessCacheTTL = 30 * time.Second
+
+// FreshnessService reports how fresh the imported data is
+type FreshnessService struct {
+	repo FreshnessRepository
+	now  func() time.Time
+
+	mu       sync.Mutex
+	cached   *models.DataFreshness
+	cachedAt time.Time
+}
+
+// FreshnessRepository interface for dependency injection
+type FreshnessRepository interface {
+	DataFreshness(ctx context.Context) (*models.DataFreshness, error)
+}
+
+// NewFreshnessService creates a new freshness service
+func NewFreshnessService(repo FreshnessRepository) *FreshnessService {
+	return &FreshnessService{repo: repo, now: time.Now}
+}
+
+// Freshness returns the last import time and record count, cached for a short period
+func (s *FreshnessService) Freshness(ctx context.Context) (*models.DataFreshness, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && s.now().Sub(s.cachedAt) < freshnessCacheTTL {
+		return s.cached, nil
+	}
+
+	freshness, err := s.repo.DataFreshness(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get data freshness: %w", err)
+	}
+
+	s.cached = freshness
+	s.cachedAt = s.now()
+	return freshness, nil
+}
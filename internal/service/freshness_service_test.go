@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFreshnessRepository is a mock implementation of the FreshnessRepository interface
+type MockFreshnessRepository struct {
+	mock.Mock
+}
+
+// DataFreshness implements FreshnessRepository.
+func (m *MockFreshnessRepository) DataFreshness(ctx context.Context) (*models.DataFreshness, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.DataFreshness), args.Error(1)
+}
+
+func TestFreshnessService_Freshness(t *testing.T) {
+	imported := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockFreshness *models.DataFreshness
+		mockError     error
+		expectError   bool
+	}{
+		{
+			name:          "imported data",
+			mockFreshness: &models.DataFreshness{LastImportedAt: &imported, RecordCount: 42},
+		},
+		{
+			name:          "never imported",
+			mockFreshness: &models.DataFreshness{},
+		},
+		{
+			name:        "repository error",
+			mockError:   assert.AnError,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockFreshnessRepository)
+			service := NewFreshnessService(mockRepo)
+			mockRepo.On("DataFreshness", mock.Anything).Return(tt.mockFreshness, tt.mockError).Once()
+
+			// Execute
+			result, err := service.Freshness(context.Background())
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockFreshness, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFreshnessService_Freshness_Caches(t *testing.T) {
+	now := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	first := &models.DataFreshness{RecordCount: 1}
+	second := &models.DataFreshness{RecordCount: 2}
+
+	mockRepo := new(MockFreshnessRepository)
+	mockRepo.On("DataFreshness", mock.Anything).Return(first, nil).Once()
+	mockRepo.On("DataFreshness", mock.Anything).Return(second, nil).Once()
+
+	service := NewFreshnessService(mockRepo)
+	service.now = func() time.Time { return now }
+
+	result, err := service.Freshness(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first, result)
+
+	// Within the TTL the cached value is reused
+	now = now.Add(freshnessCacheTTL - time.Second)
+	result, err = service.Freshness(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first, result)
+
+	// After the TTL the repository is queried again
+	now = now.Add(time.Second)
+	result, err = service.Freshness(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, second, result)
+
+	mockRepo.AssertExpectations(t)
+}
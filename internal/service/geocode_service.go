@@ -3,37 +3,305 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/normalize"
+	"geocoding-api/internal/stats"
+
+	"github.com/rs/zerolog"
 )
 
+// maxSuggestions is the number of "did you mean" suggestions returned when a search has no matches
+const maxSuggestions = 5
+
+// outputSRIDs are the coordinate systems results can be transformed to: WGS84, Web Mercator
+// for tile overlays and JGD2011, the Japanese geodetic datum
+var outputSRIDs = map[int]bool{
+	models.DefaultSRID: true,
+	3857:               true,
+	6668:               true,
+}
+
+// SupportedSRID reports whether results can be returned in the given SRID
+func SupportedSRID(srid int) bool {
+	return outputSRIDs[srid]
+}
+
 // GeocodeService contains the core business logic for geocoding operations
 type GeoCodeService struct {
-	repo GeoCodeRepository
+	repo       GeoCodeRepository
+	cache      Cache
+	stats      *stats.Counters
+	strategies []SearchStrategy
+	minResults int
+	// disambiguation is GeoCodeConfig.DisambiguationThreshold
+	disambiguation float64
+	// snapshots holds the pagination snapshots; nil when they are disabled
+	snapshots Cache
+	// densityRadius and maxDensityResults are GeoCodeConfig.DensityRadius and MaxDensityResults
+	densityRadius     float64
+	maxDensityResults int
+}
+
+// GeoCodeConfig holds the geocode service settings
+type GeoCodeConfig struct {
+	// CacheTTL is how long geocode results are cached in memory; 0 disables the cache
+	CacheTTL time.Duration
+	// CacheSize is the maximum number of cached results
+	CacheSize int
+	// Cache replaces the in-memory cache, e.g. with one shared by every API instance; it applies
+	// its own expiry, so CacheTTL and CacheSize are ignored when it is set
+	Cache Cache
+	// Stats counts cache hits and misses; nil disables counting
+	Stats *stats.Counters
+	// Strategies are tried in order until one finds at least MinStrategyResults locations (see
+	// NewSearchStrategies); empty uses full-text search alone
+	Strategies []SearchStrategy
+	// MinStrategyResults is the number of results that stops the strategy pipeline; 0 means 1.
+	// When no strategy finds enough, the last strategy's results are returned.
+	MinStrategyResults int
+	// DisambiguationThreshold is how far below the best score, as a fraction of it, a result's
+	// score may be and still count as a close match when deciding whether a query is ambiguous
+	// (see disambiguate); 0 disables ambiguity detection
+	DisambiguationThreshold float64
+	// SnapshotTTL is how long a pagination snapshot, the ranked results offset pages of one query
+	// are cut from (see searchPage), is kept; 0 searches every page live. A snapshot holds up to
+	// MaxSnapshotResults locations, so memory grows with SnapshotSize times that, and rows changed
+	// during the TTL only show up in sessions started afterwards. Keep it at least CacheTTL, so a
+	// cached page never outlives the snapshot it was cut from.
+	SnapshotTTL time.Duration
+	// SnapshotSize is the maximum number of pagination snapshots kept
+	SnapshotSize int
+	// DensityRadius is the radius in meters that SearchOptions.IncludeDensity counts locations
+	// within around each result; 0 means DefaultDensityRadius
+	DensityRadius float64
+	// MaxDensityResults caps the results of a search with SearchOptions.IncludeDensity, as each
+	// costs a spatial count; 0 means DefaultMaxDensityResults
+	MaxDensityResults int
 }
 
+const (
+	// DefaultDensityRadius is the default GeoCodeConfig.DensityRadius
+	DefaultDensityRadius = 100.0
+	// DefaultMaxDensityResults is the default GeoCodeConfig.MaxDensityResults
+	DefaultMaxDensityResults = 20
+)
+
 // Repository interface for dependency injection
 type GeoCodeRepository interface {
-	SearchLocationsByText(ctx context.Context, query string) ([]models.Location, error)
+	SearchLocationsByText(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	SuggestAddresses(ctx context.Context, query string, limit int) ([]string, error)
+	MunicipalityBBoxes(ctx context.Context, areas []models.Area) (map[models.Area][]float64, error)
+	NeighborCounts(ctx context.Context, points []models.Point, radius float64) ([]int, error)
+	CountLocationsByText(ctx context.Context, query, config string, bbox *models.BoundingBox) (int, error)
 }
 
 // NewGeoCodeService creates a new geo code service
-func NewGeoCodeService(repo GeoCodeRepository) *GeoCodeService {
-	return &GeoCodeService{repo: repo}
+func NewGeoCodeService(repo GeoCodeRepository, cfg GeoCodeConfig) *GeoCodeService {
+	s := &GeoCodeService{repo: repo, cache: cfg.Cache, stats: cfg.Stats, strategies: cfg.Strategies, minResults: cfg.MinStrategyResults, disambiguation: cfg.DisambiguationThreshold, densityRadius: cfg.DensityRadius, maxDensityResults: cfg.MaxDensityResults}
+	if len(s.strategies) == 0 {
+		s.strategies = []SearchStrategy{searchFunc{name: StrategyFullText, search: repo.SearchLocationsByText}}
+	}
+	if s.minResults <= 0 {
+		s.minResults = 1
+	}
+	if s.densityRadius <= 0 {
+		s.densityRadius = DefaultDensityRadius
+	}
+	if s.maxDensityResults <= 0 {
+		s.maxDensityResults = DefaultMaxDensityResults
+	}
+	if s.cache == nil && cfg.CacheTTL > 0 && cfg.CacheSize > 0 {
+		s.cache = newResultCache(cfg.CacheTTL, cfg.CacheSize)
+	}
+	if cfg.SnapshotTTL > 0 && cfg.SnapshotSize > 0 {
+		s.snapshots = newResultCache(cfg.SnapshotTTL, cfg.SnapshotSize)
+	}
+	return s
 }
 
-// Geocode searches for locations by address text using full-text search
-func (s *GeoCodeService) Geocode(ctx context.Context, address string) ([]models.Location, error) {
-	if address == "" {
-		return nil, fmt.Errorf("service: address cannot be empty")
+// Geocode searches for locations by address text using full-text search.
+// When opts.Suggest is true and nothing matches, similar addresses are returned as suggestions.
+// The query is searched with full-width digits, letters and spaces made half-width
+// (normalize.HalfWidth) and block numbers in arabic numerals (normalize.KanjiToArabic), as the
+// imported data writes them.
+func (s *GeoCodeService) Geocode(ctx context.Context, opts models.SearchOptions) (*models.GeocodeResult, error) {
+	if opts.Query == "" {
+		return nil, ErrEmptyQuery
+	}
+	if query := normalize.KanjiToArabic(normalize.HalfWidth(opts.Query)); query != opts.Query {
+		zerolog.Ctx(ctx).Debug().Str("query", opts.Query).Str("normalized_query", query).Msg("normalized query")
+		opts.Query = query
+	}
+	if opts.Limit < 0 || opts.Limit > opts.LimitCap() {
+		return nil, fmt.Errorf("%w: must be between 0 and %d (0 uses the default)", ErrInvalidLimit, opts.LimitCap())
+	}
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("%w: cannot be negative", ErrInvalidOffset)
+	}
+	if opts.After != nil && opts.Offset != 0 {
+		return nil, ErrCursorWithOffset
+	}
+	switch opts.OrderBy {
+	case "", models.OrderByRelevance:
+	case models.OrderByImportance:
+		if opts.After != nil {
+			return nil, ErrCursorWithOrder
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOrderBy, opts.OrderBy)
+	}
+	if opts.SRID != 0 && !SupportedSRID(opts.SRID) {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSRID, opts.SRID)
+	}
+	for _, field := range opts.Fields {
+		if !models.IsLocationField(field) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownField, field)
+		}
+	}
+	if opts.BBox != nil && !opts.BBox.Valid() {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidBBox, *opts.BBox)
+	}
+	opts = opts.WithDefaults()
+	if opts.IncludeDensity && opts.Limit > s.maxDensityResults {
+		opts.Limit = s.maxDensityResults
+	}
+
+	if s.cache != nil {
+		if result, ok := s.cache.Get(ctx, opts.CacheKey()); ok {
+			s.stats.CacheHit()
+			// Mark a copy, the cached result is shared with other requests
+			hit := *result
+			hit.Cache = models.CacheHit
+			return &hit, nil
+		}
+		s.stats.CacheMiss()
 	}
 
-	locations, err := s.repo.SearchLocationsByText(ctx, address)
+	locations, strategy, err := s.searchPage(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("service: failed to search locations: %w", err)
+		return nil, err
 	}
 
-	return locations, nil
+	if opts.IncludeBBox && len(locations) > 0 {
+		if err := s.attachBBoxes(ctx, locations); err != nil {
+			return nil, err
+		}
+	}
+	if opts.IncludeDensity && len(locations) > 0 {
+		if err := s.attachDensities(ctx, locations); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.GeocodeResult{Results: locations}
+	if len(locations) > 0 {
+		result.Strategy = strategy
+		result.TextSearchConfig = locations[0].TextSearchConfig
+	}
+	// Only full-text matches can be counted, the other strategies have no count query
+	if opts.IncludeTotal && strategy == StrategyFullText {
+		total, err := s.repo.CountLocationsByText(ctx, opts.Query, result.TextSearchConfig, opts.BBox)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to count locations: %w", err)
+		}
+		result.Total = &total
+	}
+	// A full page may be followed by more results; a short one is the last. Cursors only
+	// follow the full-text relevance order, so other orders and strategies page with offsets.
+	if len(locations) == opts.Limit && opts.OrderBy != models.OrderByImportance && strategy == StrategyFullText {
+		last := locations[len(locations)-1]
+		result.NextCursor = models.SearchCursor{Rank: last.Score, ID: last.ID, Config: last.TextSearchConfig}.Encode()
+	}
+	// Only the first page prompts for an area, later ones continue a query the client has seen
+	if s.disambiguation > 0 && opts.Offset == 0 && opts.After == nil {
+		result.DisambiguationOptions = disambiguate(locations, s.disambiguation)
+		result.NeedsDisambiguation = len(result.DisambiguationOptions) > 0
+	}
+	if opts.Suggest && len(locations) == 0 {
+		suggestions, err := s.repo.SuggestAddresses(ctx, opts.Query, maxSuggestions)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to suggest addresses: %w", err)
+		}
+		result.Suggestions = suggestions
+	}
+
+	if s.cache != nil {
+		result.Cache = models.CacheMiss
+		s.cache.Set(ctx, opts.CacheKey(), result)
+	}
+
+	return result, nil
 }
 
+// search runs the strategies in order, returning the results of the first that finds at least
+// minResults locations, or of the last one run when none does, with the strategy's name. A
+// cursor continues a full-text search, so only the full-text strategy runs for one, and
+// opts.NoFuzzy skips the fuzzy strategy.
+func (s *GeoCodeService) search(ctx context.Context, opts models.SearchOptions) ([]models.Location, string, error) {
+	var locations []models.Location
+	var matched string
+	for _, strategy := range s.strategies {
+		if opts.After != nil && strategy.Name() != StrategyFullText {
+			continue
+		}
+		if opts.NoFuzzy && strategy.Name() == StrategyFuzzy {
+			continue
+		}
+		found, err := strategy.Search(ctx, opts)
+		if err != nil {
+			return nil, "", fmt.Errorf("service: failed to search locations (%s): %w", strategy.Name(), err)
+		}
+		locations, matched = found, strategy.Name()
+		if len(found) >= s.minResults {
+			break
+		}
+	}
+	return locations, matched, nil
+}
+
+// attachBBoxes sets each location's BBox to the extent of its municipality, fetched in one query
+func (s *GeoCodeService) attachBBoxes(ctx context.Context, locations []models.Location) error {
+	seen := make(map[models.Area]bool)
+	var areas []models.Area
+	for _, loc := range locations {
+		area := models.Area{Prefecture: loc.Prefecture, Municipality: loc.Municipality}
+		if !seen[area] {
+			seen[area] = true
+			areas = append(areas, area)
+		}
+	}
 
+	bboxes, err := s.repo.MunicipalityBBoxes(ctx, areas)
+	if err != nil {
+		return fmt.Errorf("service: failed to find municipality extents: %w", err)
+	}
+
+	for i := range locations {
+		locations[i].BBox = bboxes[models.Area{Prefecture: locations[i].Prefecture, Municipality: locations[i].Municipality}]
+	}
+	return nil
+}
+
+// attachDensities sets each location's Density to the number of locations within the density
+// radius of it, counted in one query
+func (s *GeoCodeService) attachDensities(ctx context.Context, locations []models.Location) error {
+	points := make([]models.Point, len(locations))
+	for i, loc := range locations {
+		points[i] = models.Point{Lat: loc.Latitude, Lon: loc.Longitude}
+	}
+
+	counts, err := s.repo.NeighborCounts(ctx, points, s.densityRadius)
+	if err != nil {
+		return fmt.Errorf("service: failed to count neighboring locations: %w", err)
+	}
+
+	for i := range locations {
+		if i < len(counts) {
+			density := counts[i]
+			locations[i].Density = &density
+		}
+	}
+	return nil
+}
@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"geocoding-api/internal/models"
+	"geocoding-api/internal/stats"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockRepository is a mock implementation of the Repository interface
@@ -16,11 +19,35 @@ type MockGeoCodeRepository struct {
 }
 
 // SearchLocationsByText implements GeoCodeRepository.
-func (m *MockGeoCodeRepository) SearchLocationsByText(ctx context.Context, query string) ([]models.Location, error) {
-	args := m.Called(ctx, query)
+func (m *MockGeoCodeRepository) SearchLocationsByText(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
 	return args.Get(0).([]models.Location), args.Error(1)
 }
 
+// SuggestAddresses implements GeoCodeRepository.
+func (m *MockGeoCodeRepository) SuggestAddresses(ctx context.Context, query string, limit int) ([]string, error) {
+	args := m.Called(ctx, query, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// MunicipalityBBoxes implements GeoCodeRepository.
+func (m *MockGeoCodeRepository) MunicipalityBBoxes(ctx context.Context, areas []models.Area) (map[models.Area][]float64, error) {
+	args := m.Called(ctx, areas)
+	return args.Get(0).(map[models.Area][]float64), args.Error(1)
+}
+
+// NeighborCounts implements GeoCodeRepository.
+func (m *MockGeoCodeRepository) NeighborCounts(ctx context.Context, points []models.Point, radius float64) ([]int, error) {
+	args := m.Called(ctx, points, radius)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+// CountLocationsByText implements GeoCodeRepository.
+func (m *MockGeoCodeRepository) CountLocationsByText(ctx context.Context, query, config string, bbox *models.BoundingBox) (int, error) {
+	args := m.Called(ctx, query, config, bbox)
+	return args.Int(0), args.Error(1)
+}
+
 func TestGeoCodeService_Geocode(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -29,11 +56,13 @@ func TestGeoCodeService_Geocode(t *testing.T) {
 		mockError     error
 		expected      []models.Location
 		expectError   bool
+		expectedErr   error
 	}{
 		{
 			name:        "empty address",
 			address:     "",
 			expectError: true,
+			expectedErr: ErrEmptyQuery,
 		},
 		{
 			name:    "successful search with results",
@@ -81,21 +110,26 @@ func TestGeoCodeService_Geocode(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup
 			mockRepo := new(MockGeoCodeRepository)
-			service := NewGeoCodeService(mockRepo)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
 
 			if tt.address != "" {
-				mockRepo.On("SearchLocationsByText", mock.Anything, tt.address).Return(tt.mockLocations, tt.mockError)
+				opts := models.SearchOptions{Query: tt.address, Limit: models.DefaultSearchLimit}
+				mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return(tt.mockLocations, tt.mockError)
 			}
 
 			// Execute
-			result, err := service.Geocode(context.Background(), tt.address)
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: tt.address})
 
 			// Assert
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expected, result)
+				assert.Equal(t, tt.expected, result.Results)
+				assert.Nil(t, result.Suggestions)
 			}
 
 			if tt.address != "" {
@@ -104,3 +138,554 @@ func TestGeoCodeService_Geocode(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoCodeService_Geocode_NormalizedQuery(t *testing.T) {
+	location := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1丁目"}
+
+	for _, query := range []string{"東京都千代田区丸の内１丁目", "東京都千代田区丸の内一丁目", "東京都千代田区丸の内1丁目"} {
+		t.Run(query, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+			mockRepo.On("SearchLocationsByText", mock.Anything, models.SearchOptions{Query: "東京都千代田区丸の内1丁目", Limit: models.DefaultSearchLimit}).
+				Return([]models.Location{location}, nil)
+
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: query})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []models.Location{location}, result.Results)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_Suggestions(t *testing.T) {
+	tests := []struct {
+		name            string
+		address         string
+		mockLocations   []models.Location
+		mockSuggestions []string
+		mockSuggestErr  error
+		expectSuggest   bool
+		expected        *models.GeocodeResult
+		expectError     bool
+	}{
+		{
+			name:            "no matches returns suggestions",
+			address:         "東京都千代田区丸之内",
+			mockLocations:   []models.Location{},
+			mockSuggestions: []string{"東京都千代田区丸の内"},
+			expectSuggest:   true,
+			expected: &models.GeocodeResult{
+				Results:     []models.Location{},
+				Suggestions: []string{"東京都千代田区丸の内"},
+			},
+		},
+		{
+			name:    "matches skip suggestion query",
+			address: "東京都千代田区丸の内",
+			mockLocations: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+			},
+			expected: &models.GeocodeResult{
+				Results: []models.Location{
+					{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+				},
+				Strategy: StrategyFullText,
+			},
+		},
+		{
+			name:    "matches found by a retried text search configuration",
+			address: "東京都千代田区丸の内",
+			mockLocations: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", TextSearchConfig: "simple"},
+			},
+			expected: &models.GeocodeResult{
+				Results: []models.Location{
+					{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", TextSearchConfig: "simple"},
+				},
+				Strategy:         StrategyFullText,
+				TextSearchConfig: "simple",
+			},
+		},
+		{
+			name:           "suggestion error",
+			address:        "東京都千代田区丸之内",
+			mockLocations:  []models.Location{},
+			mockSuggestErr: assert.AnError,
+			expectSuggest:  true,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+
+			opts := models.SearchOptions{Query: tt.address, Suggest: true, Limit: models.DefaultSearchLimit}
+			mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return(tt.mockLocations, nil)
+			if tt.expectSuggest {
+				mockRepo.On("SuggestAddresses", mock.Anything, tt.address, maxSuggestions).Return(tt.mockSuggestions, tt.mockSuggestErr)
+			}
+
+			// Execute
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: tt.address, Suggest: true})
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_Options(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        models.SearchOptions
+		expected    models.SearchOptions
+		expectedErr error
+	}{
+		{
+			name:     "zero limit uses default",
+			opts:     models.SearchOptions{Query: "丸の内"},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit},
+		},
+		{
+			name:     "explicit limit and offset",
+			opts:     models.SearchOptions{Query: "丸の内", Limit: 5, Offset: 20},
+			expected: models.SearchOptions{Query: "丸の内", Limit: 5, Offset: 20},
+		},
+		{
+			name:        "limit too large",
+			opts:        models.SearchOptions{Query: "丸の内", Limit: models.MaxSearchLimit + 1},
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:     "limit within raised maximum",
+			opts:     models.SearchOptions{Query: "丸の内", Limit: 50000, MaxLimit: 100000},
+			expected: models.SearchOptions{Query: "丸の内", Limit: 50000, MaxLimit: 100000},
+		},
+		{
+			name:        "limit above raised maximum",
+			opts:        models.SearchOptions{Query: "丸の内", Limit: 100001, MaxLimit: 100000},
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:        "negative limit",
+			opts:        models.SearchOptions{Query: "丸の内", Limit: -1},
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:        "negative offset",
+			opts:        models.SearchOptions{Query: "丸の内", Offset: -1},
+			expectedErr: ErrInvalidOffset,
+		},
+		{
+			name:     "supported srid",
+			opts:     models.SearchOptions{Query: "丸の内", SRID: 3857},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, SRID: 3857},
+		},
+		{
+			name:        "unsupported srid",
+			opts:        models.SearchOptions{Query: "丸の内", SRID: 2193},
+			expectedErr: ErrUnsupportedSRID,
+		},
+		{
+			name:     "cursor",
+			opts:     models.SearchOptions{Query: "丸の内", After: &models.SearchCursor{Rank: 0.5, ID: 42}},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, After: &models.SearchCursor{Rank: 0.5, ID: 42}},
+		},
+		{
+			name:     "fields",
+			opts:     models.SearchOptions{Query: "丸の内", Fields: []string{"id", "latitude", "longitude"}},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, Fields: []string{"id", "latitude", "longitude"}},
+		},
+		{
+			name:        "unknown field",
+			opts:        models.SearchOptions{Query: "丸の内", Fields: []string{"id", "full_address_tsvector"}},
+			expectedErr: ErrUnknownField,
+		},
+		{
+			name:        "cursor with offset",
+			opts:        models.SearchOptions{Query: "丸の内", Offset: 10, After: &models.SearchCursor{Rank: 0.5, ID: 42}},
+			expectedErr: ErrCursorWithOffset,
+		},
+		{
+			name:     "order by importance",
+			opts:     models.SearchOptions{Query: "丸の内", OrderBy: models.OrderByImportance, Offset: 10},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, OrderBy: models.OrderByImportance, Offset: 10},
+		},
+		{
+			name:     "order by relevance",
+			opts:     models.SearchOptions{Query: "丸の内", OrderBy: models.OrderByRelevance},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, OrderBy: models.OrderByRelevance},
+		},
+		{
+			name:     "bounding box",
+			opts:     models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+			expected: models.SearchOptions{Query: "丸の内", Limit: models.DefaultSearchLimit, BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+		},
+		{
+			name:        "bounding box minimum above maximum",
+			opts:        models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.8, MinLat: 35.6, MaxLon: 139.7, MaxLat: 35.7}},
+			expectedErr: ErrInvalidBBox,
+		},
+		{
+			name:        "bounding box latitude out of range",
+			opts:        models.SearchOptions{Query: "丸の内", BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 95}},
+			expectedErr: ErrInvalidBBox,
+		},
+		{
+			name:        "unknown order",
+			opts:        models.SearchOptions{Query: "丸の内", OrderBy: "population"},
+			expectedErr: ErrInvalidOrderBy,
+		},
+		{
+			name:        "cursor with importance order",
+			opts:        models.SearchOptions{Query: "丸の内", OrderBy: models.OrderByImportance, After: &models.SearchCursor{Rank: 0.5, ID: 42}},
+			expectedErr: ErrCursorWithOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+
+			if tt.expectedErr == nil {
+				mockRepo.On("SearchLocationsByText", mock.Anything, tt.expected).Return([]models.Location{}, nil)
+			}
+
+			_, err := service.Geocode(context.Background(), tt.opts)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_IncludeBBox(t *testing.T) {
+	chiyoda := models.Area{Prefecture: "東京都", Municipality: "千代田区"}
+	minato := models.Area{Prefecture: "東京都", Municipality: "港区"}
+	chiyodaBBox := []float64{139.73, 35.66, 139.78, 35.70}
+
+	tests := []struct {
+		name        string
+		locations   []models.Location
+		mockBBoxes  map[models.Area][]float64
+		mockError   error
+		expected    []models.Location
+		expectError bool
+	}{
+		{
+			name: "attaches extents and queries each area once",
+			locations: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区"},
+				{ID: 2, Prefecture: "東京都", Municipality: "千代田区"},
+				{ID: 3, Prefecture: "東京都", Municipality: "港区"},
+			},
+			mockBBoxes: map[models.Area][]float64{chiyoda: chiyodaBBox},
+			expected: []models.Location{
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", BBox: chiyodaBBox},
+				{ID: 2, Prefecture: "東京都", Municipality: "千代田区", BBox: chiyodaBBox},
+				{ID: 3, Prefecture: "東京都", Municipality: "港区"},
+			},
+		},
+		{
+			name:        "extent error",
+			locations:   []models.Location{{ID: 1, Prefecture: "東京都", Municipality: "千代田区"}},
+			mockBBoxes:  map[models.Area][]float64{},
+			mockError:   assert.AnError,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+
+			opts := models.SearchOptions{Query: "東京都", IncludeBBox: true}
+			mockRepo.On("SearchLocationsByText", mock.Anything, opts.WithDefaults()).Return(tt.locations, nil)
+			areas := []models.Area{chiyoda}
+			if len(tt.locations) > 1 {
+				areas = []models.Area{chiyoda, minato}
+			}
+			mockRepo.On("MunicipalityBBoxes", mock.Anything, areas).Return(tt.mockBBoxes, tt.mockError)
+
+			result, err := service.Geocode(context.Background(), opts)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result.Results)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_IncludeTotal(t *testing.T) {
+	total := func(n int) *int { return &n }
+
+	tests := []struct {
+		name          string
+		opts          models.SearchOptions
+		locations     []models.Location
+		countConfig   string
+		mockCount     int
+		mockError     error
+		expectedTotal *int
+		expectError   bool
+	}{
+		{
+			name:          "counts with the configuration that found the page",
+			opts:          models.SearchOptions{Query: "東京都", Limit: 1, IncludeTotal: true},
+			locations:     []models.Location{{ID: 1, TextSearchConfig: "simple"}},
+			countConfig:   "simple",
+			mockCount:     2,
+			expectedTotal: total(2),
+		},
+		{
+			name:          "offset past the end",
+			opts:          models.SearchOptions{Query: "東京都", Offset: 50, IncludeTotal: true},
+			locations:     []models.Location{},
+			mockCount:     2,
+			expectedTotal: total(2),
+		},
+		{
+			name:          "counts within the bounding box",
+			opts:          models.SearchOptions{Query: "東京都", IncludeTotal: true, BBox: &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7}},
+			locations:     []models.Location{{ID: 1}},
+			mockCount:     1,
+			expectedTotal: total(1),
+		},
+		{
+			name:      "not requested",
+			opts:      models.SearchOptions{Query: "東京都"},
+			locations: []models.Location{{ID: 1}},
+		},
+		{
+			name:        "count error",
+			opts:        models.SearchOptions{Query: "東京都", IncludeTotal: true},
+			locations:   []models.Location{{ID: 1}},
+			mockError:   assert.AnError,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+
+			mockRepo.On("SearchLocationsByText", mock.Anything, tt.opts.WithDefaults()).Return(tt.locations, nil)
+			if tt.opts.IncludeTotal {
+				mockRepo.On("CountLocationsByText", mock.Anything, tt.opts.Query, tt.countConfig, tt.opts.BBox).Return(tt.mockCount, tt.mockError)
+			}
+
+			result, err := service.Geocode(context.Background(), tt.opts)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedTotal, result.Total)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_IncludeDensity(t *testing.T) {
+	locations := []models.Location{
+		{ID: 1, Latitude: 35.681236, Longitude: 139.767125},
+		{ID: 2, Latitude: 34.702485, Longitude: 135.495951},
+	}
+	points := []models.Point{{Lat: 35.681236, Lon: 139.767125}, {Lat: 34.702485, Lon: 135.495951}}
+	density := func(n int) *int { return &n }
+
+	tests := []struct {
+		name          string
+		config        GeoCodeConfig
+		limit         int
+		expectedLimit int
+		mockCounts    []int
+		mockError     error
+		expected      []models.Location
+		expectError   bool
+	}{
+		{
+			name:          "attaches counts with the default radius",
+			expectedLimit: models.DefaultSearchLimit,
+			mockCounts:    []int{120, 3},
+			expected: []models.Location{
+				{ID: 1, Latitude: 35.681236, Longitude: 139.767125, Density: density(120)},
+				{ID: 2, Latitude: 34.702485, Longitude: 135.495951, Density: density(3)},
+			},
+		},
+		{
+			name:          "caps the limit",
+			config:        GeoCodeConfig{DensityRadius: 50, MaxDensityResults: 5},
+			limit:         50,
+			expectedLimit: 5,
+			mockCounts:    []int{7, 1},
+			expected: []models.Location{
+				{ID: 1, Latitude: 35.681236, Longitude: 139.767125, Density: density(7)},
+				{ID: 2, Latitude: 34.702485, Longitude: 135.495951, Density: density(1)},
+			},
+		},
+		{
+			name:          "count error",
+			expectedLimit: models.DefaultSearchLimit,
+			mockCounts:    []int{},
+			mockError:     assert.AnError,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, tt.config)
+
+			opts := models.SearchOptions{Query: "東京都", Limit: tt.limit, IncludeDensity: true}
+			expectedOpts := opts
+			expectedOpts.Limit = tt.expectedLimit
+			radius := tt.config.DensityRadius
+			if radius == 0 {
+				radius = DefaultDensityRadius
+			}
+			mockRepo.On("SearchLocationsByText", mock.Anything, expectedOpts).Return(append([]models.Location(nil), locations...), nil)
+			mockRepo.On("NeighborCounts", mock.Anything, points, radius).Return(tt.mockCounts, tt.mockError)
+
+			result, err := service.Geocode(context.Background(), opts)
+
+			if tt.expectError {
+				require.ErrorIs(t, err, assert.AnError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result.Results)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_Cache(t *testing.T) {
+	mockRepo := new(MockGeoCodeRepository)
+	counters := stats.New()
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{CacheTTL: time.Minute, CacheSize: 10, Stats: counters})
+
+	firstPage := models.SearchOptions{Query: "東京都", Offset: 0}
+	secondPage := models.SearchOptions{Query: "東京都", Offset: 10}
+	mockRepo.On("SearchLocationsByText", mock.Anything, firstPage.WithDefaults()).
+		Return([]models.Location{{ID: 1}}, nil).Once()
+	mockRepo.On("SearchLocationsByText", mock.Anything, secondPage.WithDefaults()).
+		Return([]models.Location{{ID: 11}}, nil).Once()
+
+	first, err := service.Geocode(context.Background(), firstPage)
+	require.NoError(t, err)
+	second, err := service.Geocode(context.Background(), secondPage)
+	require.NoError(t, err)
+	cached, err := service.Geocode(context.Background(), firstPage)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, firstPage.CacheKey(), secondPage.CacheKey())
+	assert.Equal(t, []models.Location{{ID: 1}}, first.Results)
+	assert.Equal(t, []models.Location{{ID: 11}}, second.Results)
+	assert.Equal(t, first.Results, cached.Results)
+	assert.Equal(t, models.CacheMiss, first.Cache)
+	assert.Equal(t, models.CacheMiss, second.Cache)
+	assert.Equal(t, models.CacheHit, cached.Cache)
+	assert.Equal(t, models.CacheStats{Hits: 1, Misses: 2, HitRatio: 1.0 / 3}, counters.Snapshot().Cache)
+	mockRepo.AssertExpectations(t)
+}
+
+// mapCache is a Cache without expiry, standing in for a shared cache
+type mapCache map[string]*models.GeocodeResult
+
+func (c mapCache) Get(ctx context.Context, key string) (*models.GeocodeResult, bool) {
+	result, ok := c[key]
+	return result, ok
+}
+
+func (c mapCache) Set(ctx context.Context, key string, result *models.GeocodeResult) {
+	c[key] = result
+}
+
+func TestGeoCodeService_Geocode_SharedCache(t *testing.T) {
+	mockRepo := new(MockGeoCodeRepository)
+	shared := mapCache{}
+	opts := models.SearchOptions{Query: "東京都"}
+	mockRepo.On("SearchLocationsByText", mock.Anything, opts.WithDefaults()).
+		Return([]models.Location{{ID: 1}}, nil).Once()
+
+	// A result cached by one instance is served by another without querying the database
+	first, err := NewGeoCodeService(mockRepo, GeoCodeConfig{Cache: shared}).Geocode(context.Background(), opts)
+	require.NoError(t, err)
+	cached, err := NewGeoCodeService(mockRepo, GeoCodeConfig{Cache: shared}).Geocode(context.Background(), opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Results, cached.Results)
+	assert.Equal(t, models.CacheHit, cached.Cache)
+	assert.Contains(t, shared, opts.WithDefaults().CacheKey())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGeoCodeService_Geocode_NextCursor(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderBy   string
+		locations []models.Location
+		expected  string
+	}{
+		{
+			name:      "full page",
+			locations: []models.Location{{ID: 7, Score: 0.9}, {ID: 3, Score: 0.25}},
+			expected:  models.SearchCursor{Rank: 0.25, ID: 3}.Encode(),
+		},
+		{
+			name:      "full page of a retried text search configuration",
+			locations: []models.Location{{ID: 7, Score: 0.9, TextSearchConfig: "simple"}, {ID: 3, Score: 0.25, TextSearchConfig: "simple"}},
+			expected:  models.SearchCursor{Rank: 0.25, ID: 3, Config: "simple"}.Encode(),
+		},
+		{
+			name:      "last page",
+			locations: []models.Location{{ID: 7, Score: 0.9}},
+		},
+		{
+			name:      "full page ordered by importance",
+			orderBy:   models.OrderByImportance,
+			locations: []models.Location{{ID: 3, Score: 0.25}, {ID: 7, Score: 0.9}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{})
+
+			opts := models.SearchOptions{Query: "丸の内", Limit: 2, OrderBy: tt.orderBy}
+			mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return(tt.locations, nil)
+
+			result, err := service.Geocode(context.Background(), opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.NextCursor)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MinPostGISVersion is the oldest PostGIS release the spatial queries are known to work with
+const MinPostGISVersion = "3.0"
+
+var (
+	// ErrPostGISUnavailable is returned when PostGIS_Version() cannot be queried, usually because the extension is missing
+	ErrPostGISUnavailable = errors.New("service: postgis extension is not available")
+	// ErrPostGISTooOld is returned when the installed PostGIS is older than MinPostGISVersion
+	ErrPostGISTooOld = errors.New("service: postgis version is too old")
+)
+
+// HealthService checks that the database can serve the API's queries
+type HealthService struct {
+	repo HealthRepository
+}
+
+// HealthRepository interface for dependency injection
+type HealthRepository interface {
+	PostGISVersion(ctx context.Context) (string, error)
+	WarmSpatialIndex(ctx context.Context, lat, lon float64) (int, error)
+}
+
+// NewHealthService creates a new health service
+func NewHealthService(repo HealthRepository) *HealthService {
+	return &HealthService{repo: repo}
+}
+
+// CheckPostGIS returns the installed PostGIS version, or an error when it is missing or older than MinPostGISVersion
+func (s *HealthService) CheckPostGIS(ctx context.Context) (string, error) {
+	full, err := s.repo.PostGISVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPostGISUnavailable, err)
+	}
+
+	// PostGIS_Version() looks like "3.4 USE_GEOS=1 USE_PROJ=1 USE_STATS=1"
+	version, _, _ := strings.Cut(full, " ")
+	if !versionAtLeast(version, MinPostGISVersion) {
+		return version, fmt.Errorf("%w: found %s, need %s or newer", ErrPostGISTooOld, version, MinPostGISVersion)
+	}
+
+	return version, nil
+}
+
+// WarmSpatialIndex runs a representative spatial query around the point so the index is cached
+// before traffic arrives, returning the number of locations it found
+func (s *HealthService) WarmSpatialIndex(ctx context.Context, lat, lon float64) (int, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, fmt.Errorf("%w: warmup point %f, %f", ErrInvalidCoordinates, lat, lon)
+	}
+
+	count, err := s.repo.WarmSpatialIndex(ctx, lat, lon)
+	if err != nil {
+		return 0, fmt.Errorf("service: failed to warm spatial index: %w", err)
+	}
+	return count, nil
+}
+
+// versionAtLeast compares dotted numeric versions; unparsable versions are treated as too old
+func versionAtLeast(version, minimum string) bool {
+	have := strings.Split(version, ".")
+	want := strings.Split(minimum, ".")
+	for i, w := range want {
+		wantPart, _ := strconv.Atoi(w)
+		if i >= len(have) {
+			return wantPart == 0
+		}
+		havePart, err := strconv.Atoi(have[i])
+		if err != nil {
+			return false
+		}
+		if havePart != wantPart {
+			return havePart > wantPart
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHealthRepository is a mock implementation of the HealthRepository interface
+type MockHealthRepository struct {
+	mock.Mock
+}
+
+// PostGISVersion implements HealthRepository.
+func (m *MockHealthRepository) PostGISVersion(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+// WarmSpatialIndex implements HealthRepository.
+func (m *MockHealthRepository) WarmSpatialIndex(ctx context.Context, lat, lon float64) (int, error) {
+	args := m.Called(ctx, lat, lon)
+	return args.Int(0), args.Error(1)
+}
+
+func TestHealthService_CheckPostGIS(t *testing.T) {
+	tests := []struct {
+		name            string
+		mockVersion     string
+		mockError       error
+		expectedVersion string
+		expectedErr     error
+	}{
+		{
+			name:            "supported version",
+			mockVersion:     "3.4 USE_GEOS=1 USE_PROJ=1 USE_STATS=1",
+			expectedVersion: "3.4",
+		},
+		{
+			name:            "minimum version",
+			mockVersion:     "3.0 USE_GEOS=1 USE_PROJ=1 USE_STATS=1",
+			expectedVersion: "3.0",
+		},
+		{
+			name:            "too old",
+			mockVersion:     "2.5 USE_GEOS=1 USE_PROJ=1 USE_STATS=1",
+			expectedVersion: "2.5",
+			expectedErr:     ErrPostGISTooOld,
+		},
+		{
+			name:        "extension missing",
+			mockError:   assert.AnError,
+			expectedErr: ErrPostGISUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockHealthRepository)
+			service := NewHealthService(mockRepo)
+			mockRepo.On("PostGISVersion", mock.Anything).Return(tt.mockVersion, tt.mockError)
+
+			// Execute
+			version, err := service.CheckPostGIS(context.Background())
+
+			// Assert
+			assert.Equal(t, tt.expectedVersion, version)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHealthService_WarmSpatialIndex(t *testing.T) {
+	tests := []struct {
+		name          string
+		lat           float64
+		lon           float64
+		mockCount     int
+		mockError     error
+		expectedCount int
+		expectedErr   error
+		expectError   bool
+	}{
+		{
+			name:          "warmed",
+			lat:           35.681236,
+			lon:           139.767125,
+			mockCount:     128,
+			expectedCount: 128,
+		},
+		{
+			name:        "invalid point",
+			lat:         91,
+			lon:         139.767125,
+			expectedErr: ErrInvalidCoordinates,
+			expectError: true,
+		},
+		{
+			name:        "repository error",
+			lat:         35.681236,
+			lon:         139.767125,
+			mockError:   assert.AnError,
+			expectedErr: assert.AnError,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockHealthRepository)
+			service := NewHealthService(mockRepo)
+			if tt.expectedErr != ErrInvalidCoordinates {
+				mockRepo.On("WarmSpatialIndex", mock.Anything, tt.lat, tt.lon).Return(tt.mockCount, tt.mockError)
+			}
+
+			// Execute
+			count, err := service.WarmSpatialIndex(context.Background(), tt.lat, tt.lon)
+
+			// Assert
+			assert.Equal(t, tt.expectedCount, count)
+			if tt.expectError {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
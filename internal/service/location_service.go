@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"geocoding-api/internal/models"
+)
+
+// MaxLocationIDs is the maximum number of IDs accepted in a single lookup
+const MaxLocationIDs = 100
+
+// LocationService contains the business logic for fetching locations by ID
+type LocationService struct {
+	repo LocationRepository
+}
+
+// LocationRepository interface for dependency injection
+type LocationRepository interface {
+	FindLocationsByIDs(ctx context.Context, ids []int) ([]models.Location, error)
+	FindLocationsByArea(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+}
+
+// NewLocationService creates a new location service
+func NewLocationService(repo LocationRepository) *LocationService {
+	return &LocationService{repo: repo}
+}
+
+// GetLocationsByIDs fetches the locations with the given IDs in request order
+func (s *LocationService) GetLocationsByIDs(ctx context.Context, ids []int) ([]models.Location, error) {
+	if len(ids) == 0 {
+		return nil, ErrEmptyIDs
+	}
+	if len(ids) > MaxLocationIDs {
+		return nil, fmt.Errorf("%w: %d (max %d)", ErrTooManyIDs, len(ids), MaxLocationIDs)
+	}
+
+	locations, err := s.repo.FindLocationsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find locations: %w", err)
+	}
+
+	return locations, nil
+}
+
+// GetLocationsInArea pages through the locations in a prefecture and/or municipality
+func (s *LocationService) GetLocationsInArea(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	if opts.Prefecture == "" && opts.Municipality == "" {
+		return nil, ErrMissingArea
+	}
+	if opts.Limit < 0 || opts.Limit > models.MaxSearchLimit {
+		return nil, fmt.Errorf("%w: must be between 0 and %d (0 uses the default)", ErrInvalidLimit, models.MaxSearchLimit)
+	}
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("%w: cannot be negative", ErrInvalidOffset)
+	}
+
+	locations, err := s.repo.FindLocationsByArea(ctx, opts.WithDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find locations in area: %w", err)
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLocationRepository is a mock implementation of the LocationRepository interface
+type MockLocationRepository struct {
+	mock.Mock
+}
+
+// FindLocationsByIDs implements LocationRepository.
+func (m *MockLocationRepository) FindLocationsByIDs(ctx context.Context, ids []int) ([]models.Location, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindLocationsByArea implements LocationRepository.
+func (m *MockLocationRepository) FindLocationsByArea(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+func TestLocationService_GetLocationsByIDs(t *testing.T) {
+	tooMany := make([]int, MaxLocationIDs+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	tests := []struct {
+		name          string
+		ids           []int
+		callsRepo     bool
+		mockLocations []models.Location
+		mockError     error
+		expected      []models.Location
+		expectError   bool
+	}{
+		{
+			name:        "empty ids",
+			ids:         []int{},
+			expectError: true,
+		},
+		{
+			name:        "too many ids",
+			ids:         tooMany,
+			expectError: true,
+		},
+		{
+			name:      "successful lookup preserves repository order",
+			ids:       []int{2, 1},
+			callsRepo: true,
+			mockLocations: []models.Location{
+				{ID: 2, Prefecture: "東京都", Municipality: "港区", Address1: "赤坂"},
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+			},
+			expected: []models.Location{
+				{ID: 2, Prefecture: "東京都", Municipality: "港区", Address1: "赤坂"},
+				{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内"},
+			},
+		},
+		{
+			name:        "repository error",
+			ids:         []int{1},
+			callsRepo:   true,
+			mockError:   assert.AnError,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockLocationRepository)
+			service := NewLocationService(mockRepo)
+
+			if tt.callsRepo {
+				mockRepo.On("FindLocationsByIDs", mock.Anything, tt.ids).Return(tt.mockLocations, tt.mockError)
+			}
+
+			// Execute
+			result, err := service.GetLocationsByIDs(context.Background(), tt.ids)
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLocationService_GetLocationsInArea(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          models.SearchOptions
+		expectedOpts  models.SearchOptions
+		callsRepo     bool
+		mockLocations []models.Location
+		mockError     error
+		expectError   bool
+	}{
+		{
+			name:        "missing area",
+			opts:        models.SearchOptions{Limit: 5},
+			expectError: true,
+		},
+		{
+			name:        "limit too large",
+			opts:        models.SearchOptions{Municipality: "渋谷区", Limit: models.MaxSearchLimit + 1},
+			expectError: true,
+		},
+		{
+			name:        "negative offset",
+			opts:        models.SearchOptions{Municipality: "渋谷区", Offset: -1},
+			expectError: true,
+		},
+		{
+			name:         "defaults limit",
+			opts:         models.SearchOptions{Prefecture: "東京都", Municipality: "渋谷区", Offset: 10},
+			expectedOpts: models.SearchOptions{Prefecture: "東京都", Municipality: "渋谷区", Limit: models.DefaultSearchLimit, Offset: 10},
+			callsRepo:    true,
+			mockLocations: []models.Location{
+				{ID: 3, Prefecture: "東京都", Municipality: "渋谷区", Address1: "道玄坂"},
+			},
+		},
+		{
+			name:         "repository error",
+			opts:         models.SearchOptions{Prefecture: "東京都", Limit: 5},
+			expectedOpts: models.SearchOptions{Prefecture: "東京都", Limit: 5},
+			callsRepo:    true,
+			mockError:    assert.AnError,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockLocationRepository)
+			service := NewLocationService(mockRepo)
+
+			if tt.callsRepo {
+				mockRepo.On("FindLocationsByArea", mock.Anything, tt.expectedOpts).Return(tt.mockLocations, tt.mockError)
+			}
+
+			// Execute
+			result, err := service.GetLocationsInArea(context.Background(), tt.opts)
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockLocations, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"geocoding-api/internal/models"
+)
+
+// ErrUnknownAPIKey is returned when a request's API key isn't one of the configured keys
+var ErrUnknownAPIKey = errors.New("service: unknown api key")
+
+// QuotaService counts requests against the quota of the API key they carry
+type QuotaService struct {
+	repo   QuotaRepository
+	quotas map[string]models.Quota
+	now    func() time.Time
+}
+
+// QuotaRepository interface for dependency injection
+type QuotaRepository interface {
+	IncrementAPIKeyUsage(ctx context.Context, keyID string, day time.Time) (daily, monthly int64, err error)
+}
+
+// NewQuotaService creates a quota service for the API keys and their quotas. Only the keys'
+// hashes are kept, and only hashes are stored in the database.
+func NewQuotaService(repo QuotaRepository, keys map[string]models.Quota) *QuotaService {
+	quotas := make(map[string]models.Quota, len(keys))
+	for key, quota := range keys {
+		quotas[apiKeyID(key)] = quota
+	}
+	return &QuotaService{repo: repo, quotas: quotas, now: time.Now}
+}
+
+// apiKeyID identifies an API key without revealing it
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Consume counts one request by key and reports the key's standing afterwards. A key without
+// quotas isn't counted. Requests refused for exceeding a quota are counted too, so a client
+// that keeps retrying doesn't get through before the period resets.
+func (s *QuotaService) Consume(ctx context.Context, key string) (*models.QuotaUsage, error) {
+	id := apiKeyID(key)
+	quota, ok := s.quotas[id]
+	if !ok {
+		return nil, ErrUnknownAPIKey
+	}
+	if quota.Daily == 0 && quota.Monthly == 0 {
+		return &models.QuotaUsage{}, nil
+	}
+
+	now := s.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daily, monthly, err := s.repo.IncrementAPIKeyUsage(ctx, id, day)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to count api key usage: %w", err)
+	}
+
+	var usage *models.QuotaUsage
+	exceeded := false
+	for _, period := range []models.QuotaUsage{
+		periodUsage(quota.Daily, daily, day.AddDate(0, 0, 1)),
+		periodUsage(quota.Monthly, monthly, time.Date(day.Year(), day.Month()+1, 1, 0, 0, 0, 0, time.UTC)),
+	} {
+		if period.Limit == 0 {
+			continue
+		}
+		exceeded = exceeded || period.Exceeded
+		// Report the period with the fewest requests left; once both are used up, the later
+		// reset is the one the client has to wait for
+		if usage == nil || period.Remaining < usage.Remaining || (period.Remaining == 0 && period.Reset.After(usage.Reset)) {
+			usage = &period
+		}
+	}
+	usage.Exceeded = exceeded
+	return usage, nil
+}
+
+// periodUsage is the standing in one quota period, after used requests
+func periodUsage(limit, used int64, reset time.Time) models.QuotaUsage {
+	return models.QuotaUsage{
+		Limit:     limit,
+		Remaining: max(limit-used, 0),
+		Reset:     reset,
+		Exceeded:  limit > 0 && used > limit,
+	}
+}
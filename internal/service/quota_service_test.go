@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockQuotaRepository is a mock implementation of the QuotaRepository interface
+type MockQuotaRepository struct {
+	mock.Mock
+}
+
+// IncrementAPIKeyUsage implements QuotaRepository.
+func (m *MockQuotaRepository) IncrementAPIKeyUsage(ctx context.Context, keyID string, day time.Time) (int64, int64, error) {
+	args := m.Called(ctx, keyID, day)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func TestQuotaService_Consume(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	tomorrow := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		quota       models.Quota
+		daily       int64
+		monthly     int64
+		expected    *models.QuotaUsage
+		expectCount bool
+	}{
+		{
+			name:        "within both quotas reports the closer one",
+			quota:       models.Quota{Daily: 100, Monthly: 1000},
+			daily:       10,
+			monthly:     950,
+			expected:    &models.QuotaUsage{Limit: 1000, Remaining: 50, Reset: nextMonth},
+			expectCount: true,
+		},
+		{
+			name:        "last request of the day",
+			quota:       models.Quota{Daily: 100, Monthly: 1000},
+			daily:       100,
+			monthly:     400,
+			expected:    &models.QuotaUsage{Limit: 100, Remaining: 0, Reset: tomorrow},
+			expectCount: true,
+		},
+		{
+			name:        "daily quota exhausted",
+			quota:       models.Quota{Daily: 100, Monthly: 1000},
+			daily:       101,
+			monthly:     401,
+			expected:    &models.QuotaUsage{Limit: 100, Remaining: 0, Reset: tomorrow, Exceeded: true},
+			expectCount: true,
+		},
+		{
+			name:        "both exhausted waits for the month",
+			quota:       models.Quota{Daily: 100, Monthly: 1000},
+			daily:       101,
+			monthly:     1000,
+			expected:    &models.QuotaUsage{Limit: 1000, Remaining: 0, Reset: nextMonth, Exceeded: true},
+			expectCount: true,
+		},
+		{
+			name:        "monthly quota only",
+			quota:       models.Quota{Monthly: 1000},
+			daily:       500,
+			monthly:     1001,
+			expected:    &models.QuotaUsage{Limit: 1000, Remaining: 0, Reset: nextMonth, Exceeded: true},
+			expectCount: true,
+		},
+		{
+			name:     "unlimited key isn't counted",
+			quota:    models.Quota{},
+			expected: &models.QuotaUsage{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockQuotaRepository)
+			service := NewQuotaService(mockRepo, map[string]models.Quota{"partner-key": tt.quota})
+			service.now = func() time.Time { return now }
+			if tt.expectCount {
+				mockRepo.On("IncrementAPIKeyUsage", mock.Anything, apiKeyID("partner-key"), day).Return(tt.daily, tt.monthly, nil).Once()
+			}
+
+			usage, err := service.Consume(context.Background(), "partner-key")
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, usage)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQuotaService_Consume_Exhaustion(t *testing.T) {
+	// Simulate a key running through its daily quota against a running count
+	mockRepo := new(MockQuotaRepository)
+	service := NewQuotaService(mockRepo, map[string]models.Quota{"partner-key": {Daily: 3}})
+	for used := int64(1); used <= 5; used++ {
+		mockRepo.On("IncrementAPIKeyUsage", mock.Anything, apiKeyID("partner-key"), mock.Anything).Return(used, used, nil).Once()
+	}
+
+	var remaining []int64
+	var exceeded []bool
+	for range 5 {
+		usage, err := service.Consume(context.Background(), "partner-key")
+		require.NoError(t, err)
+		remaining = append(remaining, usage.Remaining)
+		exceeded = append(exceeded, usage.Exceeded)
+	}
+
+	assert.Equal(t, []int64{2, 1, 0, 0, 0}, remaining)
+	assert.Equal(t, []bool{false, false, false, true, true}, exceeded)
+}
+
+func TestQuotaService_Consume_Errors(t *testing.T) {
+	mockRepo := new(MockQuotaRepository)
+	service := NewQuotaService(mockRepo, map[string]models.Quota{"partner-key": {Daily: 100}})
+
+	_, err := service.Consume(context.Background(), "guess")
+	assert.ErrorIs(t, err, ErrUnknownAPIKey)
+
+	mockRepo.On("IncrementAPIKeyUsage", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), int64(0), assert.AnError)
+	_, err = service.Consume(context.Background(), "partner-key")
+	assert.ErrorIs(t, err, assert.AnError)
+}
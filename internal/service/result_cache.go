@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"geocoding-api/internal/models"
+)
+
+// Cache stores geocode results between requests. Entries are keyed on SearchOptions.CacheKey,
+// which covers every option, so paginated or filtered requests for the same text never share
+// an entry. A cache that fails reports a miss, so the search falls through to the database.
+type Cache interface {
+	Get(ctx context.Context, key string) (*models.GeocodeResult, bool)
+	Set(ctx context.Context, key string, result *models.GeocodeResult)
+}
+
+// resultCache is a small in-memory TTL cache of geocode results, local to one API instance
+type resultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result   *models.GeocodeResult
+	cachedAt time.Time
+}
+
+func newResultCache(ttl time.Duration, maxEntries int) *resultCache {
+	return &resultCache{ttl: ttl, maxEntries: maxEntries, now: time.Now, entries: make(map[string]cachedResult)}
+}
+
+// Get implements Cache.
+func (c *resultCache) Get(ctx context.Context, key string) (*models.GeocodeResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().Sub(entry.cachedAt) >= c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// Set implements Cache.
+func (c *resultCache) Set(ctx context.Context, key string, result *models.GeocodeResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = cachedResult{result: result, cachedAt: c.now()}
+}
+
+// evict drops expired entries, or an arbitrary one when none have expired
+func (c *resultCache) evict() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.cachedAt) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
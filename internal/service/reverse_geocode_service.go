@@ -3,38 +3,258 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"geocoding-api/internal/models"
 )
 
+// MaxContextLocations is the maximum number of neighbouring locations returned as context
+const MaxContextLocations = 10
+
+// MaxExcludedIDs is the maximum number of location IDs a reverse geocode may skip
+const MaxExcludedIDs = 100
+
+// MaxColocatedLocations is the maximum number of co-located addresses returned with the nearest one
+const MaxColocatedLocations = 100
+
+// MaxSortCandidates is the number of nearest locations a sort preference chooses among
+const MaxSortCandidates = 10
+
 // ReverseGeoCodeService contains the core business logic for reverse geocoding operations
 type ReverseGeoCodeService struct {
-	repo ReverseGeoCodeRepository
+	repo   ReverseGeoCodeRepository
+	config SpatialConfig
+}
+
+// SpatialConfig holds the limits shared by the spatial services
+type SpatialConfig struct {
+	// MaxRadiusMeters is the largest search radius a request may ask for, and the radius used
+	// when it doesn't ask for one. Defaults to models.DefaultMaxRadiusMeters.
+	MaxRadiusMeters float64
+	// AddressRanges makes reverse geocoding fall back to the address range segments when no
+	// address point is in range, interpolating the house number; it needs the address_segments table
+	AddressRanges bool
+	// ColocationMeters is the distance within which addresses are grouped with the nearest one
+	// into a building, so units whose points are slightly offset still count as co-located.
+	// Defaults to models.DefaultColocationMeters.
+	ColocationMeters float64
+	// MunicipalityBoundaries enables ReverseGeocodeAdmin; it needs the municipality_boundaries table
+	MunicipalityBoundaries bool
+	// TieMeters is how much farther than the nearest location a candidate may be and still be
+	// reordered by a sort preference. Defaults to models.DefaultTieMeters.
+	TieMeters float64
 }
 
 // ReverseGeoCodeRepository interface for dependency injection
 type ReverseGeoCodeRepository interface {
-	FindNearestLocation(ctx context.Context, lat, lon float64) (*models.Location, error)
+	FindNearestLocation(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error)
+	FindNearestLocations(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error)
+	FindNearestSegmentAddress(ctx context.Context, lat, lon, radius float64) (*models.Location, error)
+	FindColocatedLocations(ctx context.Context, id int, meters float64, source string, exclude []int, limit int) ([]models.Location, error)
+	FindNearestLocationInMunicipality(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error)
 }
 
 // NewReverseGeoCodeService creates a new reverse geo code service
-func NewReverseGeoCodeService(repo ReverseGeoCodeRepository) *ReverseGeoCodeService {
-	return &ReverseGeoCodeService{repo: repo}
+func NewReverseGeoCodeService(repo ReverseGeoCodeRepository, cfg SpatialConfig) *ReverseGeoCodeService {
+	if cfg.MaxRadiusMeters <= 0 {
+		cfg.MaxRadiusMeters = models.DefaultMaxRadiusMeters
+	}
+	if cfg.ColocationMeters <= 0 {
+		cfg.ColocationMeters = models.DefaultColocationMeters
+	}
+	if cfg.TieMeters <= 0 {
+		cfg.TieMeters = models.DefaultTieMeters
+	}
+	return &ReverseGeoCodeService{repo: repo, config: cfg}
+}
+
+// searchRadius validates a requested search radius in meters, where 0 selects the maximum
+func (c SpatialConfig) searchRadius(radius float64) (float64, error) {
+	if radius == 0 {
+		return c.MaxRadiusMeters, nil
+	}
+	if radius < 0 || radius > c.MaxRadiusMeters {
+		return 0, fmt.Errorf("%w: %g must be between 0 and %g meters", ErrInvalidRadius, radius, c.MaxRadiusMeters)
+	}
+	return radius, nil
+}
+
+// ReverseGeocode finds the nearest address within radius meters (0 for the maximum) of the given coordinates using spatial query.
+// A non-empty source restricts the search to the dataset imported from that file, and the
+// locations whose IDs are in exclude are skipped, e.g. to ask for the next nearest address.
+// With AddressRanges, a point without an address nearby gets one interpolated along the nearest
+// address range segment instead, unless a source is given, which segments don't belong to.
+func (s *ReverseGeoCodeService) ReverseGeocode(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	radius, err := s.validate(lat, lon, radius, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return s.nearest(ctx, lat, lon, radius, source, exclude)
+}
+
+// ReverseGeocodeAdmin reverse geocodes like ReverseGeocode, but favors administrative accuracy
+// over distance: the nearest address in the municipality whose boundary contains the point wins
+// over a nearer one across the boundary. Without such an address in range, because no boundary
+// contains the point or its municipality has no address within radius, it returns the nearest
+// address as ReverseGeocode does. It needs MunicipalityBoundaries.
+func (s *ReverseGeoCodeService) ReverseGeocodeAdmin(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	if !s.config.MunicipalityBoundaries {
+		return nil, ErrBoundariesUnavailable
+	}
+	radius, err := s.validate(lat, lon, radius, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := s.repo.FindNearestLocationInMunicipality(ctx, lat, lon, radius, source, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find nearest location in municipality: %w", err)
+	}
+	if location != nil {
+		return location, nil
+	}
+	return s.nearest(ctx, lat, lon, radius, source, exclude)
 }
 
-// ReverseGeocode finds the nearest address to the given coordinates using spatial query
-func (s *ReverseGeoCodeService) ReverseGeocode(ctx context.Context, lat, lon float64) (*models.Location, error) {
+// validate checks the arguments of a single-location reverse geocode, returning the search radius
+func (s *ReverseGeoCodeService) validate(lat, lon, radius float64, exclude []int) (float64, error) {
 	if lat < -90 || lat > 90 {
-		return nil, fmt.Errorf("service: invalid latitude: %f", lat)
+		return 0, fmt.Errorf("%w: latitude %f", ErrInvalidCoordinates, lat)
 	}
 	if lon < -180 || lon > 180 {
-		return nil, fmt.Errorf("service: invalid longitude: %f", lon)
+		return 0, fmt.Errorf("%w: longitude %f", ErrInvalidCoordinates, lon)
 	}
+	if len(exclude) > MaxExcludedIDs {
+		return 0, fmt.Errorf("%w: %d, at most %d", ErrTooManyExcluded, len(exclude), MaxExcludedIDs)
+	}
+	return s.config.searchRadius(radius)
+}
 
-	location, err := s.repo.FindNearestLocation(ctx, lat, lon)
+// nearest finds the nearest address point, falling back to the address ranges as configured
+func (s *ReverseGeoCodeService) nearest(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	location, err := s.repo.FindNearestLocation(ctx, lat, lon, radius, source, exclude)
 	if err != nil {
 		return nil, fmt.Errorf("service: failed to find nearest location: %w", err)
 	}
 
+	if location == nil && s.config.AddressRanges && source == "" {
+		location, err = s.repo.FindNearestSegmentAddress(ctx, lat, lon, radius)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to interpolate nearest address: %w", err)
+		}
+	}
+
 	return location, nil
 }
+
+// ReverseGeocodeColocated reverse geocodes like ReverseGeocode and groups the addresses within
+// ColocationMeters of the nearest one with it, as the units of one building. An interpolated
+// address has no stored point to group around, so it has no co-located addresses.
+func (s *ReverseGeoCodeService) ReverseGeocodeColocated(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.ColocatedLocation, error) {
+	location, err := s.ReverseGeocode(ctx, lat, lon, radius, source, exclude)
+	if err != nil || location == nil {
+		return nil, err
+	}
+
+	result := &models.ColocatedLocation{Location: *location, Colocated: []models.Location{}}
+	if location.Interpolated {
+		return result, nil
+	}
+
+	result.Colocated, err = s.repo.FindColocatedLocations(ctx, location.ID, s.config.ColocationMeters, source, exclude, MaxColocatedLocations)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find co-located locations: %w", err)
+	}
+	return result, nil
+}
+
+// ReverseGeocodeNearest finds up to limit addresses within radius meters of the point, nearest
+// first, each with its Distance, fetched in one query; limit is between 1 and
+// models.MaxSearchLimit. Like ReverseGeocodeWithContext it only searches address points, without
+// the AddressRanges fallback. It returns an empty slice when nothing is within radius.
+func (s *ReverseGeoCodeService) ReverseGeocodeNearest(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error) {
+	if limit < 1 || limit > models.MaxSearchLimit {
+		return nil, fmt.Errorf("%w: must be between 1 and %d", ErrInvalidLimit, models.MaxSearchLimit)
+	}
+	radius, err := s.validate(lat, lon, radius, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := s.repo.FindNearestLocations(ctx, lat, lon, radius, source, exclude, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find nearest locations: %w", err)
+	}
+	if locations == nil {
+		locations = []models.Location{}
+	}
+	return locations, nil
+}
+
+// ReverseGeocodeWithContext finds the nearest address plus up to n further neighbours with
+// their distances, fetched in one query. It returns nil when nothing is within radius meters.
+func (s *ReverseGeoCodeService) ReverseGeocodeWithContext(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int) (*models.ReverseGeocodeResult, error) {
+	return s.ReverseGeocodeSorted(ctx, lat, lon, radius, source, exclude, n, models.SortPreference{})
+}
+
+// ReverseGeocodeSorted reverse geocodes like ReverseGeocodeWithContext, ordering the addresses by
+// the sort preference: among the MaxSortCandidates nearest, those within TieMeters of the nearest
+// count as equidistant, and the ones the preference favors move ahead of the others. Either group
+// keeps its distance order, and farther addresses keep their places. Like ReverseGeocodeWithContext
+// it only searches address points, without the AddressRanges fallback.
+func (s *ReverseGeoCodeService) ReverseGeocodeSorted(ctx context.Context, lat, lon, radius float64, source string, exclude []int, n int, pref models.SortPreference) (*models.ReverseGeocodeResult, error) {
+	switch pref.Key {
+	case "", models.SortByDistance, models.SortByBlockLot:
+	case models.SortByMunicipality:
+		if pref.Municipality == "" {
+			return nil, fmt.Errorf("%w: %s needs a municipality", ErrInvalidSort, pref.Key)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSort, pref.Key)
+	}
+	if n < 0 || n > MaxContextLocations {
+		return nil, fmt.Errorf("%w: must be between 0 and %d", ErrInvalidContext, MaxContextLocations)
+	}
+	radius, err := s.validate(lat, lon, radius, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := n + 1
+	if pref.Key != "" && pref.Key != models.SortByDistance {
+		limit = max(limit, MaxSortCandidates)
+	}
+	locations, err := s.repo.FindNearestLocations(ctx, lat, lon, radius, source, exclude, limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to find nearest locations: %w", err)
+	}
+	if len(locations) == 0 {
+		return nil, nil
+	}
+	sortByPreference(locations, pref, s.config.TieMeters)
+	locations = locations[:min(len(locations), n+1)]
+
+	return &models.ReverseGeocodeResult{
+		Location: locations[0],
+		Context:  append([]models.Location{}, locations[1:]...),
+	}, nil
+}
+
+// sortByPreference moves the locations pref favors ahead of the others among those within
+// tieMeters of the nearest, keeping the distance order within each group
+func sortByPreference(locations []models.Location, pref models.SortPreference, tieMeters float64) {
+	tied := 1
+	for tied < len(locations) && locations[tied].Distance-locations[0].Distance <= tieMeters {
+		tied++
+	}
+	slices.SortStableFunc(locations[:tied], func(a, b models.Location) int {
+		preferA, preferB := pref.Prefers(a), pref.Prefers(b)
+		switch {
+		case preferA && !preferB:
+			return -1
+		case preferB && !preferA:
+			return 1
+		}
+		return 0
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockRepository is a mock implementation of the Repository interface
@@ -16,8 +17,32 @@ type MockReverseGeoCodeRepository struct {
 }
 
 // FindNearestLocation implements ReverseGeoCodeRepository.
-func (m *MockReverseGeoCodeRepository) FindNearestLocation(ctx context.Context, lat float64, lon float64) (*models.Location, error) {
-	args := m.Called(ctx, lat, lon)
+func (m *MockReverseGeoCodeRepository) FindNearestLocation(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
+	return args.Get(0).(*models.Location), args.Error(1)
+}
+
+// FindNearestLocations implements ReverseGeoCodeRepository.
+func (m *MockReverseGeoCodeRepository) FindNearestLocations(ctx context.Context, lat, lon, radius float64, source string, exclude []int, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindNearestSegmentAddress implements ReverseGeoCodeRepository.
+func (m *MockReverseGeoCodeRepository) FindNearestSegmentAddress(ctx context.Context, lat, lon, radius float64) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius)
+	return args.Get(0).(*models.Location), args.Error(1)
+}
+
+// FindColocatedLocations implements ReverseGeoCodeRepository.
+func (m *MockReverseGeoCodeRepository) FindColocatedLocations(ctx context.Context, id int, meters float64, source string, exclude []int, limit int) ([]models.Location, error) {
+	args := m.Called(ctx, id, meters, source, exclude, limit)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindNearestLocationInMunicipality implements ReverseGeoCodeRepository.
+func (m *MockReverseGeoCodeRepository) FindNearestLocationInMunicipality(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
 	return args.Get(0).(*models.Location), args.Error(1)
 }
 
@@ -30,12 +55,14 @@ func TestReverseGeoCodeService_ReverseGeocode(t *testing.T) {
 		mockError     error
 		expected      *models.Location
 		expectError   bool
+		expectedErr   error
 	}{
 		{
-			name:        "empty lat and lon",
-			lat:         0,
+			name:        "invalid latitude",
+			lat:         91,
 			lon:         0,
 			expectError: true,
+			expectedErr: ErrInvalidCoordinates,
 		},
 		{
 			name: "successful search with results",
@@ -82,18 +109,21 @@ func TestReverseGeoCodeService_ReverseGeocode(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup
 			mockRepo := new(MockReverseGeoCodeRepository)
-			service := NewReverseGeoCodeService(mockRepo)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{})
 
 			if tt.lat != 0 && tt.lon != 0 {
-				mockRepo.On("FindNearestLocation", mock.Anything, tt.lat, tt.lon).Return(tt.mockLocation, tt.mockError)
+				mockRepo.On("FindNearestLocation", mock.Anything, tt.lat, tt.lon, models.DefaultMaxRadiusMeters, "", []int(nil)).Return(tt.mockLocation, tt.mockError)
 			}
 
 			// Execute
-			result, err := service.ReverseGeocode(context.Background(), tt.lat, tt.lon)
+			result, err := service.ReverseGeocode(context.Background(), tt.lat, tt.lon, 0, "", nil)
 
 			// Assert
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected, result)
@@ -105,3 +135,442 @@ func TestReverseGeoCodeService_ReverseGeocode(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseGeoCodeService_ReverseGeocodeWithContext(t *testing.T) {
+	primary := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内", Distance: 3.2}
+	neighbour := models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "大手町", Distance: 120.5}
+
+	tests := []struct {
+		name          string
+		n             int
+		callsRepo     bool
+		mockLocations []models.Location
+		mockError     error
+		expected      *models.ReverseGeocodeResult
+		expectError   bool
+	}{
+		{
+			name:          "splits primary from context",
+			n:             2,
+			callsRepo:     true,
+			mockLocations: []models.Location{primary, neighbour},
+			expected:      &models.ReverseGeocodeResult{Location: primary, Context: []models.Location{neighbour}},
+		},
+		{
+			name:          "only primary nearby",
+			n:             1,
+			callsRepo:     true,
+			mockLocations: []models.Location{primary},
+			expected:      &models.ReverseGeocodeResult{Location: primary, Context: []models.Location{}},
+		},
+		{
+			name:          "nothing nearby",
+			n:             1,
+			callsRepo:     true,
+			mockLocations: []models.Location{},
+		},
+		{
+			name:        "context too large",
+			n:           MaxContextLocations + 1,
+			expectError: true,
+		},
+		{
+			name:          "repository error",
+			n:             1,
+			callsRepo:     true,
+			mockLocations: []models.Location{},
+			mockError:     assert.AnError,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{})
+
+			if tt.callsRepo {
+				mockRepo.On("FindNearestLocations", mock.Anything, 35.681236, 139.767125, models.DefaultMaxRadiusMeters, "", []int(nil), tt.n+1).Return(tt.mockLocations, tt.mockError)
+			}
+
+			// Execute
+			result, err := service.ReverseGeocodeWithContext(context.Background(), 35.681236, 139.767125, 0, "", nil, tt.n)
+
+			// Assert
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeService_ReverseGeocodeNearest(t *testing.T) {
+	nearby := []models.Location{
+		{ID: 1, Municipality: "千代田区", Address1: "丸の内", Distance: 3.2},
+		{ID: 2, Municipality: "千代田区", Address1: "大手町", Distance: 120.5},
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		callsRepo     bool
+		mockLocations []models.Location
+		mockError     error
+		expected      []models.Location
+		expectedErr   error
+	}{
+		{
+			name:          "nearest first with distances",
+			limit:         5,
+			callsRepo:     true,
+			mockLocations: nearby,
+			expected: []models.Location{
+				{ID: 1, Municipality: "千代田区", Address1: "丸の内", Distance: 3.2},
+				{ID: 2, Municipality: "千代田区", Address1: "大手町", Distance: 120.5},
+			},
+		},
+		{
+			name:          "nothing in range",
+			limit:         5,
+			callsRepo:     true,
+			mockLocations: nil,
+			expected:      []models.Location{},
+		},
+		{
+			name:        "zero limit",
+			limit:       0,
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:        "limit too large",
+			limit:       models.MaxSearchLimit + 1,
+			expectedErr: ErrInvalidLimit,
+		},
+		{
+			name:          "repository error",
+			limit:         5,
+			callsRepo:     true,
+			mockLocations: []models.Location{},
+			mockError:     assert.AnError,
+			expectedErr:   assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{})
+
+			if tt.callsRepo {
+				mockRepo.On("FindNearestLocations", mock.Anything, 35.681236, 139.767125, models.DefaultMaxRadiusMeters, "", []int(nil), tt.limit).Return(tt.mockLocations, tt.mockError)
+			}
+
+			locations, err := service.ReverseGeocodeNearest(context.Background(), 35.681236, 139.767125, 0, "", nil, tt.limit)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, locations)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeService_ReverseGeocodeSorted(t *testing.T) {
+	nearest := models.Location{ID: 1, Municipality: "千代田区", Address1: "丸の内", Distance: 3.2}
+	tiedBlockLot := models.Location{ID: 2, Municipality: "中央区", Address1: "八重洲", BlockLot: "1", Distance: 3.8}
+	tiedMunicipality := models.Location{ID: 3, Municipality: "中央区", Address1: "八重洲", Distance: 4.1}
+	farBlockLot := models.Location{ID: 4, Municipality: "千代田区", Address1: "大手町", BlockLot: "2", Distance: 30}
+	candidates := []models.Location{nearest, tiedBlockLot, tiedMunicipality, farBlockLot}
+
+	tests := []struct {
+		name          string
+		n             int
+		pref          models.SortPreference
+		expectedLimit int
+		expectedIDs   []int
+		expectError   bool
+	}{
+		{
+			name:          "distance keeps the order",
+			n:             3,
+			pref:          models.SortPreference{Key: models.SortByDistance},
+			expectedLimit: 4,
+			expectedIDs:   []int{1, 2, 3, 4},
+		},
+		{
+			name:          "block/lot number among the tied",
+			n:             3,
+			pref:          models.SortPreference{Key: models.SortByBlockLot},
+			expectedLimit: MaxSortCandidates,
+			expectedIDs:   []int{2, 1, 3, 4},
+		},
+		{
+			name:          "municipality among the tied, keeping distance order",
+			n:             0,
+			pref:          models.SortPreference{Key: models.SortByMunicipality, Municipality: "中央区"},
+			expectedLimit: MaxSortCandidates,
+			expectedIDs:   []int{2},
+		},
+		{
+			name:          "farther candidates aren't tied",
+			n:             3,
+			pref:          models.SortPreference{Key: models.SortByMunicipality, Municipality: "千代田区"},
+			expectedLimit: MaxSortCandidates,
+			expectedIDs:   []int{1, 2, 3, 4},
+		},
+		{
+			name:        "municipality without a municipality",
+			pref:        models.SortPreference{Key: models.SortByMunicipality},
+			expectError: true,
+		},
+		{
+			name:        "unknown key",
+			pref:        models.SortPreference{Key: "closest"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{})
+			if !tt.expectError {
+				mockRepo.On("FindNearestLocations", mock.Anything, 35.681236, 139.767125, models.DefaultMaxRadiusMeters, "", []int(nil), tt.expectedLimit).
+					Return(append([]models.Location{}, candidates...), nil)
+			}
+
+			result, err := service.ReverseGeocodeSorted(context.Background(), 35.681236, 139.767125, 0, "", nil, tt.n, tt.pref)
+
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrInvalidSort)
+				return
+			}
+			require.NoError(t, err)
+			ids := []int{result.Location.ID}
+			for _, loc := range result.Context {
+				ids = append(ids, loc.ID)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeService_Radius(t *testing.T) {
+	tests := []struct {
+		name           string
+		radius         float64
+		expectedRadius float64
+		expectedErr    error
+	}{
+		{name: "default is the maximum", radius: 0, expectedRadius: 500},
+		{name: "within the maximum", radius: 200, expectedRadius: 200},
+		{name: "at the maximum", radius: 500, expectedRadius: 500},
+		{name: "above the maximum", radius: 501, expectedErr: ErrInvalidRadius},
+		{name: "negative", radius: -1, expectedErr: ErrInvalidRadius},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{MaxRadiusMeters: 500})
+
+			if tt.expectedErr == nil {
+				mockRepo.On("FindNearestLocation", mock.Anything, 35.681236, 139.767125, tt.expectedRadius, "", []int(nil)).Return((*models.Location)(nil), nil)
+				mockRepo.On("FindNearestLocations", mock.Anything, 35.681236, 139.767125, tt.expectedRadius, "", []int(nil), 4).Return([]models.Location{}, nil)
+			}
+
+			_, err := service.ReverseGeocode(context.Background(), 35.681236, 139.767125, tt.radius, "", nil)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			_, err = service.ReverseGeocodeWithContext(context.Background(), 35.681236, 139.767125, tt.radius, "", nil, 3)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReverseGeoCodeService_Exclude(t *testing.T) {
+	mockRepo := new(MockReverseGeoCodeRepository)
+	service := NewReverseGeoCodeService(mockRepo, SpatialConfig{})
+	exclude := []int{1, 2}
+	next := &models.Location{ID: 3}
+	mockRepo.On("FindNearestLocation", mock.Anything, 35.681236, 139.767125, models.DefaultMaxRadiusMeters, "", exclude).Return(next, nil)
+
+	location, err := service.ReverseGeocode(context.Background(), 35.681236, 139.767125, 0, "", exclude)
+	assert.NoError(t, err)
+	assert.Equal(t, next, location)
+
+	tooMany := make([]int, MaxExcludedIDs+1)
+	_, err = service.ReverseGeocode(context.Background(), 35.681236, 139.767125, 0, "", tooMany)
+	assert.ErrorIs(t, err, ErrTooManyExcluded)
+	_, err = service.ReverseGeocodeWithContext(context.Background(), 35.681236, 139.767125, 0, "", tooMany, 3)
+	assert.ErrorIs(t, err, ErrTooManyExcluded)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReverseGeoCodeService_AddressRanges(t *testing.T) {
+	point := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1"}
+	interpolated := &models.Location{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "7", Interpolated: true}
+
+	tests := []struct {
+		name          string
+		addressRanges bool
+		source        string
+		mockLocation  *models.Location
+		expectRanges  bool
+		expected      *models.Location
+	}{
+		{name: "address point found", addressRanges: true, mockLocation: point, expected: point},
+		{name: "falls back to segments", addressRanges: true, expectRanges: true, expected: interpolated},
+		{name: "fallback disabled", addressRanges: false},
+		{name: "no fallback for a source", addressRanges: true, source: "tokyo.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{MaxRadiusMeters: 1000, AddressRanges: tt.addressRanges})
+			mockRepo.On("FindNearestLocation", mock.Anything, 35.681236, 139.767125, 1000.0, tt.source, []int(nil)).Return(tt.mockLocation, nil)
+			if tt.expectRanges {
+				mockRepo.On("FindNearestSegmentAddress", mock.Anything, 35.681236, 139.767125, 1000.0).Return(interpolated, nil)
+			}
+
+			location, err := service.ReverseGeocode(context.Background(), 35.681236, 139.767125, 0, tt.source, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, location)
+			mockRepo.AssertExpectations(t)
+			if !tt.expectRanges {
+				mockRepo.AssertNotCalled(t, "FindNearestSegmentAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestReverseGeoCodeService_ReverseGeocodeColocated(t *testing.T) {
+	nearest := &models.Location{ID: 10, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "1-101"}
+	// Units of the same building, their points a few meters apart
+	units := []models.Location{{ID: 11, BlockLot: "1-102"}, {ID: 12, BlockLot: "1-201"}}
+	interpolated := &models.Location{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "7", Interpolated: true}
+
+	tests := []struct {
+		name             string
+		colocationMeters float64
+		mockLocation     *models.Location
+		mockSegment      *models.Location
+		expectColocated  bool
+		expectedMeters   float64
+		expected         *models.ColocatedLocation
+	}{
+		{
+			name:            "units within the default distance",
+			mockLocation:    nearest,
+			expectColocated: true,
+			expectedMeters:  models.DefaultColocationMeters,
+			expected:        &models.ColocatedLocation{Location: *nearest, Colocated: units},
+		},
+		{
+			name:             "configured distance",
+			colocationMeters: 12.5,
+			mockLocation:     nearest,
+			expectColocated:  true,
+			expectedMeters:   12.5,
+			expected:         &models.ColocatedLocation{Location: *nearest, Colocated: units},
+		},
+		{
+			name:        "interpolated address has no stored point to group around",
+			mockSegment: interpolated,
+			expected:    &models.ColocatedLocation{Location: *interpolated, Colocated: []models.Location{}},
+		},
+		{
+			name: "nothing nearby",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{MaxRadiusMeters: 1000, AddressRanges: true, ColocationMeters: tt.colocationMeters})
+			exclude := []int{3}
+			mockRepo.On("FindNearestLocation", mock.Anything, 35.681236, 139.767125, 1000.0, "", exclude).Return(tt.mockLocation, nil)
+			if tt.mockLocation == nil {
+				mockRepo.On("FindNearestSegmentAddress", mock.Anything, 35.681236, 139.767125, 1000.0).Return(tt.mockSegment, nil)
+			}
+			if tt.expectColocated {
+				mockRepo.On("FindColocatedLocations", mock.Anything, 10, tt.expectedMeters, "", exclude, MaxColocatedLocations).Return(units, nil)
+			}
+
+			result, err := service.ReverseGeocodeColocated(context.Background(), 35.681236, 139.767125, 0, "", exclude)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+			mockRepo.AssertExpectations(t)
+			if !tt.expectColocated {
+				mockRepo.AssertNotCalled(t, "FindColocatedLocations", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestReverseGeoCodeService_ReverseGeocodeAdmin(t *testing.T) {
+	// The nearest point is across the boundary in 中央区, the point itself lies in 千代田区
+	inMunicipality := &models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1"}
+	nearest := &models.Location{ID: 2, Prefecture: "東京都", Municipality: "中央区", Address1: "八重洲1"}
+
+	tests := []struct {
+		name             string
+		boundaries       bool
+		mockMunicipality *models.Location
+		expectNearest    bool
+		expected         *models.Location
+		expectedErr      error
+	}{
+		{name: "address in the containing municipality wins", boundaries: true, mockMunicipality: inMunicipality, expected: inMunicipality},
+		{name: "falls back to the nearest", boundaries: true, expectNearest: true, expected: nearest},
+		{name: "boundaries not configured", expectedErr: ErrBoundariesUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockReverseGeoCodeRepository)
+			service := NewReverseGeoCodeService(mockRepo, SpatialConfig{MaxRadiusMeters: 1000, MunicipalityBoundaries: tt.boundaries})
+			if tt.boundaries {
+				mockRepo.On("FindNearestLocationInMunicipality", mock.Anything, 35.681236, 139.767125, 1000.0, "", []int(nil)).Return(tt.mockMunicipality, nil)
+			}
+			if tt.expectNearest {
+				mockRepo.On("FindNearestLocation", mock.Anything, 35.681236, 139.767125, 1000.0, "", []int(nil)).Return(nearest, nil)
+			}
+
+			location, err := service.ReverseGeocodeAdmin(context.Background(), 35.681236, 139.767125, 0, "", nil)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, location)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, location)
+			}
+			mockRepo.AssertExpectations(t)
+			if !tt.expectNearest {
+				mockRepo.AssertNotCalled(t, "FindNearestLocation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	// Validated like ReverseGeocode
+	service := NewReverseGeoCodeService(new(MockReverseGeoCodeRepository), SpatialConfig{MunicipalityBoundaries: true})
+	_, err := service.ReverseGeocodeAdmin(context.Background(), 91, 0, 0, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidCoordinates)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"geocoding-api/internal/models"
+)
+
+// RoundTripGeocoder geocodes the query of a round trip
+type RoundTripGeocoder interface {
+	Geocode(ctx context.Context, opts models.SearchOptions) (*models.GeocodeResult, error)
+}
+
+// RoundTripReverseGeocoder reverse geocodes the coordinates of a round trip's geocode match
+type RoundTripReverseGeocoder interface {
+	ReverseGeocode(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error)
+}
+
+// RoundTripService checks the dataset's consistency by geocoding an address and reverse
+// geocoding the result
+type RoundTripService struct {
+	geocoder        RoundTripGeocoder
+	reverseGeocoder RoundTripReverseGeocoder
+}
+
+// NewRoundTripService creates a new round trip service
+func NewRoundTripService(geocoder RoundTripGeocoder, reverseGeocoder RoundTripReverseGeocoder) *RoundTripService {
+	return &RoundTripService{geocoder: geocoder, reverseGeocoder: reverseGeocoder}
+}
+
+// RoundTrip geocodes the query, reverse geocodes the top match's coordinates and compares the
+// address components of the two locations
+func (s *RoundTripService) RoundTrip(ctx context.Context, query string) (*models.RoundTripResult, error) {
+	geocoded, err := s.geocoder.Geocode(ctx, models.SearchOptions{Query: query, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to geocode round trip query: %w", err)
+	}
+
+	result := &models.RoundTripResult{Query: query, Mismatches: []string{}}
+	if len(geocoded.Results) == 0 {
+		return result, nil
+	}
+	result.Geocoded = &geocoded.Results[0]
+
+	reversed, err := s.reverseGeocoder.ReverseGeocode(ctx, result.Geocoded.Latitude, result.Geocoded.Longitude, 0, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to reverse geocode round trip match: %w", err)
+	}
+	if reversed == nil {
+		return result, nil
+	}
+	result.Reversed = reversed
+
+	result.Mismatches = addressMismatches(*result.Geocoded, *reversed)
+	result.Match = len(result.Mismatches) == 0
+	return result, nil
+}
+
+// addressMismatches returns the JSON names of the address components that differ between a and b
+func addressMismatches(a, b models.Location) []string {
+	components := []struct {
+		name string
+		a, b string
+	}{
+		{"prefecture", a.Prefecture, b.Prefecture},
+		{"municipality", a.Municipality, b.Municipality},
+		{"address1", a.Address1, b.Address1},
+		{"address2", a.Address2, b.Address2},
+		{"block_lot", a.BlockLot, b.BlockLot},
+	}
+
+	mismatches := []string{}
+	for _, c := range components {
+		if c.a != c.b {
+			mismatches = append(mismatches, c.name)
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoundTripReverseGeocoder is a mock implementation of the RoundTripReverseGeocoder interface
+type MockRoundTripReverseGeocoder struct {
+	mock.Mock
+}
+
+func (m *MockRoundTripReverseGeocoder) ReverseGeocode(ctx context.Context, lat, lon, radius float64, source string, exclude []int) (*models.Location, error) {
+	args := m.Called(ctx, lat, lon, radius, source, exclude)
+	return args.Get(0).(*models.Location), args.Error(1)
+}
+
+func TestRoundTripService_RoundTrip(t *testing.T) {
+	marunouchi := models.Location{ID: 1, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "1", Latitude: 35.681236, Longitude: 139.767125}
+	neighbour := models.Location{ID: 2, Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内一丁目", BlockLot: "2", Latitude: 35.6813, Longitude: 139.7672}
+
+	tests := []struct {
+		name          string
+		geocoded      []models.Location
+		reversed      *models.Location
+		reverseError  error
+		expectReverse bool
+		expected      *models.RoundTripResult
+		expectError   bool
+	}{
+		{
+			name:          "consistent address",
+			geocoded:      []models.Location{marunouchi},
+			reversed:      &marunouchi,
+			expectReverse: true,
+			expected:      &models.RoundTripResult{Query: "丸の内1-1", Geocoded: &marunouchi, Reversed: &marunouchi, Match: true, Mismatches: []string{}},
+		},
+		{
+			name:          "reverse geocodes to a neighbour",
+			geocoded:      []models.Location{marunouchi},
+			reversed:      &neighbour,
+			expectReverse: true,
+			expected:      &models.RoundTripResult{Query: "丸の内1-1", Geocoded: &marunouchi, Reversed: &neighbour, Mismatches: []string{"block_lot"}},
+		},
+		{
+			name:     "no geocode match",
+			expected: &models.RoundTripResult{Query: "丸の内1-1", Mismatches: []string{}},
+		},
+		{
+			name:          "nothing near the match",
+			geocoded:      []models.Location{marunouchi},
+			reversed:      nil,
+			expectReverse: true,
+			expected:      &models.RoundTripResult{Query: "丸の内1-1", Geocoded: &marunouchi, Mismatches: []string{}},
+		},
+		{
+			name:          "reverse geocode error",
+			geocoded:      []models.Location{marunouchi},
+			reversed:      nil,
+			reverseError:  assert.AnError,
+			expectReverse: true,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			geocoder := new(MockBatchGeocoder)
+			reverseGeocoder := new(MockRoundTripReverseGeocoder)
+			service := NewRoundTripService(geocoder, reverseGeocoder)
+			geocoder.On("Geocode", mock.Anything, models.SearchOptions{Query: "丸の内1-1", Limit: 1}).
+				Return(&models.GeocodeResult{Results: tt.geocoded}, nil)
+			if tt.expectReverse {
+				reverseGeocoder.On("ReverseGeocode", mock.Anything, marunouchi.Latitude, marunouchi.Longitude, 0.0, "", []int(nil)).
+					Return(tt.reversed, tt.reverseError)
+			}
+
+			// Execute
+			result, err := service.RoundTrip(context.Background(), "丸の内1-1")
+
+			// Assert
+			if tt.expectError {
+				assert.ErrorIs(t, err, tt.reverseError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			geocoder.AssertExpectations(t)
+			reverseGeocoder.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"geocoding-api/internal/models"
+	"geocoding-api/internal/normalize"
+)
+
+// Names of the search strategies a geocode pipeline can be built from
+const (
+	// StrategyExact matches the full address exactly, with or without its block/lot number
+	StrategyExact = "exact"
+	// StrategyFullText is the weighted full-text search, the only strategy cursors can continue
+	StrategyFullText = "fulltext"
+	// StrategyFuzzy matches addresses by trigram similarity, tolerating typos
+	StrategyFuzzy = "fuzzy"
+	// StrategyInterpolated places the query's house number along the address range segment of
+	// the rest of the address, for numbers without a stored point; it needs the address_segments table
+	StrategyInterpolated = "interpolated"
+	// StrategyNormalized matches the query's normalize.SearchKey against the search key stored by
+	// the importer's --normalized-key, independent of the text search configuration's tokenizer
+	StrategyNormalized = "normalized"
+	// StrategyKana matches a query written in kana against the readings of datasets imported with
+	// them; other queries find nothing, so the pipeline moves on to the next strategy
+	StrategyKana = "kana"
+)
+
+// houseNumberPattern splits a query into its address and trailing house number, as in
+// "東京都千代田区丸の内1-5" (normalized) or "東京都千代田区丸の内一丁目5番地"
+var houseNumberPattern = regexp.MustCompile(`^(.*?)[-\s]*(\d+)(?:番地|番|号)?$`)
+
+// SearchStrategy is one way of finding the locations matching a geocode query. GeoCodeService
+// tries its strategies in order and returns the results of the first that finds enough.
+type SearchStrategy interface {
+	// Name identifies the strategy in results, see models.GeocodeResult.Strategy
+	Name() string
+	Search(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+}
+
+// StrategyRepository is the data access every built-in search strategy needs
+type StrategyRepository interface {
+	FindLocationsByAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	SearchLocationsByText(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	SearchLocationsByTrigram(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	FindLocationsByNormalizedAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	FindLocationsByKana(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+	InterpolateAddress(ctx context.Context, address string, number int, srid int) (*models.Location, error)
+}
+
+// searchFunc adapts a repository search to a SearchStrategy
+type searchFunc struct {
+	name   string
+	search func(ctx context.Context, opts models.SearchOptions) ([]models.Location, error)
+}
+
+func (s searchFunc) Name() string { return s.name }
+
+func (s searchFunc) Search(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	return s.search(ctx, opts)
+}
+
+// NewSearchStrategies builds the named strategies in order, failing on an unknown or repeated name
+func NewSearchStrategies(names []string, repo StrategyRepository) ([]SearchStrategy, error) {
+	searches := map[string]func(ctx context.Context, opts models.SearchOptions) ([]models.Location, error){
+		StrategyExact:    repo.FindLocationsByAddress,
+		StrategyFullText: repo.SearchLocationsByText,
+		StrategyFuzzy:    repo.SearchLocationsByTrigram,
+		StrategyInterpolated: func(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+			return interpolate(ctx, repo, opts)
+		},
+		StrategyNormalized: func(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+			opts.Query = normalize.SearchKey(opts.Query)
+			return repo.FindLocationsByNormalizedAddress(ctx, opts)
+		},
+		StrategyKana: func(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+			if !normalize.IsKana(opts.Query) {
+				return nil, nil
+			}
+			opts.Query = normalize.Kana(opts.Query)
+			return repo.FindLocationsByKana(ctx, opts)
+		},
+	}
+
+	seen := make(map[string]bool, len(names))
+	strategies := make([]SearchStrategy, 0, len(names))
+	for _, name := range names {
+		search, ok := searches[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown search strategy %q (available: %s, %s, %s, %s, %s, %s)", name, StrategyExact, StrategyFullText, StrategyFuzzy, StrategyInterpolated, StrategyNormalized, StrategyKana)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("search strategy %q is listed twice", name)
+		}
+		seen[name] = true
+		strategies = append(strategies, searchFunc{name: name, search: search})
+	}
+	return strategies, nil
+}
+
+// splitHouseNumber splits the trailing house number off an address, ignoring whitespace
+func splitHouseNumber(query string) (string, int, bool) {
+	m := houseNumberPattern.FindStringSubmatch(strings.Join(strings.Fields(query), ""))
+	if m == nil || m[1] == "" {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return m[1], number, true
+}
+
+// interpolate runs the StrategyInterpolated search. It finds at most one location, so later
+// pages are empty, as is a query without a house number or whose position is outside opts.BBox.
+func interpolate(ctx context.Context, repo StrategyRepository, opts models.SearchOptions) ([]models.Location, error) {
+	address, number, ok := splitHouseNumber(opts.Query)
+	if !ok || opts.Offset > 0 {
+		return nil, nil
+	}
+	location, err := repo.InterpolateAddress(ctx, address, number, opts.SRID)
+	if err != nil || location == nil {
+		return nil, err
+	}
+	if opts.BBox != nil && !opts.BBox.Contains(location.Latitude, location.Longitude) {
+		return nil, nil
+	}
+	return []models.Location{*location}, nil
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockStrategyRepository is a mock implementation of the StrategyRepository interface
+type MockStrategyRepository struct {
+	MockGeoCodeRepository
+}
+
+// FindLocationsByAddress implements StrategyRepository.
+func (m *MockStrategyRepository) FindLocationsByAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// SearchLocationsByTrigram implements StrategyRepository.
+func (m *MockStrategyRepository) SearchLocationsByTrigram(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindLocationsByNormalizedAddress implements StrategyRepository.
+func (m *MockStrategyRepository) FindLocationsByNormalizedAddress(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// FindLocationsByKana implements StrategyRepository.
+func (m *MockStrategyRepository) FindLocationsByKana(ctx context.Context, opts models.SearchOptions) ([]models.Location, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]models.Location), args.Error(1)
+}
+
+// InterpolateAddress implements StrategyRepository.
+func (m *MockStrategyRepository) InterpolateAddress(ctx context.Context, address string, number int, srid int) (*models.Location, error) {
+	args := m.Called(ctx, address, number, srid)
+	return args.Get(0).(*models.Location), args.Error(1)
+}
+
+func TestNewSearchStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		names       []string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "all in order",
+			names:    []string{StrategyExact, StrategyFullText, StrategyFuzzy},
+			expected: []string{StrategyExact, StrategyFullText, StrategyFuzzy},
+		},
+		{
+			name:     "reordered subset",
+			names:    []string{StrategyFuzzy, StrategyExact},
+			expected: []string{StrategyFuzzy, StrategyExact},
+		},
+		{
+			name:        "unknown strategy",
+			names:       []string{StrategyExact, "soundex"},
+			expectedErr: `unknown search strategy "soundex"`,
+		},
+		{
+			name:        "repeated strategy",
+			names:       []string{StrategyFullText, StrategyFullText},
+			expectedErr: `search strategy "fulltext" is listed twice`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategies, err := NewSearchStrategies(tt.names, new(MockStrategyRepository))
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, len(strategies))
+			for i, s := range strategies {
+				names[i] = s.Name()
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_Strategies(t *testing.T) {
+	exact := []models.Location{{ID: 1, Municipality: "千代田区"}}
+	fullText := []models.Location{{ID: 2, Municipality: "千代田区"}, {ID: 3, Municipality: "中央区"}}
+	fuzzy := []models.Location{{ID: 4, Municipality: "千代田区"}}
+
+	tests := []struct {
+		name             string
+		minResults       int
+		exact            []models.Location
+		fullText         []models.Location
+		fuzzy            []models.Location
+		expected         []models.Location
+		expectedStrategy string
+		skipFullText     bool
+		skipFuzzy        bool
+	}{
+		{
+			name:             "first strategy matches",
+			exact:            exact,
+			expected:         exact,
+			expectedStrategy: StrategyExact,
+			skipFullText:     true,
+			skipFuzzy:        true,
+		},
+		{
+			name:             "falls through to full-text",
+			exact:            []models.Location{},
+			fullText:         fullText,
+			expected:         fullText,
+			expectedStrategy: StrategyFullText,
+			skipFuzzy:        true,
+		},
+		{
+			name:             "falls through to fuzzy",
+			exact:            []models.Location{},
+			fullText:         []models.Location{},
+			fuzzy:            fuzzy,
+			expected:         fuzzy,
+			expectedStrategy: StrategyFuzzy,
+		},
+		{
+			name:             "too few results fall through",
+			minResults:       2,
+			exact:            exact,
+			fullText:         fullText,
+			expected:         fullText,
+			expectedStrategy: StrategyFullText,
+			skipFuzzy:        true,
+		},
+		{
+			name:             "last strategy's results when none finds enough",
+			minResults:       3,
+			exact:            exact,
+			fullText:         fullText,
+			fuzzy:            fuzzy,
+			expected:         fuzzy,
+			expectedStrategy: StrategyFuzzy,
+		},
+		{
+			name:     "nothing found",
+			exact:    []models.Location{},
+			fullText: []models.Location{},
+			fuzzy:    []models.Location{},
+			expected: []models.Location{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockStrategyRepository)
+			strategies, err := NewSearchStrategies([]string{StrategyExact, StrategyFullText, StrategyFuzzy}, mockRepo)
+			require.NoError(t, err)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies, MinStrategyResults: tt.minResults})
+
+			opts := models.SearchOptions{Query: "千代田区", Limit: models.DefaultSearchLimit}
+			mockRepo.On("FindLocationsByAddress", mock.Anything, opts).Return(tt.exact, nil)
+			if !tt.skipFullText {
+				mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return(tt.fullText, nil)
+			}
+			if !tt.skipFuzzy {
+				mockRepo.On("SearchLocationsByTrigram", mock.Anything, opts).Return(tt.fuzzy, nil)
+			}
+
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: "千代田区"})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Results)
+			assert.Equal(t, tt.expectedStrategy, result.Strategy)
+			mockRepo.AssertExpectations(t)
+			if tt.skipFullText {
+				mockRepo.AssertNotCalled(t, "SearchLocationsByText", mock.Anything, mock.Anything)
+			}
+			if tt.skipFuzzy {
+				mockRepo.AssertNotCalled(t, "SearchLocationsByTrigram", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_StrategyError(t *testing.T) {
+	mockRepo := new(MockStrategyRepository)
+	strategies, err := NewSearchStrategies([]string{StrategyExact, StrategyFullText}, mockRepo)
+	require.NoError(t, err)
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+	mockRepo.On("FindLocationsByAddress", mock.Anything, mock.Anything).Return([]models.Location(nil), assert.AnError)
+
+	result, err := service.Geocode(context.Background(), models.SearchOptions{Query: "千代田区"})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "(exact)")
+	mockRepo.AssertNotCalled(t, "SearchLocationsByText", mock.Anything, mock.Anything)
+}
+
+func TestGeoCodeService_Geocode_NoFuzzy(t *testing.T) {
+	mockRepo := new(MockStrategyRepository)
+	strategies, err := NewSearchStrategies([]string{StrategyFullText, StrategyFuzzy}, mockRepo)
+	require.NoError(t, err)
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+	opts := models.SearchOptions{Query: "千代田区丸の肉", Limit: models.DefaultSearchLimit, NoFuzzy: true}
+	mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return([]models.Location{}, nil)
+
+	result, err := service.Geocode(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Results)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "SearchLocationsByTrigram", mock.Anything, mock.Anything)
+}
+
+func TestGeoCodeService_Geocode_StrategyCursor(t *testing.T) {
+	mockRepo := new(MockStrategyRepository)
+	strategies, err := NewSearchStrategies([]string{StrategyExact, StrategyFullText}, mockRepo)
+	require.NoError(t, err)
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+	// A cursor continues a full-text search, so the exact strategy is skipped
+	after := &models.SearchCursor{Rank: 0.5, ID: 42}
+	opts := models.SearchOptions{Query: "千代田区", Limit: 1, After: after}
+	mockRepo.On("SearchLocationsByText", mock.Anything, opts).Return([]models.Location{{ID: 41, Score: 0.5}}, nil)
+
+	result, err := service.Geocode(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, StrategyFullText, result.Strategy)
+	assert.Equal(t, models.SearchCursor{Rank: 0.5, ID: 41}.Encode(), result.NextCursor)
+	mockRepo.AssertNotCalled(t, "FindLocationsByAddress", mock.Anything, mock.Anything)
+
+	// Pages of other strategies have no cursor
+	opts = models.SearchOptions{Query: "千代田区", Limit: 1}
+	mockRepo.On("FindLocationsByAddress", mock.Anything, opts).Return([]models.Location{{ID: 1}}, nil)
+
+	result, err = service.Geocode(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, StrategyExact, result.Strategy)
+	assert.Empty(t, result.NextCursor)
+}
+
+func TestSplitHouseNumber(t *testing.T) {
+	tests := []struct {
+		query           string
+		expectedAddress string
+		expectedNumber  int
+		expectedOK      bool
+	}{
+		{query: "東京都千代田区丸の内1-5", expectedAddress: "東京都千代田区丸の内1", expectedNumber: 5, expectedOK: true},
+		{query: "東京都千代田区丸の内一丁目12番地", expectedAddress: "東京都千代田区丸の内一丁目", expectedNumber: 12, expectedOK: true},
+		{query: "東京都 千代田区 丸の内 3", expectedAddress: "東京都千代田区丸の内", expectedNumber: 3, expectedOK: true},
+		{query: "東京都千代田区丸の内", expectedOK: false},
+		{query: "42", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			address, number, ok := splitHouseNumber(tt.query)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedAddress, address)
+			assert.Equal(t, tt.expectedNumber, number)
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_InterpolatedStrategy(t *testing.T) {
+	interpolated := &models.Location{Prefecture: "東京都", Municipality: "千代田区", Address1: "丸の内1", BlockLot: "5", Latitude: 35.6815, Longitude: 139.7665, Interpolated: true}
+
+	tests := []struct {
+		name             string
+		query            string
+		offset           int
+		bbox             *models.BoundingBox
+		mockLocation     *models.Location
+		expectLookup     bool
+		expected         []models.Location
+		expectedStrategy string
+	}{
+		{
+			name:             "interpolated when full-text finds nothing",
+			query:            "東京都千代田区丸の内1-5",
+			mockLocation:     interpolated,
+			expectLookup:     true,
+			expected:         []models.Location{*interpolated},
+			expectedStrategy: StrategyInterpolated,
+		},
+		{
+			name:         "no segment covers the number",
+			query:        "東京都千代田区丸の内1-5",
+			mockLocation: nil,
+			expectLookup: true,
+		},
+		{
+			name:             "inside the bounding box",
+			query:            "東京都千代田区丸の内1-5",
+			bbox:             &models.BoundingBox{MinLon: 139.7, MinLat: 35.6, MaxLon: 139.8, MaxLat: 35.7},
+			mockLocation:     interpolated,
+			expectLookup:     true,
+			expected:         []models.Location{*interpolated},
+			expectedStrategy: StrategyInterpolated,
+		},
+		{
+			name:         "outside the bounding box",
+			query:        "東京都千代田区丸の内1-5",
+			bbox:         &models.BoundingBox{MinLon: 135.4, MinLat: 34.6, MaxLon: 135.6, MaxLat: 34.8},
+			mockLocation: interpolated,
+			expectLookup: true,
+		},
+		{
+			name:  "query without a house number",
+			query: "東京都千代田区丸の内",
+		},
+		{
+			name:   "later page",
+			query:  "東京都千代田区丸の内1-5",
+			offset: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockStrategyRepository)
+			strategies, err := NewSearchStrategies([]string{StrategyFullText, StrategyInterpolated}, mockRepo)
+			require.NoError(t, err)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+			mockRepo.On("SearchLocationsByText", mock.Anything, mock.Anything).Return([]models.Location{}, nil)
+			if tt.expectLookup {
+				mockRepo.On("InterpolateAddress", mock.Anything, "東京都千代田区丸の内1", 5, 0).Return(tt.mockLocation, nil)
+			}
+
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: tt.query, Offset: tt.offset, BBox: tt.bbox})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Results)
+			assert.Equal(t, tt.expectedStrategy, result.Strategy)
+			mockRepo.AssertExpectations(t)
+			if !tt.expectLookup {
+				mockRepo.AssertNotCalled(t, "InterpolateAddress", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGeoCodeService_Geocode_NormalizedStrategy(t *testing.T) {
+	mockRepo := new(MockStrategyRepository)
+	strategies, err := NewSearchStrategies([]string{StrategyNormalized}, mockRepo)
+	require.NoError(t, err)
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+	// The repository is queried with the search key the importer stored, not the raw query
+	expected := []models.Location{{ID: 1, Municipality: "千代田区"}}
+	opts := models.SearchOptions{Query: "東京都千代田区丸の内1-9-1", Limit: 10}
+	mockRepo.On("FindLocationsByNormalizedAddress", mock.Anything, opts).Return(expected, nil)
+
+	result, err := service.Geocode(context.Background(), models.SearchOptions{Query: "東京都 千代田区 丸の内一丁目９番１号", Limit: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, expected, result.Results)
+	assert.Equal(t, StrategyNormalized, result.Strategy)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGeoCodeService_Geocode_KanaStrategy(t *testing.T) {
+	fullText := []models.Location{{ID: 2, Municipality: "千代田区"}}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedKana     string
+		mockKana         []models.Location
+		expected         []models.Location
+		expectedStrategy string
+	}{
+		{
+			name:             "hiragana reading searched as its katakana key",
+			query:            "ちよだく まるのうち",
+			expectedKana:     "チヨダクマルノウチ",
+			mockKana:         []models.Location{{ID: 1, Municipality: "千代田区", MunicipalityKana: "チヨダク", Address1Kana: "マルノウチ"}},
+			expected:         []models.Location{{ID: 1, Municipality: "千代田区", MunicipalityKana: "チヨダク", Address1Kana: "マルノウチ"}},
+			expectedStrategy: StrategyKana,
+		},
+		{
+			name:             "half-width katakana without matches falls through",
+			query:            "ﾏﾙﾉｳﾁ",
+			expectedKana:     "マルノウチ",
+			mockKana:         []models.Location{},
+			expected:         fullText,
+			expectedStrategy: StrategyFullText,
+		},
+		{
+			name:             "kanji query skips the kana search",
+			query:            "丸の内",
+			expected:         fullText,
+			expectedStrategy: StrategyFullText,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockStrategyRepository)
+			strategies, err := NewSearchStrategies([]string{StrategyKana, StrategyFullText}, mockRepo)
+			require.NoError(t, err)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{Strategies: strategies})
+
+			if tt.expectedKana != "" {
+				mockRepo.On("FindLocationsByKana", mock.Anything, models.SearchOptions{Query: tt.expectedKana, Limit: 10}).Return(tt.mockKana, nil)
+			}
+			if tt.expectedStrategy == StrategyFullText {
+				mockRepo.On("SearchLocationsByText", mock.Anything, models.SearchOptions{Query: tt.query, Limit: 10}).Return(fullText, nil)
+			}
+
+			result, err := service.Geocode(context.Background(), models.SearchOptions{Query: tt.query, Limit: 10})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Results)
+			assert.Equal(t, tt.expectedStrategy, result.Strategy)
+			mockRepo.AssertExpectations(t)
+			if tt.expectedKana == "" {
+				mockRepo.AssertNotCalled(t, "FindLocationsByKana", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"geocoding-api/internal/models"
+)
+
+// MaxSnapshotResults is the number of ranked results a pagination snapshot holds; pages past it
+// are searched live
+const MaxSnapshotResults = 1000
+
+// snapshotKey identifies the pagination session of opts: every option except the page window
+func snapshotKey(opts models.SearchOptions) string {
+	opts.Offset, opts.Limit, opts.MaxLimit = 0, MaxSnapshotResults, 0
+	return opts.CacheKey()
+}
+
+// searchPage returns the page of results opts asks for. With pagination snapshots enabled, an
+// offset page is cut from a ranked snapshot of the query's first MaxSnapshotResults results,
+// taken by the first page requested and kept for the snapshot TTL. Every page of a session
+// then comes from the same ranking, so rows imported or rescored in between can't make pages
+// overlap or skip rows. Cursor pages are already stable and always run live, as do pages past
+// the snapshot.
+func (s *GeoCodeService) searchPage(ctx context.Context, opts models.SearchOptions) ([]models.Location, string, error) {
+	if s.snapshots == nil || opts.After != nil {
+		return s.search(ctx, opts)
+	}
+
+	key := snapshotKey(opts)
+	snapshot, ok := s.snapshots.Get(ctx, key)
+	if !ok {
+		full := opts
+		full.Offset, full.Limit = 0, MaxSnapshotResults
+		locations, strategy, err := s.search(ctx, full)
+		if err != nil {
+			return nil, "", err
+		}
+		snapshot = &models.GeocodeResult{Results: locations, Strategy: strategy}
+		s.snapshots.Set(ctx, key, snapshot)
+	}
+
+	end := opts.Offset + opts.Limit
+	if end > len(snapshot.Results) && len(snapshot.Results) == MaxSnapshotResults {
+		return s.search(ctx, opts)
+	}
+	if opts.Offset >= len(snapshot.Results) {
+		return []models.Location{}, snapshot.Strategy, nil
+	}
+	// Copy the page, results get their bounding boxes attached in place
+	page := append([]models.Location(nil), snapshot.Results[opts.Offset:min(end, len(snapshot.Results))]...)
+	return page, snapshot.Strategy, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// rankedLocations returns n locations with IDs 1..n, best first
+func rankedLocations(n int) []models.Location {
+	locations := make([]models.Location, n)
+	for i := range locations {
+		locations[i] = models.Location{ID: i + 1, Score: float64(n - i)}
+	}
+	return locations
+}
+
+func TestGeoCodeService_Geocode_PaginationSnapshot(t *testing.T) {
+	mockRepo := new(MockGeoCodeRepository)
+	service := NewGeoCodeService(mockRepo, GeoCodeConfig{SnapshotTTL: time.Minute, SnapshotSize: 10})
+
+	// The first page takes the snapshot; later pages are cut from it even though the data has
+	// since changed, so they neither repeat nor skip a row
+	snapshot := models.SearchOptions{Query: "東京都", Limit: MaxSnapshotResults}
+	mockRepo.On("SearchLocationsByText", mock.Anything, snapshot).Return(rankedLocations(25), nil).Once()
+
+	first, err := service.Geocode(context.Background(), models.SearchOptions{Query: "東京都"})
+	require.NoError(t, err)
+	second, err := service.Geocode(context.Background(), models.SearchOptions{Query: "東京都", Offset: 10})
+	require.NoError(t, err)
+	last, err := service.Geocode(context.Background(), models.SearchOptions{Query: "東京都", Offset: 20})
+	require.NoError(t, err)
+	past, err := service.Geocode(context.Background(), models.SearchOptions{Query: "東京都", Offset: 30})
+	require.NoError(t, err)
+
+	assert.Equal(t, rankedLocations(25)[:10], first.Results)
+	assert.Equal(t, rankedLocations(25)[10:20], second.Results)
+	assert.Equal(t, rankedLocations(25)[20:], last.Results)
+	assert.Empty(t, past.Results)
+	assert.Equal(t, StrategyFullText, second.Strategy)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGeoCodeService_Geocode_PaginationSnapshotLive(t *testing.T) {
+	tests := []struct {
+		name string
+		opts models.SearchOptions
+	}{
+		{
+			name: "cursor page",
+			opts: models.SearchOptions{Query: "東京都", After: &models.SearchCursor{Rank: 0.5, ID: 42}},
+		},
+		{
+			name: "page past the snapshot",
+			opts: models.SearchOptions{Query: "東京都", Offset: MaxSnapshotResults - 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockGeoCodeRepository)
+			service := NewGeoCodeService(mockRepo, GeoCodeConfig{SnapshotTTL: time.Minute, SnapshotSize: 10})
+
+			mockRepo.On("SearchLocationsByText", mock.Anything, models.SearchOptions{Query: "東京都", Limit: MaxSnapshotResults}).
+				Return(rankedLocations(MaxSnapshotResults), nil).Maybe()
+			live := []models.Location{{ID: 5000}}
+			mockRepo.On("SearchLocationsByText", mock.Anything, tt.opts.WithDefaults()).Return(live, nil).Once()
+
+			result, err := service.Geocode(context.Background(), tt.opts)
+
+			require.NoError(t, err)
+			assert.Equal(t, live, result.Results)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package stats keeps the in-process counters behind the admin runtime stats endpoint, a quick
+// operational snapshot of one instance that needs no metrics stack.
+package stats
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/models"
+)
+
+// Counters are the runtime counters of one API instance. They are safe for concurrent use, and
+// every method is a no-op on a nil *Counters, so components can be built without stats.
+type Counters struct {
+	started time.Time
+
+	requestsInFlight atomic.Int64
+	requestsTotal    atomic.Int64
+	clientErrors     atomic.Int64
+	serverErrors     atomic.Int64
+
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	breaker *breaker.Breaker
+}
+
+// New creates counters starting from zero now
+func New() *Counters {
+	return &Counters{started: time.Now()}
+}
+
+// RequestStarted counts a request that is being handled
+func (c *Counters) RequestStarted() {
+	if c == nil {
+		return
+	}
+	c.requestsInFlight.Add(1)
+}
+
+// RequestFinished counts a request answered with status, ending the RequestStarted before it
+func (c *Counters) RequestFinished(status int) {
+	if c == nil {
+		return
+	}
+	c.requestsInFlight.Add(-1)
+	c.requestsTotal.Add(1)
+	switch {
+	case status >= 500:
+		c.serverErrors.Add(1)
+	case status >= 400:
+		c.clientErrors.Add(1)
+	}
+}
+
+// CacheHit counts a result served from the geocode cache
+func (c *Counters) CacheHit() {
+	if c == nil {
+		return
+	}
+	c.cacheHits.Add(1)
+}
+
+// CacheMiss counts a geocode cache lookup that fell through to the database
+func (c *Counters) CacheMiss() {
+	if c == nil {
+		return
+	}
+	c.cacheMisses.Add(1)
+}
+
+// SetBreaker reports the state of the database circuit breaker b in snapshots. Call it before
+// the counters are shared; nil leaves the breaker out.
+func (c *Counters) SetBreaker(b *breaker.Breaker) {
+	if c == nil {
+		return
+	}
+	c.breaker = b
+}
+
+// Snapshot returns the current counts. Each counter is read atomically, but not all of them at
+// the same instant, so counts updated concurrently may be off by the requests in flight.
+func (c *Counters) Snapshot() models.RuntimeStats {
+	snapshot := models.RuntimeStats{
+		StartedAt:     c.started,
+		UptimeSeconds: time.Since(c.started).Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		Requests: models.RequestStats{
+			InFlight:     c.requestsInFlight.Load(),
+			Total:        c.requestsTotal.Load(),
+			ClientErrors: c.clientErrors.Load(),
+			ServerErrors: c.serverErrors.Load(),
+		},
+		Cache: models.CacheStats{
+			Hits:   c.cacheHits.Load(),
+			Misses: c.cacheMisses.Load(),
+		},
+	}
+	if lookups := snapshot.Cache.Hits + snapshot.Cache.Misses; lookups > 0 {
+		snapshot.Cache.HitRatio = float64(snapshot.Cache.Hits) / float64(lookups)
+	}
+	if c.breaker != nil {
+		opens, rejected := c.breaker.Counts()
+		snapshot.DatabaseBreaker = &models.BreakerStats{State: c.breaker.State().String(), Opens: opens, Rejected: rejected}
+	}
+	return snapshot
+}
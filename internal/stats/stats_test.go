@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"geocoding-api/internal/breaker"
+	"geocoding-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounters(t *testing.T) {
+	c := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.RequestStarted()
+			if i%4 == 0 {
+				c.CacheHit()
+			} else {
+				c.CacheMiss()
+			}
+			status := http.StatusOK
+			switch {
+			case i%10 == 0:
+				status = http.StatusInternalServerError
+			case i%5 == 0:
+				status = http.StatusBadRequest
+			}
+			c.RequestFinished(status)
+		}(i)
+	}
+	wg.Wait()
+	c.RequestStarted()
+
+	snapshot := c.Snapshot()
+
+	assert.Equal(t, int64(1), snapshot.Requests.InFlight)
+	assert.Equal(t, int64(100), snapshot.Requests.Total)
+	assert.Equal(t, int64(10), snapshot.Requests.ClientErrors)
+	assert.Equal(t, int64(10), snapshot.Requests.ServerErrors)
+	assert.Equal(t, int64(25), snapshot.Cache.Hits)
+	assert.Equal(t, int64(75), snapshot.Cache.Misses)
+	assert.Equal(t, 0.25, snapshot.Cache.HitRatio)
+	assert.Positive(t, snapshot.Goroutines)
+	assert.False(t, snapshot.StartedAt.IsZero())
+}
+
+func TestCounters_Nil(t *testing.T) {
+	var c *Counters
+
+	// Components built without stats can call every method
+	assert.NotPanics(t, func() {
+		c.RequestStarted()
+		c.RequestFinished(http.StatusOK)
+		c.CacheHit()
+		c.CacheMiss()
+	})
+}
+
+func TestCounters_NoLookups(t *testing.T) {
+	assert.Zero(t, New().Snapshot().Cache.HitRatio)
+}
+
+func TestCounters_Breaker(t *testing.T) {
+	c := New()
+	assert.Nil(t, c.Snapshot().DatabaseBreaker)
+
+	b := breaker.New(breaker.Config{FailureThreshold: 1, Cooldown: time.Hour})
+	c.SetBreaker(b)
+	require.NoError(t, b.Allow())
+	b.Done(true)
+	assert.ErrorIs(t, b.Allow(), breaker.ErrOpen)
+
+	assert.Equal(t, &models.BreakerStats{State: "open", Opens: 1, Rejected: 1}, c.Snapshot().DatabaseBreaker)
+}
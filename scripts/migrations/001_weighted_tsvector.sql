@@ -0,0 +1,24 @@
+-- Migration: weight full_address_tsvector components for ranking
+--
+-- Existing databases created before weighted ranking have an unweighted
+-- full_address_tsvector. A generated column's expression can't be altered in
+-- place, so it is dropped and re-added, which rewrites the table and rebuilds
+-- the GIN index. Run during a maintenance window on large tables.
+--
+-- Use the same text search configuration as the rest of the deployment
+-- ('japanese' for the importer defaults, 'simple' for scripts/setup-db.sql).
+
+BEGIN;
+
+DROP INDEX IF EXISTS locations_full_address_tsvector_idx;
+ALTER TABLE locations DROP COLUMN IF EXISTS full_address_tsvector;
+
+ALTER TABLE locations ADD COLUMN full_address_tsvector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('japanese', coalesce(municipality, '')), 'A') ||
+    setweight(to_tsvector('japanese', coalesce(prefecture, '')), 'B') ||
+    setweight(to_tsvector('japanese', coalesce(address_1, '') || ' ' || coalesce(address_2, '')), 'C')
+) STORED;
+
+CREATE INDEX locations_full_address_tsvector_idx ON locations USING GIN (full_address_tsvector);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration: tag locations with the file they were imported from
+--
+-- The importer records each row's source file so its integrity check can
+-- compare per-file row counts. Rows imported before this migration keep a
+-- NULL source_file. Adding a nullable column without a default doesn't
+-- rewrite the table. The importer also adds the column itself if missing.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS source_file TEXT;
@@ -0,0 +1,8 @@
+-- Migration: index locations by administrative area
+--
+-- Browsing by area (/locations/in) and municipality bounding boxes
+-- (include_bbox on /geocode) filter on prefecture and municipality. Without
+-- this index each request scans the whole table. CONCURRENTLY avoids blocking
+-- writes while it builds, so this must run outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_area_idx ON locations (prefecture, municipality);
@@ -0,0 +1,23 @@
+-- Migration: persist asynchronous batch geocoding jobs
+--
+-- POST /geocode/batch stores each job here and API workers claim queued jobs
+-- in creation order. A job moves from queued to running when claimed and
+-- ends as succeeded (results filled in) or failed (error set). updated_at is
+-- refreshed as a running job makes progress, so a job whose worker died is
+-- claimed again once it goes stale.
+
+CREATE TABLE IF NOT EXISTS geocode_jobs (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    addresses JSONB NOT NULL,
+    results JSONB,
+    error TEXT,
+    total INTEGER NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS geocode_jobs_pending_idx ON geocode_jobs (created_at) WHERE status IN ('queued', 'running');
@@ -0,0 +1,11 @@
+-- Migration: stable external IDs for imported addresses
+--
+-- Some datasets carry their own ID for each address. The importer stores it
+-- (--external-id-column) and upserts on it, so re-importing a dataset updates
+-- its rows in place instead of duplicating them, and other systems can refer
+-- to an address by the ID they already know. Rows from datasets without IDs
+-- keep a NULL external_id, which the unique index doesn't constrain.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS locations_external_id_idx ON locations (external_id);
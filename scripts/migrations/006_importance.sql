@@ -0,0 +1,12 @@
+-- Migration: importance ranking for ambiguous place names
+--
+-- Common district names (中央, 本町, ...) exist all over the country, and clients
+-- usually want the most prominent match first. The importer stores a per-address
+-- importance from a source column (--importance-column), typically the population
+-- of the address's area; /geocode?order_by=importance ranks by it and breaks ties
+-- with ts_rank. Nothing is derived when the source has no such column: rows keep a
+-- NULL importance and are ranked after every row that has one, by ts_rank alone.
+--
+-- Sorting only touches the rows that matched the text search, so no index is needed.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS importance REAL;
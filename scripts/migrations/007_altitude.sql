@@ -0,0 +1,10 @@
+-- Migration: elevation for 3D-aware datasets
+--
+-- Some datasets include the elevation of each address. The importer stores it from
+-- a source column (--altitude-column) and the API returns it as altitude on every
+-- location, omitting it when NULL. geom stays GEOGRAPHY(POINT, 4326): a typed
+-- POINT column rejects Z coordinates, and a POINTZ column would reject every 2D
+-- row, so the elevation is kept in its own column instead. Rows from datasets
+-- without one keep a NULL altitude and are unaffected.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS altitude REAL;
@@ -0,0 +1,32 @@
+-- Migration: address range segments for house number interpolation
+--
+-- Street data often gives the range of house numbers along each segment rather
+-- than a point per address. Storing those segments lets the API estimate the
+-- position of a number that has no address point: the geocode "interpolated"
+-- strategy (listed in GEOCODE_STRATEGIES) splits the trailing house number off
+-- the query, finds the narrowest segment of the remaining address whose range
+-- contains it and places it proportionally along the line with
+-- ST_LineInterpolatePoint. With ADDRESS_RANGES enabled, reverse geocoding falls
+-- back to the nearest segment within the search radius when no address point
+-- is in range, estimating the number from the point's position along it
+-- (ST_LineLocatePoint). Address points always take priority, and interpolated
+-- results carry "interpolated": true and no location ID.
+--
+-- from_number is at the start of the line and to_number at its end; ranges may
+-- descend, and a segment with a single number places it at the midpoint. The
+-- address columns must be normalized like locations (ADDRESS_NORMALIZATION).
+
+CREATE TABLE IF NOT EXISTS address_segments (
+    id BIGSERIAL PRIMARY KEY,
+    prefecture VARCHAR(255) NOT NULL,
+    municipality VARCHAR(255) NOT NULL,
+    address_1 VARCHAR(255) NOT NULL,
+    address_2 VARCHAR(255) NOT NULL DEFAULT '',
+    from_number INTEGER NOT NULL,
+    to_number INTEGER NOT NULL,
+    geom GEOGRAPHY(LINESTRING, 4326) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS address_segments_geom_idx ON address_segments USING GIST (geom);
+
+CREATE INDEX IF NOT EXISTS address_segments_address_idx ON address_segments ((prefecture || municipality || address_1 || address_2));
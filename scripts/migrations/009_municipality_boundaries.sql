@@ -0,0 +1,25 @@
+-- Migration: municipality boundary polygons for reverse geocoding
+--
+-- The nearest address point is not always in the municipality the queried
+-- point lies in: near a boundary, the closest point can be just across it.
+-- With MUNICIPALITY_BOUNDARIES enabled, /reverse-geocode?prefer=admin resolves
+-- the point against these polygons first. Among the addresses within the
+-- search radius, it returns the nearest one whose prefecture and municipality
+-- match the polygon containing the point (ST_Contains). It falls back to the
+-- plain nearest address when no polygon contains the point or no address of
+-- that municipality is in range. prefer=nearest, the default, ignores the
+-- table.
+--
+-- prefecture and municipality must be spelled exactly as in locations. The
+-- table is loaded from boundary data outside the importer, e.g. the national
+-- administrative area polygons with ogr2ogr, dissolved to one row per
+-- municipality.
+
+CREATE TABLE IF NOT EXISTS municipality_boundaries (
+    id BIGSERIAL PRIMARY KEY,
+    prefecture VARCHAR(255) NOT NULL,
+    municipality VARCHAR(255) NOT NULL,
+    geom GEOMETRY(MULTIPOLYGON, 4326) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS municipality_boundaries_geom_idx ON municipality_boundaries USING GIST (geom);
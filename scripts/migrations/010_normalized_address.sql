@@ -0,0 +1,20 @@
+-- Migration: normalized search key
+--
+-- The importer's --normalized-key stores each address's search key, computed by
+-- the shared normalize package (NFKC, numeral and dash canonicalization,
+-- building names dropped, whitespace removed), in normalized_address. The
+-- "normalized" geocode strategy (GEOCODE_STRATEGIES) applies the same function
+-- to the query and looks the key up in this index. Matching no longer depends on
+-- how the text search configuration tokenizes the address.
+--
+-- The keys are computed by the importer, not by Postgres, so they reflect the
+-- normalization rules of the importer that wrote them. After the rules change,
+-- reimport the data with --normalized-key (or recompute the column) before
+-- deploying an API with the new rules, or queries stop matching the stored keys.
+-- Existing rows have no key until then and are never found by the strategy.
+-- CONCURRENTLY avoids blocking writes while the index builds, so this must run
+-- outside a transaction.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS normalized_address TEXT;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_normalized_address_idx ON locations (normalized_address);
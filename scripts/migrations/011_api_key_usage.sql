@@ -0,0 +1,13 @@
+-- Migration: count requests per API key for API_KEYS quotas
+--
+-- Each API instance adds every request a key makes to the row of its UTC
+-- day, so all instances enforce the same daily and monthly quotas. Keys are
+-- stored as the SHA-256 of the key, never the key itself. Rows of past months
+-- are no longer read and can be deleted.
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
@@ -0,0 +1,22 @@
+-- Migration: kana readings
+--
+-- Some datasets carry the kana reading (furigana) of each address part. The importer
+-- stores them from the columns named like the address columns plus "_カナ" (e.g.
+-- 市区町村名_カナ) when a file has them, and the API returns them as prefecture_kana,
+-- municipality_kana, address1_kana and address2_kana, omitting them when NULL.
+--
+-- kana_key holds the readings joined and reduced by the shared normalize package
+-- (normalize.Kana: katakana, no whitespace), which the "kana" geocode strategy
+-- (GEOCODE_STRATEGIES) matches queries written in kana against. The trigram index
+-- serves its substring matches, so a query may name any part of the reading.
+-- Existing rows have no readings until reimported and are never found by the
+-- strategy. CONCURRENTLY avoids blocking writes while the index builds, so this
+-- must run outside a transaction.
+
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS prefecture_kana TEXT;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS municipality_kana TEXT;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS address_1_kana TEXT;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS address_2_kana TEXT;
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS kana_key TEXT;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_kana_key_idx ON locations USING GIN (kana_key gin_trgm_ops);
@@ -0,0 +1,13 @@
+-- Migration: trigram index on the full address
+--
+-- The "fuzzy" geocode strategy (GEOCODE_STRATEGIES, e.g. "fulltext,fuzzy" to fall back
+-- to it when full-text search finds nothing) matches the query against the
+-- concatenated address by trigram similarity, catching typos and partial tokens.
+-- Without this index every fuzzy search scans the whole table. The indexed
+-- expression must stay identical to repository.FullAddress for the planner to use
+-- it. CONCURRENTLY avoids blocking writes while the index builds, so this must run
+-- outside a transaction.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_full_address_trgm_idx ON locations USING GIN ((prefecture || municipality || address_1 || address_2) gin_trgm_ops);
@@ -0,0 +1,16 @@
+-- Migration: prefix indexes for autocomplete
+--
+-- GET /autocomplete matches the typed text as a prefix of the full address down to
+-- the town (prefecture, municipality and first address part), of the address without
+-- the prefecture, or of the first address part alone, and suggests municipalities the
+-- same way. A text_pattern_ops B-tree index on each of these expressions turns the
+-- LIKE 'prefix%' matches into index range scans; without them every keystroke scans
+-- the whole table. The indexed expressions must stay identical to the
+-- repository.Suggest* constants for the planner to use them. CONCURRENTLY avoids
+-- blocking writes while the indexes build, so this must run outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_suggest_full_town_idx ON locations ((prefecture || municipality || address_1) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_suggest_municipality_town_idx ON locations ((municipality || address_1) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_suggest_town_idx ON locations (address_1 text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_suggest_full_municipality_idx ON locations ((prefecture || municipality) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS locations_suggest_municipality_idx ON locations (municipality text_pattern_ops);
@@ -0,0 +1,32 @@
+-- Migration: altitude as the Z coordinate of geom
+--
+-- Migration 007 kept the elevation in its own altitude column next to a 2D geom.
+-- The API now reads it as ST_Z(geom), from a GEOGRAPHY(POINTZ, 4326) column, and the
+-- importer creates that column for tables imported with --altitude-column. This moves
+-- any stored altitude into geom and drops the column. A POINTZ column rejects 2D
+-- points, so a table where only some rows have an altitude can't be converted: the
+-- migration fails instead, and such a table has to be reimported with an altitude on
+-- every row, or without --altitude-column to stay 2D. A table without any altitude
+-- keeps its 2D geom.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'locations' AND column_name = 'altitude'
+    ) THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM locations WHERE altitude IS NOT NULL) THEN
+        IF EXISTS (SELECT 1 FROM locations WHERE altitude IS NULL) THEN
+            RAISE EXCEPTION 'locations mixes rows with and without altitude, which a POINTZ geom cannot store; reimport it';
+        END IF;
+
+        ALTER TABLE locations ALTER COLUMN geom TYPE GEOGRAPHY(POINTZ, 4326)
+            USING ST_SetSRID(ST_MakePoint(ST_X(geom::geometry), ST_Y(geom::geometry), altitude), 4326)::geography;
+    END IF;
+
+    ALTER TABLE locations DROP COLUMN altitude;
+END
+$$;
@@ -24,11 +24,40 @@ CREATE TABLE IF NOT EXISTS locations (
     address_1 VARCHAR(255),
     address_2 VARCHAR(255),
     block_lot VARCHAR(255),
-    -- Full-text search vector
+    -- Stable ID from the source dataset, NULL for datasets without one; the importer upserts on it
+    external_id TEXT,
+    -- CSV/TSV file the row was imported from, used by the importer's integrity check
+    source_file TEXT,
+    -- Prominence of the address, e.g. the population of its area, from the importer's
+    -- --importance-column; /geocode?order_by=importance ranks by it, NULLs last
+    importance REAL,
+    -- Search key of the full address (normalize.SearchKey) from the importer's --normalized-key,
+    -- matched by the "normalized" geocode strategy; NULL for rows imported without the flag
+    normalized_address TEXT,
+    -- Kana readings of the address parts, from the importer's "_カナ" columns; NULL for datasets without them
+    prefecture_kana TEXT,
+    municipality_kana TEXT,
+    address_1_kana TEXT,
+    address_2_kana TEXT,
+    -- Readings joined and reduced by normalize.Kana, matched by the "kana" geocode strategy
+    kana_key TEXT,
+    -- Prefecture, municipality and street-level address normalized by the importer, tab-separated;
+    -- only used by tables the importer created with --app-search-text, NULL otherwise
+    search_text TEXT,
+    -- Full-text search vector, weighted so municipality (A) and prefecture (B) matches
+    -- outrank street-level address (C) matches in ts_rank. A table created by the importer with
+    -- --app-search-text generates it from the parts of search_text instead, e.g.
+    -- split_part(coalesce(search_text, ''), E'\t', 2) for the municipality, so normalization is
+    -- done in Go and doesn't depend on the text search configuration; changing the importer's
+    -- normalization rules then requires a reimport.
     full_address_tsvector TSVECTOR GENERATED ALWAYS AS (
-        to_tsvector('simple', prefecture || ' ' || municipality || ' ' || address_1 || ' ' || address_2)
+        setweight(to_tsvector('simple', coalesce(municipality, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(prefecture, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(address_1, '') || ' ' || coalesce(address_2, '')), 'C')
     ) STORED,
-    -- PostGIS geography column for spatial queries (SRID 4326 = WGS84)
+    -- PostGIS geography column for spatial queries (SRID 4326 = WGS84). A table the importer
+    -- creates with --altitude-column stores GEOGRAPHY(POINTZ, 4326) instead, the Z being the
+    -- elevation in meters that the API returns as altitude
     geom GEOGRAPHY(POINT, 4326)
 );
 
@@ -38,6 +67,41 @@ CREATE INDEX IF NOT EXISTS locations_geom_idx ON locations USING GIST (geom);
 -- Create GIN index for full-text search
 CREATE INDEX IF NOT EXISTS locations_full_address_tsvector_idx ON locations USING GIN (full_address_tsvector);
 
+-- Create B-tree index for administrative area lookups (browse by area, municipality extents)
+CREATE INDEX IF NOT EXISTS locations_area_idx ON locations (prefecture, municipality);
+
+-- Create B-tree index for the "normalized" strategy's search key lookups
+CREATE INDEX IF NOT EXISTS locations_normalized_address_idx ON locations (normalized_address);
+
+-- Create trigram GIN index for the "kana" strategy's substring matches of the reading
+CREATE INDEX IF NOT EXISTS locations_kana_key_idx ON locations USING GIN (kana_key gin_trgm_ops);
+
+-- Create trigram GIN index for the "fuzzy" strategy's similarity matches of the full address
+CREATE INDEX IF NOT EXISTS locations_full_address_trgm_idx ON locations USING GIN ((prefecture || municipality || address_1 || address_2) gin_trgm_ops);
+
+-- Create B-tree indexes for the autocomplete prefix matches, one per matched expression
+CREATE INDEX IF NOT EXISTS locations_suggest_full_town_idx ON locations ((prefecture || municipality || address_1) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS locations_suggest_municipality_town_idx ON locations ((municipality || address_1) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS locations_suggest_town_idx ON locations (address_1 text_pattern_ops);
+CREATE INDEX IF NOT EXISTS locations_suggest_full_municipality_idx ON locations ((prefecture || municipality) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS locations_suggest_municipality_idx ON locations (municipality text_pattern_ops);
+
+-- Create unique index for external ID upserts and lookups
+CREATE UNIQUE INDEX IF NOT EXISTS locations_external_id_idx ON locations (external_id);
+
+-- Partitioning by prefecture
+--
+-- For very large datasets the importer can create this table partitioned by
+-- LIST (prefecture) instead (--partition-by-prefecture, new tables only): one
+-- partition per prefecture named after its JIS code (locations_p13 for 東京都),
+-- added as each prefecture first appears, plus locations_default for anything
+-- else. The indexes above are defined on the parent and built on every
+-- partition. Postgres requires unique constraints to include the partition key,
+-- so such a table's primary key is (id, prefecture) and its external ID index
+-- is (external_id, prefecture): an address re-imported under another prefecture
+-- is added as a new row. Load it without --swap, whose staging table is not
+-- partitioned.
+
 -- Create processed_files table for tracking imported CSV files
 CREATE TABLE IF NOT EXISTS processed_files (
     id BIGSERIAL PRIMARY KEY,
@@ -47,4 +111,73 @@ CREATE TABLE IF NOT EXISTS processed_files (
 );
 
 -- Create index on file_path for faster lookups
-CREATE INDEX IF NOT EXISTS processed_files_path_idx ON processed_files (file_path);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS processed_files_path_idx ON processed_files (file_path);
+-- Create geocode_jobs table for asynchronous batch geocoding (POST /geocode/batch).
+-- Jobs move from queued to running when a worker claims them and end as succeeded or failed.
+CREATE TABLE IF NOT EXISTS geocode_jobs (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    addresses JSONB NOT NULL,
+    results JSONB,
+    error TEXT,
+    total INTEGER NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- Refreshed as a running job makes progress; a stale running job is claimed again
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create partial index so workers find unfinished jobs without scanning finished ones
+CREATE INDEX IF NOT EXISTS geocode_jobs_pending_idx ON geocode_jobs (created_at) WHERE status IN ('queued', 'running');
+
+-- Create api_key_usage table for API_KEYS quotas: the requests each key (stored as its
+-- SHA-256) made per UTC day. A key's monthly usage is the sum of its days in the month.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
+
+-- Create address_segments table for interpolating house numbers along street segments.
+-- Each segment covers the house numbers from_number..to_number of one address (the same
+-- prefecture/municipality/address_1/address_2 split as locations, normalized the same way),
+-- running from from_number at the line's start to to_number at its end; the numbers may
+-- descend. The geocode "interpolated" strategy (GEOCODE_STRATEGIES) places a queried number
+-- along its segment with ST_LineInterpolatePoint, and with ADDRESS_RANGES reverse geocoding
+-- falls back to the nearest segment when no address point is in range. Interpolated results
+-- are estimates: they have no location ID and are marked "interpolated": true. The table is
+-- loaded from street data outside the importer, e.g. with ogr2ogr.
+CREATE TABLE IF NOT EXISTS address_segments (
+    id BIGSERIAL PRIMARY KEY,
+    prefecture VARCHAR(255) NOT NULL,
+    municipality VARCHAR(255) NOT NULL,
+    address_1 VARCHAR(255) NOT NULL,
+    address_2 VARCHAR(255) NOT NULL DEFAULT '',
+    from_number INTEGER NOT NULL,
+    to_number INTEGER NOT NULL,
+    geom GEOGRAPHY(LINESTRING, 4326) NOT NULL
+);
+
+-- Create GIST index for nearest segment searches
+CREATE INDEX IF NOT EXISTS address_segments_geom_idx ON address_segments USING GIST (geom);
+
+-- Create B-tree index for looking up an address's segments
+CREATE INDEX IF NOT EXISTS address_segments_address_idx ON address_segments ((prefecture || municipality || address_1 || address_2));
+
+-- Create municipality_boundaries table holding each municipality's polygon, spelled exactly as
+-- in locations. With MUNICIPALITY_BOUNDARIES, /reverse-geocode?prefer=admin returns the nearest
+-- address in range from the municipality whose polygon contains the point, falling back to the
+-- plain nearest address when none does. The table is loaded from boundary data outside the
+-- importer, e.g. with ogr2ogr.
+CREATE TABLE IF NOT EXISTS municipality_boundaries (
+    id BIGSERIAL PRIMARY KEY,
+    prefecture VARCHAR(255) NOT NULL,
+    municipality VARCHAR(255) NOT NULL,
+    geom GEOMETRY(MULTIPOLYGON, 4326) NOT NULL
+);
+
+-- Create GIST index for finding the polygon containing a point
+CREATE INDEX IF NOT EXISTS municipality_boundaries_geom_idx ON municipality_boundaries USING GIST (geom);